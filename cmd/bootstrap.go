@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+var bootstrapInstall bool
+
+// userHomeDir locates rc files. Declared as a var so tests can use a temp home.
+var userHomeDir = os.UserHomeDir
+
+// Markers delimit the installed snippet, so --install can tell it is already
+// there and users can find (and remove) it.
+const (
+	bootstrapBegin = "# >>> wsl-screenshot-cli >>>"
+	bootstrapEnd   = "# <<< wsl-screenshot-cli <<<"
+)
+
+const posixBootstrap = bootstrapBegin + `
+if command -v wsl-screenshot-cli >/dev/null 2>&1; then
+    wsl-screenshot-cli start --daemon --ensure --quiet >/dev/null 2>&1
+    source <(wsl-screenshot-cli completion %s)
+fi
+` + bootstrapEnd + "\n"
+
+const fishBootstrap = bootstrapBegin + `
+if type -q wsl-screenshot-cli
+    wsl-screenshot-cli start --daemon --ensure --quiet >/dev/null 2>&1
+    wsl-screenshot-cli completion fish | source
+end
+` + bootstrapEnd + "\n"
+
+// rcFiles are the per-shell startup files --install appends to, relative to
+// the home directory.
+var rcFiles = map[string]string{
+	"bash": ".bashrc",
+	"zsh":  ".zshrc",
+	"fish": filepath.Join(".config", "fish", "config.fish"),
+}
+
+var bootstrapCmd = &cobra.Command{
+	Use:   "bootstrap [bash|zsh|fish]",
+	Short: "Print or install a shell rc snippet that keeps the daemon running",
+	Long: `Print a guarded shell snippet that starts the daemon if needed (start --daemon
+--ensure, which is silent when it already runs) and loads shell completion.
+With --install, append it to the shell's rc file instead:
+
+  ~/.bashrc, ~/.zshrc or ~/.config/fish/config.fish
+
+Installing twice is a no-op. The shell is detected from $SHELL when not given.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := filepath.Base(os.Getenv("SHELL"))
+		if len(args) == 1 {
+			shell = args[0]
+		}
+		snippet, err := bootstrapSnippet(shell)
+		if err != nil {
+			return err
+		}
+		if !bootstrapInstall {
+			fmt.Fprint(cmd.OutOrStdout(), snippet)
+			return nil
+		}
+		return installBootstrap(cmd.OutOrStdout(), shell, snippet)
+	},
+}
+
+// bootstrapSnippet returns the rc snippet for shell.
+func bootstrapSnippet(shell string) (string, error) {
+	switch shell {
+	case "bash", "zsh":
+		return fmt.Sprintf(posixBootstrap, shell), nil
+	case "fish":
+		return fishBootstrap, nil
+	default:
+		return "", i18n.Errorf("cmd.unsupported_shell", shell, "bash, zsh, fish")
+	}
+}
+
+// installBootstrap appends snippet to shell's rc file unless it is already there.
+func installBootstrap(w io.Writer, shell, snippet string) error {
+	home, err := userHomeDir()
+	if err != nil {
+		return fmt.Errorf("Failed to find home directory: %w", err)
+	}
+	path := filepath.Join(home, rcFiles[shell])
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the user's own rc file
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if strings.Contains(string(data), bootstrapBegin) {
+		fmt.Fprintf(w, "Already installed in %s\n", path)
+		return nil
+	}
+	switch {
+	case len(data) == 0:
+	case strings.HasSuffix(string(data), "\n"):
+		snippet = "\n" + snippet // blank line before the block
+	default:
+		snippet = "\n\n" + snippet
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644) // #nosec G302 G304 -- rc files are conventionally world-readable
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(snippet); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Installed in %s, open a new shell to use it\n", path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(bootstrapCmd)
+
+	bootstrapCmd.Flags().BoolVar(&bootstrapInstall, "install", false, "Append the snippet to the shell's rc file instead of printing it")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBootstrapSnippet(t *testing.T) {
+	tests := []struct {
+		shell   string
+		want    []string
+		wantErr bool
+	}{
+		{"bash", []string{"start --daemon --ensure --quiet", "source <(wsl-screenshot-cli completion bash)", "command -v"}, false},
+		{"zsh", []string{"source <(wsl-screenshot-cli completion zsh)"}, false},
+		{"fish", []string{"type -q wsl-screenshot-cli", "completion fish | source", "\nend\n"}, false},
+		{"tcsh", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			got, err := bootstrapSnippet(tt.shell)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %s", tt.shell)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range append(tt.want, bootstrapBegin, bootstrapEnd) {
+				if !strings.Contains(got, w) {
+					t.Errorf("snippet missing %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallBootstrap_Idempotent(t *testing.T) {
+	home := t.TempDir()
+	orig := userHomeDir
+	userHomeDir = func() (string, error) { return home, nil }
+	defer func() { userHomeDir = orig }()
+
+	rc := filepath.Join(home, ".config", "fish", "config.fish")
+	os.MkdirAll(filepath.Dir(rc), 0755)
+	os.WriteFile(rc, []byte("set -x EDITOR vim"), 0644)
+
+	snippet, _ := bootstrapSnippet("fish")
+	for i := 0; i < 2; i++ {
+		if err := installBootstrap(io.Discard, "fish", snippet); err != nil {
+			t.Fatalf("installBootstrap() error: %v", err)
+		}
+	}
+	data, _ := os.ReadFile(rc)
+	if n := strings.Count(string(data), bootstrapBegin); n != 1 {
+		t.Errorf("snippet installed %d times, want 1", n)
+	}
+	if !strings.HasPrefix(string(data), "set -x EDITOR vim\n\n"+bootstrapBegin) {
+		t.Errorf("existing content not preserved:\n%s", data)
+	}
+}
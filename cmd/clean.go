@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var (
+	cleanOutputDir string
+	cleanOlderThan string
+	cleanKeepLast  int
+	cleanMaxSize   string
+	cleanDryRun    bool
+)
+
+// clipboardScreenshot returns the screenshot the daemon last put on the
+// clipboard, or "" when no daemon has. Declared as a var for tests.
+var clipboardScreenshot = func() string {
+	hb, err := daemon.LiveHeartbeat()
+	if err != nil {
+		return ""
+	}
+	return hb.Stats.ClipboardPath
+}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete old screenshots from the output directory",
+	Long: `Delete screenshots from the output directory that fall outside the given
+retention bounds: older than --older-than, beyond the newest --keep-last, or
+beyond the newest ones that fit in --max-size. A screenshot goes when any bound
+excludes it.
+
+The screenshot the daemon last put on the clipboard is never deleted, so
+pasting it still works. Files other than screenshots are left alone.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := cleanRetention()
+		if err != nil {
+			return err
+		}
+		dir := cleanOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		var protect []string
+		if path := clipboardScreenshot(); path != "" {
+			protect = append(protect, path)
+		}
+
+		var files []store.File
+		if cleanDryRun {
+			snap, err := store.Scan(dir)
+			if err != nil {
+				return err
+			}
+			files = r.Expired(snap, time.Now(), protect...)
+		} else if files, err = store.Prune(dir, r, time.Now(), protect...); err != nil {
+			return fmt.Errorf("Failed to clean %s: %w", dir, err)
+		}
+
+		w := cmd.OutOrStdout()
+		var bytes int64
+		for _, f := range files {
+			bytes += f.Size
+			if cleanDryRun {
+				fmt.Fprintln(w, f.Path)
+			}
+		}
+		verb := "Removed"
+		if cleanDryRun {
+			verb = "Would remove"
+		}
+		fmt.Fprintf(w, "%s %d screenshots (%s) from %s\n", verb, len(files), formatSize(bytes), dir)
+		return nil
+	},
+}
+
+// cleanRetention builds the retention bounds from clean's flags.
+func cleanRetention() (store.Retention, error) {
+	var r store.Retention
+	var err error
+	if cleanOlderThan != "" {
+		if r.MaxAge, err = config.ParseAge(cleanOlderThan); err != nil {
+			return r, i18n.Errorf("cmd.invalid_flag", "older-than", err)
+		}
+	}
+	if cleanMaxSize != "" {
+		if r.MaxBytes, err = config.ParseSize(cleanMaxSize); err != nil {
+			return r, i18n.Errorf("cmd.invalid_flag", "max-size", err)
+		}
+	}
+	if cleanKeepLast < 0 {
+		return r, i18n.Errorf("cmd.flag_negative", "keep-last", cleanKeepLast)
+	}
+	r.KeepLast = cleanKeepLast
+	if r.IsZero() {
+		return r, errors.New("Nothing to clean: set --older-than, --keep-last or --max-size")
+	}
+	return r, nil
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	cleanCmd.Flags().StringVarP(&cleanOutputDir, "output", "o", "", "Screenshot directory (default: the running daemon's output dir)")
+	cleanCmd.Flags().StringVar(&cleanOlderThan, "older-than", "", "Delete screenshots older than this, e.g. 7d, 2w or 12h")
+	cleanCmd.Flags().IntVar(&cleanKeepLast, "keep-last", 0, "Keep only this many of the newest screenshots (0 = no limit)")
+	cleanCmd.Flags().StringVar(&cleanMaxSize, "max-size", "", "Keep the newest screenshots that fit in this size, e.g. 500MB")
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List what would be deleted without deleting anything")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanCommand(t *testing.T) {
+	defer func() {
+		cleanOutputDir, cleanOlderThan, cleanKeepLast, cleanMaxSize, cleanDryRun = "", "", 0, "", false
+	}()
+	cleanOutputDir = t.TempDir()
+	now := time.Now()
+	var paths []string
+	for i, name := range []string{"a", "b", "c"} { // a is the oldest
+		path := filepath.Join(cleanOutputDir, name+".png")
+		if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(-time.Duration(3-i) * 24 * time.Hour)
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	orig := clipboardScreenshot
+	clipboardScreenshot = func() string { return paths[0] }
+	defer func() { clipboardScreenshot = orig }()
+
+	if err := cleanCmd.RunE(cleanCmd, nil); err == nil {
+		t.Error("clean without a bound should fail")
+	}
+
+	var out bytes.Buffer
+	cleanCmd.SetOut(&out)
+	cleanKeepLast, cleanDryRun = 1, true
+	if err := cleanCmd.RunE(cleanCmd, nil); err != nil {
+		t.Fatalf("clean --dry-run error: %v", err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, paths[1]+"\nWould remove 1 screenshots") {
+		t.Errorf("clean --keep-last 1 --dry-run =\n%s\nwant only b listed (a is on the clipboard)", got)
+	}
+	if _, err := os.Stat(paths[1]); err != nil {
+		t.Errorf("--dry-run deleted b: %v", err)
+	}
+
+	out.Reset()
+	cleanKeepLast, cleanDryRun, cleanOlderThan = 0, false, "36h"
+	if err := cleanCmd.RunE(cleanCmd, nil); err != nil {
+		t.Fatalf("clean error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Removed 1 screenshots") {
+		t.Errorf("clean --older-than 36h = %q, want 1 removed", out.String())
+	}
+	for i, want := range []bool{true, false, true} {
+		if _, err := os.Stat(paths[i]); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", paths[i], err == nil, want)
+		}
+	}
+
+	cleanOlderThan, cleanMaxSize = "", "lots"
+	if err := cleanCmd.RunE(cleanCmd, nil); err == nil || !strings.Contains(err.Error(), "--max-size") {
+		t.Errorf("clean --max-size lots error = %v, want an invalid --max-size error", err)
+	}
+}
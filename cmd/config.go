@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and write persistent settings",
+	Long: `Read and write the settings stored in the config file
+(~/.config/wsl-screenshot-cli/config.yaml by default, or --config).
+
+Keys are flag names without the dashes. "list" prints the effective settings
+of the start command after merging the environment, the config file and the
+built-in defaults, along with where each value came from.`,
+	// The config file may be the thing being fixed, so only the environment
+	// (for WSL_SCREENSHOT_CONFIG) is applied before these commands run.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.ApplyEnv(cmd.Flags(), envPrefix, lookupEnv, "help"); err != nil {
+			return i18n.Errorf("cmd.invalid_env_override", err)
+		}
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := effectiveSettings(cmd.Root())
+		if err != nil {
+			return err
+		}
+		for _, s := range settings {
+			if s.Name == args[0] {
+				fmt.Fprintln(cmd.OutOrStdout(), s.Value)
+				return nil
+			}
+		}
+		return i18n.Errorf("cmd.unknown_setting", args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Store a setting in the config file",
+	Long: `Store a setting in the config file, replacing any previous value for the key.
+List settings take a comma-separated value: config set breaker-ignore backend,disk
+
+A running daemon keeps its current settings until it is restarted.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+		f := lookupSetting(cmd.Root(), key)
+		if f == nil {
+			return i18n.Errorf("cmd.unknown_setting", key)
+		}
+		if err := validateSetting(f, value); err != nil {
+			return i18n.Errorf("cmd.invalid_setting_value", key, err)
+		}
+		path, _, err := resolveConfigPath()
+		if err != nil {
+			return fmt.Errorf("Failed to locate config file: %w", err)
+		}
+		_, list := f.Value.(pflag.SliceValue)
+		if err := config.Set(path, key, config.FormatValue(value, list)); err != nil {
+			return i18n.Errorf("cmd.config_write_failed", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Set %s = %s in %s\n", key, value, path)
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the effective settings and their sources",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := effectiveSettings(cmd.Root())
+		if err != nil {
+			return err
+		}
+		printSettings(cmd.OutOrStdout(), settings)
+		return nil
+	},
+}
+
+// setting is one resolved configuration value.
+type setting struct {
+	Name   string
+	Value  string
+	Source string // "default", "environment" or "config file"
+}
+
+// effectiveSettings resolves every start setting the way the daemon would see
+// it without flags: environment > config file > default. Keys the file sets
+// for other commands are listed too.
+func effectiveSettings(root *cobra.Command) ([]setting, error) {
+	path, optional, err := resolveConfigPath()
+	var values map[string]string
+	if err == nil {
+		if values, err = config.Load(path, optional); err != nil {
+			return nil, i18n.Errorf("cmd.config_read_failed", err)
+		}
+	}
+
+	names := map[string]bool{}
+	startCmd.Flags().VisitAll(func(f *pflag.Flag) { names[f.Name] = true })
+	for k := range values {
+		names[k] = true
+	}
+	for _, name := range unconfigurable {
+		delete(names, name)
+	}
+
+	var settings []setting
+	for name := range names {
+		f := lookupSetting(root, name)
+		if f == nil {
+			continue // unknown key; start reports it
+		}
+		s := setting{Name: name, Value: f.DefValue, Source: "default"}
+		if v, ok := values[name]; ok {
+			s.Value, s.Source = v, "config file"
+		}
+		if v, ok := lookupEnv(config.EnvName(envPrefix, name)); ok && v != "" {
+			s.Value, s.Source = v, "environment"
+		}
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings, nil
+}
+
+// lookupSetting finds the flag behind a setting, preferring start's, since
+// that is the command the config mostly exists for.
+func lookupSetting(root *cobra.Command, name string) *pflag.Flag {
+	for _, n := range unconfigurable {
+		if n == name {
+			return nil
+		}
+	}
+	if f := startCmd.Flags().Lookup(name); f != nil {
+		return f
+	}
+	var found *pflag.Flag
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			if found == nil {
+				found = sub.Flags().Lookup(name)
+			}
+			walk(sub)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// validateSetting checks value against the flag's type without touching the
+// flag itself. Value-specific rules (e.g. --on-collision names) are still
+// enforced when start runs.
+func validateSetting(f *pflag.Flag, value string) error {
+	_, err := normalizeSetting(f, value)
+	return err
+}
+
+// normalizeSetting parses value as f's type and prints it back the way the
+// flag would, so "1s" and "1000ms" or "a, b" and "[a,b]" compare equal.
+func normalizeSetting(f *pflag.Flag, value string) (string, error) {
+	fs := pflag.NewFlagSet("validate", pflag.ContinueOnError)
+	switch f.Value.Type() {
+	case "bool":
+		fs.Bool(f.Name, false, "")
+	case "int":
+		fs.Int(f.Name, 0, "")
+	case "duration":
+		fs.Var(new(config.Duration), f.Name, "")
+	case "stringSlice":
+		fs.StringSlice(f.Name, nil, "")
+	default:
+		return value, nil
+	}
+	if err := fs.Set(f.Name, value); err != nil {
+		return "", err
+	}
+	return fs.Lookup(f.Name).Value.String(), nil
+}
+
+func printSettings(w io.Writer, settings []setting) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range settings {
+		v := s.Value
+		if v == "" || strings.TrimSpace(v) != v {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t(%s)\n", s.Name, v, s.Source)
+	}
+	_ = tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configListCmd)
+}
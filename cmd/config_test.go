@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTestConfig points the config commands at a temp file and a fixed
+// environment.
+func useTestConfig(t *testing.T, env map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	origPath, origEnv := defaultConfigPath, lookupEnv
+	defaultConfigPath = func() (string, error) { return path, nil }
+	lookupEnv = func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	t.Cleanup(func() { defaultConfigPath, lookupEnv = origPath, origEnv })
+	return path
+}
+
+func runConfigCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := configGetCmd
+	switch args[0] {
+	case "set":
+		cmd = configSetCmd
+	case "list":
+		cmd = configListCmd
+	}
+	cmd.SetOut(&out)
+	defer cmd.SetOut(nil)
+	err := cmd.RunE(cmd, args[1:])
+	return out.String(), err
+}
+
+func TestConfig_SetThenGetAndList(t *testing.T) {
+	path := useTestConfig(t, map[string]string{"WSL_SCREENSHOT_QUIET": "true"})
+
+	if _, err := runConfigCmd(t, "set", "interval", "1s"); err != nil {
+		t.Fatalf("config set: %v", err)
+	}
+	if _, err := runConfigCmd(t, "set", "breaker-ignore", "backend,disk"); err != nil {
+		t.Fatalf("config set list: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "interval: 1s\nbreaker-ignore: [backend, disk]\n"; string(data) != want {
+		t.Errorf("config file = %q, want %q", data, want)
+	}
+
+	if got, err := runConfigCmd(t, "get", "interval"); err != nil || got != "1s\n" {
+		t.Errorf("config get interval = %q, %v", got, err)
+	}
+
+	list, err := runConfigCmd(t, "list")
+	if err != nil {
+		t.Fatalf("config list: %v", err)
+	}
+	rows := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(list), "\n") {
+		if f := strings.Fields(line); len(f) >= 3 {
+			rows[f[0]] = f[1] + " " + strings.Join(f[2:], " ")
+		}
+	}
+	for name, want := range map[string]string{
+		"interval":       "1s (config file)",
+		"breaker-ignore": "backend,disk (config file)",
+		"quiet":          "true (environment)",
+		"timezone":       "Local (default)",
+	} {
+		if rows[name] != want {
+			t.Errorf("config list %s = %q, want %q", name, rows[name], want)
+		}
+	}
+	if strings.Contains(list, "daemon") {
+		t.Errorf("config list should not offer daemon:\n%s", list)
+	}
+}
+
+func TestConfig_SetRejectsBadInput(t *testing.T) {
+	path := useTestConfig(t, nil)
+
+	tests := [][]string{
+		{"set", "intervall", "1s"},
+		{"set", "daemon", "true"},
+		{"set", "interval", "fast"},
+		{"set", "seq-check", "maybe"},
+	}
+	for _, args := range tests {
+		if _, err := runConfigCmd(t, args...); err == nil {
+			t.Errorf("config %v should fail", args)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("rejected settings must not create the config file")
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+var configPath string
+
+// defaultConfigPath locates the config file used when --config is not given.
+// Declared as a var so tests can point it at a temp directory.
+var defaultConfigPath = config.DefaultPath
+
+// applyConfigFile fills in the running command's flags from the config file.
+// config.Apply only touches flags that neither the command line nor the
+// environment set, so the file ranks just above the built-in defaults.
+func applyConfigFile(cmd *cobra.Command, args []string) error {
+	path, optional, err := resolveConfigPath()
+	if err != nil {
+		return nil // no home directory: nothing to load
+	}
+	values, err := config.Load(path, optional)
+	if err != nil {
+		return i18n.Errorf("cmd.config_read_failed", err)
+	}
+	known := knownFlags(cmd.Root())
+	for key := range values {
+		if !known[key] {
+			return fmt.Errorf("Unknown setting %q in %s", key, path)
+		}
+	}
+	if err := config.Apply(cmd.Flags(), values); err != nil {
+		return fmt.Errorf("Invalid setting in %s: %w", path, err)
+	}
+	return nil
+}
+
+// commandLineFlags records which flags of the running command were given on
+// the command line, before the environment and config file fill in the rest
+// (which marks those flags as set too). Nil until applySettings runs.
+var commandLineFlags map[string]bool
+
+// fromCommandLine reports whether flag name was given on the command line.
+// Without recorded provenance (commands run directly, as in tests) any set
+// flag counts.
+func fromCommandLine(cmd *cobra.Command, name string) bool {
+	if commandLineFlags == nil {
+		return cmd.Flags().Changed(name)
+	}
+	return commandLineFlags[name]
+}
+
+// resolveConfigPath returns the config file in use: --config (or
+// WSL_SCREENSHOT_CONFIG) if given, which must exist, else the optional
+// default location.
+func resolveConfigPath() (path string, optional bool, err error) {
+	if configPath != "" {
+		return configPath, false, nil
+	}
+	p, err := defaultConfigPath()
+	return p, true, err
+}
+
+// knownFlags collects every flag name defined anywhere in the command tree.
+// A setting only needs to be meaningful to one command; the others ignore it.
+func knownFlags(root *cobra.Command) map[string]bool {
+	known := map[string]bool{}
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+	for _, name := range unconfigurable {
+		delete(known, name)
+	}
+	return known
+}
+
+// unconfigurable flags only make sense on the command line (or, for "config",
+// the environment). "daemon" in particular would make the re-exec'd child
+// daemonize again.
+var unconfigurable = []string{"config", "help", "version", "daemon"}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// configTestTree builds a throwaway command tree so tests do not leak
+// settings into the real commands' flags.
+func configTestTree(t *testing.T, config string) (*cobra.Command, *string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	orig := defaultConfigPath
+	defaultConfigPath = func() (string, error) { return path, nil }
+	t.Cleanup(func() { defaultConfigPath = orig })
+
+	root := &cobra.Command{Use: "root"}
+	sub := &cobra.Command{Use: "start"}
+	out := sub.Flags().String("output", "/tmp/default", "")
+	sub.Flags().Bool("daemon", false, "")
+	other := &cobra.Command{Use: "status"}
+	other.Flags().Bool("prompt", false, "")
+	root.AddCommand(sub, other)
+	return sub, out
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	sub, out := configTestTree(t, "output: /tmp/from-config\nprompt: true\n")
+
+	if err := applyConfigFile(sub, nil); err != nil {
+		t.Fatalf("applyConfigFile() error: %v", err)
+	}
+	if *out != "/tmp/from-config" {
+		t.Errorf("output = %q, want the config file value", *out)
+	}
+}
+
+func TestApplyConfigFile_UnknownSetting(t *testing.T) {
+	for _, key := range []string{"intervall", "daemon"} {
+		sub, _ := configTestTree(t, key+": true\n")
+		err := applyConfigFile(sub, nil)
+		if err == nil || !strings.Contains(err.Error(), `Unknown setting "`+key+`"`) {
+			t.Errorf("%s: error = %v, want unknown setting", key, err)
+		}
+	}
+}
+
+func TestApplyConfigFile_ExplicitPathMustExist(t *testing.T) {
+	sub, _ := configTestTree(t, "")
+	configPath = filepath.Join(t.TempDir(), "missing.yaml")
+	defer func() { configPath = "" }()
+
+	if err := applyConfigFile(sub, nil); err == nil {
+		t.Error("expected an error for a missing --config file")
+	}
+}
+
+func TestApplySettings_Precedence(t *testing.T) {
+	sub, out := configTestTree(t, "output: /tmp/from-config\n")
+	prompt := sub.Flags().Bool("prompt", false, "")
+
+	env := map[string]string{"WSL_SCREENSHOT_OUTPUT": "/tmp/from-env", "WSL_SCREENSHOT_DAEMON": "true"}
+	orig := lookupEnv
+	lookupEnv = func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	defer func() { lookupEnv, commandLineFlags = orig, nil }()
+
+	if err := sub.Flags().Set("prompt", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySettings(sub, nil); err != nil {
+		t.Fatalf("applySettings() error: %v", err)
+	}
+	if *out != "/tmp/from-env" {
+		t.Errorf("output = %q, want the environment to beat the config file", *out)
+	}
+	if !*prompt {
+		t.Error("command-line flag was overridden")
+	}
+	if d, _ := sub.Flags().GetBool("daemon"); d {
+		t.Error("daemon must not be read from the environment")
+	}
+	if !commandLineFlags["prompt"] || commandLineFlags["output"] {
+		t.Errorf("commandLineFlags = %v, want only the flag set before applySettings", commandLineFlags)
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logging"
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var copyOutputDir string
+
+// copyWinPath converts the path a one-shot copy puts on the clipboard. Nil
+// means wslpath -w; tests substitute a fake.
+var copyWinPath func(wslPath string) (string, error)
+
+// copyWait bounds each step of the daemon's copy command, queueing the
+// request and the clipboard update, within the control socket's timeout.
+const copyWait = 2 * time.Second
+
+// copyArgs are the arguments of the daemon's copy control command.
+type copyArgs struct {
+	Path string `json:"path"`
+}
+
+var copyCmd = &cobra.Command{
+	Use:   "copy <hash|path|latest>",
+	Short: "Put a saved screenshot back on the clipboard",
+	Long: `Set the clipboard to a screenshot saved earlier, as the daemon did right after
+capturing it: the path as text, the image, and the file for pasting in
+Explorer. The screenshot is named by a hash prefix (as shown by 'list'), a
+path, or latest.
+
+A running daemon sets the clipboard with its own PowerShell helper; without
+one, a helper is started just for this.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := copyOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		path, err := resolveScreenshot(dir, args[0])
+		if err != nil {
+			return err
+		}
+		if err := copyScreenshot(cmd, path); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), path)
+		return nil
+	},
+}
+
+// resolveScreenshot finds the screenshot ref names in dir: latest, a path to
+// an existing file, or a unique prefix of a screenshot's name or of the hash
+// the capture index records for it.
+func resolveScreenshot(dir, ref string) (string, error) {
+	if ref == "latest" {
+		path, err := store.Latest(dir)
+		if errors.Is(err, store.ErrEmpty) {
+			return "", i18n.Errorf("cmd.no_screenshots", dir)
+		}
+		return path, err
+	}
+	if strings.ContainsRune(ref, '/') || strings.EqualFold(filepath.Ext(ref), ".png") {
+		info, err := os.Stat(ref)
+		if err != nil {
+			return "", err
+		}
+		if !info.Mode().IsRegular() {
+			return "", fmt.Errorf("%s is not a file", ref)
+		}
+		return filepath.Abs(ref)
+	}
+	snap, err := store.Scan(dir)
+	if err != nil {
+		return "", err
+	}
+	ix, err := store.ReadIndex(dir)
+	if err != nil {
+		return "", err
+	}
+	ref = strings.ToLower(ref)
+	var matches []string
+	for _, f := range snap.Files {
+		e, _ := ix.Find(f.Path)
+		if strings.HasPrefix(strings.ToLower(filepath.Base(f.Path)), ref) || e.Hash != "" && strings.HasPrefix(e.Hash, ref) {
+			matches = append(matches, f.Path)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", i18n.Errorf("cmd.no_match", dir, ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q matches %d screenshots in %s, give more of the hash", ref, len(matches), dir)
+	}
+}
+
+// copyScreenshot sets the clipboard to the screenshot at path, through the
+// running daemon if there is one, else with a one-shot client.
+func copyScreenshot(cmd *cobra.Command, path string) error {
+	err := daemon.Call("copy", copyArgs{Path: path}, nil)
+	if !errors.Is(err, daemon.ErrNoDaemon) {
+		if err != nil {
+			return i18n.Errorf("cmd.copy_failed", path, err)
+		}
+		return nil
+	}
+
+	logger := logging.New(cmd.ErrOrStderr(), logging.FormatText, slog.LevelWarn)
+	client, err := newOneShotClient(logger)
+	if err != nil {
+		return i18n.Errorf("cmd.clipboard_client_failed", err)
+	}
+	defer client.Close()
+	if err := poller.Copy(client, logger, poller.Config{ToWinPath: copyWinPath}, path); err != nil {
+		return i18n.Errorf("cmd.copy_failed", path, err)
+	}
+	return nil
+}
+
+// copyHandler serves the daemon's copy control command: it hands the
+// screenshot to the poll loop, which sets the clipboard with its client.
+func copyHandler(copies chan<- poller.CopyRequest) daemon.Handler {
+	return func(raw json.RawMessage) (any, error) {
+		var args copyArgs
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(args.Path); err != nil || !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("no image file at %s", args.Path)
+		}
+		done := make(chan error, 1)
+		select {
+		case copies <- poller.CopyRequest{Path: args.Path, Done: done}:
+		case <-time.After(copyWait):
+			return nil, errors.New("the poll loop is busy, try again")
+		}
+		select {
+		case err := <-done:
+			return nil, err
+		case <-time.After(copyWait):
+			return nil, errors.New("timed out waiting for the clipboard update")
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+
+	copyCmd.Flags().StringVarP(&copyOutputDir, "output", "o", "", "Screenshot directory to look up hashes and latest in (default: the running daemon's output dir)")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestResolveScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"ab12.png", "ab34.png", "cd56.png"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	named := filepath.Join(dir, "2024-05-01_shot.png")
+	if err := os.WriteFile(named, []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(named, now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AppendIndex(dir, named, store.IndexEntry{Hash: "9f78"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"9f7":                          "2024-05-01_shot.png",
+		"2024":                         "2024-05-01_shot.png",
+		"latest":                       "cd56.png",
+		"ab3":                          "ab34.png",
+		"CD":                           "cd56.png",
+		filepath.Join(dir, "ab12.png"): "ab12.png",
+	}
+	for ref, want := range tests {
+		got, err := resolveScreenshot(dir, ref)
+		if err != nil || got != filepath.Join(dir, want) {
+			t.Errorf("resolveScreenshot(%q) = %q, %v; want %s", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{"ab", "ef", filepath.Join(dir, "gone.png")} {
+		if got, err := resolveScreenshot(dir, ref); err == nil {
+			t.Errorf("resolveScreenshot(%q) = %q, want an error", ref, got)
+		}
+	}
+}
+
+func TestCopyHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.png")
+	if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	copies := make(chan poller.CopyRequest)
+	go func() {
+		req := <-copies
+		if req.Path != path {
+			t.Errorf("copy request for %q, want %q", req.Path, path)
+		}
+		req.Done <- nil
+	}()
+
+	h := copyHandler(copies)
+	args, _ := json.Marshal(copyArgs{Path: path})
+	if _, err := h(args); err != nil {
+		t.Errorf("copy handler error: %v", err)
+	}
+	missing, _ := json.Marshal(copyArgs{Path: path + ".gone"})
+	if _, err := h(missing); err == nil {
+		t.Error("copy handler should reject a missing file")
+	}
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+// doctorWSLPath runs wslpath with flag (-w or -u) on path. Declared as a var
+// so tests can fake the conversions.
+var doctorWSLPath = func(flag, path string) (string, error) {
+	out, err := exec.Command("wslpath", flag, path).Output() // #nosec G204 -- argv-separated (no shell), flag is -w or -u
+	if err != nil {
+		return "", fmt.Errorf("wslpath %s %q: %w", flag, path, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// doctorVisible asks Windows whether each path exists, in one powershell.exe
+// call. Declared as a var so tests don't need Windows.
+var doctorVisible = func(paths []string) ([]bool, error) {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = "'" + strings.ReplaceAll(p, "'", "''") + "'"
+	}
+	script := "foreach ($p in @(" + strings.Join(quoted, ",") + ")) { if (Test-Path -LiteralPath $p) { 'yes' } else { 'no' } }"
+	out, err := exec.Command("powershell.exe", "-NoLogo", "-NoProfile", "-NonInteractive", "-Command", script).Output() // #nosec G204 -- paths are single-quoted PowerShell literals
+	if err != nil {
+		return nil, fmt.Errorf("powershell.exe: %w", err)
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) != len(paths) {
+		return nil, fmt.Errorf("powershell.exe: expected %d answers, got %q", len(paths), out)
+	}
+	seen := make([]bool, len(paths))
+	for i, l := range lines {
+		seen[i] = l == "yes"
+	}
+	return seen, nil
+}
+
+// doctorWindowsVersion returns the output of cmd.exe's ver. Declared as a
+// var so tests can pick a build.
+var doctorWindowsVersion = func() (string, error) {
+	out, err := exec.Command("cmd.exe", "/c", "ver").Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// doctorDriveFile is a file that exists on the Windows drive on any normal
+// installation, used for the drive path and symlink cases without writing
+// anything to the Windows side.
+var doctorDriveFile = "/mnt/c/Windows/win.ini"
+
+// wslLocalhostBuild is the first Windows build that serves \\wsl.localhost\.
+// Older builds only know \\wsl$\.
+const wslLocalhostBuild = 21354
+
+// pathCase is one tricky path exercised by completion doctor.
+type pathCase struct {
+	Name string
+	Path string // WSL path, or a Windows path when FromWindows is set
+
+	FromWindows bool   // convert with wslpath -u, not -w
+	Want        string // the WSL path a FromWindows case should map to
+
+	WinPath   string // what a file drop would carry
+	RoundTrip bool   // converting back gives Path (or Want) again
+	Visible   bool   // Windows can open WinPath
+	Err       error
+}
+
+var completionDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check which path forms Windows accepts for pasted files",
+	Long: `Exercise wslpath round-trips for a set of tricky paths (spaces, unicode,
+symlinks into /mnt, \\wsl.localhost\ and \\wsl$\ UNC paths, drive paths) and
+report which forms this Windows build accepts for file drops.
+
+Screenshots are pasted as files using the Windows form of their path. When
+pasting as a file works in one application but not in another, this shows
+which form is failing and what to change. The checks create a scratch
+directory under /tmp and remove it afterwards; nothing is written to Windows.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := doctorWSLPath("-w", "/"); err != nil {
+			return i18n.Errorf("doctor.wslpath_unavailable", err)
+		}
+		scratch, err := os.MkdirTemp("", "wsl-screenshot-doctor-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(scratch)
+
+		cases, err := doctorCases(scratch)
+		if err != nil {
+			return err
+		}
+		cases = runPathCases(cases)
+		version, _ := doctorWindowsVersion()
+		writeDoctorReport(cmd.OutOrStdout(), version, cases)
+		return nil
+	},
+}
+
+// doctorCases creates the scratch files under dir and lists the cases.
+func doctorCases(dir string) ([]*pathCase, error) {
+	var cases []*pathCase
+	for _, c := range []struct{ name, rel string }{
+		{"plain", "shot.png"},
+		{"spaces", "with spaces/shot 1.png"},
+		{"unicode", "écran/スクリーン 📷.png"},
+	} {
+		path := filepath.Join(dir, c.rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			return nil, err
+		}
+		cases = append(cases, &pathCase{Name: c.name, Path: path})
+	}
+
+	if _, err := os.Stat(doctorDriveFile); err == nil {
+		link := filepath.Join(dir, "link.ini")
+		if err := os.Symlink(doctorDriveFile, link); err != nil {
+			return nil, err
+		}
+		cases = append(cases,
+			&pathCase{Name: "drive", Path: doctorDriveFile},
+			&pathCase{Name: "symlink into /mnt", Path: link},
+		)
+	}
+	return cases, nil
+}
+
+// runPathCases converts every case, adds the UNC variants of the plain case,
+// and asks Windows which of the results it can open.
+func runPathCases(cases []*pathCase) []*pathCase {
+	for _, c := range cases {
+		convert(c)
+	}
+	if plain := cases[0]; plain.Err == nil {
+		for _, prefix := range []string{`\\wsl.localhost\`, `\\wsl$\`} {
+			if unc := swapUNCPrefix(plain.WinPath, prefix); unc != "" {
+				c := &pathCase{Name: prefix, Path: unc, FromWindows: true, Want: plain.Path}
+				convert(c)
+				cases = append(cases, c)
+			}
+		}
+	}
+
+	var paths []string
+	for _, c := range cases {
+		if c.Err == nil {
+			paths = append(paths, c.WinPath)
+		}
+	}
+	seen, err := doctorVisible(paths)
+	i := 0
+	for _, c := range cases {
+		if c.Err != nil {
+			continue
+		}
+		if err != nil {
+			c.Err = err
+		} else {
+			c.Visible = seen[i]
+		}
+		i++
+	}
+	return cases
+}
+
+// convert fills in c's Windows form and checks the round-trip.
+func convert(c *pathCase) {
+	if c.FromWindows {
+		out, err := doctorWSLPath("-u", c.Path)
+		if err != nil {
+			c.Err = err
+			return
+		}
+		c.WinPath, c.RoundTrip = c.Path, out == c.Want
+		return
+	}
+	out, err := doctorWSLPath("-w", c.Path)
+	if err != nil {
+		c.Err = err
+		return
+	}
+	again, err := doctorWSLPath("-u", out)
+	if err != nil {
+		c.Err = err
+		return
+	}
+	// A symlink converts to its target, which is what a drop should carry.
+	resolved, _ := filepath.EvalSymlinks(c.Path)
+	c.WinPath, c.RoundTrip = out, again == c.Path || again == resolved
+}
+
+// swapUNCPrefix rewrites a \\wsl.localhost\ or \\wsl$\ path to use prefix.
+// It returns "" for paths that are not UNC paths into the distribution.
+func swapUNCPrefix(winPath, prefix string) string {
+	for _, p := range []string{`\\wsl.localhost\`, `\\wsl$\`} {
+		if len(winPath) >= len(p) && strings.EqualFold(winPath[:len(p)], p) {
+			return prefix + winPath[len(p):]
+		}
+	}
+	return ""
+}
+
+var windowsBuild = regexp.MustCompile(`\d+\.\d+\.(\d+)`)
+
+// parseWindowsBuild extracts the build number from ver's output, or 0.
+func parseWindowsBuild(ver string) int {
+	m := windowsBuild.FindStringSubmatch(ver)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+func writeDoctorReport(w io.Writer, version string, cases []*pathCase) {
+	if version == "" {
+		version = i18n.T("doctor.version_unknown")
+	}
+	fmt.Fprintf(w, "%s\n\n", i18n.T("doctor.windows", version))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("doctor.header"))
+	byName := map[string]*pathCase{}
+	for _, c := range cases {
+		byName[c.Name] = c
+		if c.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t%s\n", c.Name, i18n.T("doctor.case_error", c.Err))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, c.WinPath, okFailed(c.RoundTrip), opensIt(c.Visible))
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, i18n.T("doctor.file_drops"))
+	for _, line := range doctorAdvice(parseWindowsBuild(version), byName) {
+		fmt.Fprintln(w, "  "+line)
+	}
+}
+
+// doctorAdvice turns the results into what works for file drops.
+func doctorAdvice(build int, byName map[string]*pathCase) []string {
+	works := func(name string) (known, ok bool) {
+		c := byName[name]
+		if c == nil || c.Err != nil {
+			return false, false
+		}
+		return true, c.RoundTrip && c.Visible
+	}
+
+	var advice []string
+	if known, ok := works("drive"); known {
+		if ok {
+			advice = append(advice, i18n.T("doctor.drive_ok"))
+		} else {
+			advice = append(advice, i18n.T("doctor.drive_failed"))
+		}
+	}
+	for _, prefix := range []string{`\\wsl.localhost\`, `\\wsl$\`} {
+		known, ok := works(prefix)
+		switch {
+		case !known:
+		case ok:
+			advice = append(advice, i18n.T("doctor.unc_ok", prefix))
+		case prefix == `\\wsl.localhost\` && build > 0 && build < wslLocalhostBuild:
+			advice = append(advice, i18n.T("doctor.wsl_localhost_too_old", build, wslLocalhostBuild))
+		default:
+			advice = append(advice, i18n.T("doctor.unc_failed", prefix))
+		}
+	}
+	// Judge the tricky names against the plain path, so a UNC form that
+	// fails everywhere isn't blamed on spaces or unicode.
+	_, plainOK := works("plain")
+	for _, name := range []string{"spaces", "unicode", "symlink into /mnt"} {
+		if c := byName[name]; c != nil && c.Err == nil && (!c.RoundTrip || plainOK && !c.Visible) {
+			advice = append(advice, i18n.T("doctor.name_failed", name))
+		}
+	}
+	if plainOK {
+		advice = append(advice, i18n.T("doctor.unc_hint"))
+	}
+	if len(advice) == 0 {
+		advice = append(advice, i18n.T("doctor.nothing_checked"))
+	}
+	return advice
+}
+
+// okFailed renders a round-trip result.
+func okFailed(ok bool) string {
+	if ok {
+		return i18n.T("doctor.ok")
+	}
+	return i18n.T("doctor.failed")
+}
+
+// opensIt renders whether Windows can open a path.
+func opensIt(ok bool) string {
+	if ok {
+		return i18n.T("doctor.yes")
+	}
+	return i18n.T("doctor.no")
+}
+
+func init() {
+	// Cobra normally adds its completion command when the root runs; create
+	// it now so doctor can hang off it next to the shell scripts.
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == "completion" {
+			c.AddCommand(completionDoctorCmd)
+		}
+	}
+}
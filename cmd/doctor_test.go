@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+// fakeWSLPath converts like wslpath for a distro named Ubuntu on an older
+// build that names UNC paths \\wsl$\, with drive C: mounted at mnt.
+func fakeWSLPath(mnt string) func(flag, path string) (string, error) {
+	return func(flag, path string) (string, error) {
+		if flag == "-w" {
+			if resolved, err := filepath.EvalSymlinks(path); err == nil {
+				path = resolved
+			}
+			if rest, ok := strings.CutPrefix(path, mnt+"/"); ok {
+				return `C:\` + strings.ReplaceAll(rest, "/", `\`), nil
+			}
+			return `\\wsl$\Ubuntu` + strings.ReplaceAll(path, "/", `\`), nil
+		}
+		if rest, ok := strings.CutPrefix(path, `C:\`); ok {
+			return mnt + "/" + strings.ReplaceAll(rest, `\`, "/"), nil
+		}
+		for _, prefix := range []string{`\\wsl.localhost\Ubuntu`, `\\wsl$\Ubuntu`} {
+			if rest, ok := strings.CutPrefix(path, prefix); ok {
+				return strings.ReplaceAll(rest, `\`, "/"), nil
+			}
+		}
+		return path, nil
+	}
+}
+
+func TestCompletionDoctor(t *testing.T) {
+	mnt := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mnt, "win.ini"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	origPath, origVisible, origVersion, origDrive := doctorWSLPath, doctorVisible, doctorWindowsVersion, doctorDriveFile
+	defer func() {
+		doctorWSLPath, doctorVisible, doctorWindowsVersion, doctorDriveFile = origPath, origVisible, origVersion, origDrive
+	}()
+	doctorWSLPath = fakeWSLPath(mnt)
+	doctorDriveFile = filepath.Join(mnt, "win.ini")
+	doctorWindowsVersion = func() (string, error) { return "Microsoft Windows [Version 10.0.19045.4291]", nil }
+	// An older build: \\wsl$\ is served, \\wsl.localhost\ is not.
+	doctorVisible = func(paths []string) ([]bool, error) {
+		seen := make([]bool, len(paths))
+		for i, p := range paths {
+			seen[i] = !strings.HasPrefix(p, `\\wsl.localhost\`)
+		}
+		return seen, nil
+	}
+
+	var buf bytes.Buffer
+	completionDoctorCmd.SetOut(&buf)
+	if err := completionDoctorCmd.RunE(completionDoctorCmd, nil); err != nil {
+		t.Fatalf("completion doctor error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"Windows: Microsoft Windows [Version 10.0.19045.4291]",
+		`drive              C:\win.ini`,
+		`symlink into /mnt  C:\win.ini`,
+		`Drive paths (C:\...) work.`,
+		`\\wsl.localhost\ paths do not work: Windows build 19045 predates them (21354+).`,
+		`\\wsl$\ paths work.`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "FAILED") || strings.Contains(line, "error:") {
+			t.Errorf("unexpected failure: %s", line)
+		}
+	}
+	if !strings.Contains(out, "unicode") || !strings.Contains(out, "スクリーン 📷.png") {
+		t.Errorf("unicode case missing:\n%s", out)
+	}
+}
+
+func TestDoctorAdvice_FollowsLocale(t *testing.T) {
+	defer i18n.SetLanguage("fr")()
+	byName := map[string]*pathCase{
+		"drive":            {Name: "drive", RoundTrip: true, Visible: true},
+		`\\wsl.localhost\`: {Name: `\\wsl.localhost\`, RoundTrip: true},
+	}
+	got := doctorAdvice(19045, byName)
+	want := []string{
+		`Les chemins de lecteur (C:\...) fonctionnent. Toutes les applications les acceptent.`,
+		`Les chemins \\wsl.localhost\ ne fonctionnent pas : la version 19045 de Windows est antérieure à leur arrivée (21354+). Utilisez \\wsl$\ ou un chemin de lecteur.`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("doctorAdvice(fr) = %q, want %q", got, want)
+	}
+}
+
+func TestParseWindowsBuild(t *testing.T) {
+	for ver, want := range map[string]int{
+		"Microsoft Windows [Version 10.0.22631.3447]": 22631,
+		"Microsoft Windows [Versión 10.0.19045.4291]": 19045,
+		"": 0,
+	} {
+		if got := parseWindowsBuild(ver); got != want {
+			t.Errorf("parseWindowsBuild(%q) = %d, want %d", ver, got, want)
+		}
+	}
+}
+
+func TestCompletionDoctorIsUnderCompletion(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"completion", "doctor"})
+	if err != nil || cmd != completionDoctorCmd {
+		t.Errorf("completion doctor not registered: %v", err)
+	}
+	if _, _, err := rootCmd.Find([]string{"completion", "bash"}); err != nil {
+		t.Errorf("completion bash lost: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/events"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+var (
+	eventsTypes []string
+	eventsSince string
+	eventsJSON  bool
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the daemon's event history (captures, errors, restarts)",
+	Long: `Show events from the journal the daemon appends to, oldest first. Unlike the
+log, the journal is structured, so scripts can rely on it:
+
+  wsl-screenshot-cli events --type capture --since 1h --json
+
+Types: start, stop, capture, error, restart.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, t := range eventsTypes {
+			if !slices.Contains(events.Types, t) {
+				return fmt.Errorf("Invalid --type %q (want %s)", t, strings.Join(events.Types, ", "))
+			}
+		}
+		f := events.Filter{Types: eventsTypes}
+		if eventsSince != "" {
+			age, err := config.ParseAge(eventsSince)
+			if err != nil {
+				return i18n.Errorf("cmd.invalid_flag", "since", err)
+			}
+			f.Since = time.Now().Add(-age)
+		}
+
+		evs, err := events.Read(daemon.EventsFile, f)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if eventsJSON {
+			enc := json.NewEncoder(w)
+			for _, e := range evs {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if len(evs) == 0 {
+			fmt.Fprintln(w, i18n.T("cmd.no_events"))
+			return nil
+		}
+		for _, e := range evs {
+			fmt.Fprintf(w, "%s  %-8s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Type, e.Message)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().StringSliceVar(&eventsTypes, "type", nil, "Only show these event types (start, stop, capture, error, restart)")
+	eventsCmd.Flags().StringVar(&eventsSince, "since", "", "Only show events from this long ago, e.g. 30m, 1h or 7d")
+	eventsCmd.Flags().BoolVar(&eventsJSON, "json", false, "Print one JSON object per event")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/events"
+)
+
+func TestEventsCommand(t *testing.T) {
+	orig := daemon.EventsFile
+	daemon.EventsFile = filepath.Join(t.TempDir(), "events")
+	defer func() {
+		daemon.EventsFile = orig
+		eventsTypes, eventsSince, eventsJSON = nil, "", false
+	}()
+
+	j := events.Open(daemon.EventsFile)
+	now := time.Now()
+	j.Append(events.Event{Time: now.Add(-2 * time.Hour), Type: events.TypeCapture, Message: "old"})
+	j.Append(events.Event{Time: now.Add(-time.Minute), Type: events.TypeError, Message: "check failed"})
+	j.Append(events.Event{Time: now, Type: events.TypeCapture, Message: "new", Path: "/tmp/x.png"})
+
+	var buf bytes.Buffer
+	eventsCmd.SetOut(&buf)
+	eventsTypes, eventsSince, eventsJSON = []string{"capture"}, "1h", true
+	if err := eventsCmd.RunE(eventsCmd, nil); err != nil {
+		t.Fatalf("events error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var e events.Event
+	if len(lines) != 1 || json.Unmarshal([]byte(lines[0]), &e) != nil || e.Path != "/tmp/x.png" {
+		t.Errorf("events --type capture --since 1h --json = %q, want only the new capture", buf.String())
+	}
+
+	// Ages take days and weeks, as everywhere else.
+	buf.Reset()
+	eventsTypes, eventsSince = nil, "1d"
+	if err := eventsCmd.RunE(eventsCmd, nil); err != nil {
+		t.Fatalf("events --since 1d error: %v", err)
+	}
+	if got := strings.Count(strings.TrimSpace(buf.String()), "\n") + 1; got != 3 {
+		t.Errorf("events --since 1d printed %d events, want 3", got)
+	}
+	eventsSince = "soon"
+	if err := eventsCmd.RunE(eventsCmd, nil); err == nil || !strings.Contains(err.Error(), "--since") {
+		t.Errorf("events --since soon error = %v, want an invalid --since", err)
+	}
+	eventsSince = ""
+
+	eventsTypes = []string{"bogus"}
+	if err := eventsCmd.RunE(eventsCmd, nil); err == nil {
+		t.Error("an unknown --type should be rejected")
+	}
+}
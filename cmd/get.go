@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logging"
+)
+
+var getOutput string
+
+// imageReader is implemented by clients that can read any image on the
+// clipboard, including the ones CHECK skips, such as our own updates.
+type imageReader interface {
+	ReadImage(w io.Writer) (bool, error)
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get [file|-]",
+	Short: "Write the clipboard image to a file or stdout",
+	Long: `Read the image on the clipboard once and write it as PNG to a file, or to
+stdout with - (the default), without saving it in the output directory or
+starting a daemon:
+
+  wsl-screenshot-cli get - | convert - -resize 50% small.png
+  wsl-screenshot-cli get -o shot.png
+
+The exit code is 1 when the clipboard holds no image.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := getOutput
+		if len(args) == 1 {
+			if cmd.Flags().Changed("output") && args[0] != out {
+				return fmt.Errorf("Give the output file either as an argument or with --output, not both")
+			}
+			out = args[0]
+		}
+
+		logger := logging.New(cmd.ErrOrStderr(), logging.FormatText, slog.LevelWarn)
+		client, err := newOneShotClient(logger)
+		if err != nil {
+			return i18n.Errorf("cmd.clipboard_client_failed", err)
+		}
+		defer client.Close()
+
+		var img bytes.Buffer
+		found := false
+		if ir, ok := client.(imageReader); ok {
+			found, err = ir.ReadImage(&img)
+		} else {
+			var data []byte
+			data, err = client.Check()
+			found = data != nil
+			img.Write(data)
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read the clipboard: %w", err)
+		}
+		if !found {
+			return i18n.Errorf("cmd.no_clipboard_image")
+		}
+
+		if out == "-" {
+			_, err = cmd.OutOrStdout().Write(img.Bytes())
+			return err
+		}
+		return os.WriteFile(out, img.Bytes(), 0644) // #nosec G306 -- an image file the user asked for, like a screenshot
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().StringVarP(&getOutput, "output", "o", "-", "File to write the PNG to, or - for stdout")
+}
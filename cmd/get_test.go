@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+)
+
+// readerClipboard is a grabClipboard whose image only ReadImage returns,
+// like our own update on a real clipboard.
+type readerClipboard struct {
+	grabClipboard
+	own []byte
+}
+
+func (c *readerClipboard) ReadImage(w io.Writer) (bool, error) {
+	_, err := w.Write(c.own)
+	return c.own != nil, err
+}
+
+func TestGetCommand(t *testing.T) {
+	orig := newOneShotClient
+	defer func() { newOneShotClient, getOutput = orig, "-" }()
+
+	clip := &readerClipboard{own: []byte("\x89PNG own update")}
+	newOneShotClient = func(*slog.Logger) (poller.Clipboard, error) { return clip, nil }
+	var out bytes.Buffer
+	getCmd.SetOut(&out)
+	if err := getCmd.RunE(getCmd, []string{"-"}); err != nil {
+		t.Fatalf("get - error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), clip.own) {
+		t.Errorf("get - wrote %q, want the clipboard image", out.Bytes())
+	}
+
+	plain := &grabClipboard{image: []byte("\x89PNG capture")}
+	newOneShotClient = func(*slog.Logger) (poller.Clipboard, error) { return plain, nil }
+	getOutput = filepath.Join(t.TempDir(), "shot.png")
+	if err := getCmd.RunE(getCmd, nil); err != nil {
+		t.Fatalf("get -o error: %v", err)
+	}
+	if data, err := os.ReadFile(getOutput); err != nil || !bytes.Equal(data, plain.image) {
+		t.Errorf("get -o wrote %q, %v; want the clipboard image", data, err)
+	}
+
+	plain.image = nil
+	if err := getCmd.RunE(getCmd, nil); err == nil {
+		t.Error("get with no image on the clipboard should fail")
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clipboard"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logging"
+	"github.com/nailuu/wsl-screenshot-cli/internal/platform"
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var (
+	grabOutputDir string
+	grabFormat    string
+)
+
+// grabResult is the --format json form of grab's output.
+type grabResult struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// newOneShotClient starts a clipboard client for commands that use the
+// clipboard once and exit, grab and last --copy. Declared as a var so tests
+// can substitute a fake clipboard.
+var newOneShotClient = func(logger *slog.Logger) (poller.Clipboard, error) {
+	if err := platform.CheckWSLEnvironment(); err != nil {
+		return nil, err
+	}
+	if err := platform.CheckWSLInterop(); err != nil {
+		return nil, err
+	}
+	return newClipboardClient(logger, clipboard.Options{})
+}
+
+var grabCmd = &cobra.Command{
+	Use:   "grab",
+	Short: "Save the screenshot on the clipboard now, without a daemon",
+	Long: `Read the clipboard once, save the image on it like the daemon would, set the
+clipboard to the saved file and print its WSL path. No background process is
+left running, so this suits scripts and occasional use:
+
+  vim "$(wsl-screenshot-cli grab)"
+
+The exit code is 1 when the clipboard holds no image.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if grabFormat != "path" && grabFormat != "json" {
+			return fmt.Errorf("Invalid --format %q (expected path or json)", grabFormat)
+		}
+		dir := grabOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return i18n.Errorf("cmd.output_not_writable", err)
+		}
+
+		logger := logging.New(cmd.ErrOrStderr(), logging.FormatText, slog.LevelWarn)
+		client, err := newOneShotClient(logger)
+		if err != nil {
+			return i18n.Errorf("cmd.clipboard_client_failed", err)
+		}
+		defer client.Close()
+
+		// Name files the way a --private-names daemon would, if one ever ran,
+		// so the same image is not saved twice under two names.
+		var nameKey []byte
+		if path, err := nameKeyFile(); err == nil {
+			nameKey, _ = store.ReadNameKey(path)
+		}
+		path, err := poller.Grab(client, logger, poller.Config{OutputDir: dir, NameKey: nameKey})
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return i18n.Errorf("cmd.no_clipboard_image")
+		}
+
+		if grabFormat == "path" {
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(cmd.OutOrStdout()).Encode(grabResult{Path: path, Size: info.Size()})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(grabCmd)
+
+	grabCmd.Flags().StringVarP(&grabOutputDir, "output", "o", "", "Directory to save the screenshot in (default: the running daemon's output dir)")
+	grabCmd.Flags().StringVar(&grabFormat, "format", "path", "Output format: path (the WSL path) or json (path and size)")
+}
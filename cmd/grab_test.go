@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+)
+
+// grabClipboard is a clipboard holding a fixed image, or none, that
+// remembers what it was last set to.
+type grabClipboard struct {
+	image          []byte
+	text, fileDrop string
+}
+
+func (c *grabClipboard) Check() ([]byte, error) { return c.image, nil }
+func (c *grabClipboard) Close() error           { return nil }
+
+func (c *grabClipboard) UpdateClipboard(text, winPath string) error {
+	c.text, c.fileDrop = text, winPath
+	return nil
+}
+
+func TestGrabCommand(t *testing.T) {
+	origClient, origKey := newOneShotClient, nameKeyFile
+	clip := &grabClipboard{image: []byte("\x89PNG fake")}
+	newOneShotClient = func(*slog.Logger) (poller.Clipboard, error) { return clip, nil }
+	nameKeyFile = func() (string, error) { return filepath.Join(t.TempDir(), "name.key"), nil }
+	defer func() {
+		newOneShotClient, nameKeyFile = origClient, origKey
+		grabOutputDir, grabFormat = "", "path"
+	}()
+
+	grabOutputDir = t.TempDir()
+	var out bytes.Buffer
+	grabCmd.SetOut(&out)
+	grabCmd.SetErr(&bytes.Buffer{})
+	if err := grabCmd.RunE(grabCmd, nil); err != nil {
+		t.Fatalf("grab error: %v", err)
+	}
+	path := strings.TrimSpace(out.String())
+	if filepath.Dir(path) != grabOutputDir {
+		t.Fatalf("grab printed %q, want a file in %s", path, grabOutputDir)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, clip.image) {
+		t.Errorf("saved file = %q, %v; want the clipboard image", data, err)
+	}
+
+	out.Reset()
+	grabFormat = "json"
+	if err := grabCmd.RunE(grabCmd, nil); err != nil {
+		t.Fatalf("grab --format json error: %v", err)
+	}
+	var res grabResult
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil || res.Path != path || res.Size != int64(len(clip.image)) {
+		t.Errorf("grab --format json = %s, want %s and its size", out.String(), path)
+	}
+
+	clip.image = nil
+	if err := grabCmd.RunE(grabCmd, nil); err == nil {
+		t.Error("grab with no image on the clipboard should fail")
+	}
+}
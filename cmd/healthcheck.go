@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+var healthProbe string
+var healthQuiet bool
+
+// checkHealth reads the daemon's health. Declared as a var so tests can
+// supply fixed states.
+var checkHealth = daemon.CheckHealth
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Probe the polling process and report its health in the exit code",
+	Long: `Probe the polling process for scripts and service managers.
+
+Three states are checked in order: the process is alive, its clipboard backend
+(powershell.exe) is ready, and its last poll succeeded. The exit code says
+which one failed first:
+
+  0  healthy (or the requested --probe passed)
+  1  down: no polling process
+  2  alive, but the clipboard backend is not ready
+  3  ready, but the last poll failed or none has completed yet
+
+--probe live only requires a running process; --probe ready also requires the
+backend, like a readiness probe.`,
+	Args:          cobra.NoArgs,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		want, ok := healthProbes[healthProbe]
+		if !ok {
+			cmd.SilenceErrors = false
+			return fmt.Errorf("Invalid --probe %q (want live, ready or healthy)", healthProbe)
+		}
+
+		h := checkHealth()
+		state := h.State()
+		if !healthQuiet {
+			fmt.Fprintln(cmd.OutOrStdout(), describeHealth(h, time.Now()))
+		}
+		if state >= want {
+			return nil
+		}
+		return &exitCodeError{code: healthExitCode(state), err: fmt.Errorf("%s", state)}
+	},
+}
+
+var healthProbes = map[string]daemon.HealthState{
+	"live":    daemon.HealthAlive,
+	"ready":   daemon.HealthReady,
+	"healthy": daemon.HealthHealthy,
+}
+
+// healthExitCode maps the furthest state reached to the documented exit code.
+func healthExitCode(s daemon.HealthState) int {
+	switch s {
+	case daemon.HealthDown:
+		return 1
+	case daemon.HealthAlive:
+		return 2
+	case daemon.HealthReady:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// describeHealth renders a one-line summary such as
+// "healthy: last poll ok 3s ago".
+func describeHealth(h daemon.Health, now time.Time) string {
+	switch h.State() {
+	case daemon.HealthDown:
+		return "down: polling process is not running"
+	case daemon.HealthAlive:
+		return "alive: clipboard backend not ready"
+	case daemon.HealthReady:
+		if h.LastPoll.IsZero() {
+			return "ready: no poll completed yet"
+		}
+		return fmt.Sprintf("ready: last poll failed %s ago", formatDuration(now.Sub(h.LastPoll)))
+	default:
+		return fmt.Sprintf("healthy: last poll ok %s ago", formatDuration(now.Sub(h.LastPoll)))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(healthcheckCmd)
+
+	healthcheckCmd.Flags().StringVar(&healthProbe, "probe", "healthy", "State required for exit code 0: live, ready, or healthy")
+	healthcheckCmd.Flags().BoolVarP(&healthQuiet, "quiet", "q", false, "Print nothing; report only through the exit code")
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+func TestHealthcheck_ExitCodes(t *testing.T) {
+	now := time.Now()
+	down := daemon.Health{}
+	alive := daemon.Health{Alive: true}
+	ready := daemon.Health{Alive: true, BackendReady: true, LastPoll: now}
+	healthy := daemon.Health{Alive: true, BackendReady: true, LastPollOK: true, LastPoll: now}
+
+	tests := []struct {
+		name     string
+		h        daemon.Health
+		probe    string
+		wantCode int
+	}{
+		{"healthy", healthy, "healthy", 0},
+		{"down", down, "healthy", 1},
+		{"backend not ready", alive, "healthy", 2},
+		{"last poll failed", ready, "healthy", 3},
+		{"live probe passes while not ready", alive, "live", 0},
+		{"live probe fails when down", down, "live", 1},
+		{"ready probe ignores poll failures", ready, "ready", 0},
+		{"ready probe fails before backend", alive, "ready", 2},
+	}
+
+	orig := checkHealth
+	defer func() { checkHealth = orig; healthProbe = "healthy" }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkHealth = func() daemon.Health { return tt.h }
+			healthProbe = tt.probe
+			var out bytes.Buffer
+			healthcheckCmd.SetOut(&out)
+			defer healthcheckCmd.SetOut(nil)
+
+			err := healthcheckCmd.RunE(healthcheckCmd, nil)
+			code := 0
+			var ec *exitCodeError
+			if errors.As(err, &ec) {
+				code = ec.code
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (output %q)", code, tt.wantCode, out.String())
+			}
+			if out.Len() == 0 {
+				t.Error("expected a summary line")
+			}
+		})
+	}
+}
+
+func TestHealthcheck_InvalidProbe(t *testing.T) {
+	healthProbe = "deep"
+	defer func() { healthProbe = "healthy"; healthcheckCmd.SilenceErrors = true }()
+	err := healthcheckCmd.RunE(healthcheckCmd, nil)
+	var ec *exitCodeError
+	if err == nil || errors.As(err, &ec) {
+		t.Errorf("error = %v, want a plain usage error", err)
+	}
+}
+
+func TestDescribeHealth(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		h    daemon.Health
+		want string
+	}{
+		{daemon.Health{}, "down: polling process is not running"},
+		{daemon.Health{Alive: true}, "alive: clipboard backend not ready"},
+		{daemon.Health{Alive: true, BackendReady: true}, "ready: no poll completed yet"},
+		{daemon.Health{Alive: true, BackendReady: true, LastPoll: now.Add(-5 * time.Second)}, "ready: last poll failed 5s ago"},
+		{daemon.Health{Alive: true, BackendReady: true, LastPollOK: true, LastPoll: now.Add(-2 * time.Second)}, "healthy: last poll ok 2s ago"},
+	}
+	for _, tt := range tests {
+		if got := describeHealth(tt.h, now); got != tt.want {
+			t.Errorf("describeHealth(%+v) = %q, want %q", tt.h, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var (
+	infoOutputDir string
+	infoJSON      bool
+)
+
+// infoWinPath converts a screenshot's WSL path to the path Windows sees.
+// Declared as a var so tests don't need wslpath.
+var infoWinPath = func(path string) (string, error) {
+	out, err := exec.Command("wslpath", "-w", path).Output() // #nosec G204 -- argv-separated (no shell)
+	if err != nil {
+		return "", fmt.Errorf("wslpath -w %q: %w", path, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// infoResult is what info prints about a screenshot.
+type infoResult struct {
+	Hash        string    `json:"hash"`
+	Path        string    `json:"path"`
+	WinPath     string    `json:"win_path,omitempty"`
+	Captured    time.Time `json:"captured,omitzero"` // from the index; zero when it has no record
+	Modified    time.Time `json:"modified"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	Window      string    `json:"window,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Seq         int       `json:"seq,omitempty"`
+	OnClipboard bool      `json:"on_clipboard"`
+	Indexed     bool      `json:"indexed"`
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <hash|path|latest>",
+	Short: "Show everything known about a saved screenshot",
+	Long: `Print the metadata of a screenshot, named by a hash prefix (as shown by
+'list'), a path, or latest: when it was captured, its dimensions and size on
+disk, its WSL and Windows paths, the window it was taken in, and whether
+it is what the running daemon last put on the clipboard.
+
+The details come from the capture index the daemon keeps in the output
+directory. For a screenshot the index has no record of, such as one saved by
+an older version, they are read from the file itself and the capture time and
+source are unknown.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := infoOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		path, err := resolveScreenshot(dir, args[0])
+		if err != nil {
+			return err
+		}
+		res, err := screenshotInfo(dir, path)
+		if err != nil {
+			return err
+		}
+		if infoJSON {
+			return json.NewEncoder(cmd.OutOrStdout()).Encode(res)
+		}
+		printInfo(cmd.OutOrStdout(), res)
+		return nil
+	},
+}
+
+// screenshotInfo gathers what the index, the file and the daemon know about
+// the screenshot at path in dir.
+func screenshotInfo(dir, path string) (infoResult, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return infoResult{}, err
+	}
+	res := infoResult{
+		Hash:     strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path:     path,
+		Modified: stat.ModTime(),
+		Size:     stat.Size(),
+	}
+	if ix, err := store.ReadIndex(dir); err == nil {
+		if e, ok := ix.Find(path); ok {
+			res.Indexed = true
+			res.Hash, res.Captured, res.Seq = e.Hash, e.Time, e.Seq
+			res.Width, res.Height, res.Window, res.Title = e.Width, e.Height, e.Window, e.Title
+		}
+	}
+	if res.Width == 0 {
+		res.Width, res.Height, _ = store.Dimensions(path)
+	}
+	res.WinPath, _ = infoWinPath(path) // left out when wslpath is unavailable
+	if current := clipboardScreenshot(); current != "" {
+		if info, err := os.Stat(current); err == nil {
+			res.OnClipboard = os.SameFile(stat, info)
+		}
+	}
+	return res, nil
+}
+
+// printInfo renders res as aligned "Label: value" lines.
+func printInfo(w io.Writer, res infoResult) {
+	unknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	captured := ""
+	if !res.Captured.IsZero() {
+		captured = res.Captured.Local().Format("2006-01-02 15:04:05")
+	}
+	dims := ""
+	if res.Width > 0 {
+		dims = fmt.Sprintf("%dx%d", res.Width, res.Height)
+	}
+	window := res.Window
+	switch {
+	case window == "":
+		window = res.Title
+	case res.Title != "":
+		window += " — " + res.Title
+	}
+	clip := "no"
+	if res.OnClipboard {
+		clip = "yes"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Hash:\t%s\n", res.Hash)
+	fmt.Fprintf(tw, "Captured:\t%s\n", unknown(captured))
+	fmt.Fprintf(tw, "Modified:\t%s\n", res.Modified.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(tw, "Dimensions:\t%s\n", unknown(dims))
+	fmt.Fprintf(tw, "Size:\t%s (%d bytes)\n", formatSize(res.Size), res.Size)
+	fmt.Fprintf(tw, "WSL path:\t%s\n", res.Path)
+	fmt.Fprintf(tw, "Windows path:\t%s\n", unknown(res.WinPath))
+	fmt.Fprintf(tw, "Source window:\t%s\n", unknown(window))
+	fmt.Fprintf(tw, "On clipboard:\t%s\n", clip)
+	_ = tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().StringVarP(&infoOutputDir, "output", "o", "", "Screenshot directory (default: the running daemon's output dir)")
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "Print the metadata as a JSON object")
+}
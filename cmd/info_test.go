@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestInfoCommand(t *testing.T) {
+	dir := t.TempDir()
+	infoOutputDir = dir
+	defer func() { infoOutputDir, infoJSON = "", false }()
+	origWin, origClip := infoWinPath, clipboardScreenshot
+	defer func() { infoWinPath, clipboardScreenshot = origWin, origClip }()
+	infoWinPath = func(p string) (string, error) { return `C:\shots\` + filepath.Base(p), nil }
+
+	shot := filepath.Join(dir, "2024-05-01_14-32-07.png")
+	plain := filepath.Join(dir, "cd56.png")
+	for _, p := range []string{shot, plain} {
+		if err := os.WriteFile(p, []byte("png"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clipboardScreenshot = func() string { return shot }
+	captured := time.Date(2024, 5, 1, 14, 32, 7, 0, time.UTC)
+	entry := store.IndexEntry{Hash: "ab12cd", Time: captured, Seq: 3, Size: 3, Width: 1920, Height: 1080, Window: "chrome", Title: "Inbox - Google Chrome"}
+	if err := store.AppendIndex(dir, shot, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	infoCmd.SetOut(&out)
+	if err := infoCmd.RunE(infoCmd, []string{"ab1"}); err != nil {
+		t.Fatalf("info error: %v", err)
+	}
+	for _, want := range []string{"ab12cd", "1920x1080", "3 bytes", shot, `C:\shots\2024-05-01_14-32-07.png`, "chrome — Inbox - Google Chrome", "On clipboard:  yes"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("info output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	// Unindexed, it falls back on the file.
+	out.Reset()
+	infoJSON = true
+	if err := infoCmd.RunE(infoCmd, []string{"cd5"}); err != nil {
+		t.Fatalf("info --json error: %v", err)
+	}
+	var res infoResult
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("info --json output %q: %v", out.String(), err)
+	}
+	if res.Hash != "cd56" || res.Indexed || res.OnClipboard || !res.Captured.IsZero() || res.Path != plain {
+		t.Errorf("info --json = %+v, want the unindexed file's details", res)
+	}
+}
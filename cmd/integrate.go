@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// integrationAction is one command exposed to an editor or terminal.
+type integrationAction struct {
+	Label string
+	Args  []string
+	Key   string
+	// Insert types the command's output into the focused terminal through
+	// shell substitution instead of running it as a visible command.
+	Insert bool
+}
+
+// integrationActions are the commands wired up by integrate. Each one must
+// produce output that is safe to embed (--quiet on failure where it inserts,
+// and nothing but the path on stdout). There is no capture action: taking
+// the screenshot is Windows' job (Win+Shift+S), and grab saves it from there.
+var integrationActions = []integrationAction{
+	{Label: "Insert latest screenshot path", Args: []string{"last", "--quiet"}, Key: "alt+s", Insert: true},
+	{Label: "Save clipboard screenshot and insert its path", Args: []string{"grab", "--format", "path"}, Key: "alt+g", Insert: true},
+	{Label: "Copy latest screenshot back to the clipboard", Args: []string{"last", "--copy", "--quiet"}, Key: "ctrl+alt+c"},
+	{Label: "Screenshot daemon status", Args: []string{"status"}, Key: "ctrl+alt+s"},
+}
+
+var integrateCmd = &cobra.Command{
+	Use:   "integrate vscode|windows-terminal",
+	Short: "Print editor or terminal key bindings that call wsl-screenshot-cli",
+	Long: `Print configuration snippets that bind keyboard shortcuts to wsl-screenshot-cli
+outside the shell:
+
+  vscode             tasks.json tasks and keybindings.json entries
+  windows-terminal   actions for Windows Terminal's settings.json
+
+Both assume the focused terminal is a WSL shell. Paste each section into the
+file named in its comment.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"vscode", "windows-terminal"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "vscode":
+			return writeVSCode(cmd.OutOrStdout(), integrationActions)
+		case "windows-terminal":
+			return writeWindowsTerminal(cmd.OutOrStdout(), integrationActions)
+		default:
+			return fmt.Errorf("Unsupported target %q (supported: vscode, windows-terminal)", args[0])
+		}
+	},
+}
+
+// commandLine renders a as a shell command. Insert actions become a quoted
+// substitution followed by a space, ready for the next word.
+func (a integrationAction) commandLine() string {
+	line := "wsl-screenshot-cli " + strings.Join(a.Args, " ")
+	if a.Insert {
+		return `"$(` + line + `)" `
+	}
+	return line
+}
+
+func writeVSCode(w io.Writer, actions []integrationAction) error {
+	type task struct {
+		Label        string            `json:"label"`
+		Type         string            `json:"type"`
+		Command      string            `json:"command"`
+		Presentation map[string]string `json:"presentation"`
+		Problems     []string          `json:"problemMatcher"`
+	}
+	type keybinding struct {
+		Key     string `json:"key"`
+		Command string `json:"command"`
+		Args    any    `json:"args"`
+		When    string `json:"when,omitempty"`
+	}
+
+	tasks := []task{}
+	keys := []keybinding{}
+	for _, a := range actions {
+		if a.Insert {
+			keys = append(keys, keybinding{
+				Key:     a.Key,
+				Command: "workbench.action.terminal.sendSequence",
+				Args:    map[string]string{"text": a.commandLine()},
+				When:    "terminalFocus",
+			})
+			continue
+		}
+		label := "wsl-screenshot-cli: " + a.Label
+		tasks = append(tasks, task{
+			Label:        label,
+			Type:         "shell",
+			Command:      a.commandLine(),
+			Presentation: map[string]string{"reveal": "always", "panel": "shared"},
+			Problems:     []string{},
+		})
+		keys = append(keys, keybinding{Key: a.Key, Command: "workbench.action.tasks.runTask", Args: label})
+	}
+
+	fmt.Fprintln(w, "// .vscode/tasks.json")
+	if err := writeJSON(w, map[string]any{"version": "2.0.0", "tasks": tasks}); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// keybindings.json (Preferences: Open Keyboard Shortcuts (JSON))")
+	return writeJSON(w, keys)
+}
+
+func writeWindowsTerminal(w io.Writer, actions []integrationAction) error {
+	type action struct {
+		Name    string            `json:"name"`
+		Command map[string]string `json:"command"`
+		Keys    string            `json:"keys"`
+	}
+
+	out := make([]action, 0, len(actions))
+	for _, a := range actions {
+		input := a.commandLine()
+		if !a.Insert {
+			input += "\r"
+		}
+		out = append(out, action{
+			Name:    a.Label,
+			Command: map[string]string{"action": "sendInput", "input": input},
+			Keys:    a.Key,
+		})
+	}
+
+	fmt.Fprintln(w, `// settings.json: merge into the "actions" array`)
+	return writeJSON(w, out)
+}
+
+// writeJSON writes v indented and without HTML escaping, so shell syntax such
+// as "&&" stays readable in the pasted snippet.
+func writeJSON(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(integrateCmd)
+}
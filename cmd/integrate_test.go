@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// jsonSections splits integrate output on its "//" comment lines and decodes
+// each section.
+func jsonSections(t *testing.T, out string) []any {
+	t.Helper()
+	var sections []any
+	for _, part := range strings.Split(out, "//")[1:] {
+		_, body, _ := strings.Cut(part, "\n")
+		var v any
+		if err := json.Unmarshal([]byte(body), &v); err != nil {
+			t.Fatalf("section is not valid JSON: %v\n%s", err, body)
+		}
+		sections = append(sections, v)
+	}
+	return sections
+}
+
+func TestWriteVSCode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVSCode(&buf, integrationActions); err != nil {
+		t.Fatal(err)
+	}
+	sections := jsonSections(t, buf.String())
+	if len(sections) != 2 {
+		t.Fatalf("got %d sections, want tasks.json and keybindings.json", len(sections))
+	}
+
+	tasks := sections[0].(map[string]any)["tasks"].([]any)
+	wantTasks := []string{"wsl-screenshot-cli last --copy --quiet", "wsl-screenshot-cli status"}
+	if len(tasks) != len(wantTasks) {
+		t.Fatalf("tasks = %v, want %v", tasks, wantTasks)
+	}
+	for i, task := range tasks {
+		if got := task.(map[string]any)["command"]; got != wantTasks[i] {
+			t.Errorf("task %d command = %v, want %q", i, got, wantTasks[i])
+		}
+	}
+	keys := sections[1].([]any)
+	if len(keys) != len(integrationActions) {
+		t.Fatalf("got %d keybindings, want %d", len(keys), len(integrationActions))
+	}
+	for i, text := range []string{`"$(wsl-screenshot-cli last --quiet)" `, `"$(wsl-screenshot-cli grab --format path)" `} {
+		insert := keys[i].(map[string]any)
+		if insert["command"] != "workbench.action.terminal.sendSequence" || insert["args"].(map[string]any)["text"] != text {
+			t.Errorf("insert keybinding %d = %v, want it to send %q", i, insert, text)
+		}
+	}
+	if run := keys[2].(map[string]any); run["args"] != "wsl-screenshot-cli: Copy latest screenshot back to the clipboard" {
+		t.Errorf("task keybinding args = %v, want the task label", run["args"])
+	}
+	if run := keys[3].(map[string]any); run["args"] != "wsl-screenshot-cli: Screenshot daemon status" {
+		t.Errorf("task keybinding args = %v, want the task label", run["args"])
+	}
+}
+
+func TestWriteWindowsTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeWindowsTerminal(&buf, integrationActions); err != nil {
+		t.Fatal(err)
+	}
+	sections := jsonSections(t, buf.String())
+	actions := sections[0].([]any)
+	want := []string{
+		`"$(wsl-screenshot-cli last --quiet)" `,
+		`"$(wsl-screenshot-cli grab --format path)" `,
+		"wsl-screenshot-cli last --copy --quiet\r",
+		"wsl-screenshot-cli status\r",
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("got %d actions, want %d", len(actions), len(want))
+	}
+	for i, a := range actions {
+		cmd := a.(map[string]any)["command"].(map[string]any)
+		if cmd["action"] != "sendInput" || cmd["input"] != want[i] {
+			t.Errorf("action %d = %v, want sendInput %q", i, cmd, want[i])
+		}
+	}
+}
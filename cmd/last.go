@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var (
+	lastOutputDir string
+	lastJSON      bool
+	lastQuiet     bool
+	lastCopy      bool
+)
+
+// lastResult is the --json form of last's output.
+type lastResult struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Print the path of the most recent screenshot",
+	Long: `Print the WSL path of the most recently captured screenshot, so it can be
+used in command substitution: cp "$(wsl-screenshot-cli last)" ./docs/
+
+--quiet prints nothing when there is no screenshot (the exit code is still 1),
+which keeps editor and terminal integrations from inserting error text.
+
+--copy also puts the screenshot back on the clipboard, as the daemon set it
+after the capture, for when something else was copied since.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceErrors = lastQuiet
+
+		dir := lastOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+
+		path, err := store.Latest(dir)
+		if errors.Is(err, store.ErrEmpty) {
+			return i18n.Errorf("cmd.no_screenshots", dir)
+		}
+		if err != nil {
+			return err
+		}
+
+		if lastCopy {
+			if err := copyScreenshot(cmd, path); err != nil {
+				return err
+			}
+		}
+
+		if !lastJSON {
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		return enc.Encode(lastResult{Path: path, Size: info.Size(), Modified: info.ModTime()})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lastCmd)
+
+	lastCmd.Flags().StringVarP(&lastOutputDir, "output", "o", "", "Screenshot directory (default: the running daemon's output dir)")
+	lastCmd.Flags().BoolVar(&lastJSON, "json", false, "Print the path, size and modification time as JSON")
+	lastCmd.Flags().BoolVar(&lastCopy, "copy", false, "Also put the screenshot back on the clipboard (path, image and file)")
+	lastCmd.Flags().BoolVarP(&lastQuiet, "quiet", "q", false, "Print nothing on error, only set the exit code")
+}
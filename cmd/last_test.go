@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+)
+
+func TestLastCommand_Copy(t *testing.T) {
+	origClient, origWin, origSocket := newOneShotClient, copyWinPath, daemon.SocketFile
+	daemon.SocketFile = filepath.Join(t.TempDir(), "none.sock") // no daemon: one-shot client
+	clip := &grabClipboard{}
+	newOneShotClient = func(*slog.Logger) (poller.Clipboard, error) { return clip, nil }
+	copyWinPath = func(p string) (string, error) { return `C:\shots\` + filepath.Base(p), nil }
+	defer func() {
+		newOneShotClient, copyWinPath, daemon.SocketFile = origClient, origWin, origSocket
+		lastOutputDir, lastCopy = "", false
+	}()
+
+	lastOutputDir = t.TempDir()
+	path := filepath.Join(lastOutputDir, "abc.png")
+	if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	lastCmd.SetOut(&out)
+	lastCopy = true
+	if err := lastCmd.RunE(lastCmd, nil); err != nil {
+		t.Fatalf("last --copy error: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != path {
+		t.Errorf("last --copy printed %q, want %q", got, path)
+	}
+	if clip.text != path || clip.fileDrop != `C:\shots\abc.png` {
+		t.Errorf("clipboard set to %q / %q, want the screenshot's paths", clip.text, clip.fileDrop)
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var (
+	listOutputDir string
+	listSince     string
+	listUntil     string
+	listSource    string
+	listMinSize   string
+	listLimit     int
+	listJSON      bool
+)
+
+// listEntry is one screenshot in list's output.
+type listEntry struct {
+	Hash     string    `json:"hash"`
+	Path     string    `json:"path"`
+	Modified time.Time `json:"modified"`
+	Size     int64     `json:"size"`
+	Width    int       `json:"width,omitempty"` // 0 when the PNG header can't be read
+	Height   int       `json:"height,omitempty"`
+	Window   string    `json:"window,omitempty"` // the foreground process at capture time, when indexed
+	Title    string    `json:"title,omitempty"`  // and its window title
+}
+
+// listFilter selects the screenshots list shows. Zero fields don't filter.
+type listFilter struct {
+	since, until time.Time // bounds on the modification time
+	source       string    // lowercase; must occur in the window's process or title
+	minSize      int64
+	limit        int
+}
+
+// parseListFilter builds the filter from list's flags, relative to now.
+func parseListFilter(now time.Time) (listFilter, error) {
+	f := listFilter{source: strings.ToLower(listSource), limit: listLimit}
+	if listLimit < 0 {
+		return f, i18n.Errorf("cmd.flag_negative", "limit", listLimit)
+	}
+	if listSince != "" {
+		age, err := config.ParseAge(listSince)
+		if err != nil {
+			return f, i18n.Errorf("cmd.invalid_flag", "since", err)
+		}
+		f.since = now.Add(-age)
+	}
+	if listUntil != "" {
+		age, err := config.ParseAge(listUntil)
+		if err != nil {
+			return f, i18n.Errorf("cmd.invalid_flag", "until", err)
+		}
+		f.until = now.Add(-age)
+	}
+	if !f.since.IsZero() && !f.until.IsZero() && !f.since.Before(f.until) {
+		return f, fmt.Errorf("Invalid time range: --since %s is not further back than --until %s", listSince, listUntil)
+	}
+	if listMinSize != "" {
+		var err error
+		if f.minSize, err = config.ParseSize(listMinSize); err != nil {
+			return f, i18n.Errorf("cmd.invalid_flag", "min-size", err)
+		}
+	}
+	return f, nil
+}
+
+// matches reports whether e passes the filter's bounds, limit aside.
+func (f listFilter) matches(e listEntry) bool {
+	if !f.until.IsZero() && e.Modified.After(f.until) {
+		return false
+	}
+	if e.Size < f.minSize {
+		return false
+	}
+	return f.source == "" ||
+		strings.Contains(strings.ToLower(e.Window), f.source) ||
+		strings.Contains(strings.ToLower(e.Title), f.source)
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved screenshots, newest first",
+	Long: `List the screenshots in the output directory, newest first, with their
+modification time, size, dimensions, the application they were taken in and
+hash. These come from the capture index the daemon keeps in the output
+directory, or for screenshots it has no record of, from the file name and the
+PNG header; the images are not decoded. --json adds the window title.
+
+--since and --until bound the modification time, given as an age such as 30m,
+36h or 2d: --since 2d --until 1d lists yesterday's screenshots. --source keeps
+those taken in a window whose process or title contains the text, ignoring
+case; screenshots the index has no window for never match it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := parseListFilter(time.Now())
+		if err != nil {
+			return err
+		}
+		dir := listOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		snap, err := store.Scan(dir)
+		if err != nil {
+			return err
+		}
+		ix, err := store.ReadIndex(dir)
+		if err != nil {
+			return err
+		}
+		entries := listEntries(snap.Newest(), ix, filter)
+
+		w := cmd.OutOrStdout()
+		if listJSON {
+			if entries == nil {
+				entries = []listEntry{} // [] rather than null
+			}
+			return json.NewEncoder(w).Encode(entries)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintln(w, i18n.T("cmd.no_screenshots", dir))
+			return nil
+		}
+		printList(w, entries)
+		return nil
+	},
+}
+
+// listEntries turns files, newest first, into the list entries filter
+// selects. What ix records about a file is used rather than read again.
+func listEntries(files []store.File, ix *store.Index, filter listFilter) []listEntry {
+	var entries []listEntry
+	for _, f := range files {
+		if f.ModTime.Before(filter.since) {
+			break // the rest are older still
+		}
+		if filter.limit > 0 && len(entries) == filter.limit {
+			break
+		}
+		e := listEntry{
+			Hash:     strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path)),
+			Path:     f.Path,
+			Modified: f.ModTime,
+			Size:     f.Size,
+		}
+		if rec, ok := ix.Find(f.Path); ok {
+			e.Hash, e.Width, e.Height = rec.Hash, rec.Width, rec.Height
+			e.Window, e.Title = rec.Window, rec.Title
+		}
+		if !filter.matches(e) {
+			continue
+		}
+		if e.Width == 0 {
+			e.Width, e.Height, _ = store.Dimensions(f.Path)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// printList renders entries as a table.
+func printList(w io.Writer, entries []listEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODIFIED\tSIZE\tDIMENSIONS\tWINDOW\tHASH")
+	for _, e := range entries {
+		dims := "?"
+		if e.Width > 0 {
+			dims = fmt.Sprintf("%dx%d", e.Width, e.Height)
+		}
+		window := e.Window
+		if window == "" {
+			window = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Modified.Format("2006-01-02 15:04:05"), formatSize(e.Size), dims, window, e.Hash)
+	}
+	_ = tw.Flush()
+}
+
+// formatSize formats a file size in KB below a megabyte, in MB above.
+func formatSize(n int64) string {
+	if n < 1<<20 {
+		return fmt.Sprintf("%d KB", (n+1023)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVarP(&listOutputDir, "output", "o", "", "Screenshot directory (default: the running daemon's output dir)")
+	listCmd.Flags().StringVar(&listSince, "since", "", "Only list screenshots from this long ago, e.g. 30m, 1h or 2d")
+	listCmd.Flags().StringVar(&listUntil, "until", "", "Only list screenshots at least this old, e.g. 1d")
+	listCmd.Flags().StringVar(&listSource, "source", "", "Only list screenshots taken in a window whose process or title contains this text")
+	listCmd.Flags().StringVar(&listMinSize, "min-size", "", "Only list screenshots of at least this size, e.g. 500KB")
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 20, "List at most this many screenshots (0 = all)")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print a JSON array with the hash, path, time, size, dimensions and window of each")
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestListCommand(t *testing.T) {
+	defer func() { listOutputDir, listSince, listLimit, listJSON = "", "", 20, false }()
+	listOutputDir = t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old", "mid", "new"} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4+i, 3))); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(listOutputDir, name+".png")
+		if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(time.Duration(i-2) * time.Hour) // old: 2h ago, new: now
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	listCmd.SetOut(&out)
+	listLimit = 2
+	if err := listCmd.RunE(listCmd, nil); err != nil {
+		t.Fatalf("list error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "6x3") || !strings.HasSuffix(lines[1], "new") || !strings.HasSuffix(lines[2], "mid") {
+		t.Errorf("list --limit 2 =\n%s\nwant a header, then new and mid", out.String())
+	}
+
+	out.Reset()
+	listLimit, listSince, listJSON = 0, "90m", true
+	if err := listCmd.RunE(listCmd, nil); err != nil {
+		t.Fatalf("list --json error: %v", err)
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatalf("list --json output %q: %v", out.String(), err)
+	}
+	if len(entries) != 2 || entries[0].Hash != "new" || entries[1].Width != 5 || entries[1].Height != 3 {
+		t.Errorf("list --since 90m --json = %+v, want new and mid with their dimensions", entries)
+	}
+}
+
+func TestListCommand_UsesIndex(t *testing.T) {
+	defer func() { listOutputDir, listJSON = "", false }()
+	listOutputDir = t.TempDir()
+	path := filepath.Join(listOutputDir, "2024-05-01_14-32-07.png")
+	if err := os.WriteFile(path, []byte("not read"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entry := store.IndexEntry{Hash: "ab12cd", Width: 1920, Height: 1080, Window: "chrome", Title: "Inbox - Google Chrome"}
+	if err := store.AppendIndex(listOutputDir, path, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	listCmd.SetOut(&out)
+	if err := listCmd.RunE(listCmd, nil); err != nil {
+		t.Fatalf("list error: %v", err)
+	}
+	if !strings.Contains(out.String(), "1920x1080   chrome  ab12cd") {
+		t.Errorf("list =\n%s\nwant the indexed dimensions, window and hash", out.String())
+	}
+
+	out.Reset()
+	listJSON = true
+	if err := listCmd.RunE(listCmd, nil); err != nil {
+		t.Fatalf("list --json error: %v", err)
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatalf("list --json output %q: %v", out.String(), err)
+	}
+	if len(entries) != 1 || entries[0].Hash != "ab12cd" || entries[0].Width != 1920 || entries[0].Window != "chrome" || entries[0].Title != "Inbox - Google Chrome" {
+		t.Errorf("list --json = %+v, want the indexed hash, dimensions and window", entries)
+	}
+}
+
+func TestListCommand_Filters(t *testing.T) {
+	defer func() {
+		listOutputDir, listSince, listUntil, listSource, listMinSize, listLimit, listJSON = "", "", "", "", "", 20, false
+	}()
+	listOutputDir = t.TempDir()
+	now := time.Now()
+	for i, shot := range []struct {
+		name, window, title string
+		size                int
+	}{
+		{"old", "chrome", "Inbox - Google Chrome", 4096},
+		{"mid", "SnippingTool", "Snipping Tool", 100},
+		{"new", "Code", "list.go - Visual Studio Code", 4096},
+	} {
+		path := filepath.Join(listOutputDir, shot.name+".png")
+		if err := os.WriteFile(path, make([]byte, shot.size), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(time.Duration(i-2) * 24 * time.Hour) // old: 2 days ago, new: now
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		entry := store.IndexEntry{Hash: shot.name, Window: shot.window, Title: shot.title}
+		if err := store.AppendIndex(listOutputDir, path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		since, until, source, minSize string
+		want                          string
+	}{
+		{since: "36h", want: "new mid"},
+		{since: "3d", until: "12h", want: "mid old"},
+		{source: "CHROME", want: "old"},
+		{source: "visual studio", want: "new"}, // matches the title
+		{minSize: "1KB", want: "new old"},
+		{since: "3d", source: "snip", minSize: "1KB", want: ""},
+	} {
+		listSince, listUntil, listSource, listMinSize, listLimit, listJSON = tc.since, tc.until, tc.source, tc.minSize, 0, true
+		var out bytes.Buffer
+		listCmd.SetOut(&out)
+		if err := listCmd.RunE(listCmd, nil); err != nil {
+			t.Fatalf("list %+v error: %v", tc, err)
+		}
+		var entries []listEntry
+		if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+			t.Fatalf("list --json output %q: %v", out.String(), err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Hash)
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("list %+v = %v, want %s", tc, got, tc.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"1d", "2d"}, {"soon", ""}, {"", "later"}} {
+		listSince, listUntil, listSource, listMinSize = bad[0], bad[1], "", ""
+		if err := listCmd.RunE(listCmd, nil); err == nil {
+			t.Errorf("list --since %q --until %q succeeded, want an error", bad[0], bad[1])
+		}
+	}
+	listSince, listUntil, listMinSize = "", "", "lots"
+	if err := listCmd.RunE(listCmd, nil); err == nil || !strings.Contains(err.Error(), "--min-size") {
+		t.Errorf("list --min-size lots error = %v, want an invalid --min-size error", err)
+	}
+}
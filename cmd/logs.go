@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logtail"
+)
+
+var (
+	logsFollow bool
+	logsLines  int
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the daemon's log",
+	Long: `Show the last lines of the daemon's log file, wherever the current profile
+keeps it. With --follow, keep printing new lines as the daemon writes them
+(including across restarts) until interrupted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if logsLines < 0 {
+			return i18n.Errorf("cmd.flag_negative", "lines", logsLines)
+		}
+		w := cmd.OutOrStdout()
+		data, offset, err := logtail.Last(daemon.LogFile, logsLines)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && logsFollow:
+			// Wait for a daemon that hasn't started yet.
+		case errors.Is(err, os.ErrNotExist):
+			return i18n.Errorf("cmd.no_log_file", daemon.LogFile)
+		case err != nil:
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if !logsFollow {
+			return nil
+		}
+		return logtail.Follow(cmd.Context(), daemon.LogFile, offset, w, clock.Real{})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new log lines until interrupted")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of lines to show from the end of the log")
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+func TestLogsCommand(t *testing.T) {
+	orig := daemon.LogFile
+	daemon.LogFile = filepath.Join(t.TempDir(), "test.log")
+	defer func() {
+		daemon.LogFile = orig
+		logsFollow, logsLines = false, 100
+	}()
+
+	if err := logsCmd.RunE(logsCmd, nil); err == nil || !strings.Contains(err.Error(), daemon.LogFile) {
+		t.Errorf("logs without a log file: err = %v, want one naming the path", err)
+	}
+
+	if err := os.WriteFile(daemon.LogFile, []byte("one\ntwo\nthree\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logsCmd.SetOut(&buf)
+	logsLines = 2
+	if err := logsCmd.RunE(logsCmd, nil); err != nil {
+		t.Fatalf("logs -n 2 error: %v", err)
+	}
+	if buf.String() != "two\nthree\n" {
+		t.Errorf("logs -n 2 = %q, want the last two lines", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+// migrateStopTimeout bounds how long migrate-output waits for the daemon to exit.
+const migrateStopTimeout = 5 * time.Second
+
+var (
+	migrateFrom        string
+	migrateOnCollision string
+	migrateDirMode     string
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate-output <newdir>",
+	Short: "Move all screenshots to a new output directory",
+	Long: `Move every screenshot (including daily subdirectories) from the current
+output directory to <newdir>. A running daemon is stopped for the move and
+relaunched with the same flags pointing at the new directory, so dedup history
+carries over instead of being stranded in the old directory.
+
+A screenshot whose name is taken in <newdir> by a different file is handled
+by --on-collision, and directories created in <newdir> get --dir-mode; both
+default to the running daemon's settings.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := cmd.OutOrStdout()
+		newDir := filepath.Clean(args[0])
+
+		inst, running := daemon.LookupInstance(daemon.InstanceName)
+		oldDir := migrateFrom
+		if oldDir == "" {
+			if running {
+				oldDir = inst.OutputDir
+			} else {
+				oldDir = daemon.ReadOutputDir()
+			}
+		}
+
+		collision, err := migrateCollision(running)
+		if err != nil {
+			return err
+		}
+		mode, err := migrateMode(running)
+		if err != nil {
+			return err
+		}
+
+		if running {
+			daemon.Stop()
+			if !daemon.WaitForExit(inst.PID, migrateStopTimeout) {
+				return i18n.Errorf("cmd.migrate_not_stopped", inst.PID)
+			}
+		}
+
+		res, err := store.Move(oldDir, newDir, store.MoveOptions{OnCollision: collision, DirMode: mode})
+		if err != nil {
+			if running {
+				_ = daemon.Daemonize(inst.Args) // put the daemon back as it was
+			}
+			return fmt.Errorf("Migration failed after moving %d screenshots: %w", res.Moved, err)
+		}
+		fmt.Fprintln(w, i18n.T("cmd.migrated", res.Moved, oldDir, newDir))
+		if len(res.Skipped) > 0 {
+			fmt.Fprintf(w, "Left %d screenshots in %s whose names are taken by different files (--on-collision skip): %s\n", len(res.Skipped), oldDir, strings.Join(res.Skipped, ", "))
+		}
+
+		if running {
+			return daemon.Daemonize(withOutputDir(inst.Args, newDir))
+		}
+		return nil
+	},
+}
+
+// migrateCollision returns the collision policy for the move: --on-collision,
+// or else the running daemon's, or else the suffix policy.
+func migrateCollision(running bool) (store.CollisionPolicy, error) {
+	name := migrateOnCollision
+	if name == "" && running {
+		if st, err := daemon.ReadState(); err == nil {
+			name = st.Settings["on-collision"]
+		}
+	}
+	if name == "" {
+		return store.CollisionSuffix, nil
+	}
+	collision, err := store.ParseCollisionPolicy(name)
+	if err != nil {
+		return "", i18n.Errorf("cmd.invalid_flag", "on-collision", err)
+	}
+	return collision, nil
+}
+
+// migrateMode returns the permission bits of the directories the move
+// creates: --dir-mode, or else the running daemon's, or else 0700.
+func migrateMode(running bool) (os.FileMode, error) {
+	mode := migrateDirMode
+	if mode == "" && running {
+		if st, err := daemon.ReadState(); err == nil {
+			mode = st.Settings["dir-mode"]
+		}
+	}
+	if mode == "" {
+		return 0700, nil
+	}
+	m, err := config.ParseFileMode(mode)
+	if err != nil {
+		return 0, i18n.Errorf("cmd.invalid_flag", "dir-mode", err)
+	}
+	return m, nil
+}
+
+// withOutputDir returns a copy of the daemon's start args with --output set to dir.
+func withOutputDir(args []string, dir string) []string {
+	out := make([]string, 0, len(args)+2)
+	replaced := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--output" && i+1 < len(args) {
+			out = append(out, "--output", dir)
+			replaced = true
+			i++
+			continue
+		}
+		out = append(out, args[i])
+	}
+	if !replaced {
+		out = append(out, "--output", dir)
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "Directory to migrate from (default: the running daemon's output dir)")
+	migrateCmd.Flags().StringVar(&migrateOnCollision, "on-collision", "", "When a file name is taken by different content: suffix, overwrite, or skip (default: the running daemon's setting, else suffix)")
+	migrateCmd.Flags().StringVar(&migrateDirMode, "dir-mode", "", "Permission bits of the directories created in <newdir>, in octal (default: the running daemon's --dir-mode, else 0700)")
+}
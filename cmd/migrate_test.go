@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithOutputDir(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			"replaces existing",
+			[]string{"--interval", "250", "--output", "/tmp/old", "--daily-dirs=true"},
+			[]string{"--interval", "250", "--output", "/mnt/c/shots", "--daily-dirs=true"},
+		},
+		{
+			"appends when missing",
+			nil,
+			[]string{"--output", "/mnt/c/shots"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withOutputDir(tt.args, "/mnt/c/shots")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withOutputDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+var openOutputDir string
+
+// openInWindows opens path, a file or directory in WSL, with its default
+// Windows application: Explorer for directories, the image viewer for
+// screenshots. Declared as a var so tests don't launch anything.
+var openInWindows = func(path string) error {
+	out, err := exec.Command("wslpath", "-w", path).Output() // #nosec G204 -- argv-separated (no shell)
+	if err != nil {
+		return fmt.Errorf("wslpath -w %q: %w", path, err)
+	}
+	winPath := strings.TrimSpace(string(out))
+	err = exec.Command("explorer.exe", winPath).Run() // #nosec G204 -- argv-separated (no shell)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return nil // explorer.exe exits with 1 even when it opened the path
+	}
+	return err
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open [hash|path|latest]",
+	Short: "Open the output directory or a screenshot in Windows",
+	Long: `Open the output directory in Windows Explorer or, given a screenshot (a hash
+prefix as shown by 'list', a path, or latest), open it in the default
+Windows image viewer.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := openOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		path := dir
+		if len(args) == 1 {
+			var err error
+			if path, err = resolveScreenshot(dir, args[0]); err != nil {
+				return err
+			}
+		} else if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+		if err := openInWindows(path); err != nil {
+			return fmt.Errorf("Failed to open %s: %w", path, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+
+	openCmd.Flags().StringVarP(&openOutputDir, "output", "o", "", "Screenshot directory (default: the running daemon's output dir)")
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenCommand(t *testing.T) {
+	orig := openInWindows
+	var opened []string
+	openInWindows = func(path string) error {
+		opened = append(opened, path)
+		return nil
+	}
+	defer func() { openInWindows, openOutputDir = orig, "" }()
+
+	openOutputDir = t.TempDir()
+	shot := filepath.Join(openOutputDir, "3f2a9c.png")
+	if err := os.WriteFile(shot, []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := openCmd.RunE(openCmd, nil); err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	if err := openCmd.RunE(openCmd, []string{"3f2a"}); err != nil {
+		t.Fatalf("open 3f2a error: %v", err)
+	}
+	if len(opened) != 2 || opened[0] != openOutputDir || opened[1] != shot {
+		t.Errorf("opened %v, want the output dir, then %s", opened, shot)
+	}
+	if err := openCmd.RunE(openCmd, []string{"ffff"}); err == nil {
+		t.Error("open with an unknown hash should fail")
+	}
+}
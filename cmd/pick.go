@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var (
+	pickOutputDir string
+	pickLimit     int
+	pickPrint     bool
+)
+
+// pickCancelledCode is pick's exit status when nothing was picked, as fzf's.
+const pickCancelledCode = 130
+
+// runPicker shows m on the terminal until the user picks a screenshot or
+// gives up, drawing on out. Declared as a var so tests can drive the model
+// without a terminal.
+var runPicker = func(m pickModel, out io.Writer) (pickModel, error) {
+	// Keys come from the terminal itself, as with fzf, so pick works inside
+	// $(...) and pipelines; stdout is left for the path.
+	final, err := tea.NewProgram(m, tea.WithInputTTY(), tea.WithOutput(out), tea.WithAltScreen()).Run()
+	if err != nil {
+		return m, err
+	}
+	return final.(pickModel), nil
+}
+
+var pickCmd = &cobra.Command{
+	Use:   "pick",
+	Short: "Choose a past screenshot interactively and copy it",
+	Long: `Show the recent screenshots in an interactive list, newest first, with when
+they were taken and the window they were taken in, and narrow it down by
+typing. Like fzf, the letters typed need only appear in order: "chr 10m" finds
+the screenshot taken in Chrome 10 minutes ago. Words are matched separately
+against the date, age, process, window title and hash.
+
+Up and Down (or Ctrl-P and Ctrl-N) move the selection, Enter puts the
+screenshot back on the clipboard as 'copy' does and prints its path, and Esc
+or Ctrl-C leaves without picking (exit status 130). With --print the path is
+only printed, for use in command substitution:
+
+  cp "$(wsl-screenshot-cli pick --print)" ./docs/
+
+The list is drawn on the terminal, not stdout, so it works inside $(...) too.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pickLimit < 0 {
+			return i18n.Errorf("cmd.flag_negative", "limit", pickLimit)
+		}
+		dir := pickOutputDir
+		if dir == "" {
+			dir = daemon.ReadOutputDir()
+		}
+		snap, err := store.Scan(dir)
+		if err != nil {
+			return err
+		}
+		ix, err := store.ReadIndex(dir)
+		if err != nil {
+			return err
+		}
+		entries := listEntries(snap.Newest(), ix, listFilter{limit: pickLimit})
+		if len(entries) == 0 {
+			return i18n.Errorf("cmd.no_screenshots", dir)
+		}
+
+		m, err := runPicker(newPickModel(entries, time.Now()), cmd.ErrOrStderr())
+		if err != nil {
+			return fmt.Errorf("Failed to run the picker: %w", err)
+		}
+		if m.chosen == "" {
+			cmd.SilenceErrors = true // like Esc in fzf, leaving is not an error worth a message
+			return &exitCodeError{code: pickCancelledCode, err: errors.New("no screenshot picked")}
+		}
+		if !pickPrint {
+			if err := copyScreenshot(cmd, m.chosen); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), m.chosen)
+		return nil
+	},
+}
+
+// pickModel is the picker's state: the screenshots, what has been typed, and
+// which of them match it, best first.
+type pickModel struct {
+	entries []listEntry
+	rows    []string // each entry as displayed, newest first
+	search  []string // each row lowercased, for matching
+
+	query   []rune
+	matches []int // indexes into entries, best match first
+	cursor  int   // into matches
+	offset  int   // first match shown
+	width   int
+	height  int // rows available for matches
+
+	chosen string // the picked screenshot's path, "" until Enter
+}
+
+// newPickModel lists entries, newest first, as they look at now.
+func newPickModel(entries []listEntry, now time.Time) pickModel {
+	m := pickModel{entries: entries, width: 80, height: 10}
+	for _, e := range entries {
+		window := e.Window
+		if window == "" {
+			window = "-"
+		}
+		hash := e.Hash
+		if len(hash) > 8 {
+			hash = hash[:8]
+		}
+		row := fmt.Sprintf("%s  %8s  %-14s  %s  %s", e.Modified.Local().Format("2006-01-02 15:04"), pickAge(now.Sub(e.Modified)), window, hash, e.Title)
+		m.rows = append(m.rows, strings.TrimRight(row, " "))
+		m.search = append(m.search, strings.ToLower(row))
+	}
+	m.filter()
+	return m
+}
+
+// pickAge formats how long ago a screenshot was taken, in its largest unit.
+func pickAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", max(int(d.Seconds()), 0))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// filter recomputes the matches for the query and moves the selection back
+// to the best one.
+func (m *pickModel) filter() {
+	words := strings.Fields(strings.ToLower(string(m.query)))
+	scores := make(map[int]int, len(m.entries))
+	m.matches = nil
+	for i, text := range m.search {
+		total := 0
+		ok := true
+		for _, w := range words {
+			score, found := fuzzyScore(w, text)
+			if !found {
+				ok = false
+				break
+			}
+			total += score
+		}
+		if ok {
+			m.matches = append(m.matches, i)
+			scores[i] = total
+		}
+	}
+	// Stable, so equally good matches stay newest first.
+	slices.SortStableFunc(m.matches, func(a, b int) int { return scores[b] - scores[a] })
+	m.cursor, m.offset = 0, 0
+}
+
+// fuzzyScore reports whether the runes of pattern appear in text in order,
+// and how well: matches in a run and at the start of a word score higher, as
+// do short spans. Both are lowercase.
+func fuzzyScore(pattern, text string) (int, bool) {
+	p, t := []rune(pattern), []rune(text)
+	if len(p) == 0 {
+		return 0, true
+	}
+	// Find where the first occurrence in order ends, then walk back from
+	// there for the shortest span holding the pattern, as fzf does.
+	end, pi := -1, 0
+	for i, r := range t {
+		if r == p[pi] {
+			pi++
+			if pi == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+	start, pi := end, len(p)-1
+	for i := end; i >= 0; i-- {
+		if t[i] == p[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	score, pi, prev := 0, 0, -2
+	for i := start; i <= end && pi < len(p); i++ {
+		if t[i] != p[pi] {
+			continue
+		}
+		score += 10
+		if i == prev+1 {
+			score += 8
+		}
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 6
+		}
+		prev = i
+		pi++
+	}
+	return score - (end - start + 1 - len(p)), true
+}
+
+func (m pickModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m pickModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		if msg.Width > 0 && msg.Height > 0 { // terminals that can't tell keep the defaults
+			m.width, m.height = msg.Width, max(msg.Height-2, 1) // less the prompt and count lines
+			m.scroll()
+		}
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			return m, tea.Quit
+		case tea.KeyEnter:
+			if len(m.matches) > 0 {
+				m.chosen = m.entries[m.matches[m.cursor]].Path
+			}
+			return m, tea.Quit
+		case tea.KeyUp, tea.KeyCtrlP, tea.KeyShiftTab:
+			m.cursor = max(m.cursor-1, 0)
+			m.scroll()
+		case tea.KeyDown, tea.KeyCtrlN, tea.KeyTab:
+			m.cursor = max(min(m.cursor+1, len(m.matches)-1), 0)
+			m.scroll()
+		case tea.KeyBackspace:
+			if len(m.query) > 0 {
+				m.query = m.query[:len(m.query)-1]
+				m.filter()
+			}
+		case tea.KeyCtrlU:
+			m.query = nil
+			m.filter()
+		case tea.KeySpace:
+			m.query = append(slices.Clip(m.query), ' ')
+			m.filter()
+		case tea.KeyRunes:
+			m.query = append(slices.Clip(m.query), msg.Runes...)
+			m.filter()
+		}
+	}
+	return m, nil
+}
+
+// scroll moves the window of shown matches to keep the cursor in it.
+func (m *pickModel) scroll() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+}
+
+func (m pickModel) View() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "> %s\n", string(m.query))
+	fmt.Fprintf(&b, "  %d/%d\n", len(m.matches), len(m.entries))
+	for i := m.offset; i < len(m.matches) && i < m.offset+m.height; i++ {
+		marker := "  "
+		if i == m.cursor {
+			marker = "> "
+		}
+		line := []rune(marker + m.rows[m.matches[i]])
+		if len(line) > m.width {
+			line = line[:max(m.width, 0)]
+		}
+		b.WriteString(string(line))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+
+	pickCmd.Flags().StringVarP(&pickOutputDir, "output", "o", "", "Screenshot directory (default: the running daemon's output dir)")
+	pickCmd.Flags().IntVarP(&pickLimit, "limit", "n", 200, "Offer at most this many of the newest screenshots (0 = all)")
+	pickCmd.Flags().BoolVar(&pickPrint, "print", false, "Only print the picked screenshot's path, without copying it")
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, text string
+		found         bool
+	}{
+		{"chr", "chrome", true},
+		{"gc", "google chrome", true},
+		{"10m", "2024-05-01 12:00  10m ago  chrome", true},
+		{"rhc", "chrome", false},
+		{"", "anything", true},
+	} {
+		if _, found := fuzzyScore(tc.pattern, tc.text); found != tc.found {
+			t.Errorf("fuzzyScore(%q, %q) found = %v, want %v", tc.pattern, tc.text, found, tc.found)
+		}
+	}
+	// A run at a word start beats letters strewn about.
+	run, _ := fuzzyScore("code", "visual studio code")
+	strewn, _ := fuzzyScore("code", "chrome - docs editor")
+	if run <= strewn {
+		t.Errorf("fuzzyScore(code) = %d for a word, %d for scattered letters; want the word ahead", run, strewn)
+	}
+}
+
+// pickKeys feeds keys to m, runes typed as a whole.
+func pickKeys(m pickModel, keys ...any) pickModel {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k := k.(type) {
+		case string:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		case tea.KeyType:
+			msg = tea.KeyMsg{Type: k}
+		}
+		next, _ := m.Update(msg)
+		m = next.(pickModel)
+	}
+	return m
+}
+
+func TestPickModel(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	entries := []listEntry{
+		{Path: "/s/new.png", Hash: "aa11", Modified: now.Add(-2 * time.Minute), Window: "Code", Title: "pick.go - Visual Studio Code"},
+		{Path: "/s/mid.png", Hash: "bb22", Modified: now.Add(-10 * time.Minute), Window: "chrome", Title: "Pull requests - Google Chrome"},
+		{Path: "/s/old.png", Hash: "cc33", Modified: now.Add(-3 * time.Hour)},
+	}
+	m := newPickModel(entries, now)
+	if view := m.View(); !strings.Contains(view, "3/3") || !strings.Contains(view, "10m ago") || !strings.Contains(view, "Pull requests") {
+		t.Errorf("View() =\n%s\nwant all three screenshots with their age and title", view)
+	}
+
+	// Enter with no query takes the newest.
+	if got := pickKeys(m, tea.KeyEnter).chosen; got != "/s/new.png" {
+		t.Errorf("Enter picked %q, want the newest", got)
+	}
+	if got := pickKeys(m, tea.KeyDown, tea.KeyDown, tea.KeyDown, tea.KeyUp, tea.KeyEnter).chosen; got != "/s/mid.png" {
+		t.Errorf("Down, Down, Down, Up, Enter picked %q, want the second", got)
+	}
+
+	typed := pickKeys(m, "chr", tea.KeySpace, "10m")
+	if len(typed.matches) != 1 || !strings.Contains(typed.View(), "1/3") {
+		t.Errorf("query %q matches %v, want only the Chrome screenshot", string(typed.query), typed.matches)
+	}
+	if got := pickKeys(typed, tea.KeyEnter).chosen; got != "/s/mid.png" {
+		t.Errorf("Enter picked %q, want the Chrome screenshot", got)
+	}
+	if got := pickKeys(typed, tea.KeyCtrlU).matches; len(got) != 3 {
+		t.Errorf("Ctrl-U left %d matches, want all 3", len(got))
+	}
+
+	if got := pickKeys(m, "zzz", tea.KeyEnter).chosen; got != "" {
+		t.Errorf("Enter without matches picked %q, want nothing", got)
+	}
+	if got := pickKeys(m, tea.KeyEsc).chosen; got != "" {
+		t.Errorf("Esc picked %q, want nothing", got)
+	}
+}
+
+func TestPickCommand(t *testing.T) {
+	origRun := runPicker
+	defer func() {
+		runPicker = origRun
+		pickOutputDir, pickPrint = "", false
+	}()
+	pickOutputDir, pickPrint = t.TempDir(), true
+	path := filepath.Join(pickOutputDir, "ab12.png")
+	if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AppendIndex(pickOutputDir, path, store.IndexEntry{Hash: "ab12", Window: "chrome"}); err != nil {
+		t.Fatal(err)
+	}
+
+	runPicker = func(m pickModel, _ io.Writer) (pickModel, error) {
+		return pickKeys(m, "chrome", tea.KeyEnter), nil
+	}
+	var out bytes.Buffer
+	pickCmd.SetOut(&out)
+	if err := pickCmd.RunE(pickCmd, nil); err != nil {
+		t.Fatalf("pick --print error: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != path {
+		t.Errorf("pick --print printed %q, want %s", got, path)
+	}
+
+	runPicker = func(m pickModel, _ io.Writer) (pickModel, error) {
+		return pickKeys(m, tea.KeyEsc), nil
+	}
+	var ec *exitCodeError
+	if err := pickCmd.RunE(pickCmd, nil); !errors.As(err, &ec) || ec.code != pickCancelledCode {
+		t.Errorf("pick cancelled error = %v, want exit code %d", err, pickCancelledCode)
+	}
+}
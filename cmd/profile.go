@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+var profile string
+
+// applyProfile switches the daemon paths to the resolved --profile. start's
+// --output default follows the profile too, unless something set it.
+func applyProfile() error {
+	if err := daemon.UseProfile(profile); err != nil {
+		return err
+	}
+	f := startCmd.Flags().Lookup("output")
+	f.DefValue = daemon.DefaultOutputDir
+	if !f.Changed {
+		outputDir = daemon.DefaultOutputDir
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+func TestApplyProfile(t *testing.T) {
+	origPid, origOut, origName := daemon.PidFile, daemon.DefaultOutputDir, daemon.InstanceName
+	origLog, origState, origHeartbeat := daemon.LogFile, daemon.StateFile, daemon.HeartbeatFile
+	f := startCmd.Flags().Lookup("output")
+	origDef := f.DefValue
+	defer func() {
+		daemon.PidFile, daemon.DefaultOutputDir, daemon.InstanceName = origPid, origOut, origName
+		daemon.LogFile, daemon.StateFile, daemon.HeartbeatFile = origLog, origState, origHeartbeat
+		f.DefValue, outputDir, profile = origDef, origDef, daemon.DefaultProfile
+	}()
+
+	profile = "work"
+	if err := applyProfile(); err != nil {
+		t.Fatalf("applyProfile() error: %v", err)
+	}
+	if daemon.InstanceName != "work" || !strings.HasSuffix(daemon.PidFile, "-work.pid") {
+		t.Errorf("daemon paths not switched: name %q, pid file %q", daemon.InstanceName, daemon.PidFile)
+	}
+	if outputDir != daemon.DefaultOutputDir || f.DefValue != daemon.DefaultOutputDir {
+		t.Errorf("output = %q (default %q), want the profile dir %q", outputDir, f.DefValue, daemon.DefaultOutputDir)
+	}
+
+	profile = "no/slashes"
+	if err := applyProfile(); err == nil {
+		t.Error("applyProfile() should reject an invalid profile name")
+	}
+}
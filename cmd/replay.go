@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clipboard"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logging"
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+)
+
+var replayOutputDir string
+var replayVerbose bool
+
+var replayCmd = &cobra.Command{
+	Use:    "replay <trace-file>",
+	Short:  "Replay a recorded protocol trace offline (debugging)",
+	Hidden: true,
+	Long: `Feed a protocol trace recorded with 'start --trace FILE' through the clipboard
+client and poller offline, without PowerShell or WSL. Each recorded command is
+answered with the responses PowerShell gave at the time, so a user-reported
+failure can be reproduced and bisected on any machine.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := cmd.OutOrStdout()
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("Failed to open trace: %w", err)
+		}
+		entries, err := clipboard.ReadTrace(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("Failed to read trace: %w", err)
+		}
+
+		dir := replayOutputDir
+		if dir == "" {
+			if dir, err = os.MkdirTemp("", "wsl-screenshot-replay-"); err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+		} else if err := os.MkdirAll(dir, 0700); err != nil {
+			return i18n.Errorf("cmd.output_not_writable", err)
+		}
+
+		level := slog.LevelInfo
+		if replayVerbose {
+			level = slog.LevelDebug
+		}
+		logger := logging.New(w, logging.FormatText, level)
+		client, replay, err := clipboard.NewReplayClient(entries, logger, clipboard.Options{Verbose: replayVerbose})
+		if err != nil {
+			return fmt.Errorf("Replay handshake failed: %w", err)
+		}
+		defer client.Close()
+
+		cfg := poller.Config{
+			OutputDir: dir,
+			ToWinPath: func(wslPath string) (string, error) {
+				return `C:\replay\` + filepath.Base(wslPath), nil
+			},
+		}
+
+		failures := 0
+		for cycle := 1; !replay.Exhausted(); cycle++ {
+			before := replay.Exchanges()
+			if err := poller.PollOnce(client, logger, cfg); err != nil {
+				failures++
+				fmt.Fprintf(w, "cycle %d: error: %v\n", cycle, err)
+			}
+			if replay.Exchanges() == before {
+				break // simulated process died without consuming the trace
+			}
+		}
+
+		fmt.Fprintf(w, "\nReplayed %d exchanges, %d poll errors\n", replay.Exchanges(), failures)
+		for _, d := range replay.Divergences() {
+			fmt.Fprintf(w, "Divergence: %s\n", d)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVarP(&replayOutputDir, "output", "o", "", "Directory for replayed screenshots (default: temporary, removed afterwards)")
+	replayCmd.Flags().BoolVarP(&replayVerbose, "verbose", "v", false, "Log every replayed protocol line")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clipboard"
+)
+
+func writeTrace(t *testing.T, lines ...[2]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, l := range lines {
+		if err := enc.Encode(clipboard.TraceEntry{Dir: l[0], Line: l[1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay_RunsTraceThroughPoller(t *testing.T) {
+	img := base64.StdEncoding.EncodeToString([]byte("replayed-image"))
+	trace := writeTrace(t,
+		[2]string{"recv", "READY"},
+		[2]string{"send", "CHECK"},
+		[2]string{"recv", "IMAGE"},
+		[2]string{"recv", img},
+		[2]string{"recv", "END"},
+		[2]string{"send", `UPDATE|/tmp/x.png|C:\x.png`},
+		[2]string{"recv", "ERR|CLIPBOARD_BUSY|clipboard locked"},
+		[2]string{"send", "CHECK"},
+		[2]string{"recv", "GARBAGE"},
+		[2]string{"send", "EXIT"},
+	)
+
+	replayOutputDir = t.TempDir()
+	defer func() { replayOutputDir = "" }()
+	var out bytes.Buffer
+	replayCmd.SetOut(&out)
+	defer replayCmd.SetOut(nil)
+
+	if err := replayCmd.RunE(replayCmd, []string{trace}); err != nil {
+		t.Fatalf("replay error: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"New screenshot saved",
+		`msg="Clipboard update failed" err="powershell: CLIPBOARD_BUSY: clipboard locked"`,
+		`cycle 2: error: check clipboard: unexpected response: "GARBAGE"`,
+		"Replayed 3 exchanges, 1 poll errors",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("replay output missing %q:\n%s", want, got)
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(replayOutputDir, "*.png")); len(matches) != 1 {
+		t.Errorf("expected 1 replayed screenshot, got %d", len(matches))
+	}
+}
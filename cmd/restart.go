@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+// restartStopTimeout bounds how long restart waits for the old daemon to exit.
+const restartStopTimeout = 5 * time.Second
+
+// stopDaemon signals the running daemon to exit. Declared as a var so tests
+// never SIGTERM the test process itself.
+var stopDaemon = daemon.Stop
+
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart the polling process with its current settings",
+	Long: `Stop the running daemon and start it again with the same configuration: the
+flags it was started with, plus the interval, output directory and verbosity it
+was actually running with (including values from the environment, the config
+file or a SIGHUP reload), as recorded in its state file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pid := daemon.RunningPID()
+		if pid == 0 {
+			return i18n.Errorf("cmd.restart_not_running")
+		}
+		st, err := daemon.ReadState()
+		if err != nil {
+			return fmt.Errorf("Failed to read daemon state: %w", err)
+		}
+
+		if err := stopAndWait(pid); err != nil {
+			return err
+		}
+		return daemon.Daemonize(restartArgs(st))
+	},
+}
+
+// stopAndWait stops the daemon running as pid and waits until the process
+// is gone, so its exit cleanup can't race with a replacement's startup.
+func stopAndWait(pid int) error {
+	stopDaemon()
+	if !daemon.WaitForExit(pid, restartStopTimeout) {
+		return i18n.Errorf("cmd.restart_not_stopped", pid)
+	}
+	return nil
+}
+
+// restartArgs rebuilds the start flags from a daemon's state: its original
+// flags with the recorded effective settings and profile pinned.
+func restartArgs(st daemon.State) []string {
+	args := st.Args
+	if st.Interval > 0 {
+		args = withFlag(args, "interval", st.Interval.String())
+	}
+	if st.OutputDir != "" {
+		args = withFlag(args, "output", st.OutputDir)
+	}
+	args = withFlag(args, "verbose", strconv.FormatBool(st.Verbose))
+	if daemon.InstanceName != daemon.DefaultProfile {
+		args = withFlag(args, "profile", daemon.InstanceName)
+	}
+	return args
+}
+
+// withFlag returns a copy of args with every "--name value" or "--name=value"
+// replaced by a single "--name=value" at the end.
+func withFlag(args []string, name, value string) []string {
+	flag := "--" + name
+	out := make([]string, 0, len(args)+1)
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == flag && i+1 < len(args):
+			i++
+		case strings.HasPrefix(args[i], flag+"="):
+		default:
+			out = append(out, args[i])
+		}
+	}
+	return append(out, flag+"="+value)
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+func TestWithFlag(t *testing.T) {
+	got := withFlag([]string{"--interval", "1s", "--seq-check=true", "--interval=2s"}, "interval", "500ms")
+	want := "--seq-check=true --interval=500ms"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("withFlag() = %q, want %q", s, want)
+	}
+}
+
+func TestRestartArgs(t *testing.T) {
+	st := daemon.State{
+		OutputDir: "/shots",
+		Interval:  time.Second,
+		Verbose:   true,
+		Args:      []string{"--interval", "250ms", "--daily-dirs=true"},
+	}
+	got := strings.Join(restartArgs(st), " ")
+	want := "--daily-dirs=true --interval=1s --output=/shots --verbose=true"
+	if got != want {
+		t.Errorf("restartArgs() = %q, want %q", got, want)
+	}
+}
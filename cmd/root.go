@@ -2,9 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
 )
 
 // version is set at build time by GoReleaser via ldflags.
@@ -39,11 +45,51 @@ paste normally in Windows applications.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func ExecuteContext(ctx context.Context) {
 	err := rootCmd.ExecuteContext(ctx)
+	var ec *exitCodeError
+	if errors.As(err, &ec) {
+		os.Exit(ec.code)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// exitCodeError makes the process exit with code instead of the usual 1, for
+// commands whose exit status is part of their interface (e.g. healthcheck).
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
 func init() {
 	rootCmd.SilenceUsage = true
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default ~/.config/wsl-screenshot-cli/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", daemon.DefaultProfile, "Run or inspect the named profile, with its own PID, log and state files and output dir")
+	rootCmd.PersistentPreRunE = applySettings
+}
+
+// envPrefix namespaces environment overrides: --ps-memory-limit is read from
+// WSL_SCREENSHOT_PS_MEMORY_LIMIT.
+const envPrefix = "WSL_SCREENSHOT_"
+
+// lookupEnv reads environment overrides. Declared as a var so tests can
+// supply a fixed environment.
+var lookupEnv = os.LookupEnv
+
+// applySettings resolves the running command's flags with precedence
+// flags > environment > config file > defaults. Each layer only fills flags
+// the layers above left unset. WSL_SCREENSHOT_CONFIG can pick the config file.
+func applySettings(cmd *cobra.Command, args []string) error {
+	commandLineFlags = map[string]bool{}
+	cmd.Flags().Visit(func(f *pflag.Flag) { commandLineFlags[f.Name] = true })
+	if err := config.ApplyEnv(cmd.Flags(), envPrefix, lookupEnv, "help", "version", "daemon"); err != nil {
+		return i18n.Errorf("cmd.invalid_env_override", err)
+	}
+	if err := applyConfigFile(cmd, args); err != nil {
+		return err
+	}
+	return applyProfile()
 }
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // registers JPEG for image.DecodeConfig
+	_ "image/png"  // registers PNG for image.DecodeConfig
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set <file>",
+	Short: "Put any PNG or JPEG image on the Windows clipboard",
+	Long: `Put an image from the WSL filesystem on the Windows clipboard, the way the
+daemon sets a screenshot: the image for Windows applications, the file for
+pasting in Explorer, and its WSL path as text. The file is not copied into
+the output directory.
+
+  wsl-screenshot-cli set ./diagram.png`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if err := checkImageFile(path); err != nil {
+			return err
+		}
+		return copyScreenshot(cmd, path)
+	},
+}
+
+// checkImageFile returns an error unless path is a PNG or JPEG file.
+func checkImageFile(path string) error {
+	f, err := os.Open(path) // #nosec G304 -- the file the user asked to set
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, format, err := image.DecodeConfig(f)
+	if err != nil || (format != "png" && format != "jpeg") {
+		return fmt.Errorf("%s is not a PNG or JPEG image", path)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+}
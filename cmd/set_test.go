@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+)
+
+func TestSetCommand(t *testing.T) {
+	origClient, origWin, origSocket := newOneShotClient, copyWinPath, daemon.SocketFile
+	clip := &grabClipboard{}
+	newOneShotClient = func(*slog.Logger) (poller.Clipboard, error) { return clip, nil }
+	copyWinPath = func(p string) (string, error) { return `C:\img\` + filepath.Base(p), nil }
+	daemon.SocketFile = filepath.Join(t.TempDir(), "none.sock")
+	defer func() { newOneShotClient, copyWinPath, daemon.SocketFile = origClient, origWin, origSocket }()
+
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	photo := filepath.Join(dir, "photo.jpg")
+	notes := filepath.Join(dir, "notes.png")
+	if err := os.WriteFile(photo, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(notes, []byte("not an image"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setCmd.RunE(setCmd, []string{photo}); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	if clip.text != photo || clip.fileDrop != `C:\img\photo.jpg` {
+		t.Errorf("clipboard set to %q / %q, want the image's paths", clip.text, clip.fileDrop)
+	}
+	if err := setCmd.RunE(setCmd, []string{notes}); err == nil {
+		t.Error("set should reject a file that isn't an image")
+	}
+}
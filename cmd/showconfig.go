@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+var showConfigJSON bool
+
+// callDaemon asks the running daemon for command's result over the control
+// socket. Declared as a var so tests can answer without a daemon.
+var callDaemon = daemon.Call
+
+var showConfigCmd = &cobra.Command{
+	Use:   "show-config",
+	Short: "Print the configuration the running daemon is using",
+	Long: `Print the effective settings of the running daemon, as it resolved them from
+its flags, the environment and the config file when it started (or at its last
+SIGHUP reload), and where each value came from.
+
+Settings marked with * differ from what the environment and config file
+say now: the daemon needs a restart (or, for interval, output and verbose, a
+SIGHUP) to pick them up. "config list" shows the values on disk.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var st daemon.State
+		err := callDaemon("config", nil, &st)
+		if errors.Is(err, daemon.ErrNoDaemon) {
+			return errors.New("Polling process is not running")
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read the daemon's configuration: %w", err)
+		}
+
+		if showConfigJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(st)
+		}
+
+		onDisk := map[string]string{}
+		if settings, err := effectiveSettings(cmd.Root()); err == nil {
+			for _, s := range settings {
+				onDisk[s.Name] = s.Value
+			}
+		}
+		var settings []setting
+		stale := false
+		for name, value := range st.Settings {
+			s := setting{Name: name, Value: value, Source: st.Sources[name]}
+			if s.Source == "" {
+				s.Source = "unknown"
+			}
+			if v, ok := onDisk[name]; ok && s.Source != "command line" && !sameSetting(name, v, value) {
+				s.Source += ", *"
+				stale = true
+			}
+			settings = append(settings, s)
+		}
+		sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+		printSettings(cmd.OutOrStdout(), settings)
+		if stale {
+			fmt.Fprintln(cmd.OutOrStdout(), "\n* differs from the current environment or config file")
+		}
+		return nil
+	},
+}
+
+// sameSetting compares a value from the environment or config file with the
+// daemon's normalized one, e.g. "1s" with "1000ms" or "dir/" with "dir".
+func sameSetting(name, raw, effective string) bool {
+	if name == "output" {
+		raw = filepath.Clean(raw)
+	}
+	if f := startCmd.Flags().Lookup(name); f != nil {
+		if v, err := normalizeSetting(f, raw); err == nil {
+			raw = v
+		}
+	}
+	return raw == effective
+}
+
+func init() {
+	rootCmd.AddCommand(showConfigCmd)
+
+	showConfigCmd.Flags().BoolVar(&showConfigJSON, "json", false, "Print the daemon's full state (settings, sources, start flags) as JSON")
+}
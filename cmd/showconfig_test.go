@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+)
+
+// useDaemonState answers control socket "config" calls with st.
+func useDaemonState(t *testing.T, st daemon.State) {
+	t.Helper()
+	orig := callDaemon
+	callDaemon = func(command string, args, reply any) error {
+		data, _ := json.Marshal(st)
+		return json.Unmarshal(data, reply)
+	}
+	t.Cleanup(func() { callDaemon = orig })
+}
+
+func TestShowConfig_MarksSettingsChangedOnDisk(t *testing.T) {
+	path := useTestConfig(t, nil)
+	if err := os.WriteFile(path, []byte("interval: 1s\ntimezone: UTC\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	useDaemonState(t, daemon.State{
+		Settings: map[string]string{"interval": "1s", "timezone": "Local", "verbose": "true"},
+		Sources:  map[string]string{"interval": "config file", "timezone": "default", "verbose": "command line"},
+	})
+
+	var out bytes.Buffer
+	showConfigCmd.SetOut(&out)
+	defer showConfigCmd.SetOut(nil)
+	if err := showConfigCmd.RunE(showConfigCmd, nil); err != nil {
+		t.Fatalf("show-config error: %v", err)
+	}
+
+	lines := map[string]string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if f := strings.Fields(line); len(f) > 0 {
+			lines[f[0]] = line
+		}
+	}
+	if !strings.HasSuffix(lines["interval"], "(config file)") {
+		t.Errorf("interval line = %q, want it unmarked", lines["interval"])
+	}
+	if !strings.HasSuffix(lines["timezone"], "(default, *)") {
+		t.Errorf("timezone line = %q, want it marked as changed on disk", lines["timezone"])
+	}
+	if !strings.HasSuffix(lines["verbose"], "(command line)") {
+		t.Errorf("verbose line = %q, want command-line values unmarked", lines["verbose"])
+	}
+}
+
+func TestShowConfig_NoDaemon(t *testing.T) {
+	orig := callDaemon
+	defer func() { callDaemon = orig }()
+	callDaemon = func(string, any, any) error { return fmt.Errorf("%w: dial", daemon.ErrNoDaemon) }
+
+	err := showConfigCmd.RunE(showConfigCmd, nil)
+	if err == nil || err.Error() != "Polling process is not running" {
+		t.Errorf("error = %v, want not running", err)
+	}
+}
+
+func TestSettingSources(t *testing.T) {
+	useTestConfig(t, map[string]string{"WSL_SCREENSHOT_TIMEZONE": "UTC"})
+	commandLineFlags = map[string]bool{"interval": true}
+	defer func() { commandLineFlags = nil }()
+
+	s := settingSources(startCmd)
+	if s["interval"] != "command line" || s["timezone"] != "environment" || s["verbose"] != "default" {
+		t.Errorf("settingSources() = %v", s)
+	}
+	if _, ok := s["daemon"]; ok {
+		t.Error("per-invocation flags should not be listed")
+	}
+}
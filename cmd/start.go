@@ -2,25 +2,98 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/nailuu/wsl-screenshot-cli/internal/clipboard"
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/crash"
 	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/events"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logging"
+	"github.com/nailuu/wsl-screenshot-cli/internal/maintenance"
 	"github.com/nailuu/wsl-screenshot-cli/internal/platform"
 	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
 	versioncheck "github.com/nailuu/wsl-screenshot-cli/internal/version"
 )
 
-var interval int
+var interval config.Duration
 var outputDir string
 var daemonize bool
+var ensure bool
+var replace bool
 var verbose bool
 var quiet bool
+var dailyDirs bool
+var timezone string
+var breakerThreshold int
+var breakerAction string
+var breakerIgnore []string
+var breakerBackoff config.Duration
+var breakerMaxBackoff config.Duration
+var breakerMaxRestarts int
+var traceFile string
+var seqCheck bool
+var waitChanges bool
+var psMemoryLimit int
+var psTimeout config.Duration
+var psBinary string
+var backend string
+var shutdownTimeout config.Duration
+var maintenanceAt string
+var onCollision string
+var overwriteWindow config.Duration
+var writeLimit int
+var logMaxSize int
+var logMaxBackups int
+var logLevel string
+var logFormat string
+var chaosRate float64
+var privateNames bool
+var htmlImages bool
+var clipFormats []string
+var textTemplate string
+var filenameTemplate string
+var textPath string
+var noClipboardUpdate bool
+var latestLink bool
+var latestFile bool
+var retainAge string
+var retainCount int
+var retainSize string
+var retainInterval config.Duration
+var minFreeSpace string
+var lowDiskToast bool
+var fsckOnStart bool
+var fileMode config.FileMode
+var dirMode config.FileMode
+
+// nameKeyFile returns where --private-names keeps its secret key. Declared
+// as a var so tests can redirect it.
+var nameKeyFile = func() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wsl-screenshot-cli", "name.key"), nil
+}
 
 var startCmd = &cobra.Command{
 	Use:   "start",
@@ -35,12 +108,135 @@ var startCmd = &cobra.Command{
 			fmt.Fprintf(cmd.OutOrStdout(), "\nNew update available (v%s), run `wsl-screenshot-cli update` to install it.\n\n", latest)
 		}
 
-		if interval < 100 || interval > 5000 {
-			return fmt.Errorf("Interval must be between 100 and 5000 ms (got %d)", interval)
+		if err := config.ValidateInterval(time.Duration(interval), seqCheck, waitChanges); err != nil {
+			return err
+		}
+
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_timezone", timezone, err)
+		}
+
+		window, maintenanceOn, err := maintenance.ParseWindow(maintenanceAt)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "maintenance-at", err)
+		}
+
+		retention, err := startRetention()
+		if err != nil {
+			return err
+		}
+
+		if fileMode&0600 != 0600 || dirMode&0700 != 0700 {
+			return fmt.Errorf("File and directory modes must let the owner read and write (got --file-mode %s, --dir-mode %s)", &fileMode, &dirMode)
+		}
+
+		minFree, err := config.ParseSize(minFreeSpace)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "min-free-space", err)
+		}
+
+		collision, err := store.ParseCollisionPolicy(onCollision)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "on-collision", err)
+		}
+
+		if psMemoryLimit < 0 {
+			return fmt.Errorf("PowerShell memory limit must be 0 (disabled) or a positive number of MB (got %d)", psMemoryLimit)
+		}
+
+		if psTimeout < 0 {
+			return fmt.Errorf("PowerShell timeout must be 0 (disabled) or positive (got %s)", time.Duration(psTimeout))
+		}
+
+		if err := clipboard.CheckBackend(backend); err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "backend", err)
+		}
+
+		if err := clipboard.CheckFormats(clipFormats); err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "formats", err)
+		}
+		textMode, err := poller.ParseTextMode(textPath)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "text-path", err)
+		}
+		var clipText *template.Template
+		if textTemplate != "" {
+			if clipText, err = poller.ParseTextTemplate(textTemplate); err != nil {
+				return i18n.Errorf("cmd.invalid_flag", "text-template", err)
+			}
+		}
+		var fileNames *poller.FilenameTemplate
+		if filenameTemplate != "" {
+			if fileNames, err = poller.ParseFilenameTemplate(filenameTemplate); err != nil {
+				return i18n.Errorf("cmd.invalid_flag", "filename-template", err)
+			}
+		}
+
+		if psBinary != "" && backend != clipboard.BackendNative {
+			if err := clipboard.CheckBinary(psBinary); err != nil {
+				return i18n.Errorf("cmd.invalid_flag", "ps-binary", err)
+			}
+		}
+
+		if shutdownTimeout < 0 {
+			return fmt.Errorf("Shutdown timeout must be 0 (wait forever) or positive (got %s)", time.Duration(shutdownTimeout))
 		}
 
-		if err := os.MkdirAll(outputDir, 0750); err != nil {
-			return fmt.Errorf("Output directory is not writable: %w", err)
+		if writeLimit < 0 {
+			return fmt.Errorf("Write limit must be 0 (disabled) or a positive number of saves per second (got %d)", writeLimit)
+		}
+
+		level, err := logging.ParseLevel(logLevel)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "log-level", err)
+		}
+		format, err := logging.ParseFormat(logFormat)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "log-format", err)
+		}
+
+		if logMaxSize < 0 || logMaxBackups < 0 {
+			return fmt.Errorf("Log limits must be 0 or more (got --log-max-size %d, --log-max-backups %d)", logMaxSize, logMaxBackups)
+		}
+
+		if chaosRate < 0 || chaosRate > 1 {
+			return fmt.Errorf("Chaos rate must be between 0 (disabled) and 1 (got %g)", chaosRate)
+		}
+
+		if breakerThreshold < 1 {
+			return fmt.Errorf("Breaker threshold must be at least 1 (got %d)", breakerThreshold)
+		}
+		action, err := poller.ParseTripAction(breakerAction)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "breaker-action", err)
+		}
+		ignore, err := poller.ParseErrorClasses(breakerIgnore)
+		if err != nil {
+			return i18n.Errorf("cmd.invalid_flag", "breaker-ignore", err)
+		}
+
+		if err := os.MkdirAll(outputDir, os.FileMode(dirMode)); err != nil {
+			return i18n.Errorf("cmd.output_not_writable", err)
+		}
+		caseFold, _ := store.CaseInsensitive(outputDir) // a failed probe only loses the warning
+		if caseFold {
+			warnCaseInsensitive(daemon.Output, outputDir)
+		}
+
+		// The integrity check needs the key whenever private names were ever
+		// used, or it would take their files for corrupt ones.
+		var nameKey, verifyKey []byte
+		if path, err := nameKeyFile(); privateNames {
+			if err == nil {
+				nameKey, err = store.LoadOrCreateNameKey(path)
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to load the private names key: %w", err)
+			}
+			verifyKey = nameKey
+		} else if err == nil {
+			verifyKey, _ = store.ReadNameKey(path) // absent unless private names were used
 		}
 
 		if err := platform.CheckWSLEnvironment(); err != nil {
@@ -51,24 +247,582 @@ var startCmd = &cobra.Command{
 			return err
 		}
 
+		if (ensure || replace) && !daemonize {
+			return fmt.Errorf("--ensure and --replace require --daemon")
+		}
+		if daemonize && ensure {
+			return ensureDaemon(cmd)
+		}
+		if daemonize && replace {
+			if pid := daemon.RunningPID(); pid != 0 {
+				if err := stopAndWait(pid); err != nil {
+					return err
+				}
+			}
+		}
 		if daemonize {
-			return daemon.Daemonize(interval, outputDir, verbose)
+			return daemon.Daemonize(daemonArgs(cmd))
+		}
+
+		cfg := poller.Config{
+			Interval:           time.Duration(interval),
+			OutputDir:          outputDir,
+			DailyDirs:          dailyDirs,
+			Location:           loc,
+			SeqCheck:           seqCheck,
+			Wait:               waitChanges,
+			MaxBackendMemory:   int64(psMemoryLimit) << 20,
+			OnCollision:        collision,
+			OverwriteWindow:    auditWindow(time.Duration(overwriteWindow)),
+			MaxWritesPerSecond: writeLimit,
+			Chaos:              chaosRate,
+			NameKey:            nameKey,
+			FilenameTemplate:   fileNames,
+			HTMLImages:         htmlImages,
+			NoClipboardUpdate:  noClipboardUpdate,
+			LatestLink:         latestLink || latestFile,
+			LatestPathFile:     latestFile,
+			FileMode:           os.FileMode(fileMode),
+			DirMode:            os.FileMode(dirMode),
+			MinFreeSpace:       minFree,
+			LowDiskToast:       lowDiskToast,
+			TextMode:           textMode,
+			TextTemplate:       clipText,
+			Breaker: poller.BreakerPolicy{
+				MaxConsecutiveErrors: breakerThreshold,
+				Action:               action,
+				Ignore:               ignore,
+				Backoff:              time.Duration(breakerBackoff),
+				MaxBackoff:           time.Duration(breakerMaxBackoff),
+				MaxRestartsPerHour:   breakerMaxRestarts,
+			},
 		}
 
-		return daemon.Run(cmd.Context(), interval, outputDir, func(ctx context.Context, logger *log.Logger) error {
-			return poller.Run(ctx, logger, interval, outputDir, func() (poller.Clipboard, error) {
-				return clipboard.NewClient(logger, verbose)
-			})
+		recent := clipboard.NewRecent(crashExchanges)
+		clientOpts := clipboard.Options{Verbose: verbose, Recent: recent, Timeout: time.Duration(psTimeout), Binary: psBinary, Backend: backend}
+		var formats []string // in protocol order, without repeats
+		for _, f := range clipboard.Formats {
+			if slices.Contains(clipFormats, f) {
+				formats = append(formats, f)
+			}
+		}
+		if len(formats) < len(clipboard.Formats) {
+			clientOpts.Formats = formats
+		}
+		if traceFile != "" {
+			f, err := os.OpenFile(traceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+			if err != nil {
+				return fmt.Errorf("Failed to open trace file: %w", err)
+			}
+			defer f.Close()
+			clientOpts.Trace = f
+		}
+
+		copies := make(chan poller.CopyRequest)
+		cfg.Copies = copies
+		daemon.Handle("copy", copyHandler(copies))
+
+		daemon.StartArgs = daemonArgs(cmd)
+		daemon.StartVerbose = verbose
+		daemon.StartCaseInsensitive = caseFold
+		daemon.StartSettings = startSettings(cmd)
+		daemon.StartSources = settingSources(cmd)
+		daemon.IsBackend = clipboard.IsBackendCommand
+		daemon.LogMaxSize = int64(logMaxSize) << 20
+		daemon.LogMaxBackups = logMaxBackups
+		daemon.LogLevel.Set(effectiveLogLevel(level, verbose))
+		daemon.LogFormat = format
+		daemon.ShutdownTimeout = time.Duration(shutdownTimeout)
+		err = daemon.Run(cmd.Context(), int(time.Duration(interval).Milliseconds()), outputDir, func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+			cfg.Stats = counters
+			cfg.Events = events.Open(daemon.EventsFile)
+			_ = cfg.Events.Append(events.Event{Time: time.Now(), Type: events.TypeStart, Message: fmt.Sprintf("Polling started (PID %d, output %s)", os.Getpid(), outputDir)})
+			defer func() {
+				_ = cfg.Events.Append(events.Event{Time: time.Now(), Type: events.TypeStop, Message: "Polling stopped"})
+			}()
+			var dir atomic.Pointer[string]
+			dir.Store(&outputDir)
+			updates := make(chan poller.Settings, 1)
+			cfg.Reload = updates
+
+			// A panic in any of the loops below is written to a crash file
+			// and the loop restarted, rather than taking the daemon down.
+			supervise := func(component string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+				sup := &crash.Supervisor{
+					Component: component,
+					Logger:    logging.Component(logger, component),
+					Path:      daemon.CrashFile,
+					Exchanges: recent.Entries,
+					OnCrash: func(value any, path string) {
+						_ = cfg.Events.Append(events.Event{Time: time.Now(), Type: events.TypeError, Message: crashMessage(component, value, path)})
+					},
+				}
+				return func(ctx context.Context) error { return sup.Run(ctx, fn) }
+			}
+
+			var wg sync.WaitGroup
+			defer wg.Wait()
+			// Stop the other loops too when polling gives up.
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = supervise("reload", func(ctx context.Context) error {
+					forwardReloads(ctx, cmd, logging.Component(logger, "daemon"), reload, updates, &dir)
+					return nil
+				})(ctx)
+			}()
+			if maintenanceOn {
+				sched := &maintenance.Scheduler{
+					Window:   window,
+					Location: loc,
+					Tasks:    maintenanceTasks(func() string { return *dir.Load() }, verifyKey),
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = supervise("maintenance", func(ctx context.Context) error {
+						sched.Run(ctx, logging.Component(logger, "maintenance"))
+						return nil
+					})(ctx)
+				}()
+			}
+			if fsckOnStart {
+				// In the background: a large store must not delay the first poll.
+				fsck := fsckTask(func() string { return *dir.Load() }, verifyKey)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = supervise("fsck", func(ctx context.Context) error {
+						log := logging.Component(logger, "maintenance")
+						if err := fsck.Run(ctx, log); err != nil && ctx.Err() == nil {
+							log.Error("Startup integrity check failed", "err", err)
+						}
+						return nil
+					})(ctx)
+				}()
+			}
+			if !retention.IsZero() {
+				janitor := &maintenance.Janitor{
+					Retention: retention,
+					Interval:  time.Duration(retainInterval),
+					Dir:       func() string { return *dir.Load() },
+					Protect:   counters.ClipboardPath,
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = supervise("janitor", func(ctx context.Context) error {
+						janitor.Run(ctx, logging.Component(logger, "janitor"))
+						return nil
+					})(ctx)
+				}()
+			}
+			err := supervise("poller", func(ctx context.Context) error {
+				return poller.Run(ctx, logging.Component(logger, "poller"), cfg, func() (poller.Clipboard, error) {
+					return newClipboardClient(logging.Component(logger, "clipboard"), clientOpts)
+				})
+			})(ctx)
+			if errors.Is(err, poller.ErrRestartLimit) {
+				return &daemon.ExitError{Code: exitRestartLimit, Err: err}
+			}
+			return err
 		})
+		var ee *daemon.ExitError
+		if errors.As(err, &ee) {
+			return &exitCodeError{code: ee.Code, err: ee.Err}
+		}
+		return err
 	},
 }
 
+// newClipboardClient starts the backend opts select. When PowerShell, the
+// default, cannot be started at all (e.g. blocked by policy), it falls back
+// to the reduced clipboard.FallbackClient if its tools are installed.
+func newClipboardClient(logger *slog.Logger, opts clipboard.Options) (poller.Clipboard, error) {
+	if opts.Backend == clipboard.BackendFallback {
+		fb, err := clipboard.NewFallbackClient(logger)
+		if err != nil {
+			return nil, err
+		}
+		return fb, nil
+	}
+	c, err := clipboard.NewClient(logger, opts)
+	if err == nil {
+		return c, nil
+	}
+	if opts.Backend != "" && opts.Backend != clipboard.BackendPowerShell || errors.Is(err, clipboard.ErrProtocolMismatch) {
+		return nil, err
+	}
+	fb, fbErr := clipboard.NewFallbackClient(logger)
+	if fbErr != nil {
+		return nil, err
+	}
+	logger.Warn("PowerShell could not be started, using the fallback backend", "err", err)
+	return fb, nil
+}
+
+// exitRestartLimit is the daemon's exit status when it gives up after
+// --breaker-max-restarts restarts of the PowerShell client within an hour.
+const exitRestartLimit = 3
+
+// crashExchanges is how many protocol lines a crash report shows.
+const crashExchanges = 50
+
+// crashMessage is the event journal entry for a recovered panic.
+func crashMessage(component string, value any, path string) string {
+	if path == "" {
+		return fmt.Sprintf("%s panicked and was restarted: %v", component, value)
+	}
+	return fmt.Sprintf("%s panicked and was restarted: %v (report: %s)", component, value, path)
+}
+
+// warnCaseInsensitive explains what a case-insensitive output dir (drvfs,
+// e.g. /mnt/c) means for the screenshots stored there.
+func warnCaseInsensitive(w io.Writer, dir string) {
+	fmt.Fprintln(w, i18n.T("cmd.case_insensitive", dir))
+}
+
+// auditWindow maps the --overwrite-window flag, where 0 disables the audit,
+// onto poller.Config, where 0 means the default and negative disables.
+func auditWindow(d time.Duration) time.Duration {
+	if d <= 0 {
+		return -1
+	}
+	return d
+}
+
+// reloadable are the start settings a SIGHUP applies without a restart.
+var reloadable = []string{"interval", "output", "verbose"}
+
+// forwardReloads re-resolves the reloadable settings on every reload signal
+// and hands them to the poller. A bad config file is logged and the current
+// settings are kept. dir tracks the live output directory.
+func forwardReloads(ctx context.Context, cmd *cobra.Command, logger *slog.Logger, reload <-chan struct{}, updates chan<- poller.Settings, dir *atomic.Pointer[string]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+		}
+		s, err := reloadSettings(cmd)
+		if err != nil {
+			logger.Error("Reload failed, keeping current settings", "err", err)
+			continue
+		}
+		caseFold, _ := store.CaseInsensitive(s.OutputDir)
+		if caseFold && s.OutputDir != *dir.Load() {
+			logger.Warn("Output directory is case-insensitive (a Windows drive mount)", "dir", s.OutputDir)
+		}
+		err = daemon.UpdateState(func(st *daemon.State) {
+			st.OutputDir, st.Interval, st.Verbose = s.OutputDir, s.Interval, s.Verbose
+			st.CaseInsensitive = caseFold
+			if st.Settings != nil {
+				iv := config.Duration(s.Interval)
+				st.Settings["interval"] = iv.String()
+				st.Settings["output"] = filepath.Clean(s.OutputDir)
+				st.Settings["verbose"] = strconv.FormatBool(s.Verbose)
+			}
+			for _, name := range reloadable {
+				if st.Sources != nil && !fromCommandLine(cmd, name) {
+					st.Sources[name] = "reload"
+				}
+			}
+		})
+		if err != nil {
+			logger.Warn("Could not update the state file", "err", err)
+		}
+		if level, err := logging.ParseLevel(logLevel); err == nil {
+			daemon.LogLevel.Set(effectiveLogLevel(level, s.Verbose))
+		}
+		dir.Store(&s.OutputDir)
+		select {
+		case updates <- s:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadSettings resolves cmd's reloadable settings again with the startup
+// precedence. Values given on the daemon's command line stay fixed; the rest
+// are re-read from the environment and the config file.
+func reloadSettings(cmd *cobra.Command) (poller.Settings, error) {
+	fs := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	var iv config.Duration
+	fs.Var(&iv, "interval", "")
+	out := fs.String("output", "", "")
+	vb := fs.Bool("verbose", false, "")
+	for _, name := range reloadable {
+		f := cmd.Flags().Lookup(name)
+		v, fixed := f.DefValue, fromCommandLine(cmd, name)
+		if fixed {
+			v = f.Value.String()
+		}
+		if err := fs.Set(name, v); err != nil {
+			return poller.Settings{}, err
+		}
+		fs.Lookup(name).Changed = fixed
+	}
+
+	if err := config.ApplyEnv(fs, envPrefix, lookupEnv); err != nil {
+		return poller.Settings{}, fmt.Errorf("environment override %w", err)
+	}
+	if path, optional, err := resolveConfigPath(); err == nil {
+		values, err := config.Load(path, optional)
+		if err != nil {
+			return poller.Settings{}, err
+		}
+		if err := config.Apply(fs, values); err != nil {
+			return poller.Settings{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := config.ValidateInterval(time.Duration(iv), seqCheck, waitChanges); err != nil {
+		return poller.Settings{}, err
+	}
+	if err := os.MkdirAll(*out, os.FileMode(dirMode)); err != nil {
+		return poller.Settings{}, i18n.Errorf("cmd.output_not_writable", err)
+	}
+	return poller.Settings{Interval: time.Duration(iv), OutputDir: *out, Verbose: *vb}, nil
+}
+
+// perInvocation are start flags that control how this invocation launches
+// the daemon rather than how the daemon behaves.
+var perInvocation = map[string]bool{"daemon": true, "quiet": true, "ensure": true, "replace": true, "help": true}
+
+// startSettings returns the effective value of every setting of cmd (start),
+// keyed by flag name, with the output dir cleaned so equivalent spellings
+// compare equal.
+func startSettings(cmd *cobra.Command) map[string]string {
+	settings := map[string]string{}
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if !perInvocation[f.Name] {
+			settings[f.Name] = f.Value.String()
+		}
+	})
+	settings["output"] = filepath.Clean(outputDir)
+	return settings
+}
+
+// settingSources returns where each of startSettings(cmd) came from: the
+// command line, the environment, the config file (which also marks its flags
+// as changed) or the built-in default.
+func settingSources(cmd *cobra.Command) map[string]string {
+	sources := map[string]string{}
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if perInvocation[f.Name] {
+			return
+		}
+		switch v, env := lookupEnv(config.EnvName(envPrefix, f.Name)); {
+		case fromCommandLine(cmd, f.Name):
+			sources[f.Name] = "command line"
+		case env && v != "":
+			sources[f.Name] = "environment"
+		case f.Changed:
+			sources[f.Name] = "config file"
+		default:
+			sources[f.Name] = "default"
+		}
+	})
+	return sources
+}
+
+// ensureDaemon makes sure a daemon with this invocation's effective settings
+// is running: it does nothing when a matching, healthy one already is,
+// restarts one that differs or is failing, and starts one otherwise.
+func ensureDaemon(cmd *cobra.Command) error {
+	pid := daemon.RunningPID()
+	if pid == 0 {
+		return daemon.Daemonize(daemonArgs(cmd))
+	}
+
+	st, err := daemon.ReadState()
+	h := checkHealth()
+	switch {
+	case err != nil || !maps.Equal(st.Settings, startSettings(cmd)):
+		fmt.Fprintln(daemon.Output, i18n.T("daemon.settings_changed", pid))
+	case h.State() == daemon.HealthReady && !h.LastPoll.IsZero():
+		fmt.Fprintln(daemon.Output, i18n.T("daemon.failing", pid))
+	default:
+		return nil // already running as requested
+	}
+
+	if err := stopAndWait(pid); err != nil {
+		return err
+	}
+	return daemon.Daemonize(daemonArgs(cmd))
+}
+
+// effectiveLogLevel lowers level to debug when verbose is set, since the
+// PowerShell I/O that --verbose enables is logged at debug level.
+func effectiveLogLevel(level slog.Level, verbose bool) slog.Level {
+	if verbose {
+		return min(level, slog.LevelDebug)
+	}
+	return level
+}
+
+// startRetention builds the janitor's retention bounds from the --retain-*
+// flags. The zero Retention means the janitor does not run.
+func startRetention() (store.Retention, error) {
+	var r store.Retention
+	var err error
+	if retainAge != "" {
+		if r.MaxAge, err = config.ParseAge(retainAge); err != nil {
+			return r, i18n.Errorf("cmd.invalid_flag", "retain-age", err)
+		}
+	}
+	if retainSize != "" {
+		if r.MaxBytes, err = config.ParseSize(retainSize); err != nil {
+			return r, i18n.Errorf("cmd.invalid_flag", "retain-size", err)
+		}
+	}
+	if retainCount < 0 {
+		return r, fmt.Errorf("Retain count must be 0 (no limit) or more (got %d)", retainCount)
+	}
+	r.KeepLast = retainCount
+	if !r.IsZero() && retainInterval <= 0 {
+		return r, fmt.Errorf("Retain interval must be positive (got %s)", time.Duration(retainInterval))
+	}
+	return r, nil
+}
+
+// maintenanceTasks lists the housekeeping run in the nightly maintenance
+// window. dir returns the current output directory, which a reload may change;
+// nameKey is the --private-names key, if one exists. Retention is not among
+// them: the janitor applies it every --retain-interval, so the store stays
+// within its bounds all day instead of only after the window. The daemon log
+// rotates by size as it is written.
+func maintenanceTasks(dir func() string, nameKey []byte) []maintenance.Task {
+	return []maintenance.Task{fsckTask(dir, nameKey), indexTask(dir)}
+}
+
+// fsckTask re-hashes the screenshots in dir() and moves corrupt ones aside,
+// nightly and, with --fsck-on-start, when the daemon starts.
+func fsckTask(dir func() string, nameKey []byte) maintenance.Task {
+	return maintenance.Task{Name: "fsck", Run: func(ctx context.Context, logger *slog.Logger) error {
+		res, err := store.Verify(ctx, dir(), nameKey)
+		for _, path := range res.Corrupt {
+			logger.Warn("fsck: screenshot does not match its hash, moved aside as .corrupt", "path", path)
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info("fsck: finished", "checked", res.Checked, "corrupt", len(res.Corrupt))
+		return nil
+	}}
+}
+
+// indexTask drops the capture index entries of screenshots that no longer
+// exist, such as ones deleted by hand or moved aside by fsck.
+func indexTask(dir func() string) maintenance.Task {
+	return maintenance.Task{Name: "index", Run: func(ctx context.Context, logger *slog.Logger) error {
+		n, err := store.CompactIndex(dir())
+		if err != nil {
+			return err
+		}
+		logger.Info("index: compacted", "removed", n)
+		return nil
+	}}
+}
+
+// daemonArgs rebuilds the start flags for the re-exec'd daemon child. Only
+// flags given on the command line are forwarded (the interval and cleaned
+// output dir first), except the ones that only make sense in the launching
+// process. Settings from the environment or config file are left for the
+// child to resolve itself, so a SIGHUP reload can still change them.
+func daemonArgs(cmd *cobra.Command) []string {
+	var args []string
+	if fromCommandLine(cmd, "interval") {
+		args = append(args, "--interval", interval.String())
+	}
+	if fromCommandLine(cmd, "output") {
+		args = append(args, "--output", filepath.Clean(outputDir))
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "daemon", "quiet", "ensure", "replace", "interval", "output":
+			return
+		}
+		if !fromCommandLine(cmd, f.Name) {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
+
 func init() {
 	rootCmd.AddCommand(startCmd)
 
-	startCmd.Flags().IntVarP(&interval, "interval", "i", 250, "Clipboard polling interval in ms (100-5000)")
-	startCmd.Flags().StringVarP(&outputDir, "output", "o", "/tmp/.wsl-screenshot-cli/", "Directory to store PNGs")
+	interval = config.Duration(250 * time.Millisecond)
+	startCmd.Flags().VarP(&interval, "interval", "i", "Clipboard polling interval, e.g. 250ms or 1s; bare integers are ms (100ms-5s, or 20ms-5s with --wait)")
+	startCmd.Flags().StringVar(&onCollision, "on-collision", "suffix", "When a file name is taken by different content: suffix, overwrite, or skip")
+	overwriteWindow = config.Duration(5 * time.Second)
+	startCmd.Flags().Var(&overwriteWindow, "overwrite-window", "Report other apps replacing the clipboard within this long after an update (0 disables)")
+	startCmd.Flags().StringVarP(&outputDir, "output", "o", daemon.DefaultOutputDir, "Directory to store PNGs")
+	fileMode, dirMode = 0600, 0700
+	startCmd.Flags().Var(&fileMode, "file-mode", "Permission bits of saved screenshots, in octal (0644 lets other local users read them)")
+	startCmd.Flags().Var(&dirMode, "dir-mode", "Permission bits of the output directory and daily subdirectories the daemon creates, in octal")
+	startCmd.Flags().IntVar(&writeLimit, "write-limit", 0, "Save at most this many new screenshots per second, queueing bursts (0 disables)")
 	startCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Log all PowerShell I/O for debugging")
 	startCmd.Flags().BoolVarP(&daemonize, "daemon", "d", false, "Run as a background daemon")
+	startCmd.Flags().BoolVar(&ensure, "ensure", false, "With --daemon: do nothing if a healthy daemon with the same settings runs, restart it if they differ")
+	startCmd.Flags().BoolVar(&replace, "replace", false, "With --daemon: stop a running daemon first and start this one in its place")
+	startCmd.MarkFlagsMutuallyExclusive("ensure", "replace")
 	startCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Suppress informational messages")
+	startCmd.Flags().BoolVar(&dailyDirs, "daily-dirs", false, "Save screenshots in YYYY-MM-DD subdirectories")
+	startCmd.Flags().StringSliceVar(&clipFormats, "formats", clipboard.Formats, "Clipboard formats to set after a capture: text (WSL path), image, filedrop (Windows path); without image, the source app's bitmap is kept as is")
+	startCmd.Flags().BoolVar(&latestLink, "latest-link", true, "Keep a latest.png symlink in the output directory pointing at the newest screenshot")
+	startCmd.Flags().BoolVar(&latestFile, "latest-file", false, "Also keep a 'latest' file in the output directory holding the newest screenshot's path")
+	startCmd.Flags().BoolVar(&noClipboardUpdate, "no-clipboard-update", false, "Only save screenshots: leave the clipboard as the source app set it")
+	startCmd.Flags().StringVar(&textPath, "text-path", "wsl", "Path to put in the clipboard text after a capture: wsl, windows (for Windows apps), or both on two lines")
+	startCmd.Flags().StringVar(&filenameTemplate, "filename-template", "", "Name screenshots from tokens instead of their hash, e.g. '{date}_{time}_{shorthash}' (tokens: {date}, {time}, {seq}, {hash}, {shorthash}, {window})")
+	startCmd.Flags().StringVar(&textTemplate, "text-template", "", "Go template for the clipboard text after a capture, e.g. '![screenshot]({{.WSLPath}})' (fields: WSLPath, WinPath, Name; default: the WSL path)")
+	startCmd.MarkFlagsMutuallyExclusive("text-path", "text-template")
+	startCmd.Flags().BoolVar(&htmlImages, "html-images", false, "Save the image of an HTML-only clipboard (a lone <img>, e.g. some apps' Copy image), downloading remote images")
+	startCmd.Flags().IntVar(&breakerThreshold, "breaker-threshold", 5, "Consecutive poll errors before the circuit breaker trips")
+	startCmd.Flags().StringVar(&breakerAction, "breaker-action", "restart", "What to do when the breaker trips: restart (PowerShell client) or exit")
+	breakerBackoff = config.Duration(time.Second)
+	startCmd.Flags().Var(&breakerBackoff, "breaker-backoff", "Delay before restarting the PowerShell helper again when the last restart did not help; doubles each time")
+	breakerMaxBackoff = config.Duration(time.Minute)
+	startCmd.Flags().Var(&breakerMaxBackoff, "breaker-max-backoff", "Upper bound for --breaker-backoff as it doubles")
+	startCmd.Flags().IntVar(&breakerMaxRestarts, "breaker-max-restarts", 0, "Exit with status 3 after this many PowerShell client restarts within an hour (0 = no limit)")
+	startCmd.Flags().StringSliceVar(&breakerIgnore, "breaker-ignore", nil, "Error classes that never trip the breaker (backend, disk)")
+	startCmd.Flags().BoolVar(&seqCheck, "seq-check", false, "Skip full clipboard checks while the Windows clipboard sequence number is unchanged")
+	_ = startCmd.Flags().MarkDeprecated("seq-check", "every CHECK already skips an unchanged clipboard by its sequence number; use --wait for intervals below 100ms")
+	startCmd.Flags().BoolVar(&waitChanges, "wait", false, "Wake up on clipboard changes instead of polling every --interval (the interval applies if the helper can't listen)")
+	startCmd.Flags().StringVar(&logLevel, "log-level", "info", "Minimum level of daemon log records: debug, info, warn, or error (--verbose implies debug)")
+	startCmd.Flags().StringVar(&logFormat, "log-format", "text", "Daemon log format: text (key=value) or json (one object per line, for log aggregators)")
+	startCmd.Flags().IntVar(&logMaxSize, "log-max-size", 10, "Rotate the daemon log when it would exceed this many MB (0 disables)")
+	startCmd.Flags().IntVar(&logMaxBackups, "log-max-backups", 3, "Rotated daemon logs to keep (.log.1 is the newest)")
+	startCmd.Flags().StringVar(&maintenanceAt, "maintenance-at", "03:30", "Daily time (HH:MM in --timezone) for housekeeping such as integrity checks, or off")
+	startCmd.Flags().StringVar(&minFreeSpace, "min-free-space", "100MB", "Skip saving screenshots that would leave less than this free on the output filesystem, e.g. 100MB (0 disables)")
+	startCmd.Flags().BoolVar(&lowDiskToast, "low-disk-toast", false, "Show a Windows notification when screenshots start being skipped for --min-free-space")
+	startCmd.Flags().StringVar(&retainAge, "retain-age", "", "Delete screenshots older than this in the background, e.g. 7d, 2w or 12h (default: keep forever)")
+	startCmd.Flags().IntVar(&retainCount, "retain-count", 0, "Keep only this many of the newest screenshots, deleting older ones in the background (0 = no limit)")
+	startCmd.Flags().StringVar(&retainSize, "retain-size", "", "Keep the newest screenshots that fit in this size, e.g. 500MB, deleting older ones in the background")
+	retainInterval = config.Duration(10 * time.Minute)
+	startCmd.Flags().Var(&retainInterval, "retain-interval", "How often the background janitor applies --retain-age, --retain-count and --retain-size")
+	startCmd.Flags().BoolVar(&fsckOnStart, "fsck-on-start", true, "Re-hash saved screenshots in the background at startup, moving corrupt ones aside (also runs in the maintenance window)")
+	startCmd.Flags().StringVar(&backend, "backend", clipboard.BackendPowerShell, "Clipboard helper: powershell (clipboard script), native (compiled helper, in builds that include it) or fallback (wl-paste and win32yank.exe/clip.exe, path text only)")
+	startCmd.Flags().StringVar(&psBinary, "ps-binary", "", "PowerShell executable for the clipboard helper, a name in PATH or a path (default: pwsh.exe if installed, else powershell.exe)")
+	startCmd.Flags().IntVar(&psMemoryLimit, "ps-memory-limit", 0, "Restart the PowerShell helper when its working set exceeds this many MB (0 disables)")
+	psTimeout = config.Duration(10 * time.Second)
+	startCmd.Flags().Var(&psTimeout, "ps-timeout", "Kill and restart the PowerShell helper when it takes longer than this to answer a command (0 waits forever)")
+	startCmd.Flags().StringVar(&traceFile, "trace", "", "Record the PowerShell protocol to this file for offline replay (debugging)")
+	startCmd.Flags().Float64Var(&chaosRate, "chaos", 0, "Inject simulated faults (failed CHECKs, slow responses, failed writes) at this rate, 0-1, to test recovery")
+	_ = startCmd.Flags().MarkHidden("chaos")
+	startCmd.Flags().BoolVar(&privateNames, "private-names", false, "Name screenshots by a keyed hash (HMAC-SHA256 with a local secret) so names don't reveal content hashes")
+	shutdownTimeout = config.Duration(10 * time.Second)
+	startCmd.Flags().Var(&shutdownTimeout, "shutdown-timeout", "How long to wait for in-flight work and the PowerShell helper when stopping before killing it (0 waits forever)")
+	startCmd.Flags().StringVar(&timezone, "timezone", "Local", "Timezone for date-based paths (Local, UTC, or an IANA name like Europe/Paris)")
 }
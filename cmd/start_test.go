@@ -1,10 +1,23 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/nailuu/wsl-screenshot-cli/internal/config"
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
 	"github.com/nailuu/wsl-screenshot-cli/internal/platform"
+	"github.com/nailuu/wsl-screenshot-cli/internal/poller"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
 )
 
 func TestStart_FailsOnWSLCheckError(t *testing.T) {
@@ -15,7 +28,7 @@ func TestStart_FailsOnWSLCheckError(t *testing.T) {
 	platform.CheckWSLEnvironment = func() error { return wslErr }
 
 	// Reset flags to defaults before test
-	interval = 250
+	interval = config.Duration(250 * time.Millisecond)
 	outputDir = t.TempDir()
 	daemonize = true
 	verbose = false
@@ -41,7 +54,7 @@ func TestStart_FailsOnInteropCheckError(t *testing.T) {
 	interopErr := fmt.Errorf("WSL interop is disabled")
 	platform.CheckWSLInterop = func() error { return interopErr }
 
-	interval = 250
+	interval = config.Duration(250 * time.Millisecond)
 	outputDir = t.TempDir()
 	daemonize = false
 	verbose = false
@@ -58,23 +71,304 @@ func TestStart_FailsOnInteropCheckError(t *testing.T) {
 func TestStart_InvalidInterval(t *testing.T) {
 	tests := []struct {
 		name     string
-		interval int
+		interval string
 	}{
-		{"too_low", 50},
-		{"too_high", 6000},
+		{"too_low", "50"},
+		{"too_high", "6000"},
+		{"too_low_duration", "50ms"},
+		{"too_high_duration", "25s"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			interval = tt.interval
+			if err := interval.Set(tt.interval); err != nil {
+				t.Fatalf("interval.Set(%q): %v", tt.interval, err)
+			}
 			outputDir = t.TempDir()
 			daemonize = false
 			verbose = false
 
 			err := startCmd.RunE(startCmd, nil)
 			if err == nil {
-				t.Fatalf("expected error for interval %d, got nil", tt.interval)
+				t.Fatalf("expected error for interval %s, got nil", tt.interval)
+			}
+		})
+	}
+}
+
+func TestMaintenanceTasks_CompactIndex(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"gone", "kept"} {
+		path := filepath.Join(dir, name+".png")
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AppendIndex(dir, path, store.IndexEntry{Hash: name, Seq: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Remove(filepath.Join(dir, "gone.png"))
+
+	var names []string
+	for _, task := range maintenanceTasks(func() string { return dir }, nil) {
+		names = append(names, task.Name)
+		if task.Name != "index" {
+			continue
+		}
+		if err := task.Run(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+			t.Fatalf("index task error: %v", err)
+		}
+	}
+	if strings.Join(names, ",") != "fsck,index" {
+		t.Errorf("maintenance tasks = %v, want [fsck index]", names)
+	}
+	ix, err := store.ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ix.Entries(); len(got) != 1 || got[0].Hash != "kept" {
+		t.Errorf("index after maintenance = %+v, want only kept.png", got)
+	}
+}
+
+func TestStart_InvalidMaintenanceWindow(t *testing.T) {
+	interval = config.Duration(250 * time.Millisecond)
+	outputDir = t.TempDir()
+	daemonize = false
+	maintenanceAt = "midnight"
+	defer func() { maintenanceAt = "03:30" }()
+
+	if err := startCmd.RunE(startCmd, nil); err == nil {
+		t.Fatal("expected error for invalid maintenance window, got nil")
+	}
+}
+
+func TestStart_InvalidCollisionPolicy(t *testing.T) {
+	interval = config.Duration(250 * time.Millisecond)
+	outputDir = t.TempDir()
+	daemonize = false
+	onCollision = "rename"
+	defer func() { onCollision = "suffix" }()
+
+	if err := startCmd.RunE(startCmd, nil); err == nil {
+		t.Fatal("expected error for invalid collision policy, got nil")
+	}
+}
+
+func TestStart_InvalidFilenameTemplate(t *testing.T) {
+	interval = config.Duration(250 * time.Millisecond)
+	outputDir = t.TempDir()
+	daemonize = false
+	filenameTemplate = "{date}/{nope}"
+	defer func() { filenameTemplate = "" }()
+
+	err := startCmd.RunE(startCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--filename-template") {
+		t.Fatalf("expected an --filename-template error, got %v", err)
+	}
+}
+
+func TestStart_InvalidRetention(t *testing.T) {
+	interval = config.Duration(250 * time.Millisecond)
+	outputDir = t.TempDir()
+	daemonize = false
+	defer func() {
+		retainAge, retainCount, retainSize = "", 0, ""
+		retainInterval = config.Duration(10 * time.Minute)
+	}()
+
+	for _, set := range []func(){
+		func() { retainAge = "a week" },
+		func() { retainSize = "big" },
+		func() { retainCount = -1 },
+		func() { retainCount, retainInterval = 100, 0 },
+	} {
+		retainAge, retainCount, retainSize = "", 0, ""
+		retainInterval = config.Duration(10 * time.Minute)
+		set()
+		err := startCmd.RunE(startCmd, nil)
+		if err == nil || !strings.Contains(err.Error(), "etain") {
+			t.Errorf("start with --retain-age %q --retain-count %d --retain-size %q --retain-interval %s: error = %v, want a retention error",
+				retainAge, retainCount, retainSize, time.Duration(retainInterval), err)
+		}
+	}
+}
+
+func TestStart_InvalidTimezone(t *testing.T) {
+	interval = config.Duration(250 * time.Millisecond)
+	outputDir = t.TempDir()
+	daemonize = false
+	verbose = false
+	timezone = "Mars/Olympus_Mons"
+	defer func() { timezone = "Local" }()
+
+	err := startCmd.RunE(startCmd, nil)
+	if err == nil {
+		t.Fatal("expected error for invalid timezone, got nil")
+	}
+}
+
+func TestDaemonArgs_ForwardsChangedFlags(t *testing.T) {
+	for name, v := range map[string]string{"timezone": "UTC", "daemon": "true", "interval": "500ms", "output": "/tmp/shots/"} {
+		if err := startCmd.Flags().Set(name, v); err != nil {
+			t.Fatalf("set %s: %v", name, err)
+		}
+	}
+	defer func() {
+		for _, name := range []string{"timezone", "daemon", "interval", "output"} {
+			startCmd.Flags().Lookup(name).Changed = false
+		}
+		timezone = "Local"
+		daemonize = false
+	}()
+
+	got := strings.Join(daemonArgs(startCmd), " ")
+	want := "--interval 500ms --output /tmp/shots --timezone=UTC"
+	if got != want {
+		t.Errorf("daemonArgs() = %q, want %q", got, want)
+	}
+
+	// Flags filled in from the environment or config file are left for the
+	// child to resolve, so a reload can still change them.
+	commandLineFlags = map[string]bool{"timezone": true}
+	defer func() { commandLineFlags = nil }()
+	if got := strings.Join(daemonArgs(startCmd), " "); got != "--timezone=UTC" {
+		t.Errorf("daemonArgs() = %q, want only command-line flags", got)
+	}
+}
+
+func TestReloadSettings(t *testing.T) {
+	path := useTestConfig(t, map[string]string{"WSL_SCREENSHOT_VERBOSE": "true"})
+	dir := t.TempDir()
+	if err := os.WriteFile(path, []byte("interval: 2s\noutput: "+dir+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := reloadSettings(startCmd)
+	if err != nil {
+		t.Fatalf("reloadSettings() error: %v", err)
+	}
+	want := poller.Settings{Interval: 2 * time.Second, OutputDir: dir, Verbose: true}
+	if s != want {
+		t.Errorf("reloadSettings() = %+v, want %+v", s, want)
+	}
+
+	// A command-line interval stays fixed across reloads.
+	commandLineFlags = map[string]bool{"interval": true}
+	interval = config.Duration(time.Second)
+	defer func() { commandLineFlags, interval = nil, config.Duration(250*time.Millisecond) }()
+	if s, err := reloadSettings(startCmd); err != nil || s.Interval != time.Second {
+		t.Errorf("reloadSettings() = %+v, %v; want the command-line interval", s, err)
+	}
+
+	if err := os.WriteFile(path, []byte("interval: 10ms\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	commandLineFlags = nil
+	if _, err := reloadSettings(startCmd); err == nil {
+		t.Error("reloadSettings() should reject an out-of-range interval")
+	}
+}
+
+func TestStart_InvalidBreakerSettings(t *testing.T) {
+	defer func() {
+		breakerThreshold = 5
+		breakerAction = "restart"
+		breakerIgnore = nil
+	}()
+
+	tests := []struct {
+		name      string
+		threshold int
+		action    string
+		ignore    []string
+	}{
+		{"zero_threshold", 0, "restart", nil},
+		{"unknown_action", 5, "explode", nil},
+		{"unknown_class", 5, "restart", []string{"network"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval = config.Duration(250 * time.Millisecond)
+			outputDir = t.TempDir()
+			daemonize = false
+			breakerThreshold = tt.threshold
+			breakerAction = tt.action
+			breakerIgnore = tt.ignore
+
+			if err := startCmd.RunE(startCmd, nil); err == nil {
+				t.Fatal("expected validation error, got nil")
 			}
 		})
 	}
 }
+
+// useTestDaemonFiles points the daemon's files at a temp dir and silences
+// its messages for the duration of a test.
+func useTestDaemonFiles(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	origPid, origState, origHeartbeat := daemon.PidFile, daemon.StateFile, daemon.HeartbeatFile
+	origRegistry, origSocket, origOutput := daemon.RegistryDir, daemon.SocketFile, daemon.Output
+	origHistory := daemon.HistoryFile
+	daemon.PidFile = filepath.Join(dir, "test.pid")
+	daemon.StateFile = filepath.Join(dir, "test.state")
+	daemon.HeartbeatFile = filepath.Join(dir, "test.heartbeat")
+	daemon.RegistryDir = filepath.Join(dir, "registry")
+	daemon.SocketFile = filepath.Join(dir, "test.sock")
+	daemon.HistoryFile = filepath.Join(dir, "history.json")
+	daemon.Output = io.Discard
+	t.Cleanup(func() {
+		daemon.PidFile, daemon.StateFile, daemon.HeartbeatFile = origPid, origState, origHeartbeat
+		daemon.RegistryDir, daemon.SocketFile, daemon.Output = origRegistry, origSocket, origOutput
+		daemon.HistoryFile = origHistory
+	})
+}
+
+func TestStartSettings(t *testing.T) {
+	outputDir = "/tmp/shots/"
+	defer func() { outputDir = startCmd.Flags().Lookup("output").DefValue }()
+
+	s := startSettings(startCmd)
+	if s["output"] != "/tmp/shots" {
+		t.Errorf("output = %q, want the cleaned dir", s["output"])
+	}
+	if s["interval"] != "250ms" || s["seq-check"] != "false" {
+		t.Errorf("interval = %q, seq-check = %q; want the effective values", s["interval"], s["seq-check"])
+	}
+	for name := range perInvocation {
+		if _, ok := s[name]; ok {
+			t.Errorf("per-invocation flag %q should not be recorded", name)
+		}
+	}
+}
+
+func TestEnsureDaemon_MatchingHealthyDaemonIsLeftAlone(t *testing.T) {
+	useTestDaemonFiles(t)
+	os.WriteFile(daemon.PidFile, []byte(strconv.Itoa(os.Getpid())), 0600)
+	state, _ := json.Marshal(daemon.State{Settings: startSettings(startCmd)})
+	os.WriteFile(daemon.StateFile, state, 0600)
+
+	origHealth, origStop := checkHealth, stopDaemon
+	defer func() { checkHealth, stopDaemon = origHealth, origStop }()
+	checkHealth = func() daemon.Health { return daemon.Health{Alive: true, BackendReady: true, LastPollOK: true} }
+	stopped := false
+	stopDaemon = func() { stopped = true }
+
+	if err := ensureDaemon(startCmd); err != nil {
+		t.Fatalf("ensureDaemon() error: %v", err)
+	}
+	if stopped {
+		t.Error("a matching healthy daemon was restarted")
+	}
+}
+
+func TestEffectiveLogLevel(t *testing.T) {
+	if got := effectiveLogLevel(slog.LevelWarn, true); got != slog.LevelDebug {
+		t.Errorf("--verbose with warn = %v, want debug", got)
+	}
+	if got := effectiveLogLevel(slog.LevelWarn, false); got != slog.LevelWarn {
+		t.Errorf("warn without --verbose = %v, want warn", got)
+	}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+var statsSinceLast bool
+var statsExportCSV bool
+
+// statsSnapshotFile returns where stats --since-last keeps its snapshot.
+// Declared as a var so tests can redirect it.
+var statsSnapshotFile = func() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wsl-screenshot-cli", "stats-snapshot.json"), nil
+}
+
+// statsSnapshot is the state stats --since-last diffs against.
+type statsSnapshot struct {
+	Time     time.Time `json:"time"`
+	PID      int       `json:"pid"` // daemon the counters came from, 0 if none
+	Captures int64     `json:"captures"`
+	Errors   int64     `json:"errors"`
+	Injected int64     `json:"injected,omitempty"` // errors simulated by --chaos
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// statsDelta is what changed between two snapshots.
+type statsDelta struct {
+	Elapsed  time.Duration
+	Captures int64
+	Errors   int64
+	Bytes    int64 // negative when screenshots were deleted
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show capture counters and output directory usage",
+	Long: `Show the running daemon's capture and error counters and how much the output
+directory holds. With --since-last, report what changed since the previous
+'stats --since-last' run instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := cmd.OutOrStdout()
+		cur, err := currentStats()
+		if err != nil {
+			return err
+		}
+
+		if !statsSinceLast {
+			printStats(w, cur)
+			return nil
+		}
+
+		path, err := statsSnapshotFile()
+		if err != nil {
+			return fmt.Errorf("Failed to locate stats snapshot: %w", err)
+		}
+		prev, err := loadStatsSnapshot(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("Failed to read stats snapshot: %w", err)
+		}
+		if err == nil {
+			printDelta(w, diffStats(prev, cur))
+		} else {
+			fmt.Fprintln(w, "No previous snapshot, saved one now. Run again later to see what changed.")
+		}
+		if err := saveStatsSnapshot(path, cur); err != nil {
+			return fmt.Errorf("Failed to save stats snapshot: %w", err)
+		}
+		return nil
+	},
+}
+
+// currentStats gathers the live daemon counters and output directory usage.
+// Both are read inside one store.View, so no capture lands between them and
+// the counters always agree with the files.
+func currentStats() (statsSnapshot, error) {
+	s := statsSnapshot{Time: time.Now()}
+	err := store.View(daemon.ReadOutputDir(), func(snap *store.Snapshot) error {
+		if hb, err := liveHeartbeat(); err == nil && hb.PID == daemon.RunningPID() {
+			s.PID = hb.PID
+			s.Captures = hb.Stats.Captures
+			s.Errors = hb.Stats.Errors
+			s.Injected = hb.Stats.InjectedErrors
+		}
+		s.Files, s.Bytes = snap.Usage()
+		return nil
+	})
+	if err != nil {
+		return s, fmt.Errorf("Failed to scan output directory: %w", err)
+	}
+	return s, nil
+}
+
+// liveHeartbeat reads the daemon's counters. Declared as a var so tests can
+// supply fixed ones.
+var liveHeartbeat = daemon.LiveHeartbeat
+
+// diffStats computes cur minus prev. Daemon counters restart from zero with a
+// new process, so when the PID changed the current values are all new.
+func diffStats(prev, cur statsSnapshot) statsDelta {
+	d := statsDelta{
+		Elapsed:  cur.Time.Sub(prev.Time),
+		Captures: cur.Captures,
+		Errors:   cur.Errors,
+		Bytes:    cur.Bytes - prev.Bytes,
+	}
+	if cur.PID != 0 && cur.PID == prev.PID {
+		d.Captures -= prev.Captures
+		d.Errors -= prev.Errors
+	}
+	return d
+}
+
+func loadStatsSnapshot(path string) (statsSnapshot, error) {
+	var s statsSnapshot
+	data, err := os.ReadFile(path) // #nosec G304 -- path is under the user's cache dir
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+func saveStatsSnapshot(path string, s statsSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func printStats(w io.Writer, s statsSnapshot) {
+	fmt.Fprintf(w, "Captures:     %d\n", s.Captures)
+	fmt.Fprintf(w, "Errors:       %d\n", s.Errors)
+	if s.Injected > 0 {
+		fmt.Fprintf(w, "Injected:     %d (simulated by --chaos)\n", s.Injected)
+	}
+	fmt.Fprintf(w, "Screenshots:  %d (%.1f MB)\n", s.Files, float64(s.Bytes)/(1024*1024))
+}
+
+func printDelta(w io.Writer, d statsDelta) {
+	fmt.Fprintf(w, "Since last check (%s ago):\n", formatDuration(d.Elapsed))
+	fmt.Fprintf(w, "Captures:     %+d\n", d.Captures)
+	fmt.Fprintf(w, "Errors:       %+d\n", d.Errors)
+	fmt.Fprintf(w, "Bytes added:  %+.1f MB\n", float64(d.Bytes)/(1024*1024))
+}
+
+var statsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export daily capture statistics",
+	Long: `Export one row per day with the captures, bytes saved, errors and average
+CHECK latency, for spreadsheets (--csv) or scripts (JSON, the default).
+
+The daemon rolls its counters up into daily rows every minute and when it
+stops, in a history file that survives restarts (the last 366 days are kept).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := stats.LoadHistory(daemon.HistoryFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read stats history: %w", err)
+		}
+		if statsExportCSV {
+			return writeHistoryCSV(cmd.OutOrStdout(), h)
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(h.Days)
+	},
+}
+
+// writeHistoryCSV writes h as CSV with a header row.
+func writeHistoryCSV(w io.Writer, h stats.History) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "captures", "bytes", "errors", "avg_latency_ms"})
+	for _, d := range h.Days {
+		_ = cw.Write([]string{
+			d.Date,
+			strconv.FormatInt(d.Captures, 10),
+			strconv.FormatInt(d.Bytes, 10),
+			strconv.FormatInt(d.Errors, 10),
+			strconv.FormatFloat(d.AvgLatencyMs(), 'f', 1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.AddCommand(statsExportCmd)
+
+	statsExportCmd.Flags().BoolVar(&statsExportCSV, "csv", false, "Print CSV instead of JSON")
+
+	statsCmd.Flags().BoolVar(&statsSinceLast, "since-last", false, "Report changes since the previous --since-last run")
+}
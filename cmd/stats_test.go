@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+func TestDiffStats(t *testing.T) {
+	base := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	prev := statsSnapshot{Time: base, PID: 100, Captures: 10, Errors: 2, Files: 10, Bytes: 5000}
+
+	tests := []struct {
+		name string
+		cur  statsSnapshot
+		want statsDelta
+	}{
+		{
+			"same daemon",
+			statsSnapshot{Time: base.Add(3 * time.Hour), PID: 100, Captures: 14, Errors: 3, Bytes: 7000},
+			statsDelta{Elapsed: 3 * time.Hour, Captures: 4, Errors: 1, Bytes: 2000},
+		},
+		{
+			"daemon restarted",
+			statsSnapshot{Time: base.Add(time.Hour), PID: 200, Captures: 1, Errors: 0, Bytes: 4000},
+			statsDelta{Elapsed: time.Hour, Captures: 1, Errors: 0, Bytes: -1000},
+		},
+		{
+			"daemon stopped",
+			statsSnapshot{Time: base.Add(time.Hour), Bytes: 5000},
+			statsDelta{Elapsed: time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffStats(prev, tt.cur); got != tt.want {
+				t.Errorf("diffStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsSnapshot_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "stats.json")
+	want := statsSnapshot{Time: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), PID: 7, Captures: 3, Errors: 1, Files: 3, Bytes: 1234}
+
+	if err := saveStatsSnapshot(path, want); err != nil {
+		t.Fatalf("saveStatsSnapshot() error: %v", err)
+	}
+	got, err := loadStatsSnapshot(path)
+	if err != nil {
+		t.Fatalf("loadStatsSnapshot() error: %v", err)
+	}
+	if !got.Time.Equal(want.Time) {
+		t.Errorf("Time = %v, want %v", got.Time, want.Time)
+	}
+	got.Time = want.Time
+	if got != want {
+		t.Errorf("loadStatsSnapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrintDelta(t *testing.T) {
+	var buf bytes.Buffer
+	printDelta(&buf, statsDelta{Elapsed: 90 * time.Minute, Captures: 4, Errors: 0, Bytes: -1 << 20})
+	out := buf.String()
+	for _, want := range []string{"1h 30m 0s ago", "Captures:     +4", "Errors:       +0", "Bytes added:  -1.0 MB"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printDelta() output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	var buf bytes.Buffer
+	h := stats.History{Days: []stats.Day{{Date: "2024-05-01", Captures: 3, Bytes: 4096, Errors: 1, Checks: 4, CheckMs: 50}}}
+	if err := writeHistoryCSV(&buf, h); err != nil {
+		t.Fatal(err)
+	}
+	want := "date,captures,bytes,errors,avg_latency_ms\n2024-05-01,3,4096,1,12.5\n"
+	if buf.String() != want {
+		t.Errorf("CSV = %q, want %q", buf.String(), want)
+	}
+}
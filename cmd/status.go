@@ -1,37 +1,303 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+var (
+	statusPrompt bool
+	statusAll    bool
+	statusJSON   bool
+	statusPlain  bool
 )
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show status of the clipboard polling process",
-	Args:  cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Show status of the clipboard polling process.
+
+The exit code tells scripts the outcome without parsing the output:
+
+  0  running and healthy (or still starting up)
+  1  not running
+  2  running but degraded: the clipboard backend is down or its polls fail
+
+--plain prints one "Label: value" line per field, without alignment,
+symbols or translation, for screen readers and scripts. Its labels, their
+order and value formats stay the same across versions; new lines may only be
+appended.
+
+--prompt and --all always exit 0.`,
+	Args:          cobra.NoArgs,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		w := cmd.OutOrStdout()
+		if statusPrompt {
+			hb, _ := daemon.ReadHeartbeat() // a missing or unreadable heartbeat renders as down
+			fmt.Fprintln(w, promptToken(hb, time.Now()))
+			return nil
+		}
+		if statusAll {
+			printInstances(w, daemon.Instances())
+			return nil
+		}
+
 		info := daemon.Status()
+		if statusPlain {
+			printPlainStatus(w, info)
+			return statusExit(info)
+		}
+		if statusJSON {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if info == nil {
+				stopped := map[string]any{"running": false}
+				if exit := daemon.LastExit(); exit != nil {
+					stopped["last_exit"] = exit
+				}
+				_ = enc.Encode(stopped)
+			} else {
+				_ = enc.Encode(info)
+			}
+			return statusExit(info)
+		}
 		if info == nil {
-			fmt.Fprintln(w, "Status:  not running")
-			return
+			fmt.Fprintln(w, i18n.T("cmd.status_not_running"))
+			if exit := daemon.LastExit(); exit != nil {
+				fmt.Fprintln(w, i18n.T("cmd.status_last_exit", formatDuration(time.Since(exit.Time)), exit.Code, exit.Reason))
+			}
+			return statusExit(info)
 		}
 
-		fmt.Fprintf(w, "Status:       running\n")
+		if degraded(info.Health) {
+			fmt.Fprintf(w, "Status:       running (degraded)\n")
+		} else {
+			fmt.Fprintf(w, "Status:       running\n")
+		}
+		if daemon.InstanceName != daemon.DefaultProfile {
+			fmt.Fprintf(w, "Profile:      %s\n", daemon.InstanceName)
+		}
 		fmt.Fprintf(w, "PID:          %d\n", info.PID)
-		fmt.Fprintf(w, "Uptime:       %s\n", formatDuration(info.Uptime))
-		fmt.Fprintf(w, "CPU usage:    %.1f%%\n", info.CPUPercent())
-		fmt.Fprintf(w, "Memory:       %.1f MB\n", float64(info.MemoryRSSKB)/1024.0)
+		if info.MetricsSource == "" {
+			fmt.Fprintf(w, "Uptime:       unavailable (/proc unreadable, no heartbeat yet)\n")
+		} else {
+			fmt.Fprintf(w, "Uptime:       %s\n", formatDuration(info.Uptime))
+			fmt.Fprintf(w, "CPU usage:    %.1f%%\n", info.CPUPercent())
+			fmt.Fprintf(w, "Memory:       %.1f MB\n", float64(info.MemoryRSSKB)/1024.0)
+			if info.MetricsSource == daemon.SourceHeartbeat {
+				fmt.Fprintf(w, "              (self-reported by the daemon, /proc unreadable)\n")
+			}
+		}
+		fmt.Fprintf(w, "Health:       %s\n", describeHealth(info.Health, time.Now()))
+		if info.SessionLocked {
+			fmt.Fprintf(w, "Session:      locked, polling paused until unlock\n")
+		}
+		if ps := describeBackend(info); ps != "" {
+			fmt.Fprintf(w, "PowerShell:   %s\n", ps)
+		}
+		if !info.LastPing.IsZero() {
+			fmt.Fprintf(w, "Ping:         %s\n", describePing(info, time.Now()))
+		}
+		if info.ClipboardSeq != 0 {
+			fmt.Fprintf(w, "Clipboard:    sequence %d\n", info.ClipboardSeq)
+		}
+		if info.Overwrites > 0 {
+			who := info.LastOverwriter
+			if who == "" {
+				who = "unknown process"
+			}
+			fmt.Fprintf(w, "Overwrites:   %d (last by %s)\n", info.Overwrites, who)
+		}
+		if info.OrphanedBackends > 0 {
+			fmt.Fprintf(w, "Orphans:      %d orphaned powershell.exe processes detected\n", info.OrphanedBackends)
+		}
+		if info.Polls > 0 {
+			fmt.Fprintf(w, "Polls:        %d (%d errors, %d in a row)\n", info.Polls, info.Errors, info.ConsecutiveErrors)
+			fmt.Fprintf(w, "Captures:     %d new, %d duplicates\n", info.Captures, info.DedupHits)
+			fmt.Fprintf(w, "Last capture: %s\n", describeLastCapture(info.LastCapture, time.Now()))
+			if info.Restarts > 0 {
+				fmt.Fprintf(w, "Restarts:     %d (PowerShell backend)\n", info.Restarts)
+			}
+		}
 		fmt.Fprintf(w, "Screenshots:  %d\n", info.Screenshots)
-		fmt.Fprintf(w, "Output dir:   %s\n", info.OutputDir)
+		if info.OutputCaseInsensitive {
+			fmt.Fprintf(w, "Output dir:   %s (case-insensitive Windows drive)\n", info.OutputDir)
+		} else {
+			fmt.Fprintf(w, "Output dir:   %s\n", info.OutputDir)
+		}
 		fmt.Fprintf(w, "Log file:     %s\n", info.LogFile)
+		return statusExit(info)
 	},
 }
 
+// describeBackend renders the PowerShell backend's binary and working set,
+// whichever are known.
+func describeBackend(info *daemon.ProcessInfo) string {
+	var parts []string
+	if info.BackendBinary != "" {
+		parts = append(parts, info.BackendBinary)
+	}
+	if info.BackendMemoryKB > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f MB", float64(info.BackendMemoryKB)/1024.0))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describePing renders the last PING round trip to the PowerShell backend.
+func describePing(info *daemon.ProcessInfo, now time.Time) string {
+	ago := formatDuration(now.Sub(info.LastPing))
+	if !info.LastPingOK {
+		return fmt.Sprintf("no answer (%s ago)", ago)
+	}
+	return fmt.Sprintf("%.1f ms (%s ago)", info.PingMs, ago)
+}
+
+// describeLastCapture renders when the daemon last saved a screenshot.
+func describeLastCapture(t, now time.Time) string {
+	if t.IsZero() {
+		return "none yet"
+	}
+	return formatDuration(now.Sub(t)) + " ago"
+}
+
+// degraded reports whether a running daemon is failing at its job: its
+// backend is not ready (or it stopped reporting) or its last poll failed. A
+// daemon that has not completed its first poll yet is only starting up.
+func degraded(h daemon.Health) bool {
+	switch h.State() {
+	case daemon.HealthHealthy:
+		return false
+	case daemon.HealthReady:
+		return !h.LastPoll.IsZero()
+	default:
+		return true
+	}
+}
+
+// statusExit turns the status into status's documented exit code: nil for
+// healthy, 1 when not running (info is nil) and 2 when degraded.
+func statusExit(info *daemon.ProcessInfo) error {
+	switch {
+	case info == nil:
+		return &exitCodeError{code: 1, err: fmt.Errorf("not running")}
+	case degraded(info.Health):
+		return &exitCodeError{code: 2, err: fmt.Errorf("degraded: %s", describeHealth(info.Health, time.Now()))}
+	default:
+		return nil
+	}
+}
+
+// promptToken renders a compact status token for shell prompt segments:
+// "📸<captures> ✓" when healthy, "📸<captures> ⚠" while polls are failing,
+// and "✗ down" when there is no fresh heartbeat.
+func promptToken(hb *daemon.Heartbeat, now time.Time) string {
+	if hb == nil || !hb.Fresh(now) {
+		return "✗ down"
+	}
+	mark := "✓"
+	if hb.Stats.ConsecutiveErrors > 0 {
+		mark = "⚠"
+	}
+	return fmt.Sprintf("📸%d %s", hb.Stats.Captures, mark)
+}
+
+// printPlainStatus renders status --plain: one "Label: value" line per field,
+// in a fixed order, with no alignment padding, symbols or translation, so it
+// reads well in a screen reader and parses with a simple split on ": ".
+//
+// The format is a compatibility promise: lines may be appended in later
+// versions, but existing labels, their order and their value formats never
+// change. Every line is printed even when its value is unknown ("unknown")
+// or absent ("none"). A stopped daemon prints only the Status line.
+func printPlainStatus(w io.Writer, info *daemon.ProcessInfo) {
+	if info == nil {
+		fmt.Fprintln(w, "Status: not running")
+		return
+	}
+	status := "running"
+	if degraded(info.Health) {
+		status = "degraded"
+	}
+	uptime, cpu, memory := "unknown", "unknown", "unknown"
+	if info.MetricsSource != "" {
+		uptime = fmt.Sprintf("%d seconds", int64(info.Uptime.Seconds()))
+		cpu = fmt.Sprintf("%.1f percent", info.CPUPercent())
+		memory = fmt.Sprintf("%.1f megabytes", float64(info.MemoryRSSKB)/1024.0)
+	}
+	lastCapture := "none"
+	if !info.LastCapture.IsZero() {
+		lastCapture = info.LastCapture.UTC().Format(time.RFC3339)
+	}
+
+	lines := []struct{ label, value string }{
+		{"Status", status},
+		{"Health", info.Health.State().String()},
+		{"Profile", info.Profile},
+		{"PID", fmt.Sprint(info.PID)},
+		{"Uptime", uptime},
+		{"CPU", cpu},
+		{"Memory", memory},
+		{"Polls", fmt.Sprint(info.Polls)},
+		{"Errors", fmt.Sprint(info.Errors)},
+		{"Consecutive errors", fmt.Sprint(info.ConsecutiveErrors)},
+		{"Captures", fmt.Sprint(info.Captures)},
+		{"Duplicates", fmt.Sprint(info.DedupHits)},
+		{"Last capture", lastCapture},
+		{"Restarts", fmt.Sprint(info.Restarts)},
+		{"Screenshots", fmt.Sprint(info.Screenshots)},
+		{"Output directory", info.OutputDir},
+		{"Output directory case-insensitive", yesNo(info.OutputCaseInsensitive)},
+		{"Log file", info.LogFile},
+		{"PowerShell", orUnknown(info.BackendBinary)},
+	}
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s: %s\n", l.label, l.value)
+	}
+}
+
+// orUnknown renders an empty value as "unknown".
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// printInstances renders one table row per running instance.
+func printInstances(w io.Writer, instances []daemon.InstanceStatus) {
+	if len(instances) == 0 {
+		fmt.Fprintln(w, i18n.T("cmd.no_instances"))
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPID\tUPTIME\tCAPTURES\tOUTPUT DIR")
+	for _, inst := range instances {
+		captures := fmt.Sprintf("%d", inst.Captures)
+		if !inst.Healthy {
+			captures += " ⚠"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", inst.Name, inst.PID, formatDuration(inst.Uptime), captures, inst.OutputDir)
+	}
+	_ = tw.Flush()
+}
+
 // formatDuration formats a duration as "Xh Ym Zs", omitting zero leading components.
 func formatDuration(d time.Duration) string {
 	totalSeconds := int(d.Seconds())
@@ -54,4 +320,10 @@ func formatDuration(d time.Duration) string {
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().BoolVar(&statusPrompt, "prompt", false, "Print a compact token for shell prompts (reads only the heartbeat file)")
+	statusCmd.Flags().BoolVar(&statusAll, "all", false, "List every running instance")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print every status field as JSON")
+	statusCmd.Flags().BoolVar(&statusPlain, "plain", false, "Print one \"Label: value\" line per field, without symbols, in a format stable across versions")
+	statusCmd.MarkFlagsMutuallyExclusive("prompt", "all", "json", "plain")
 }
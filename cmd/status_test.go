@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
 )
 
 func TestFormatDuration(t *testing.T) {
@@ -30,3 +36,161 @@ func TestFormatDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestPromptToken(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		hb   *daemon.Heartbeat
+		want string
+	}{
+		{"no_heartbeat", nil, "✗ down"},
+		{"stale", &daemon.Heartbeat{UpdatedAt: now.Add(-time.Hour)}, "✗ down"},
+		{"healthy", &daemon.Heartbeat{UpdatedAt: now, Stats: stats.Snapshot{Captures: 3}}, "📸3 ✓"},
+		{"failing", &daemon.Heartbeat{UpdatedAt: now, Stats: stats.Snapshot{Captures: 3, ConsecutiveErrors: 2}}, "📸3 ⚠"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptToken(tt.hb, now); got != tt.want {
+				t.Errorf("promptToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintInstances(t *testing.T) {
+	var buf bytes.Buffer
+	printInstances(&buf, nil)
+	if got := buf.String(); got != "No running instances\n" {
+		t.Errorf("empty listing = %q", got)
+	}
+
+	buf.Reset()
+	printInstances(&buf, []daemon.InstanceStatus{
+		{Instance: daemon.Instance{Name: "default", PID: 42, OutputDir: "/tmp/shots"}, Uptime: 90 * time.Second, Captures: 3, Healthy: true},
+		{Instance: daemon.Instance{Name: "work", PID: 43, OutputDir: "/tmp/work"}, Captures: 1},
+	})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header + 2 rows, got %q", buf.String())
+	}
+	for i, want := range [][]string{
+		{"NAME", "PID", "UPTIME", "CAPTURES", "OUTPUT DIR"},
+		{"default", "42", "1m 30s", "3", "/tmp/shots"},
+		{"work", "43", "0s", "1 ⚠", "/tmp/work"},
+	} {
+		for _, field := range want {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("line %d = %q, missing %q", i, lines[i], field)
+			}
+		}
+	}
+}
+
+func TestStatusExit(t *testing.T) {
+	ago := time.Now().Add(-time.Second)
+	tests := []struct {
+		name string
+		info *daemon.ProcessInfo
+		want int
+	}{
+		{"not_running", nil, 1},
+		{"healthy", &daemon.ProcessInfo{Health: daemon.Health{Alive: true, BackendReady: true, LastPollOK: true, LastPoll: ago}}, 0},
+		{"starting", &daemon.ProcessInfo{Health: daemon.Health{Alive: true, BackendReady: true}}, 0},
+		{"polls_failing", &daemon.ProcessInfo{Health: daemon.Health{Alive: true, BackendReady: true, LastPoll: ago}}, 2},
+		{"backend_down", &daemon.ProcessInfo{Health: daemon.Health{Alive: true}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := statusExit(tt.info)
+			code := 0
+			var ec *exitCodeError
+			if errors.As(err, &ec) {
+				code = ec.code
+			} else if err != nil {
+				t.Fatalf("statusExit() = %v, want an exitCodeError", err)
+			}
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeLastCapture(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := describeLastCapture(time.Time{}, now); got != "none yet" {
+		t.Errorf("zero time = %q, want none yet", got)
+	}
+	if got := describeLastCapture(now.Add(-90*time.Second), now); got != "1m 30s ago" {
+		t.Errorf("90s ago = %q", got)
+	}
+}
+
+// TestPrintPlainStatus pins the --plain format, which is promised to stay
+// stable: only append lines here, never change existing ones.
+func TestPrintPlainStatus(t *testing.T) {
+	var buf bytes.Buffer
+	printPlainStatus(&buf, nil)
+	if buf.String() != "Status: not running\n" {
+		t.Errorf("not running = %q", buf.String())
+	}
+
+	buf.Reset()
+	printPlainStatus(&buf, &daemon.ProcessInfo{
+		PID:           4242,
+		Profile:       "default",
+		Uptime:        90 * time.Minute,
+		CPUTime:       54,
+		MemoryRSSKB:   12800,
+		MetricsSource: daemon.SourceProc,
+		Polls:         100,
+		Errors:        2,
+		Captures:      7,
+		DedupHits:     3,
+		LastCapture:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Screenshots:   40,
+		OutputDir:     "/mnt/c/shots",
+		LogFile:       "/tmp/.wsl-screenshot-cli.log",
+
+		OutputCaseInsensitive: true,
+		Health:                daemon.Health{Alive: true, BackendReady: true, LastPollOK: true},
+	})
+	want := `Status: running
+Health: healthy
+Profile: default
+PID: 4242
+Uptime: 5400 seconds
+CPU: 1.0 percent
+Memory: 12.5 megabytes
+Polls: 100
+Errors: 2
+Consecutive errors: 0
+Captures: 7
+Duplicates: 3
+Last capture: 2024-05-01T12:00:00Z
+Restarts: 0
+Screenshots: 40
+Output directory: /mnt/c/shots
+Output directory case-insensitive: yes
+Log file: /tmp/.wsl-screenshot-cli.log
+PowerShell: unknown
+`
+	if buf.String() != want {
+		t.Errorf("printPlainStatus() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDescribePing(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	info := &daemon.ProcessInfo{LastPing: now.Add(-5 * time.Second), LastPingOK: true, PingMs: 4.25}
+	if got := describePing(info, now); got != "4.2 ms (5s ago)" {
+		t.Errorf("answered ping = %q", got)
+	}
+	info.LastPingOK = false
+	if got := describePing(info, now); got != "no answer (5s ago)" {
+		t.Errorf("failed ping = %q", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/nailuu/wsl-screenshot-cli/internal/daemon"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
 )
 
 const installScriptURL = "https://nailu.dev/wscli/install.sh"
@@ -23,7 +24,7 @@ var updateCmd = &cobra.Command{
 
 		daemonWasRunning := daemon.RunningPID() != 0
 		if daemonWasRunning {
-			fmt.Fprintln(cmd.OutOrStdout(), "Stopping running daemon before update...")
+			fmt.Fprintln(cmd.OutOrStdout(), i18n.T("cmd.update_stopping"))
 			daemon.Stop()
 		}
 
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
+
+var widgetKey string
+
+const bashWidget = `# wsl-screenshot-cli: insert the latest screenshot path at the cursor
+__wsl_screenshot_insert_last() {
+    local p
+    p="$(wsl-screenshot-cli last 2>/dev/null)" || return
+    printf -v p '%%q' "$p"
+    READLINE_LINE="${READLINE_LINE:0:READLINE_POINT}${p}${READLINE_LINE:READLINE_POINT}"
+    READLINE_POINT=$((READLINE_POINT + ${#p}))
+}
+bind -x '"%s": __wsl_screenshot_insert_last'
+`
+
+const zshWidget = `# wsl-screenshot-cli: insert the latest screenshot path at the cursor
+__wsl_screenshot_insert_last() {
+    local p
+    p="$(wsl-screenshot-cli last 2>/dev/null)" || return
+    LBUFFER+="${(q)p}"
+}
+zle -N __wsl_screenshot_insert_last
+bindkey '%s' __wsl_screenshot_insert_last
+`
+
+var widgetCmd = &cobra.Command{
+	Use:   "shell-widget [bash|zsh]",
+	Short: "Print a key binding that inserts the latest screenshot path at the cursor",
+	Long: `Print a shell snippet that binds a key (Alt-S by default) to insert the path of
+the most recent screenshot into the current command line. Load it from your
+shell rc file:
+
+  eval "$(wsl-screenshot-cli shell-widget bash)"   # ~/.bashrc
+  eval "$(wsl-screenshot-cli shell-widget zsh)"    # ~/.zshrc
+
+The shell is detected from $SHELL when not given.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"bash", "zsh"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := filepath.Base(os.Getenv("SHELL"))
+		if len(args) == 1 {
+			shell = args[0]
+		}
+
+		script, err := widgetScript(shell, widgetKey)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), script)
+		return nil
+	},
+}
+
+// widgetScript returns the key binding snippet for shell, bound to key
+// (readline/zle notation, e.g. "\es" for Alt-S).
+func widgetScript(shell, key string) (string, error) {
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(bashWidget, key), nil
+	case "zsh":
+		return fmt.Sprintf(zshWidget, key), nil
+	default:
+		return "", i18n.Errorf("cmd.unsupported_shell", shell, "bash, zsh")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(widgetCmd)
+
+	widgetCmd.Flags().StringVar(&widgetKey, "key", `\es`, "Key sequence to bind, in readline/zle notation")
+}
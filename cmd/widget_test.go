@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWidgetScript(t *testing.T) {
+	tests := []struct {
+		shell   string
+		key     string
+		want    []string
+		wantErr bool
+	}{
+		{"bash", `\es`, []string{`bind -x '"\es": __wsl_screenshot_insert_last'`, "printf -v p '%q'", "wsl-screenshot-cli last"}, false},
+		{"zsh", `^[s`, []string{`bindkey '^[s' __wsl_screenshot_insert_last`, "zle -N", "${(q)p}"}, false},
+		{"fish", `\es`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			got, err := widgetScript(tt.shell, tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %s, got nil", tt.shell)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("widgetScript(%q) error: %v", tt.shell, err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("widgetScript(%q) missing %q:\n%s", tt.shell, w, got)
+				}
+			}
+		})
+	}
+}
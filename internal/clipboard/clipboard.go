@@ -2,14 +2,22 @@ package clipboard
 
 import (
 	"bufio"
+	"bytes"
 	_ "embed"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/dib"
 )
 
 // PowerShell script embedded at compile time. Runs in a loop reading commands
@@ -19,30 +27,210 @@ import (
 //go:embed clipboard.ps1
 var psScript string
 
+// backendMarker is the first line of psScript. It ends up in the backend's
+// command line, which lets the daemon tell its own powershell.exe processes
+// apart from the user's.
+const backendMarker = "# wsl-screenshot-cli clipboard backend"
+
+// IsBackendCommand reports whether a process command line is a clipboard
+// backend started by this tool.
+func IsBackendCommand(cmdline string) bool {
+	return strings.Contains(cmdline, backendMarker)
+}
+
+// ErrTimeout is returned when the backend does not answer a command within
+// Options.Timeout. The backend has been killed; see Client.Broken.
+var ErrTimeout = errors.New("powershell did not respond in time")
+
+// ErrBroken is returned by every command of a client whose backend was killed
+// after a timeout.
+var ErrBroken = errors.New("clipboard client is broken (backend killed after a timeout)")
+
+// ErrProtocolMismatch is returned by NewClient when the backend script speaks
+// a different protocol version than this client, e.g. a stale script left
+// behind by an older binary.
+var ErrProtocolMismatch = errors.New("clipboard backend protocol mismatch")
+
+// protocolVersion is the protocol version this client speaks. Bump it
+// together with $protocolVersion in clipboard.ps1 and ProtocolVersion in
+// native/helper.cs whenever a command or response changes in a way the other
+// side must know about.
+const protocolVersion = 14
+
+// maxLine bounds a protocol line. Payloads arrive in base64 frames far below
+// it, so only a confused backend gets near.
+const maxLine = 1 << 20
+
+// maxLegacyLine bounds the single base64 line a protocol 1 payload is sent
+// as, which replayed traces may still contain.
+const maxLegacyLine = 32 << 20
+
+// maxPayload bounds the size a payload header may announce, so a corrupt
+// header can't make the client allocate without limit.
+const maxPayload = 512 << 20
+
+// versionTimeout bounds the VERSION exchange when Options.Timeout is zero: a
+// script that predates versioning never answers it.
+var versionTimeout = 5 * time.Second
+
+// closeTimeout is how long Close waits for the backend to exit after EXIT
+// before killing it.
+var closeTimeout = 3 * time.Second
+
+// Options configures a Client.
+type Options struct {
+	// Verbose logs every protocol line sent to and received from PowerShell,
+	// at debug level.
+	Verbose bool
+
+	// Trace, when non-nil, receives every protocol line as a JSON TraceEntry
+	// so the session can be replayed offline with NewReplayClient.
+	Trace io.Writer
+
+	// Recent, when non-nil, keeps the last protocol lines for crash reports.
+	Recent *Recent
+
+	// Timeout bounds each command, from sending it to reading the last line
+	// of the response. A backend that overruns it (e.g. stuck in a clipboard
+	// call another application blocks) is killed and the client is marked
+	// broken, for the caller to replace. Zero waits forever.
+	Timeout time.Duration
+
+	// Binary is the PowerShell executable to run, a name looked up in PATH
+	// or a path. Empty tries Binaries in order.
+	Binary string
+
+	// Backend is BackendPowerShell (the default when empty) or
+	// BackendNative, which runs the compiled helper instead and ignores
+	// Binary.
+	Backend string
+
+	// Formats lists the clipboard formats updates set, from Formats. Empty
+	// means all of them. Without image, the source application's own image
+	// is left on the clipboard as it was rather than re-rendered.
+	Formats []string
+}
+
+// Formats are the clipboard formats an update can set, in protocol order.
+var Formats = []string{"text", "image", "filedrop"}
+
+// CheckFormats reports whether formats, an Options.Formats, is usable: known
+// names and at least one of them.
+func CheckFormats(formats []string) error {
+	if len(formats) == 0 {
+		return errors.New("no clipboard format selected")
+	}
+	for _, f := range formats {
+		if !slices.Contains(Formats, f) {
+			return fmt.Errorf("unknown clipboard format %q (want %s)", f, strings.Join(Formats, ", "))
+		}
+	}
+	return nil
+}
+
+// Binaries is the fallback chain NewClient tries when Options.Binary is
+// empty. PowerShell 7 (pwsh.exe) starts noticeably faster than Windows
+// PowerShell, so it comes first when installed.
+var Binaries = []string{"pwsh.exe", "powershell.exe"}
+
+// lookPath finds a binary in PATH. Declared as a var so tests can pick which
+// binaries are installed.
+var lookPath = exec.LookPath
+
+// candidates lists the binaries NewClient tries for binary, an
+// Options.Binary. An explicit binary must exist. With none, the installed
+// ones from Binaries are tried, or all of them if none is found in PATH, so
+// the error reports what was attempted.
+func candidates(binary string) ([]string, error) {
+	if binary != "" {
+		if _, err := lookPath(binary); err != nil {
+			return nil, err
+		}
+		return []string{binary}, nil
+	}
+	var found []string
+	for _, b := range Binaries {
+		if _, err := lookPath(b); err == nil {
+			found = append(found, b)
+		}
+	}
+	if len(found) == 0 {
+		return Binaries, nil
+	}
+	return found, nil
+}
+
+// CheckBinary reports whether binary, an Options.Binary, can be used.
+func CheckBinary(binary string) error {
+	_, err := candidates(binary)
+	return err
+}
+
 // Client manages a persistent PowerShell process for clipboard operations.
 // All methods are goroutine-safe via a mutex that serializes pipe communication.
 type Client struct {
-	cmd     *exec.Cmd
-	stdin   io.WriteCloser
-	stdout  *bufio.Scanner
-	mu      sync.Mutex
-	logger  *log.Logger
-	verbose bool
-}
-
-// newPSCommand creates the exec.Cmd for the PowerShell subprocess.
-// Declared as a var so tests can override it with a fake process.
-var newPSCommand = func() *exec.Cmd {
-	return exec.Command("powershell.exe", // #nosec G204 -- psScript is a compile-time embed constant
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	wait   func() error
+	kill   func() error // nil when the backend can't be killed (replay)
+	mu     sync.Mutex
+	logger *slog.Logger
+	opts   Options
+	trace  *traceWriter
+	stderr *stderrTail // nil for backends without a stderr (replay)
+
+	timer    *time.Timer   // the running command's timeout, guarded by mu
+	limit    time.Duration // the running command's timeout, for its error
+	timedOut atomic.Bool   // the timer fired and killed the backend
+	broken   atomic.Bool
+
+	binary string // the PowerShell executable running the backend
+	source string // clipboard format the last CHECK's image came from, guarded by mu
+	fgProc string // foreground process when the last CHECK read its image, guarded by mu
+	fgText string // and that window's title, guarded by mu
+}
+
+// newPSCommand creates the exec.Cmd for the PowerShell subprocess run by
+// binary. Declared as a var so tests can override it with a fake process.
+var newPSCommand = func(binary string) *exec.Cmd {
+	return exec.Command(binary, // #nosec G204 -- binary is the user's --ps-binary or from Binaries; psScript is a compile-time embed constant
 		"-STA", "-NoLogo", "-NoProfile", "-NonInteractive",
 		"-Command", psScript,
 	)
 }
 
-// NewClient spawns a persistent powershell.exe -STA process and waits for
-// the READY signal. The process loads .NET assemblies once at startup.
-func NewClient(logger *log.Logger, verbose bool) (*Client, error) {
-	cmd := newPSCommand()
+// NewClient spawns a persistent PowerShell -STA process and waits for the
+// READY signal. The process loads .NET assemblies once at startup. A binary
+// from the fallback chain that fails to start or to get ready is skipped for
+// the next one; a protocol mismatch is not, as every binary runs the same
+// script.
+func NewClient(logger *slog.Logger, opts Options) (*Client, error) {
+	if opts.Backend == BackendNative {
+		path, err := installNativeHelper()
+		if err != nil {
+			return nil, fmt.Errorf("install native helper: %w", err)
+		}
+		return startClient(newNativeCommand(path), nativeHelperName, logger, opts)
+	}
+	binaries, err := candidates(opts.Binary)
+	if err != nil {
+		return nil, fmt.Errorf("find powershell: %w", err)
+	}
+	for i, binary := range binaries {
+		var c *Client
+		if c, err = startClient(newPSCommand(binary), binary, logger, opts); err == nil || errors.Is(err, ErrProtocolMismatch) {
+			return c, err
+		}
+		if i+1 < len(binaries) {
+			logger.Warn("PowerShell failed to start, trying the next one", "binary", binary, "next", binaries[i+1], "err", err)
+		}
+	}
+	return nil, err
+}
+
+// startClient runs the backend cmd, whose executable is binary, and waits
+// for it to get ready.
+func startClient(cmd *exec.Cmd, binary string, logger *slog.Logger, opts Options) (*Client, error) {
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -55,143 +243,872 @@ func NewClient(logger *log.Logger, verbose bool) (*Client, error) {
 		return nil, fmt.Errorf("stdout pipe: %w", err)
 	}
 
-	if err := cmd.Start(); err != nil {
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
 		_ = stdin.Close()
-		return nil, fmt.Errorf("start powershell: %w", err)
+		return nil, fmt.Errorf("stderr pipe: %w", err)
 	}
 
-	scanner := bufio.NewScanner(stdout)
-	// 32 MB buffer for large base64-encoded 4K screenshots
-	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	if err := cmd.Start(); err != nil {
+		_ = stdin.Close()
+		return nil, fmt.Errorf("start %s: %w", binary, err)
+	}
 
-	// Wait for READY signal
-	if !scanner.Scan() {
+	tail := watchStderr(stderr, logger)
+	c := newClient(stdin, stdout, func() error {
+		tail.wait()
+		return cmd.Wait()
+	}, logger, opts)
+	c.kill = cmd.Process.Kill
+	c.stderr = tail
+	c.binary = binary
+	if err := c.handshake(); err != nil {
 		_ = cmd.Process.Kill()
-		_ = cmd.Wait()
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("waiting for READY: %w", err)
+		_ = c.wait() // reap it, or every failed start leaves a zombie
+		return nil, c.stderr.annotate(err)
+	}
+
+	logger.Info("Clipboard client started", "binary", binary)
+	return c, nil
+}
+
+// Binary returns the executable running the backend (a PowerShell or the
+// native helper), or "" for replayed sessions.
+func (c *Client) Binary() string {
+	return c.binary
+}
+
+// Source returns the clipboard format the image of the last CHECK was read
+// from, as named by the backend: PNG, DIBV5, DIB, Bitmap or FileDrop. It is
+// "" when that CHECK found no image or the backend did not say.
+func (c *Client) Source() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.source
+}
+
+// Foreground returns the process and title of the window that was in the
+// foreground when the last CHECK read its image: the application the user
+// took the screenshot in, as far as anyone can tell. Both are "" when that
+// CHECK found no image or the backend could not say.
+func (c *Client) Foreground() (process, title string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fgProc, c.fgText
+}
+
+// newClient wires a Client to an already running backend's pipes.
+func newClient(stdin io.WriteCloser, stdout io.Reader, wait func() error, logger *slog.Logger, opts Options) *Client {
+	return &Client{
+		stdin:  stdin,
+		stdout: bufio.NewReaderSize(stdout, 64*1024),
+		wait:   wait,
+		logger: logger,
+		opts:   opts,
+		trace:  newTraceWriter(opts.Trace),
+	}
+}
+
+// handshake waits for the READY signal the backend prints once initialized,
+// then checks it speaks our protocol version.
+func (c *Client) handshake() error {
+	line, err := c.readLine(maxLine)
+	if err == io.EOF {
+		return fmt.Errorf("powershell exited before READY")
+	}
+	if err != nil {
+		return fmt.Errorf("waiting for READY: %w", err)
+	}
+	c.trace.record(dirRecv, line)
+	c.opts.Recent.record(dirRecv, line)
+	if line != "READY" {
+		return fmt.Errorf("expected READY, got %q", line)
+	}
+	return c.negotiate()
+}
+
+// negotiate sends VERSION and fails with ErrProtocolMismatch unless the
+// backend answers VERSION|<protocolVersion>.
+func (c *Client) negotiate() (err error) {
+	limit := c.opts.Timeout
+	if limit <= 0 {
+		limit = versionTimeout
+	}
+	if err := c.beginWithin(limit); err != nil {
+		return err
+	}
+	defer func() {
+		// Runs after end: a script without VERSION ignores it and times out.
+		if errors.Is(err, ErrTimeout) {
+			err = fmt.Errorf("%w: no answer to VERSION, the script predates protocol version %d (%v)", ErrProtocolMismatch, protocolVersion, err)
 		}
-		return nil, fmt.Errorf("powershell exited before READY")
+	}()
+	defer c.end("VERSION", &err)
+
+	if err := c.send("VERSION"); err != nil {
+		return fmt.Errorf("send VERSION: %w", err)
 	}
-	if line := strings.TrimSpace(scanner.Text()); line != "READY" {
-		_ = cmd.Process.Kill()
-		_ = cmd.Wait()
-		return nil, fmt.Errorf("expected READY, got %q", line)
+	line, err := c.recv("VERSION response")
+	if err != nil {
+		return err
 	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	v, ok := strings.CutPrefix(line, "VERSION|")
+	if !ok {
+		return fmt.Errorf("%w: expected VERSION|%d, got %q", ErrProtocolMismatch, protocolVersion, line)
+	}
+	if v != strconv.Itoa(protocolVersion) {
+		return fmt.Errorf("%w: backend speaks version %s, this client needs %d", ErrProtocolMismatch, v, protocolVersion)
+	}
+	return nil
+}
 
-	logger.Println("PowerShell clipboard client started")
+// send writes one protocol line to PowerShell.
+func (c *Client) send(line string) error {
+	return c.sendAs(line, line)
+}
 
-	return &Client{
-		cmd:     cmd,
-		stdin:   stdin,
-		stdout:  scanner,
-		logger:  logger,
-		verbose: verbose,
-	}, nil
+// sendAs writes line but shows display in verbose logs, for lines carrying
+// large payloads. Traces always record the full line.
+func (c *Client) sendAs(line, display string) error {
+	if c.opts.Verbose {
+		c.logger.Debug("ps:send", "line", display)
+	}
+	c.trace.record(dirSend, line)
+	c.opts.Recent.record(dirSend, line)
+	_, err := fmt.Fprintln(c.stdin, line)
+	return err
+}
+
+// recv reads one protocol line from PowerShell. what names the expected
+// response for error messages.
+func (c *Client) recv(what string) (string, error) {
+	return c.recvLimit(what, maxLine)
+}
+
+// recvLimit is recv for a line of up to limit bytes.
+func (c *Client) recvLimit(what string, limit int) (string, error) {
+	line, err := c.readLine(limit)
+	if err == io.EOF {
+		return "", c.stderr.annotate(fmt.Errorf("read %s: powershell process exited", what))
+	}
+	if err != nil {
+		return "", c.stderr.annotate(fmt.Errorf("read %s: %w", what, err))
+	}
+	c.trace.record(dirRecv, line)
+	c.opts.Recent.record(dirRecv, line)
+	return line, nil
+}
+
+// readLine reads one line from stdout and trims it. A line longer than limit
+// is an error rather than a reason to keep buffering.
+func (c *Client) readLine(limit int) (string, error) {
+	var line []byte
+	for {
+		frag, err := c.stdout.ReadSlice('\n')
+		line = append(line, frag...)
+		if len(line) > limit {
+			return "", fmt.Errorf("line longer than %d bytes", limit)
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF) && len(line) > 0:
+			// the backend exited after an unterminated last line
+		default:
+			return "", err
+		}
+		return strings.TrimSpace(string(line)), nil
+	}
+}
+
+// begin starts a command: it fails fast on a broken client and arms the
+// timeout. The caller must hold c.mu and defer c.end.
+func (c *Client) begin() error {
+	return c.beginWithin(c.opts.Timeout)
+}
+
+// beginWithin is begin with an explicit timeout; zero waits forever.
+func (c *Client) beginWithin(limit time.Duration) error {
+	if c.broken.Load() {
+		return ErrBroken
+	}
+	if limit > 0 && c.kill != nil {
+		c.limit = limit
+		c.timer = time.AfterFunc(limit, func() {
+			c.timedOut.Store(true)
+			c.broken.Store(true)
+			_ = c.kill() // unblocks the pending read or write
+		})
+	}
+	return nil
+}
+
+// end disarms the timeout of the command verb. If it fired, *err becomes an
+// ErrTimeout: whatever the command saw after the kill is beside the point.
+func (c *Client) end(verb string, err *error) {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.timedOut.Swap(false) {
+		*err = c.stderr.annotate(fmt.Errorf("%s: %w after %s", verb, ErrTimeout, c.limit))
+	}
+}
+
+// Broken reports whether the client's backend was killed after a timeout.
+// A broken client fails every command and should be closed and replaced.
+func (c *Client) Broken() bool {
+	return c.broken.Load()
+}
+
+// SetVerbose switches logging of all PowerShell I/O on or off.
+func (c *Client) SetVerbose(verbose bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts.Verbose = verbose
 }
 
 // Check queries the clipboard for an image. Returns the PNG bytes if an image
 // is present, or nil if the clipboard is empty / contains non-image data.
+// Raw DIBs sent by the script are converted to PNG before returning.
 func (c *Client) Check() ([]byte, error) {
+	var buf bytes.Buffer
+	ok, err := c.CheckTo(&buf)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CheckTo is Check writing the PNG to w as it arrives, so the caller can
+// stream it to disk instead of holding it in memory. ok reports whether the
+// clipboard held an image; on error, w may have received part of it. Raw DIBs
+// are still converted in memory, but they only come from legacy applications
+// and are small.
+func (c *Client) CheckTo(w io.Writer) (ok bool, err error) {
+	_, ok, err = c.CheckSince(0, w)
+	return ok, err
+}
+
+// CheckSince is CheckTo that also returns the clipboard sequence number
+// (GetClipboardSequenceNumber) read at the same time. When it still equals
+// since, the backend skips reading the clipboard altogether and ok is false:
+// nothing changed since the caller last looked. since 0 always reads it. seq
+// is 0 when the backend cannot read the sequence number.
+func (c *Client) CheckSince(since uint32, w io.Writer) (seq uint32, ok bool, err error) {
+	seq, _, ok, err = c.CheckKnown(since, nil, w)
+	return seq, ok, err
+}
+
+// CheckKnown is CheckSince for callers that keep track of the images they
+// already have. The backend announces a PNG by its SHA256 (hex) before
+// sending it; when known(sha) returns true, the image is not transferred and
+// nothing is written to w, but ok is still true. sha is "" for images the
+// backend sends without a hash (raw DIBs, older traces). A nil known fetches
+// every image.
+func (c *Client) CheckKnown(since uint32, known func(sha string) bool, w io.Writer) (seq uint32, sha string, ok bool, err error) {
+	cmd := "CHECK"
+	if since != 0 {
+		cmd += "|" + strconv.FormatUint(uint64(since), 10)
+	}
+	return c.check(cmd, known, w)
+}
+
+// ReadImage writes whatever image the clipboard holds to w, as PNG, for
+// one-shot reads. Unlike CheckTo, it also returns images the poller skips:
+// our own clipboard updates and spreadsheet cells copied as pictures.
+func (c *Client) ReadImage(w io.Writer) (ok bool, err error) {
+	_, _, ok, err = c.check("CHECK|0|all", nil, w)
+	return ok, err
+}
+
+// check sends a CHECK command and reads its response.
+func (c *Client) check(cmd string, known func(sha string) bool, w io.Writer) (seq uint32, sha string, ok bool, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-
-	if c.verbose {
-		c.logger.Println("[ps:send] CHECK")
+	if err := c.begin(); err != nil {
+		return 0, "", false, err
 	}
-	if _, err := fmt.Fprintln(c.stdin, "CHECK"); err != nil {
-		return nil, fmt.Errorf("send CHECK: %w", err)
+	defer c.end("CHECK", &err)
+
+	c.source, c.fgProc, c.fgText = "", "", ""
+	if err := c.send(cmd); err != nil {
+		return 0, "", false, fmt.Errorf("send CHECK: %w", err)
 	}
 
-	if !c.stdout.Scan() {
-		if err := c.stdout.Err(); err != nil {
-			return nil, fmt.Errorf("read response: %w", err)
+	line, err := c.recvCheck()
+	if err != nil {
+		return 0, "", false, err
+	}
+	// Traces recorded before protocol 3 answer without the SEQ line.
+	if n, found := strings.CutPrefix(line, "SEQ|"); found {
+		v, err := strconv.ParseUint(n, 10, 32)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("parse CHECK sequence %q: %w", line, err)
+		}
+		seq = uint32(v)
+		if line, err = c.recvCheck(); err != nil {
+			return seq, "", false, err
+		}
+	}
+	// Since protocol 13 an image may be preceded by WINDOW|<process>|<title>.
+	if rest, found := strings.CutPrefix(line, "WINDOW|"); found {
+		c.fgProc, c.fgText, _ = strings.Cut(rest, "|")
+		if line, err = c.recvCheck(); err != nil {
+			return seq, "", false, err
 		}
-		return nil, fmt.Errorf("powershell process exited")
 	}
+	sha, ok, err = c.readCheck(w, line, known)
+	return seq, sha, ok, err
+}
 
-	line := strings.TrimSpace(c.stdout.Text())
-	if c.verbose {
-		c.logger.Printf("[ps:recv] %s", line)
+// recvCheck reads one line of a CHECK response. The caller must hold c.mu.
+func (c *Client) recvCheck() (string, error) {
+	line, err := c.recv("response")
+	if err == nil && c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
 	}
+	return line, err
+}
 
-	switch line {
-	case "NONE":
-		return nil, nil
+// readCheck handles the CHECK response line and its payload, fetching an
+// image announced by HASH unless known says the caller has it. The caller
+// must hold c.mu.
+func (c *Client) readCheck(w io.Writer, line string, known func(sha string) bool) (sha string, ok bool, err error) {
+	kind, rest, _ := strings.Cut(line, "|")
+	switch kind {
+	case "NONE", "SAME":
+		return "", false, nil
+	case "LOCKED":
+		return "", false, ErrSessionLocked
+	case "BUSY":
+		return "", false, errBusy
+	case "HASH":
+		// HASH|<sha>|<bytes>|<source>; traces before protocol 8 lack source.
+		fields := strings.Split(rest, "|")
+		sha = fields[0]
+		if len(fields) > 2 {
+			c.source = fields[2]
+		}
+		if known != nil && known(sha) {
+			return sha, true, nil // the backend drops the image on our next command
+		}
+		if err := c.send("FETCH"); err != nil {
+			return sha, true, fmt.Errorf("send FETCH: %w", err)
+		}
+		if line, err = c.recvCheck(); err != nil {
+			return sha, true, err
+		}
+		if !strings.HasPrefix(line, "IMAGE|") {
+			return sha, true, c.stderr.annotate(fmt.Errorf("unexpected FETCH response: %q", line))
+		}
+		return sha, true, c.copyPayload(w, line)
 	case "IMAGE":
-		// Read base64 data line
-		if !c.stdout.Scan() {
-			return nil, fmt.Errorf("read base64: powershell process exited")
+		return "", true, c.copyPayload(w, line)
+	case "DIB":
+		// Palettized and 16-bit bitmaps arrive raw so their colors are
+		// decoded here rather than through GDI+.
+		size, source, found := strings.Cut(rest, "|")
+		if found {
+			c.source = source
+			line = kind + "|" + size
+		}
+		var raw bytes.Buffer
+		if err := c.copyPayload(&raw, line); err != nil {
+			return "", true, err
+		}
+		data, err := dib.ToPNG(raw.Bytes())
+		if err != nil {
+			return "", true, fmt.Errorf("decode DIB: %w", err)
 		}
-		b64 := strings.TrimSpace(c.stdout.Text())
-		if c.verbose {
-			c.logger.Printf("[ps:recv] IMAGE data (%d chars base64)", len(b64))
+		_, err = w.Write(data)
+		return "", true, err
+	default:
+		return "", false, c.stderr.annotate(fmt.Errorf("unexpected response: %q", line))
+	}
+}
+
+// copyPayload copies the payload announced by header, IMAGE|<bytes>,
+// DIB|<bytes> or HTML|<bytes>, to w: base64 frames of at most a few tens of
+// KB each, up to an END line. The frames are decoded as a stream while w
+// consumes them, so neither the base64 text nor (unless w keeps it) the
+// payload is ever held whole. The caller must hold c.mu.
+func (c *Client) copyPayload(w io.Writer, header string) error {
+	kind, size, framed := strings.Cut(header, "|")
+	if !framed {
+		data, err := c.readLegacyPayload(kind)
+		if err != nil {
+			return err
 		}
+		_, err = w.Write(data)
+		return err
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 || n > maxPayload {
+		return c.stderr.annotate(fmt.Errorf("bad %s payload size %q", kind, size))
+	}
+	if g, ok := w.(interface{ Grow(int) }); ok {
+		g.Grow(int(n))
+	}
 
-		// Read END marker
-		if !c.stdout.Scan() {
-			return nil, fmt.Errorf("read END marker: powershell process exited")
+	frames := &frameReader{c: c, kind: kind}
+	limited := &limitedWriter{w: w, left: n}
+	copied, err := io.Copy(limited, base64.NewDecoder(base64.StdEncoding, frames))
+	switch {
+	case frames.err != nil:
+		return frames.err
+	case errors.Is(err, errPayloadTooLong):
+		_, _ = io.Copy(io.Discard, frames) // skip to END, to stay in step
+		return c.stderr.annotate(fmt.Errorf("%s payload longer than the announced %d bytes", kind, n))
+	case err != nil:
+		_, _ = io.Copy(io.Discard, frames)
+		return fmt.Errorf("decode %s payload: %w", kind, err)
+	case copied != n:
+		return c.stderr.annotate(fmt.Errorf("%s payload ended after %d of %d bytes", kind, copied, n))
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", kind+" data", "bytes", n, "frames", frames.count)
+	}
+	return nil
+}
+
+// frameReader reads the base64 text of a framed payload, one frame line at a
+// time, and reports io.EOF at END. Every frame but the last holds a multiple
+// of 3 bytes, so the frames concatenate into one valid base64 stream.
+type frameReader struct {
+	c     *Client
+	kind  string
+	frame []byte
+	count int
+	done  bool
+	err   error // a failed read, as opposed to bad base64
+}
+
+func (f *frameReader) Read(p []byte) (int, error) {
+	for len(f.frame) == 0 {
+		if f.done || f.err != nil {
+			return 0, io.EOF
 		}
-		if end := strings.TrimSpace(c.stdout.Text()); end != "END" {
-			return nil, fmt.Errorf("expected END, got %q", end)
+		line, err := f.c.recv(f.kind + " frame")
+		switch {
+		case err != nil:
+			f.err = err
+		case line == "END":
+			f.done = true
+		default:
+			f.frame = []byte(line)
+			f.count++
 		}
-		if c.verbose {
-			c.logger.Println("[ps:recv] END")
+	}
+	n := copy(p, f.frame)
+	f.frame = f.frame[n:]
+	return n, nil
+}
+
+// errPayloadTooLong is returned by limitedWriter past its limit.
+var errPayloadTooLong = errors.New("payload too long")
+
+// limitedWriter passes at most left bytes on to w.
+type limitedWriter struct {
+	w    io.Writer
+	left int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.left {
+		return 0, errPayloadTooLong
+	}
+	n, err := l.w.Write(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// readLegacyPayload reads a protocol 1 payload: one base64 line and END.
+// Only replayed traces recorded by older clients still send it.
+func (c *Client) readLegacyPayload(kind string) ([]byte, error) {
+	b64, err := c.recvLimit("base64", maxLegacyLine)
+	if err != nil {
+		return nil, err
+	}
+	end, err := c.recv("END marker")
+	if err != nil {
+		return nil, err
+	}
+	if end != "END" {
+		return nil, c.stderr.annotate(fmt.Errorf("expected END, got %q", end))
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", kind+" data", "base64_chars", len(b64))
+	}
+
+	data, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+	return data, nil
+}
+
+// Ping sends PING and waits for PONG, returning the round trip time. The
+// backend answers without touching the clipboard, so a failed or slow ping
+// points at a wedged powershell.exe rather than at another application.
+func (c *Client) Ping() (rtt time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(); err != nil {
+		return 0, err
+	}
+	defer c.end("PING", &err)
+
+	start := time.Now()
+	if err := c.send("PING"); err != nil {
+		return 0, fmt.Errorf("send PING: %w", err)
+	}
+	line, err := c.recv("PONG")
+	if err != nil {
+		return 0, err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if line != "PONG" {
+		return 0, c.stderr.annotate(fmt.Errorf("unexpected PING response: %q", line))
+	}
+	return time.Since(start), nil
+}
+
+// Sequence returns the Windows clipboard sequence number, which changes on
+// every clipboard write. It is much cheaper than Check and lets callers skip
+// full checks while nothing has changed.
+func (c *Client) Sequence() (seq uint32, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(); err != nil {
+		return 0, err
+	}
+	defer c.end("SEQ", &err)
+
+	if err := c.send("SEQ"); err != nil {
+		return 0, fmt.Errorf("send SEQ: %w", err)
+	}
+
+	line, err := c.recv("SEQ response")
+	if err != nil {
+		return 0, err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if berr := parseBackendError(line); berr != nil {
+		return 0, berr
+	}
+	if !strings.HasPrefix(line, "SEQ|") {
+		return 0, c.stderr.annotate(fmt.Errorf("unexpected SEQ response: %q", line))
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(line, "SEQ|"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parse SEQ response %q: %w", line, err)
+	}
+	return uint32(n), nil
+}
+
+// Wait blocks until the clipboard sequence number differs from since, or
+// timeout passes, and returns the current number; changed is false on a
+// timeout. The backend sleeps on a clipboard format listener meanwhile, so
+// waiting costs next to no CPU. Other commands queue behind a Wait, Close
+// included. The client's own timeout counts on top of timeout.
+func (c *Client) Wait(since uint32, timeout time.Duration) (seq uint32, changed bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var limit time.Duration
+	if c.opts.Timeout > 0 {
+		limit = timeout + c.opts.Timeout
+	}
+	if err := c.beginWithin(limit); err != nil {
+		return 0, false, err
+	}
+	defer c.end("WAIT", &err)
+
+	if err := c.send(fmt.Sprintf("WAIT|%d|%d", since, timeout.Milliseconds())); err != nil {
+		return 0, false, fmt.Errorf("send WAIT: %w", err)
+	}
+	line, err := c.recv("WAIT response")
+	if err != nil {
+		return 0, false, err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if berr := parseBackendError(line); berr != nil {
+		return 0, false, berr
+	}
+	kind, n, _ := strings.Cut(line, "|")
+	if kind != "CHANGED" && kind != "TIMEOUT" {
+		return 0, false, c.stderr.annotate(fmt.Errorf("unexpected WAIT response: %q", line))
+	}
+	v, err := strconv.ParseUint(n, 10, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("parse WAIT response %q: %w", line, err)
+	}
+	return uint32(v), kind == "CHANGED", nil
+}
+
+// SessionLocked reports whether the Windows session is locked (or has no
+// interactive desktop), asking the backend without touching the clipboard.
+func (c *Client) SessionLocked() (locked bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(); err != nil {
+		return false, err
+	}
+	defer c.end("SESSION", &err)
+
+	if err := c.send("SESSION"); err != nil {
+		return false, fmt.Errorf("send SESSION: %w", err)
+	}
+	line, err := c.recv("SESSION response")
+	if err != nil {
+		return false, err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if berr := parseBackendError(line); berr != nil {
+		return false, berr
+	}
+	switch line {
+	case "SESSION|locked":
+		return true, nil
+	case "SESSION|active":
+		return false, nil
+	}
+	return false, c.stderr.annotate(fmt.Errorf("unexpected SESSION response: %q", line))
+}
+
+// Owner returns the PID and process name of the application that currently
+// owns the clipboard. pid is 0 when the last writer registered no owner window.
+func (c *Client) Owner() (pid int, name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(); err != nil {
+		return 0, "", err
+	}
+	defer c.end("OWNER", &err)
+
+	if err := c.send("OWNER"); err != nil {
+		return 0, "", fmt.Errorf("send OWNER: %w", err)
+	}
+
+	line, err := c.recv("OWNER response")
+	if err != nil {
+		return 0, "", err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if berr := parseBackendError(line); berr != nil {
+		return 0, "", berr
+	}
+	parts := strings.SplitN(line, "|", 3)
+	if len(parts) != 3 || parts[0] != "OWNER" {
+		return 0, "", c.stderr.annotate(fmt.Errorf("unexpected OWNER response: %q", line))
+	}
+	pid, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, "", fmt.Errorf("parse OWNER response %q: %w", line, err)
+	}
+	return pid, parts[2], nil
+}
+
+// HTML returns the clipboard's CF_HTML text, header included, or nil when it
+// holds none. Like CHECK, it fails with a ClipboardBusy error while another
+// process holds the clipboard open.
+func (c *Client) HTML() (data []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(); err != nil {
+		return nil, err
+	}
+	defer c.end("HTML", &err)
+
+	if err := c.send("HTML"); err != nil {
+		return nil, fmt.Errorf("send HTML: %w", err)
+	}
+	line, err := c.recv("HTML response")
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	switch {
+	case line == "NONE":
+		return nil, nil
+	case line == "BUSY":
+		return nil, errBusy
+	case strings.HasPrefix(line, "HTML|"):
+		var buf bytes.Buffer
+		if err := c.copyPayload(&buf, line); err != nil {
+			return nil, err
 		}
+		return buf.Bytes(), nil
+	}
+	if berr := parseBackendError(line); berr != nil {
+		return nil, berr
+	}
+	return nil, c.stderr.annotate(fmt.Errorf("unexpected HTML response: %q", line))
+}
+
+// BackendStats describes the PowerShell process's resource usage.
+type BackendStats struct {
+	WorkingSet   int64 // bytes
+	PrivateBytes int64
+	Handles      int64
+}
+
+// Stats asks PowerShell to report its own resource usage. The response is
+// STATS|key=value|..., unknown keys are ignored.
+func (c *Client) Stats() (st BackendStats, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(); err != nil {
+		return st, err
+	}
+	defer c.end("STATS", &err)
+
+	if err := c.send("STATS"); err != nil {
+		return st, fmt.Errorf("send STATS: %w", err)
+	}
 
-		data, err := base64.StdEncoding.DecodeString(b64)
+	line, err := c.recv("STATS response")
+	if err != nil {
+		return st, err
+	}
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if berr := parseBackendError(line); berr != nil {
+		return st, berr
+	}
+	fields := strings.Split(line, "|")
+	if fields[0] != "STATS" {
+		return st, c.stderr.annotate(fmt.Errorf("unexpected STATS response: %q", line))
+	}
+	for _, f := range fields[1:] {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
-			return nil, fmt.Errorf("decode base64: %w", err)
+			return st, fmt.Errorf("parse STATS field %q: %w", f, err)
 		}
-		return data, nil
-	default:
-		return nil, fmt.Errorf("unexpected response: %q", line)
+		switch key {
+		case "ws":
+			st.WorkingSet = n
+		case "private":
+			st.PrivateBytes = n
+		case "handles":
+			st.Handles = n
+		}
+	}
+	return st, nil
+}
+
+// BackendMemory returns the PowerShell process's working set in bytes.
+func (c *Client) BackendMemory() (int64, error) {
+	st, err := c.Stats()
+	return st.WorkingSet, err
+}
+
+// UpdatableFormats lists the clipboard formats updates set, per
+// Options.Formats.
+func (c *Client) UpdatableFormats() []string {
+	if len(c.opts.Formats) == 0 {
+		return Formats
+	}
+	return c.opts.Formats
+}
+
+// updateOptions returns the options field UPDATE ends with, or
+// "" when the defaults apply.
+func (c *Client) updateOptions() string {
+	if len(c.opts.Formats) == 0 {
+		return ""
 	}
+	return "|formats=" + strings.Join(c.opts.Formats, ",")
 }
 
+// fieldEscaper percent-encodes what a text or path field of UPDATE can't
+// hold as is: the field separator, line breaks, and % itself so the backend
+// can decode every %XX it sees.
+var fieldEscaper = strings.NewReplacer("%", "%25", "|", "%7C", "\r", "%0D", "\n", "%0A")
+
 // UpdateClipboard tells PowerShell to load the image from winPath and set
-// all three clipboard formats (image, text with wslPath, file drop with winPath).
-func (c *Client) UpdateClipboard(wslPath, winPath string) error {
+// the clipboard formats (image, text, file drop with winPath), all three
+// unless Options.Formats says otherwise. text is usually the screenshot's
+// WSL path, and may span lines. Only the paths cross the pipe: images are
+// streamed on reads, and updates never send one.
+func (c *Client) UpdateClipboard(text, winPath string) (err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-
-	cmd := fmt.Sprintf("UPDATE|%s|%s", wslPath, winPath)
-	if c.verbose {
-		c.logger.Printf("[ps:send] %s", cmd)
+	if err := c.begin(); err != nil {
+		return err
 	}
-	if _, err := fmt.Fprintln(c.stdin, cmd); err != nil {
+	defer c.end("UPDATE", &err)
+
+	if err := c.send(fmt.Sprintf("UPDATE|%s|%s%s", fieldEscaper.Replace(text), fieldEscaper.Replace(winPath), c.updateOptions())); err != nil {
 		return fmt.Errorf("send UPDATE: %w", err)
 	}
+	return c.readUpdateResult()
+}
 
-	if !c.stdout.Scan() {
-		if err := c.stdout.Err(); err != nil {
-			return fmt.Errorf("read UPDATE response: %w", err)
-		}
-		return fmt.Errorf("powershell process exited")
+// readUpdateResult reads the OK|<per-format results> / ERR|code|msg reply to
+// UPDATE. A partial success is returned as *PartialUpdateError.
+func (c *Client) readUpdateResult() error {
+	line, err := c.recv("UPDATE response")
+	if err != nil {
+		return err
 	}
-
-	line := strings.TrimSpace(c.stdout.Text())
-	if c.verbose {
-		c.logger.Printf("[ps:recv] %s", line)
+	if c.opts.Verbose {
+		c.logger.Debug("ps:recv", "line", line)
+	}
+	if ok, err := parseUpdateResult(line); ok {
+		return err
 	}
-	if line == "OK" {
-		return nil
+	if line == "BUSY" {
+		return errBusy
 	}
-	if strings.HasPrefix(line, "ERR|") {
-		return fmt.Errorf("powershell: %s", strings.TrimPrefix(line, "ERR|"))
+	if berr := parseBackendError(line); berr != nil {
+		return berr
 	}
-	return fmt.Errorf("unexpected UPDATE response: %q", line)
+	return c.stderr.annotate(fmt.Errorf("unexpected UPDATE response: %q", line))
 }
 
 // Close sends EXIT to the PowerShell process and waits for it to terminate.
+// A backend that hasn't exited after closeTimeout (hung in a clipboard call)
+// is killed; either way it is waited for, so it never lingers as a zombie.
 func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.verbose {
-		c.logger.Println("[ps:send] EXIT")
-	}
-	fmt.Fprintln(c.stdin, "EXIT")
+	_ = c.send("EXIT") // best-effort, the process may already be gone
 	_ = c.stdin.Close()
-	return c.cmd.Wait()
+	if c.kill == nil {
+		return c.wait()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(closeTimeout):
+		c.logger.Warn("PowerShell did not exit in time, killing it", "timeout", closeTimeout)
+		_ = c.kill()
+		return <-done
+	}
 }
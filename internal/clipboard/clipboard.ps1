@@ -1,3 +1,4 @@
+# wsl-screenshot-cli clipboard backend
 Add-Type -AssemblyName System.Windows.Forms
 Add-Type -AssemblyName System.Drawing
 
@@ -10,6 +11,329 @@ Add-Type -AssemblyName System.Drawing
 # responsive, preventing Explorer/Snipping Tool freezes during OLE/COM
 # clipboard operations.
 
+# A few user32 functions back the cheap SEQ and OWNER verbs and the WINDOW
+# line of CHECK. They are bound with Reflection.Emit, which defines the
+# P/Invokes in memory without csc.exe, so the EDR constraint above still
+# holds. If binding fails, those verbs report ERR, CHECK leaves out WINDOW,
+# and the Go side falls back to full CHECKs.
+$user32 = $null
+try {
+    $asm = [System.Reflection.Emit.AssemblyBuilder]::DefineDynamicAssembly(
+        (New-Object System.Reflection.AssemblyName("ClipUser32")),
+        [System.Reflection.Emit.AssemblyBuilderAccess]::Run)
+    $type = $asm.DefineDynamicModule("ClipUser32").DefineType("ClipUser32", "Public, Class")
+    $imports = @(
+        @("GetClipboardSequenceNumber", [UInt32], [Type[]]@()),
+        @("GetClipboardOwner", [IntPtr], [Type[]]@()),
+        @("GetWindowThreadProcessId", [UInt32], [Type[]]@([IntPtr], [UInt32].MakeByRefType())),
+        @("GetForegroundWindow", [IntPtr], [Type[]]@()),
+        @("GetWindowText", [Int32], [Type[]]@([IntPtr], [System.Text.StringBuilder], [Int32])),
+        @("AddClipboardFormatListener", [Boolean], [Type[]]@([IntPtr])),
+        @("MsgWaitForMultipleObjects", [UInt32], [Type[]]@([UInt32], [IntPtr], [Boolean], [UInt32], [UInt32])),
+        @("OpenInputDesktop", [IntPtr], [Type[]]@([UInt32], [Boolean], [UInt32])),
+        @("CloseDesktop", [Boolean], [Type[]]@([IntPtr]))
+    )
+    foreach ($imp in $imports) {
+        [void]$type.DefinePInvokeMethod($imp[0], "user32.dll",
+            [System.Reflection.MethodAttributes]"Public, Static, PinvokeImpl",
+            [System.Reflection.CallingConventions]::Standard, $imp[1], $imp[2],
+            [System.Runtime.InteropServices.CallingConvention]::Winapi,
+            [System.Runtime.InteropServices.CharSet]::Auto)
+    }
+    $user32 = $type.CreateType()
+} catch {
+    $user32 = $null
+}
+
+# WAIT sleeps until a window message arrives. A clipboard format listener on
+# a hidden window makes every clipboard change post one (WM_CLIPBOARDUPDATE),
+# so an idle WAIT costs no CPU. If registering fails, WAIT checks the
+# sequence number every 10 ms instead.
+$listening = $false
+if ($user32 -ne $null) {
+    try {
+        $listenerWindow = New-Object System.Windows.Forms.Form
+        $listening = $user32::AddClipboardFormatListener($listenerWindow.Handle)
+    } catch {
+        $listening = $false
+    }
+}
+
+# A locked workstation switches input to the secure desktop, which this
+# process cannot open; so does a session without an interactive desktop
+# (a disconnected RDP session). Clipboard reads then fail or return stale
+# data, so CHECK answers LOCKED instead of reading.
+function Test-SessionLocked {
+    if ($user32 -eq $null) { return $false }
+    $desktop = $user32::OpenInputDesktop(0, $false, 0x0100) # DESKTOP_SWITCHDESKTOP
+    if ($desktop -eq [IntPtr]::Zero) { return $true }
+    [void]$user32::CloseDesktop($desktop)
+    return $false
+}
+
+# Errors go back as ERR|<code>|<detail>. The code is derived from the .NET
+# exception type, never from its message, because messages are localized and
+# the Go side must be able to act on them on any Windows display language.
+function Format-Err($err) {
+    $ex = $err.Exception
+    while ($ex -is [System.Management.Automation.MethodInvocationException] -and $ex.InnerException -ne $null) {
+        $ex = $ex.InnerException
+    }
+    $code = switch ($ex) {
+        { $_ -is [System.IO.FileNotFoundException] }                    { "NOT_FOUND"; break }
+        { $_ -is [System.IO.DirectoryNotFoundException] }               { "NOT_FOUND"; break }
+        { $_ -is [System.UnauthorizedAccessException] }                 { "ACCESS_DENIED"; break }
+        { $_ -is [System.OutOfMemoryException] }                        { "INVALID_IMAGE"; break } # GDI+ reports bad image files as OOM
+        { $_ -is [System.FormatException] }                             { "INVALID_IMAGE"; break }
+        { $_ -is [System.Runtime.InteropServices.ExternalException] }   { "CLIPBOARD_BUSY"; break }
+        default                                                         { "UNKNOWN" }
+    }
+    $detail = ($ex.GetType().FullName + ": " + $ex.Message) -replace "[\r\n|]", " "
+    return $code + "|" + $detail
+}
+
+function Write-Err($err) {
+    [Console]::Out.WriteLine("ERR|" + (Format-Err $err))
+}
+
+function Test-ClipboardBusy($err) {
+    return (Format-Err $err).StartsWith("CLIPBOARD_BUSY|")
+}
+
+# Another process holding the clipboard open (OpenClipboard) makes clipboard
+# calls throw, usually for a few milliseconds only. Retry $op up to
+# $busyRetries times, pumping messages and doubling the delay from
+# $busyRetryMs in between; other failures and the last busy one are
+# rethrown. Callers that still see busy answer BUSY, which the Go side treats
+# as "no change" rather than a failed poll.
+$busyRetries = 5
+$busyRetryMs = 10
+
+function Invoke-ClipboardRetry([scriptblock]$op) {
+    $delay = $busyRetryMs
+    for ($i = 1; ; $i++) {
+        try {
+            return & $op
+        } catch {
+            if ($i -ge $busyRetries -or -not (Test-ClipboardBusy $_)) { throw }
+        }
+        $deadline = [DateTime]::UtcNow.AddMilliseconds($delay)
+        while ([DateTime]::UtcNow -lt $deadline) {
+            [System.Windows.Forms.Application]::DoEvents()
+            Start-Sleep -Milliseconds 5
+        }
+        $delay *= 2
+    }
+}
+
+# Every update carries this private format, so CHECK recognises our own
+# writes whichever formats they hold.
+$ownFormat = "WslScreenshotCli.Update"
+
+# The formats an image write keeps from the source application when the
+# client leaves out image: they are copied over as they are, so the bitmap
+# is never re-rendered.
+$keptImageFormats = @("PNG", "image/png", "Format17", "DeviceIndependentBitmap", "Bitmap")
+
+# UPDATE takes an optional last field of options, key=value
+# pairs separated by ";". formats=<list> names the formats to set (text,
+# image, filedrop, comma-separated); without it, all three are set.
+function Get-UpdateFormats($options) {
+    $formats = @("text", "image", "filedrop")
+    if ($options) {
+        foreach ($opt in $options.Split(";")) {
+            $kv = $opt.Split("=", 2)
+            if ($kv[0] -eq "formats" -and $kv.Length -eq 2) { $formats = $kv[1].Split(",") }
+        }
+    }
+    return ,$formats
+}
+
+# Sets the image, text and file-drop formats independently, so one failing
+# format (e.g. a denied file drop) doesn't cost the others. Replies
+# OK|text=1,image=1,filedrop=0, listing only the formats asked for, followed
+# by |<code>|<detail> of the first failure when a format could not be set,
+# ERR when none could, or BUSY when another process kept the clipboard open.
+# $setImage adds the image to the DataObject and returns anything that must
+# be disposed once the clipboard holds its own copy.
+function Update-Clipboard($text, $winPath, [scriptblock]$setImage, $formats) {
+    $data = New-Object System.Windows.Forms.DataObject
+    $set = [ordered]@{}
+    foreach ($f in @("text", "image", "filedrop")) { if ($formats -contains $f) { $set[$f] = 0 } }
+    $firstErr = $null
+    $disposable = $null
+
+    if ($set.Contains("image")) {
+        try { $disposable = & $setImage $data; $set.image = 1 } catch { if ($firstErr -eq $null) { $firstErr = $_ } }
+    } else {
+        try {
+            $current = Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::GetDataObject() }
+            if ($current -ne $null) {
+                $present = $current.GetFormats()
+                foreach ($f in $keptImageFormats) {
+                    if ($present -contains $f) { $data.SetData($f, $current.GetData($f)) }
+                }
+            }
+        } catch {} # the source image is a courtesy; the path still goes out
+    }
+    if ($set.Contains("text")) {
+        try {
+            $data.SetText($text, [System.Windows.Forms.TextDataFormat]::UnicodeText)
+            $set.text = 1
+        } catch { if ($firstErr -eq $null) { $firstErr = $_ } }
+    }
+    if ($set.Contains("filedrop")) {
+        try {
+            $files = New-Object System.Collections.Specialized.StringCollection
+            [void]$files.Add($winPath)
+            $data.SetFileDropList($files)
+            $set.filedrop = 1
+        } catch { if ($firstErr -eq $null) { $firstErr = $_ } }
+    }
+    $data.SetData($ownFormat, "1")
+
+    try {
+        if (@($set.Values | Where-Object { $_ -eq 1 }).Count -eq 0) {
+            if ($firstErr -eq $null) { [Console]::Out.WriteLine("ERR|UNKNOWN|no clipboard format to set") } else { Write-Err $firstErr }
+            return
+        }
+        Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::SetDataObject($data, $true) }
+        $line = "OK|" + (($set.Keys | ForEach-Object { $_ + "=" + $set[$_] }) -join ",")
+        if ($firstErr -ne $null) { $line += "|" + (Format-Err $firstErr) }
+        [Console]::Out.WriteLine($line)
+    } catch {
+        if (Test-ClipboardBusy $_) { [Console]::Out.WriteLine("BUSY") } else { Write-Err $_ }
+    } finally {
+        if ($disposable -ne $null) { $disposable.Dispose() }
+    }
+}
+
+# Answered to VERSION. Must match protocolVersion in clipboard.go: the Go
+# client refuses to talk to a script speaking another version.
+$protocolVersion = 14
+
+# Payloads (IMAGE, DIB, HTML) go out as <kind>|<bytes>, DIB with |<source> (the
+# clipboard format it was read from) appended, then base64 frames of at most
+# $frameBytes raw bytes per line, then END. Short lines keep both sides'
+# buffers small however large the screenshot, and neither side ever holds the
+# whole payload as one base64 string. $frameBytes must stay a multiple of 3,
+# so only the last frame carries padding and the Go side can decode the
+# frames as one stream.
+$frameBytes = 49152
+
+function Write-Payload($kind, [byte[]]$bytes, $source) {
+    $out = [Console]::Out
+    $header = $kind + "|" + $bytes.Length
+    if ($source) { $header += "|" + $source }
+    $out.WriteLine($header)
+    for ($i = 0; $i -lt $bytes.Length; $i += $frameBytes) {
+        $out.WriteLine([Convert]::ToBase64String($bytes, $i, [Math]::Min($frameBytes, $bytes.Length - $i)))
+    }
+    $out.WriteLine("END")
+    $out.Flush()
+}
+
+# A rendered PNG that CHECK announced as HASH|<sha256>|<bytes>, kept until
+# the client asks for it with FETCH. Any other command drops it: the client
+# already had that image.
+$held = $null
+
+# How long CHECK waits before retrying GetImage() on a clipboard owner that
+# uses delayed rendering.
+$delayedRenderWaitMs = 150
+
+# Clipboard formats CHECK reads an image from, most faithful first. Browsers
+# and editors such as Paint.NET put a ready-made PNG ("PNG", or "image/png"
+# from Chromium) next to, or instead of, a bitmap; it is sent as is, keeping
+# its alpha channel, which GetImage() drops. CF_DIBV5 ("Format17") and
+# CF_DIB come next, then the CF_BITMAP behind GetImage(). The format used is
+# reported with HASH and DIB, under the name given here.
+$pngFormats = @("PNG", "image/png")
+$imageSources = @{ "PNG" = "PNG"; "image/png" = "PNG"; "Format17" = "DIBV5"; "DeviceIndependentBitmap" = "DIB" }
+
+# Returns the PNG bytes of the first of $pngFormats on the clipboard, or
+# $null. The bytes must start with the PNG signature: some apps register the
+# name for other data.
+function Get-ClipboardPng($dataObj, $formats) {
+    foreach ($fmt in $pngFormats) {
+        if ($formats -notcontains $fmt) { continue }
+        $stream = $dataObj.GetData($fmt)
+        if (-not ($stream -is [System.IO.MemoryStream])) { continue }
+        $bytes = $stream.ToArray()
+        if ($bytes.Length -gt 8 -and $bytes[0] -eq 0x89 -and $bytes[1] -eq 0x50 -and $bytes[2] -eq 0x4E -and $bytes[3] -eq 0x47) {
+            return ,$bytes
+        }
+    }
+    return $null
+}
+
+# Files copied in Explorer reach the clipboard as a file-drop list
+# (CF_HDROP), not a bitmap. CHECK reads the first image file in the list
+# from disk and announces it like a screenshot. PNGs go out byte for byte,
+# so their hash matches the file; other formats are converted. Later files
+# are left alone: saving the first one rewrites the clipboard anyway.
+$dropExtensions = @(".png", ".jpg", ".jpeg", ".bmp", ".gif", ".tif", ".tiff")
+$maxDropBytes = 64MB
+
+function Get-DroppedImage {
+    if (-not (Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::ContainsFileDropList() })) { return $null }
+    # Our own enriched write carries text next to its file drop.
+    if ([System.Windows.Forms.Clipboard]::ContainsText()) { return $null }
+    $files = Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::GetFileDropList() }
+    foreach ($f in $files) {
+        $ext = [System.IO.Path]::GetExtension($f).ToLowerInvariant()
+        if ($dropExtensions -notcontains $ext) { continue }
+        $info = New-Object System.IO.FileInfo($f)
+        if (-not $info.Exists -or $info.Length -gt $maxDropBytes) { continue }
+        if ($ext -eq ".png") { return ,[System.IO.File]::ReadAllBytes($f) }
+        $img = [System.Drawing.Image]::FromFile($f)
+        try {
+            $ms = New-Object System.IO.MemoryStream
+            $img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png)
+            return ,$ms.ToArray()
+        } finally {
+            $img.Dispose()
+        }
+    }
+    return $null
+}
+
+# Written before every image CHECK announces (HASH or DIB), as
+# WINDOW|<process>|<title>: the foreground window when the image was read,
+# i.e. the application the user took it in. Line breaks in the title become
+# spaces; the title may hold |, so it comes last. Without user32, or without a
+# foreground window, nothing is written.
+function Write-Foreground {
+    if ($user32 -eq $null) { return }
+    try {
+        $hwnd = $user32::GetForegroundWindow()
+        if ($hwnd -eq [IntPtr]::Zero) { return }
+        $fgPid = [UInt32]0
+        [void]$user32::GetWindowThreadProcessId($hwnd, [ref]$fgPid)
+        $name = ""
+        $proc = Get-Process -Id $fgPid -ErrorAction SilentlyContinue
+        if ($proc -ne $null) { $name = $proc.ProcessName }
+        $title = New-Object System.Text.StringBuilder 512
+        [void]$user32::GetWindowText($hwnd, $title, $title.Capacity)
+        [Console]::Out.WriteLine("WINDOW|" + $name + "|" + ($title.ToString() -replace "[\r\n]+", " "))
+    } catch {
+        # The window is a nicety: the image still goes out without it.
+    }
+}
+
+# Holds png for FETCH and announces it by its hash; the client fetches it
+# only if it has not saved it already. source names the clipboard format the
+# image was read from (see $imageSources).
+function Write-Held([byte[]]$png, $source) {
+    $script:held = $png
+    Write-Foreground
+    $sha = [System.Security.Cryptography.SHA256]::Create()
+    $hash = [BitConverter]::ToString($sha.ComputeHash($png)).Replace("-", "").ToLowerInvariant()
+    $sha.Dispose()
+    [Console]::Out.WriteLine("HASH|" + $hash + "|" + $png.Length + "|" + $source)
+    [Console]::Out.Flush()
+}
+
 [Console]::Out.WriteLine("READY")
 [Console]::Out.Flush()
 
@@ -28,22 +352,73 @@ while ($true) {
     $line = $readTask.Result
     if ($line -eq $null -or $line -eq "EXIT") { break }
 
-    if ($line -eq "CHECK") {
+    if ($line -eq "FETCH") {
+        if ($held -eq $null) {
+            [Console]::Out.WriteLine("NONE")
+            [Console]::Out.Flush()
+        } else {
+            Write-Payload "IMAGE" $held
+        }
+        $held = $null
+        $readTask = [Console]::In.ReadLineAsync()
+        continue
+    }
+    $held = $null
+
+    if ($line -eq "CHECK" -or $line.StartsWith("CHECK|")) {
+        # CHECK|<since> answers SEQ|<n> first (0 without user32), then SAME
+        # when n still equals since: the clipboard has not changed since the
+        # client last looked, so nothing is rendered or sent.
+        # CHECK|0|all reads any image, even one the filters below skip
+        # (our own update, spreadsheet cells), for one-shot reads.
+        $anyImage = $line.EndsWith("|all")
+        $seq = 0
+        if ($user32 -ne $null) { $seq = $user32::GetClipboardSequenceNumber() }
+        [Console]::Out.WriteLine("SEQ|" + $seq)
+        if (Test-SessionLocked) {
+            [Console]::Out.WriteLine("LOCKED")
+            [Console]::Out.Flush()
+            $readTask = [Console]::In.ReadLineAsync()
+            continue
+        }
+        if ($seq -ne 0 -and $line -eq ("CHECK|" + $seq)) {
+            [Console]::Out.WriteLine("SAME")
+            [Console]::Out.Flush()
+            $readTask = [Console]::In.ReadLineAsync()
+            continue
+        }
         try {
-            # Skip if no image on clipboard
-            if (-not [System.Windows.Forms.Clipboard]::ContainsImage()) {
+            $formats = $null
+            $dataObj = Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::GetDataObject() }
+            if ($dataObj -ne $null) { $formats = $dataObj.GetFormats() }
+            $hasPng = $false
+            foreach ($fmt in $pngFormats) { if ($formats -contains $fmt) { $hasPng = $true } }
+
+            # Our own update, whatever formats it set
+            if (-not $anyImage -and $formats -contains $ownFormat) {
                 [Console]::Out.WriteLine("NONE")
                 [Console]::Out.Flush()
                 $readTask = [Console]::In.ReadLineAsync()
                 continue
             }
 
+            # Without an image, look for image files copied in Explorer
+            if (-not $hasPng -and -not (Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::ContainsImage() })) {
+                $dropped = Get-DroppedImage
+                if ($dropped -eq $null) {
+                    [Console]::Out.WriteLine("NONE")
+                    [Console]::Out.Flush()
+                } else {
+                    Write-Held $dropped "FileDrop"
+                }
+                $readTask = [Console]::In.ReadLineAsync()
+                continue
+            }
+
             # Skip clipboard from spreadsheet apps (Excel, Google Sheets, etc.)
             # These apps copy cells as images but also include data formats like
             # CSV, HTML, or XML Spreadsheet that pure screenshots never have.
-            $dataObj = [System.Windows.Forms.Clipboard]::GetDataObject()
-            if ($dataObj -ne $null) {
-                $formats = $dataObj.GetFormats()
+            if (-not $anyImage -and $formats -ne $null) {
                 if ($formats -contains "XML Spreadsheet" -or
                     $formats -contains "Csv" -or
                     ($formats -contains "HTML Format" -and [System.Windows.Forms.Clipboard]::ContainsText())) {
@@ -58,7 +433,8 @@ while ($true) {
             # holds our previous enriched write (SetImage + SetText + SetFileDropList).
             # Snipping Tool / Win+Shift+S only sets the image format, so the
             # presence of all three means no new capture has arrived.
-            if ([System.Windows.Forms.Clipboard]::ContainsText() -and
+            if (-not $anyImage -and
+                [System.Windows.Forms.Clipboard]::ContainsText() -and
                 [System.Windows.Forms.Clipboard]::ContainsFileDropList()) {
                 [Console]::Out.WriteLine("NONE")
                 [Console]::Out.Flush()
@@ -66,55 +442,199 @@ while ($true) {
                 continue
             }
 
-            $img = [System.Windows.Forms.Clipboard]::GetImage()
-            if ($img -eq $null) {
+            $png = $null
+            if ($hasPng) { $png = Get-ClipboardPng $dataObj $formats }
+            if ($png -ne $null) {
+                Write-Held $png "PNG"
+                $readTask = [Console]::In.ReadLineAsync()
+                continue
+            }
+
+            # Legacy apps (RDP sessions, old tools) put 8-bit palettized or
+            # 16-bit DIBs on the clipboard, which GetImage() converts with a
+            # color shift. Send those raw as DIB; the Go side decodes the
+            # palette and bit depth itself. 24/32-bit bitmaps still go
+            # through GetImage().
+            $dibBytes = $null
+            $dibSource = $null
+            foreach ($fmt in @("Format17", [System.Windows.Forms.DataFormats]::Dib)) {
+                if ($formats -ne $null -and $formats -contains $fmt) {
+                    $stream = $dataObj.GetData($fmt)
+                    if ($stream -is [System.IO.MemoryStream]) {
+                        $dibBytes = $stream.ToArray()
+                        $dibSource = $imageSources[$fmt]
+                        break
+                    }
+                }
+            }
+            if ($dibBytes -ne $null -and $dibBytes.Length -ge 40 -and [BitConverter]::ToUInt16($dibBytes, 14) -le 16) {
+                Write-Foreground
+                Write-Payload "DIB" $dibBytes $dibSource
+                $readTask = [Console]::In.ReadLineAsync()
+                continue
+            }
+
+            $img = Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::GetImage() }
+            if ($img -eq $null -and $formats -ne $null -and
+                ($formats -contains "Bitmap" -or $formats -contains "DeviceIndependentBitmap" -or $formats -contains "Format17")) {
+                # Delayed rendering: the owner advertised an image format but
+                # renders it only on demand (WM_RENDERFORMAT), and the first
+                # read can come back empty while it does. Keep pumping
+                # messages so the owner can answer, then try once more.
+                $deadline = [DateTime]::UtcNow.AddMilliseconds($delayedRenderWaitMs)
+                while ([DateTime]::UtcNow -lt $deadline) {
+                    [System.Windows.Forms.Application]::DoEvents()
+                    Start-Sleep -Milliseconds 10
+                }
+                $img = Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::GetImage() }
+            }
+            if ($img -eq $null -and $dibBytes -ne $null -and $dibBytes.Length -ge 40) {
+                # No CF_BITMAP to go through: send the DIB itself, which the
+                # Go side decodes at any bit depth.
+                Write-Foreground
+                Write-Payload "DIB" $dibBytes $dibSource
+            } elseif ($img -eq $null) {
                 [Console]::Out.WriteLine("NONE")
                 [Console]::Out.Flush()
             } else {
                 try {
                     $ms = New-Object System.IO.MemoryStream
                     $img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png)
-                    $bytes = $ms.ToArray()
+                    Write-Held $ms.ToArray() "Bitmap"
                     $ms.Dispose()
-                    $b64 = [Convert]::ToBase64String($bytes)
-                    [Console]::Out.WriteLine("IMAGE")
-                    [Console]::Out.WriteLine($b64)
-                    [Console]::Out.WriteLine("END")
-                    [Console]::Out.Flush()
                 } finally {
                     $img.Dispose()
                 }
             }
         } catch {
-            [Console]::Out.WriteLine("NONE")
+            # BUSY: another process still held the clipboard after the
+            # retries. Read it again next time rather than reporting nothing.
+            if (Test-ClipboardBusy $_) { [Console]::Out.WriteLine("BUSY") } else { [Console]::Out.WriteLine("NONE") }
             [Console]::Out.Flush()
         }
     }
-    elseif ($line.StartsWith("UPDATE|")) {
-        $parts = $line.Split("|")
-        $wslPath = $parts[1]
-        $winPath = $parts[2]
+    elseif ($line -eq "VERSION") {
+        [Console]::Out.WriteLine("VERSION|" + $protocolVersion)
+        [Console]::Out.Flush()
+    }
+    elseif ($line -eq "PING") {
+        # Liveness probe: answered straight from the loop, without touching
+        # the clipboard, so it only fails if the loop itself is stuck.
+        [Console]::Out.WriteLine("PONG")
+        [Console]::Out.Flush()
+    }
+    elseif ($line.StartsWith("WAIT|")) {
+        # WAIT|<since>|<ms>: block until the clipboard sequence number differs
+        # from since and answer CHANGED|<n>, or TIMEOUT|<n> after <ms>
+        # without a change. Messages keep being pumped meanwhile.
+        if ($user32 -eq $null) {
+            [Console]::Out.WriteLine("ERR|UNAVAILABLE|clipboard listener unavailable")
+        } else {
+            $parts = $line.Split("|")
+            $since = [UInt32]$parts[1]
+            $deadline = [DateTime]::UtcNow.AddMilliseconds([int]$parts[2])
+            $seq = $user32::GetClipboardSequenceNumber()
+            while ($seq -eq $since -and [DateTime]::UtcNow -lt $deadline) {
+                if ($listening) {
+                    # Returns as soon as any message is queued (QS_ALLINPUT),
+                    # WM_CLIPBOARDUPDATE included.
+                    [void]$user32::MsgWaitForMultipleObjects(0, [IntPtr]::Zero, $false, 100, 0x04FF)
+                } else {
+                    Start-Sleep -Milliseconds 10
+                }
+                [System.Windows.Forms.Application]::DoEvents()
+                $seq = $user32::GetClipboardSequenceNumber()
+            }
+            if ($seq -ne $since) {
+                [Console]::Out.WriteLine("CHANGED|" + $seq)
+            } else {
+                [Console]::Out.WriteLine("TIMEOUT|" + $seq)
+            }
+        }
+        [Console]::Out.Flush()
+    }
+    elseif ($line -eq "SESSION") {
+        if ($user32 -eq $null) {
+            [Console]::Out.WriteLine("ERR|UNAVAILABLE|session state unavailable")
+        } elseif (Test-SessionLocked) {
+            [Console]::Out.WriteLine("SESSION|locked")
+        } else {
+            [Console]::Out.WriteLine("SESSION|active")
+        }
+        [Console]::Out.Flush()
+    }
+    elseif ($line -eq "SEQ") {
+        if ($user32 -eq $null) {
+            [Console]::Out.WriteLine("ERR|UNAVAILABLE|sequence number unavailable")
+        } else {
+            [Console]::Out.WriteLine("SEQ|" + $user32::GetClipboardSequenceNumber())
+        }
+        [Console]::Out.Flush()
+    }
+    elseif ($line -eq "OWNER") {
+        # Reports the process that owns the clipboard: OWNER|<pid>|<name>,
+        # or OWNER|0| when the last writer did not register an owner window.
         try {
-            $img = [System.Drawing.Image]::FromFile($winPath)
-            try {
-                $data = New-Object System.Windows.Forms.DataObject
-                $data.SetImage($img)
-                $data.SetText($wslPath, [System.Windows.Forms.TextDataFormat]::UnicodeText)
-
-                $files = New-Object System.Collections.Specialized.StringCollection
-                [void]$files.Add($winPath)
-                $data.SetFileDropList($files)
-
-                [System.Windows.Forms.Clipboard]::SetDataObject($data, $true)
-                [Console]::Out.WriteLine("OK")
-                [Console]::Out.Flush()
-            } finally {
-                $img.Dispose()
+            if ($user32 -eq $null) {
+                [Console]::Out.WriteLine("ERR|UNAVAILABLE|clipboard owner unavailable")
+            } else {
+                $hwnd = $user32::GetClipboardOwner()
+                $ownerPid = [UInt32]0
+                $name = ""
+                if ($hwnd -ne [IntPtr]::Zero) {
+                    [void]$user32::GetWindowThreadProcessId($hwnd, [ref]$ownerPid)
+                    $proc = Get-Process -Id $ownerPid -ErrorAction SilentlyContinue
+                    if ($proc -ne $null) { $name = $proc.ProcessName }
+                }
+                [Console]::Out.WriteLine("OWNER|" + $ownerPid + "|" + $name)
             }
         } catch {
-            [Console]::Out.WriteLine("ERR|" + $_.Exception.Message)
-            [Console]::Out.Flush()
+            Write-Err $_
+        }
+        [Console]::Out.Flush()
+    }
+    elseif ($line -eq "HTML") {
+        # Sends the clipboard's CF_HTML text, header included, as an
+        # HTML|<bytes> payload of UTF-8, or NONE. The Go side asks only when
+        # start --html-images is on and CHECK found no image; it looks for
+        # the image in the HTML itself.
+        try {
+            $htmlFormat = [System.Windows.Forms.TextDataFormat]::Html
+            if (Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::ContainsText($htmlFormat) }) {
+                $html = Invoke-ClipboardRetry { [System.Windows.Forms.Clipboard]::GetText($htmlFormat) }
+                Write-Payload "HTML" ([System.Text.Encoding]::UTF8.GetBytes($html))
+            } else {
+                [Console]::Out.WriteLine("NONE")
+            }
+        } catch {
+            if (Test-ClipboardBusy $_) { [Console]::Out.WriteLine("BUSY") } else { Write-Err $_ }
         }
+        [Console]::Out.Flush()
+    }
+    elseif ($line -eq "STATS") {
+        try {
+            $self = [System.Diagnostics.Process]::GetCurrentProcess()
+            [Console]::Out.WriteLine("STATS|ws=" + $self.WorkingSet64 +
+                "|private=" + $self.PrivateMemorySize64 +
+                "|handles=" + $self.HandleCount)
+            $self.Dispose()
+        } catch {
+            Write-Err $_
+        }
+        [Console]::Out.Flush()
+    }
+    elseif ($line.StartsWith("UPDATE|")) {
+        # UPDATE|<text>|<winPath>[|<options>]: text and path arrive
+        # percent-encoded (%, |, CR and LF), so text may span lines.
+        $parts = $line.Split("|")
+        $winPath = [Uri]::UnescapeDataString($parts[2])
+        Update-Clipboard ([Uri]::UnescapeDataString($parts[1])) $winPath {
+            param($data)
+            $img = [System.Drawing.Image]::FromFile($winPath)
+            $data.SetImage($img)
+            $img
+        } (Get-UpdateFormats $parts[3])
+        [Console]::Out.Flush()
     }
 
     $readTask = [Console]::In.ReadLineAsync()
@@ -2,14 +2,20 @@ package clipboard
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"image/png"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestHelperProcess is invoked by tests as a fake PowerShell subprocess.
@@ -21,27 +27,127 @@ func TestHelperProcess(t *testing.T) {
 
 	scanner := bufio.NewScanner(os.Stdin)
 
+	if os.Getenv("HELPER_FAIL_START") == "1" {
+		fmt.Fprintln(os.Stderr, "Add-Type : Cannot add type. Compilation errors occurred.")
+		os.Exit(1)
+	}
+
 	// Send READY
 	fmt.Println("READY")
 
+	var held []byte // announced by HASH, sent on FETCH
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
+		if line != "FETCH" {
+			held = nil
+		}
 		switch {
-		case line == "CHECK":
+		case line == "FETCH":
+			if held == nil {
+				fmt.Println("NONE")
+			} else {
+				writePayload("IMAGE", held, "")
+			}
+		case line == "CHECK|42":
+			fmt.Println("SEQ|42")
+			fmt.Println("SAME")
+		case line == "CHECK" || strings.HasPrefix(line, "CHECK|"):
+			fmt.Println("SEQ|42")
 			behavior := os.Getenv("HELPER_CHECK_BEHAVIOR")
 			switch behavior {
 			case "IMAGE":
-				imgData := []byte("fake-png-data-for-test")
-				b64 := base64.StdEncoding.EncodeToString(imgData)
+				held = []byte("fake-png-data-for-test")
+				fmt.Println("WINDOW|SnippingTool|Snipping Tool | Snip 1")
+				fmt.Printf("HASH|%x|%d|Bitmap\n", sha256.Sum256(held), len(held))
+			case "DIB":
+				// 2x1 8-bit palettized DIB: red, then blue.
+				raw := make([]byte, 40, 52)
+				raw[0], raw[4], raw[8], raw[12], raw[14], raw[32] = 40, 2, 1, 1, 8, 2
+				raw = append(raw, 0, 0, 255, 0, 255, 0, 0, 0) // palette (BGRX): red, blue
+				raw = append(raw, 0, 1, 0, 0)                 // pixel row, padded to 4 bytes
+				fmt.Println("WINDOW|mspaint|Untitled - Paint")
+				writePayload("DIB", raw, "DIBV5")
+			case "HANG":
+				time.Sleep(time.Hour) // stuck in a clipboard call another application blocks
+			case "GARBAGE":
+				fmt.Fprintln(os.Stderr, "Exception calling \"GetImage\": out of memory")
+				time.Sleep(100 * time.Millisecond) // let the client read stderr first
+				fmt.Println("WAT")
+			case "BAD_DIB":
+				writePayload("DIB", []byte("short"), "")
+			case "TRUNCATED":
+				fmt.Println("IMAGE|100")
+				fmt.Println(base64.StdEncoding.EncodeToString([]byte("only a bit")))
+				fmt.Println("END")
+			case "LONG":
+				fmt.Println("IMAGE|4")
+				fmt.Println(base64.StdEncoding.EncodeToString([]byte("way more than 4")))
+				fmt.Println(base64.StdEncoding.EncodeToString([]byte("and more")))
+				fmt.Println("END")
+			case "OWN":
+				// Our own update: only CHECK|0|all reads its image.
+				if strings.HasSuffix(line, "|all") {
+					held = []byte("own-update-png")
+					fmt.Printf("HASH|%x|%d|Bitmap\n", sha256.Sum256(held), len(held))
+				} else {
+					fmt.Println("NONE")
+				}
+			case "LOCKED":
+				fmt.Println("LOCKED")
+			case "BUSY":
+				fmt.Println("BUSY")
+			case "LEGACY":
 				fmt.Println("IMAGE")
-				fmt.Println(b64)
+				fmt.Println(base64.StdEncoding.EncodeToString([]byte("protocol 1 image")))
 				fmt.Println("END")
 			default:
 				fmt.Println("NONE")
 			}
+		case line == "VERSION":
+			switch v := os.Getenv("HELPER_VERSION"); v {
+			case "NONE":
+				// a script from before versioning ignores unknown commands
+			case "":
+				fmt.Printf("VERSION|%d\n", protocolVersion)
+			default:
+				fmt.Println("VERSION|" + v)
+			}
+		case line == "PING":
+			if os.Getenv("HELPER_PING_BEHAVIOR") == "HANG" {
+				time.Sleep(time.Hour) // the loop itself is wedged
+			}
+			fmt.Println("PONG")
+		case strings.HasPrefix(line, "WAIT|"):
+			if strings.HasPrefix(line, "WAIT|42|") {
+				time.Sleep(300 * time.Millisecond) // nothing happens until the timeout
+				fmt.Println("TIMEOUT|42")
+			} else {
+				fmt.Println("CHANGED|42")
+			}
+		case line == "SESSION":
+			fmt.Println("SESSION|locked")
+		case line == "SEQ":
+			fmt.Println("SEQ|42")
+		case line == "OWNER":
+			fmt.Println("OWNER|4242|Ditto")
+		case line == "HTML":
+			if html := os.Getenv("HELPER_HTML"); html != "" {
+				writePayload("HTML", []byte(html), "")
+			} else {
+				fmt.Println("NONE")
+			}
+		case line == "STATS":
+			fmt.Println("STATS|ws=104857600|private=73400320|handles=512|future=1")
 		case strings.HasPrefix(line, "UPDATE|"):
-			fmt.Println("OK")
+			if os.Getenv("HELPER_CHECK_BEHAVIOR") == "BUSY" {
+				fmt.Println("BUSY")
+			} else {
+				fmt.Println(updateReply(strings.Split(line, "|")[3:]))
+			}
 		case line == "EXIT":
+			if os.Getenv("HELPER_HANG_ON_EXIT") == "1" {
+				select {} // stuck like a backend blocked in a clipboard call
+			}
 			os.Exit(0)
 		}
 	}
@@ -49,11 +155,44 @@ func TestHelperProcess(t *testing.T) {
 	os.Exit(0)
 }
 
+// updateReply answers UPDATE, given the fields after the
+// image: per-format results for the formats option, or a bare OK.
+func updateReply(options []string) string {
+	if len(options) == 0 {
+		return "OK"
+	}
+	formats, ok := strings.CutPrefix(options[0], "formats=")
+	if !ok {
+		return "ERR|UNKNOWN|bad options " + options[0]
+	}
+	var set []string
+	for _, f := range strings.Split(formats, ",") {
+		set = append(set, f+"=1")
+	}
+	return "OK|" + strings.Join(set, ",")
+}
+
+// writePayload frames data like clipboard.ps1's Write-Payload, in 6-byte
+// frames so every payload spans several of them.
+func writePayload(kind string, data []byte, source string) {
+	header := fmt.Sprintf("%s|%d", kind, len(data))
+	if source != "" {
+		header += "|" + source
+	}
+	fmt.Println(header)
+	for len(data) > 0 {
+		n := min(6, len(data))
+		fmt.Println(base64.StdEncoding.EncodeToString(data[:n]))
+		data = data[n:]
+	}
+	fmt.Println("END")
+}
+
 // helperCommand returns a function that creates an exec.Cmd running
 // the TestHelperProcess with the given environment.
-func helperCommand(t *testing.T, envs ...string) func() *exec.Cmd {
+func helperCommand(t *testing.T, envs ...string) func(string) *exec.Cmd {
 	t.Helper()
-	return func() *exec.Cmd {
+	return func(string) *exec.Cmd {
 		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
 		cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
 		cmd.Env = append(cmd.Env, envs...)
@@ -67,20 +206,110 @@ func TestNewClient_ReadyHandshake(t *testing.T) {
 	newPSCommand = helperCommand(t)
 
 	logger := testLogger(t)
-	client, err := NewClient(logger, false)
+	client, err := NewClient(logger, Options{})
 	if err != nil {
 		t.Fatalf("NewClient() error: %v", err)
 	}
 	defer client.Close()
 }
 
+func TestNewClient_BinaryFallback(t *testing.T) {
+	orig, origLook := newPSCommand, lookPath
+	defer func() { newPSCommand, lookPath = orig, origLook }()
+	installed := map[string]bool{"pwsh.exe": true, "powershell.exe": true}
+	lookPath = func(name string) (string, error) {
+		if !installed[name] {
+			return "", exec.ErrNotFound
+		}
+		return "/mnt/c/" + name, nil
+	}
+	var tried []string
+	helper := helperCommand(t)
+	newPSCommand = func(binary string) *exec.Cmd {
+		tried = append(tried, binary)
+		if binary == "pwsh.exe" {
+			return exec.Command("/nonexistent/pwsh.exe") // installed but broken
+		}
+		return helper(binary)
+	}
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+	if want := []string{"pwsh.exe", "powershell.exe"}; !slices.Equal(tried, want) {
+		t.Errorf("tried %v, want %v", tried, want)
+	}
+	if client.Binary() != "powershell.exe" {
+		t.Errorf("Binary() = %q, want powershell.exe", client.Binary())
+	}
+
+	// An explicit binary is the only one tried, and must exist.
+	installed["pwsh.exe"] = false
+	if _, err := NewClient(testLogger(t), Options{Binary: "pwsh.exe"}); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("NewClient(missing binary) = %v, want exec.ErrNotFound", err)
+	}
+	if err := CheckBinary("powershell.exe"); err != nil {
+		t.Errorf("CheckBinary(powershell.exe) = %v", err)
+	}
+}
+
+func TestNewClient_ProtocolMismatch(t *testing.T) {
+	orig, origTimeout := newPSCommand, versionTimeout
+	defer func() { newPSCommand, versionTimeout = orig, origTimeout }()
+	versionTimeout = 200 * time.Millisecond
+
+	for _, tc := range []struct{ version, want string }{
+		{"99", "backend speaks version 99"},
+		{"NONE", "no answer to VERSION"},
+	} {
+		newPSCommand = helperCommand(t, "HELPER_VERSION="+tc.version)
+		_, err := NewClient(testLogger(t), Options{})
+		if !errors.Is(err, ErrProtocolMismatch) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("HELPER_VERSION=%s: NewClient() error = %v, want a protocol mismatch (%s)", tc.version, err, tc.want)
+		}
+	}
+}
+
+func TestClose_KillsHungBackend(t *testing.T) {
+	orig, origTimeout := newPSCommand, closeTimeout
+	defer func() { newPSCommand, closeTimeout = orig, origTimeout }()
+	newPSCommand = helperCommand(t, "HELPER_HANG_ON_EXIT=1")
+	closeTimeout = 50 * time.Millisecond
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- client.Close() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Close() of a killed backend should report its exit status")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not kill a backend that ignored EXIT")
+	}
+}
+
+func TestIsBackendCommand(t *testing.T) {
+	if !IsBackendCommand("powershell.exe -STA -NoLogo -Command " + psScript) {
+		t.Error("the backend's own command line should match")
+	}
+	if IsBackendCommand("powershell.exe -NoProfile -Command Get-Date") {
+		t.Error("an unrelated powershell.exe should not match")
+	}
+}
+
 func TestCheck_ReturnsNone(t *testing.T) {
 	orig := newPSCommand
 	defer func() { newPSCommand = orig }()
 	newPSCommand = helperCommand(t)
 
 	logger := testLogger(t)
-	client, err := NewClient(logger, false)
+	client, err := NewClient(logger, Options{})
 	if err != nil {
 		t.Fatalf("NewClient() error: %v", err)
 	}
@@ -101,7 +330,7 @@ func TestCheck_ReturnsImage(t *testing.T) {
 	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=IMAGE")
 
 	logger := testLogger(t)
-	client, err := NewClient(logger, false)
+	client, err := NewClient(logger, Options{})
 	if err != nil {
 		t.Fatalf("NewClient() error: %v", err)
 	}
@@ -119,13 +348,491 @@ func TestCheck_ReturnsImage(t *testing.T) {
 	}
 }
 
+func TestCheck_Payloads(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+
+	for _, tc := range []struct {
+		behavior, want, wantErr string
+	}{
+		{behavior: "LEGACY", want: "protocol 1 image"},
+		{behavior: "TRUNCATED", wantErr: "ended after 10 of 100 bytes"},
+		{behavior: "LONG", wantErr: "longer than the announced 4 bytes"},
+	} {
+		newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR="+tc.behavior)
+		client, err := NewClient(testLogger(t), Options{})
+		if err != nil {
+			t.Fatalf("NewClient() error: %v", err)
+		}
+		data, err := client.Check()
+		switch {
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: Check() error = %v, want %q", tc.behavior, err, tc.wantErr)
+		case tc.wantErr == "" && (err != nil || string(data) != tc.want):
+			t.Errorf("%s: Check() = %q, %v; want %q", tc.behavior, data, err, tc.want)
+		}
+		// However the payload went wrong, the next exchange lines up.
+		if seq, err := client.Sequence(); seq != 42 || err != nil {
+			t.Errorf("%s: Sequence() after the payload = %d, %v", tc.behavior, seq, err)
+		}
+		_ = client.Close()
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestReadLine_RejectsOverlongLines(t *testing.T) {
+	out := "READY\n" + strings.Repeat("A", maxLine+1) + "\n"
+	c := newClient(nopWriteCloser{io.Discard}, strings.NewReader(out), func() error { return nil }, testLogger(t), Options{})
+	if line, err := c.recv("greeting"); err != nil || line != "READY" {
+		t.Fatalf("recv() = %q, %v", line, err)
+	}
+	if _, err := c.recv("response"); err == nil || !strings.Contains(err.Error(), "line longer than") {
+		t.Errorf("recv() of an overlong line error = %v", err)
+	}
+}
+
+func TestCheckTo_StreamsImage(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=IMAGE")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	// Small writes show the payload arrives piecemeal, not as one buffer.
+	var w chunkRecorder
+	ok, err := client.CheckTo(&w)
+	if !ok || err != nil {
+		t.Fatalf("CheckTo() = %v, %v", ok, err)
+	}
+	if w.String() != "fake-png-data-for-test" || w.writes < 2 {
+		t.Errorf("CheckTo() wrote %q in %d writes, want the image in several", w.String(), w.writes)
+	}
+}
+
+// chunkRecorder is a bytes.Buffer counting the writes it receives.
+type chunkRecorder struct {
+	bytes.Buffer
+	writes int
+}
+
+func (c *chunkRecorder) Write(p []byte) (int, error) {
+	c.writes++
+	return c.Buffer.Write(p)
+}
+
+func TestCheck_DecodesDIB(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=DIB")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	data, err := client.Check()
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Check() did not return a PNG: %v", err)
+	}
+	if r, _, b, _ := img.At(0, 0).RGBA(); r>>8 != 255 || b != 0 {
+		t.Errorf("pixel 0 = %v, want red", img.At(0, 0))
+	}
+	if r, _, b, _ := img.At(1, 0).RGBA(); r != 0 || b>>8 != 255 {
+		t.Errorf("pixel 1 = %v, want blue", img.At(1, 0))
+	}
+}
+
+func TestSource(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+
+	for behavior, want := range map[string]string{"IMAGE": "Bitmap", "DIB": "DIBV5", "LEGACY": "", "NONE": ""} {
+		newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR="+behavior)
+		client, err := NewClient(testLogger(t), Options{})
+		if err != nil {
+			t.Fatalf("NewClient() error: %v", err)
+		}
+		if _, err := client.Check(); err != nil {
+			t.Errorf("%s: Check() error: %v", behavior, err)
+		}
+		if got := client.Source(); got != want {
+			t.Errorf("%s: Source() = %q, want %q", behavior, got, want)
+		}
+		client.Close()
+	}
+}
+
+func TestForeground(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+
+	for behavior, want := range map[string][2]string{
+		"IMAGE":  {"SnippingTool", "Snipping Tool | Snip 1"}, // the title may hold the separator
+		"DIB":    {"mspaint", "Untitled - Paint"},
+		"LEGACY": {"", ""},
+		"NONE":   {"", ""},
+	} {
+		newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR="+behavior)
+		client, err := NewClient(testLogger(t), Options{})
+		if err != nil {
+			t.Fatalf("NewClient() error: %v", err)
+		}
+		if _, err := client.Check(); err != nil {
+			t.Errorf("%s: Check() error: %v", behavior, err)
+		}
+		if process, title := client.Foreground(); process != want[0] || title != want[1] {
+			t.Errorf("%s: Foreground() = %q, %q, want %q, %q", behavior, process, title, want[0], want[1])
+		}
+		client.Close()
+	}
+}
+
+func TestCheck_InvalidDIB(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=BAD_DIB")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Check(); err == nil || !strings.Contains(err.Error(), "decode DIB") {
+		t.Errorf("Check() error = %v, want a DIB decode error", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	rtt, err := client.Ping()
+	if err != nil {
+		t.Fatalf("Ping() error: %v", err)
+	}
+	if rtt <= 0 {
+		t.Errorf("Ping() = %v, want a positive round trip", rtt)
+	}
+}
+
+func TestPing_TimeoutBreaksClient(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_PING_BEHAVIOR=HANG")
+
+	client, err := NewClient(testLogger(t), Options{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Ping(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Ping() error = %v, want ErrTimeout", err)
+	}
+	if !client.Broken() {
+		t.Error("client should be broken after a PING timeout")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	seq, err := client.Sequence()
+	if err != nil {
+		t.Fatalf("Sequence() error: %v", err)
+	}
+	if seq != 42 {
+		t.Errorf("Sequence() = %d, want 42", seq)
+	}
+}
+
+func TestCheckSince(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=IMAGE")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	var buf bytes.Buffer
+	seq, ok, err := client.CheckSince(0, &buf)
+	if seq != 42 || !ok || err != nil || buf.String() != "fake-png-data-for-test" {
+		t.Fatalf("CheckSince(0) = %d, %v, %v with %q", seq, ok, err, buf.String())
+	}
+
+	// Same sequence: the backend answers SAME without sending the image.
+	buf.Reset()
+	seq, ok, err = client.CheckSince(42, &buf)
+	if seq != 42 || ok || err != nil || buf.Len() != 0 {
+		t.Errorf("CheckSince(42) = %d, %v, %v with %q; want 42, false, nil and nothing written", seq, ok, err, buf.String())
+	}
+}
+
+func TestCheckKnown_SkipsKnownImage(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=IMAGE")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	want := fmt.Sprintf("%x", sha256.Sum256([]byte("fake-png-data-for-test")))
+	var asked string
+	var buf bytes.Buffer
+	_, sha, ok, err := client.CheckKnown(0, func(sha string) bool { asked = sha; return true }, &buf)
+	if sha != want || asked != want || !ok || err != nil {
+		t.Fatalf("CheckKnown() = %q, %v, %v (asked about %q); want %q, true, nil", sha, ok, err, asked, want)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("known image was transferred: %q", buf.String())
+	}
+
+	// Unknown: fetched in full.
+	_, sha, ok, err = client.CheckKnown(0, func(string) bool { return false }, &buf)
+	if sha != want || !ok || err != nil || buf.String() != "fake-png-data-for-test" {
+		t.Errorf("CheckKnown(unknown) = %q, %v, %v with %q", sha, ok, err, buf.String())
+	}
+}
+
+func TestWait(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	client, err := NewClient(testLogger(t), Options{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if seq, changed, err := client.Wait(7, time.Second); seq != 42 || !changed || err != nil {
+		t.Errorf("Wait(7) = %d, %v, %v; want 42, true, nil", seq, changed, err)
+	}
+	// The wait itself may outlast the client timeout.
+	if seq, changed, err := client.Wait(42, 300*time.Millisecond); seq != 42 || changed || err != nil {
+		t.Errorf("Wait(42) = %d, %v, %v; want 42, false, nil", seq, changed, err)
+	}
+}
+
+func TestSessionLocked(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=LOCKED")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if data, err := client.Check(); data != nil || !errors.Is(err, ErrSessionLocked) {
+		t.Errorf("Check() = %q, %v; want ErrSessionLocked", data, err)
+	}
+	if locked, err := client.SessionLocked(); !locked || err != nil {
+		t.Errorf("SessionLocked() = %v, %v; want true, nil", locked, err)
+	}
+}
+
+func TestClipboardBusy(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=BUSY")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	data, err := client.Check()
+	var busy interface{ ClipboardBusy() bool }
+	if data != nil || !errors.Is(err, ErrClipboardBusy) || !errors.As(err, &busy) || !busy.ClipboardBusy() {
+		t.Errorf("Check() = %q, %v; want a busy ErrClipboardBusy", data, err)
+	}
+	if err := client.UpdateClipboard("/tmp/x.png", `C:\x.png`); !errors.Is(err, ErrClipboardBusy) {
+		t.Errorf("UpdateClipboard() = %v, want ErrClipboardBusy", err)
+	}
+	if client.Broken() {
+		t.Error("a busy clipboard must not break the client")
+	}
+}
+
+func TestOwner(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	pid, name, err := client.Owner()
+	if err != nil {
+		t.Fatalf("Owner() error: %v", err)
+	}
+	if pid != 4242 || name != "Ditto" {
+		t.Errorf("Owner() = %d, %q; want 4242, Ditto", pid, name)
+	}
+}
+
+func TestReadImage(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=OWN")
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if img, err := client.Check(); err != nil || img != nil {
+		t.Fatalf("Check() = %q, %v; want no image for our own update", img, err)
+	}
+	var buf bytes.Buffer
+	ok, err := client.ReadImage(&buf)
+	if err != nil || !ok || buf.String() != "own-update-png" {
+		t.Errorf("ReadImage() = %v, %v, %q; want the image", ok, err, buf.String())
+	}
+}
+
+func TestHTML(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+
+	const html = `Version:0.9\r\n<html><body><img src="https://example.com/a.png"></body></html>`
+	for _, want := range []string{html, ""} {
+		newPSCommand = helperCommand(t, "HELPER_HTML="+want)
+		client, err := NewClient(testLogger(t), Options{})
+		if err != nil {
+			t.Fatalf("NewClient() error: %v", err)
+		}
+		got, err := client.HTML()
+		if err != nil || string(got) != want || (want == "") != (got == nil) {
+			t.Errorf("HTML() = %q, %v; want %q", got, err, want)
+		}
+		client.Close()
+	}
+}
+
+func TestStats(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	client, err := NewClient(testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	st, err := client.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error: %v", err)
+	}
+	want := BackendStats{WorkingSet: 104857600, PrivateBytes: 73400320, Handles: 512}
+	if st != want {
+		t.Errorf("Stats() = %+v, want %+v", st, want)
+	}
+}
+
+func TestUpdateClipboard_Formats(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	var trace bytes.Buffer
+	client, err := NewClient(testLogger(t), Options{Formats: []string{"text", "filedrop"}, Trace: &trace})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if got := client.UpdatableFormats(); !slices.Equal(got, []string{"text", "filedrop"}) {
+		t.Errorf("UpdatableFormats() = %v", got)
+	}
+	if err := client.UpdateClipboard("/tmp/a.png", `C:\a.png`); err != nil {
+		t.Errorf("UpdateClipboard() error: %v", err)
+	}
+	if n := strings.Count(trace.String(), "|formats=text,filedrop"); n != 1 {
+		t.Errorf("formats option sent %d times, want 1:\n%s", n, trace.String())
+	}
+}
+
+func TestUpdateClipboard_EscapesFields(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t)
+
+	var trace bytes.Buffer
+	client, err := NewClient(testLogger(t), Options{Trace: &trace})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.UpdateClipboard("see | 100%\r\n/tmp/a.png", `C:\a.png`); err != nil {
+		t.Fatalf("UpdateClipboard() error: %v", err)
+	}
+	if want := "UPDATE|see %7C 100%25%0D%0A/tmp/a.png|"; !strings.Contains(trace.String(), want) {
+		t.Errorf("trace lacks %q:\n%s", want, trace.String())
+	}
+}
+
+func TestCheckFormats(t *testing.T) {
+	if err := CheckFormats([]string{"text", "image"}); err != nil {
+		t.Errorf("CheckFormats(text, image) error: %v", err)
+	}
+	for _, bad := range [][]string{nil, {"text", "bitmap"}} {
+		if err := CheckFormats(bad); err == nil {
+			t.Errorf("CheckFormats(%v) should fail", bad)
+		}
+	}
+}
+
 func TestClose_SendsEXIT(t *testing.T) {
 	orig := newPSCommand
 	defer func() { newPSCommand = orig }()
 	newPSCommand = helperCommand(t)
 
 	logger := testLogger(t)
-	client, err := NewClient(logger, false)
+	client, err := NewClient(logger, Options{})
 	if err != nil {
 		t.Fatalf("NewClient() error: %v", err)
 	}
@@ -139,7 +846,91 @@ func TestClose_SendsEXIT(t *testing.T) {
 	// If Close() didn't send EXIT, the process would hang and Wait() would block.
 }
 
-func testLogger(t *testing.T) *log.Logger {
+func testLogger(t *testing.T) *slog.Logger {
 	t.Helper()
-	return log.New(io.Discard, "", 0)
+	return slog.New(slog.DiscardHandler)
+}
+
+func TestCheck_UnexpectedResponseIncludesStderr(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=GARBAGE")
+
+	var logs bytes.Buffer
+	client, err := NewClient(slog.New(slog.NewTextHandler(&logs, nil)), Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Check()
+	if err == nil {
+		t.Fatal("Check() error = nil, want an unexpected response error")
+	}
+	want := `unexpected response: "WAT" (powershell stderr: Exception calling "GetImage": out of memory)`
+	if err.Error() != want {
+		t.Errorf("Check() error = %q, want %q", err, want)
+	}
+	if !strings.Contains(logs.String(), `msg=[ps:stderr] line="Exception calling \"GetImage\": out of memory"`) {
+		t.Errorf("stderr not logged:\n%s", logs.String())
+	}
+}
+
+func TestNewClient_StartupFailureIncludesStderr(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_FAIL_START=1")
+
+	_, err := NewClient(testLogger(t), Options{})
+	if err == nil || !strings.Contains(err.Error(), "(powershell stderr: Add-Type : Cannot add type. Compilation errors occurred.)") {
+		t.Errorf("NewClient() error = %v, want it to include stderr", err)
+	}
+}
+
+func TestCheck_TimeoutKillsAndBreaksClient(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=HANG")
+
+	client, err := NewClient(testLogger(t), Options{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	_, err = client.Check()
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Check() error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Check() took %s, want about the timeout", elapsed)
+	}
+	if !client.Broken() {
+		t.Error("Broken() = false after a timeout")
+	}
+	if _, err := client.Sequence(); !errors.Is(err, ErrBroken) {
+		t.Errorf("Sequence() on a broken client = %v, want ErrBroken", err)
+	}
+}
+
+func TestCheck_WithinTimeout(t *testing.T) {
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=IMAGE")
+
+	client, err := NewClient(testLogger(t), Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Check(); err != nil {
+			t.Fatalf("Check() error: %v", err)
+		}
+	}
+	if client.Broken() {
+		t.Error("Broken() = true without a timeout")
+	}
 }
@@ -0,0 +1,162 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is the locale-independent error class PowerShell reports in
+// ERR|<code>|<message> responses. Codes are derived from .NET exception
+// types, never from (localized) exception text.
+type ErrorCode string
+
+const (
+	CodeClipboardBusy ErrorCode = "CLIPBOARD_BUSY" // another process holds the clipboard open
+	CodeNotFound      ErrorCode = "NOT_FOUND"      // the file to load does not exist
+	CodeAccessDenied  ErrorCode = "ACCESS_DENIED"  // the file or clipboard refused access
+	CodeInvalidImage  ErrorCode = "INVALID_IMAGE"  // image data could not be decoded
+	CodeUnavailable   ErrorCode = "UNAVAILABLE"    // the operation is not supported by this backend
+	CodeUnknown       ErrorCode = "UNKNOWN"        // any other failure
+)
+
+// Sentinel errors matched by errors.Is against a *BackendError.
+var (
+	ErrClipboardBusy = errors.New("clipboard is locked by another application")
+	ErrNotFound      = errors.New("file not found")
+	ErrAccessDenied  = errors.New("access denied")
+	ErrInvalidImage  = errors.New("invalid image data")
+	ErrUnavailable   = errors.New("operation unavailable")
+)
+
+// ErrSessionLocked is returned by checks while the Windows session is locked
+// or has no interactive desktop, when the clipboard can't be read.
+var ErrSessionLocked error = sessionLockedError{}
+
+type sessionLockedError struct{}
+
+func (sessionLockedError) Error() string { return "Windows session is locked" }
+
+// SessionLocked marks the error for callers that don't import this package.
+func (sessionLockedError) SessionLocked() bool { return true }
+
+// errBusy is returned for BUSY replies: another process kept the clipboard
+// open through all of the backend's retries. It matches ErrClipboardBusy.
+var errBusy = &BackendError{Code: CodeClipboardBusy, Message: "clipboard held open by another process"}
+
+var codeErrors = map[ErrorCode]error{
+	CodeClipboardBusy: ErrClipboardBusy,
+	CodeNotFound:      ErrNotFound,
+	CodeAccessDenied:  ErrAccessDenied,
+	CodeInvalidImage:  ErrInvalidImage,
+	CodeUnavailable:   ErrUnavailable,
+}
+
+// BackendError is a failure reported by the PowerShell side.
+type BackendError struct {
+	Code    ErrorCode
+	Message string // diagnostic detail, possibly localized
+}
+
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("powershell: %s: %s", e.Code, e.Message)
+}
+
+// Is lets errors.Is(err, ErrClipboardBusy) and friends match by code.
+func (e *BackendError) Is(target error) bool {
+	return codeErrors[e.Code] == target
+}
+
+// ClipboardBusy marks busy errors for callers that don't import this
+// package: the clipboard could not be read, but nothing is wrong with the
+// backend.
+func (e *BackendError) ClipboardBusy() bool { return e.Code == CodeClipboardBusy }
+
+// parseBackendError turns an ERR response into a *BackendError, or returns
+// nil if line is not an error. Scripts predating error codes sent
+// ERR|<message>; those parse as CodeUnknown.
+func parseBackendError(line string) *BackendError {
+	rest, ok := strings.CutPrefix(line, "ERR|")
+	if !ok {
+		return nil
+	}
+	code, msg, ok := strings.Cut(rest, "|")
+	if !ok || !isErrorCode(code) {
+		return &BackendError{Code: CodeUnknown, Message: rest}
+	}
+	return &BackendError{Code: ErrorCode(code), Message: msg}
+}
+
+// isErrorCode reports whether s looks like an error code (UPPER_SNAKE_CASE),
+// so legacy free-text messages containing '|' are not mistaken for one.
+func isErrorCode(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// PartialUpdateError reports an UPDATE where some clipboard formats were set
+// and others failed. The clipboard still holds the formats that succeeded.
+type PartialUpdateError struct {
+	Formats map[string]bool // format name (text, image, filedrop) → set
+	Cause   error           // first format failure, if the script reported it
+}
+
+func (e *PartialUpdateError) Error() string {
+	msg := "clipboard partially updated, failed: " + strings.Join(e.FailedFormats(), ", ")
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *PartialUpdateError) Unwrap() error { return e.Cause }
+
+// FailedFormats lists the formats that could not be set, in protocol order.
+func (e *PartialUpdateError) FailedFormats() []string {
+	var failed []string
+	for _, name := range updateFormats {
+		if set, ok := e.Formats[name]; ok && !set {
+			failed = append(failed, name)
+		}
+	}
+	return failed
+}
+
+// updateFormats is the order the script reports formats in.
+var updateFormats = []string{"text", "image", "filedrop"}
+
+// parseUpdateResult interprets an OK reply to UPDATE. A bare OK
+// (older scripts) or OK with every format set returns nil; otherwise the
+// per-format results become a *PartialUpdateError. ok is false if line is
+// not an OK reply at all.
+func parseUpdateResult(line string) (ok bool, err error) {
+	if line == "OK" {
+		return true, nil
+	}
+	rest, found := strings.CutPrefix(line, "OK|")
+	if !found {
+		return false, nil
+	}
+	results, detail, _ := strings.Cut(rest, "|")
+	partial := &PartialUpdateError{Formats: map[string]bool{}}
+	failed := false
+	for _, kv := range strings.Split(results, ",") {
+		name, v, _ := strings.Cut(kv, "=")
+		partial.Formats[name] = v == "1"
+		failed = failed || v != "1"
+	}
+	if !failed {
+		return true, nil
+	}
+	if detail != "" {
+		partial.Cause = parseBackendError("ERR|" + detail)
+	}
+	return true, partial
+}
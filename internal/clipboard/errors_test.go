@@ -0,0 +1,89 @@
+package clipboard
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseBackendError(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantNil  bool
+		wantCode ErrorCode
+		wantMsg  string
+		sentinel error
+	}{
+		{"OK", true, "", "", nil},
+		{"ERR|CLIPBOARD_BUSY|System.Runtime.InteropServices.ExternalException: Échec", false, CodeClipboardBusy, "System.Runtime.InteropServices.ExternalException: Échec", ErrClipboardBusy},
+		{"ERR|NOT_FOUND|gone", false, CodeNotFound, "gone", ErrNotFound},
+		{"ERR|UNAVAILABLE|sequence number unavailable", false, CodeUnavailable, "sequence number unavailable", ErrUnavailable},
+		{"ERR|FUTURE_CODE|detail", false, "FUTURE_CODE", "detail", nil},
+		// Legacy scripts sent ERR|<message>, which may itself contain '|'.
+		{"ERR|Der Vorgang ist fehlgeschlagen", false, CodeUnknown, "Der Vorgang ist fehlgeschlagen", nil},
+		{"ERR|path C:|x failed", false, CodeUnknown, "path C:|x failed", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := parseBackendError(tt.line)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("parseBackendError(%q) = %v, want nil", tt.line, got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("parseBackendError(%q) = nil", tt.line)
+			}
+			if got.Code != tt.wantCode || got.Message != tt.wantMsg {
+				t.Errorf("parseBackendError(%q) = %q/%q, want %q/%q", tt.line, got.Code, got.Message, tt.wantCode, tt.wantMsg)
+			}
+			if tt.sentinel != nil && !errors.Is(got, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", got, tt.sentinel)
+			}
+			if errors.Is(got, ErrAccessDenied) {
+				t.Errorf("%v should not match an unrelated sentinel", got)
+			}
+		})
+	}
+}
+
+func TestParseUpdateResult(t *testing.T) {
+	tests := []struct {
+		line       string
+		wantOK     bool
+		wantFailed []string
+		sentinel   error
+	}{
+		{"OK", true, nil, nil},
+		{"OK|text=1,image=1,filedrop=1", true, nil, nil},
+		{"OK|text=1,image=1,filedrop=0|ACCESS_DENIED|System.UnauthorizedAccessException: denied", true, []string{"filedrop"}, ErrAccessDenied},
+		{"OK|text=1,image=0,filedrop=0", true, []string{"image", "filedrop"}, nil},
+		{"ERR|UNKNOWN|boom", false, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			ok, err := parseUpdateResult(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantFailed == nil {
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+				return
+			}
+			var partial *PartialUpdateError
+			if !errors.As(err, &partial) {
+				t.Fatalf("err = %v, want *PartialUpdateError", err)
+			}
+			if got := partial.FailedFormats(); !slices.Equal(got, tt.wantFailed) {
+				t.Errorf("FailedFormats() = %v, want %v", got, tt.wantFailed)
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.sentinel)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+)
+
+// BackendFallback is the reduced backend for machines where PowerShell is
+// blocked by policy; see FallbackClient.
+const BackendFallback = "fallback"
+
+// ErrNoFallback is returned by NewFallbackClient when the tools it needs are
+// missing.
+var ErrNoFallback = errors.New("no fallback clipboard tools found (need wl-paste from wl-clipboard under WSLg, and win32yank.exe or clip.exe)")
+
+// fallbackWriters are the text writers FallbackClient tries, in order.
+// win32yank takes UTF-8; clip.exe is on every Windows but needs UTF-16.
+var fallbackWriters = []string{"win32yank.exe", "clip.exe"}
+
+// FallbackClient is a reduced clipboard backend that needs no PowerShell.
+// win32yank and clip.exe only handle text, so images are read through the
+// WSLg clipboard bridge (wl-paste), and updates only set the path as text:
+// the clipboard loses the image and there is no file drop. It implements
+// the poller's Clipboard, and reports what it can set through
+// UpdatableFormats.
+type FallbackClient struct {
+	logger *slog.Logger
+	writer string // win32yank.exe or clip.exe
+}
+
+// NewFallbackClient finds the tools the fallback backend needs.
+func NewFallbackClient(logger *slog.Logger) (*FallbackClient, error) {
+	writer, err := fallbackWriter()
+	if err != nil {
+		return nil, err
+	}
+	logger.Warn("Using the fallback clipboard backend: screenshots are pasted as their path only", "writer", writer)
+	return &FallbackClient{logger: logger, writer: writer}, nil
+}
+
+// fallbackWriter checks for wl-paste and returns the first of
+// fallbackWriters installed.
+func fallbackWriter() (string, error) {
+	if os.Getenv("WAYLAND_DISPLAY") == "" {
+		return "", fmt.Errorf("%w: WSLg is not running", ErrNoFallback)
+	}
+	if _, err := lookPath("wl-paste"); err != nil {
+		return "", ErrNoFallback
+	}
+	for _, w := range fallbackWriters {
+		if _, err := lookPath(w); err == nil {
+			return w, nil
+		}
+	}
+	return "", ErrNoFallback
+}
+
+// Check returns the clipboard image as PNG, or nil when there is none.
+func (f *FallbackClient) Check() ([]byte, error) {
+	types, err := exec.Command("wl-paste", "--list-types").Output()
+	if err != nil {
+		return nil, nil // wl-paste fails on an empty clipboard
+	}
+	if !hasLine(string(types), "image/png") {
+		return nil, nil
+	}
+	data, err := exec.Command("wl-paste", "--no-newline", "--type", "image/png").Output()
+	if err != nil {
+		return nil, fmt.Errorf("wl-paste: %w", err)
+	}
+	return data, nil
+}
+
+// UpdateClipboard sets text as the clipboard text. winPath is unused:
+// there is no file drop without PowerShell.
+func (f *FallbackClient) UpdateClipboard(text, winPath string) error {
+	input := []byte(text)
+	if f.writer == "clip.exe" {
+		input = utf16LE(text)
+	}
+	cmd := exec.Command(f.writer) // #nosec G204 -- writer comes from fallbackWriters
+	if f.writer == "win32yank.exe" {
+		cmd.Args = append(cmd.Args, "-i")
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", f.writer, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UpdatableFormats lists the clipboard formats UpdateClipboard sets.
+func (f *FallbackClient) UpdatableFormats() []string {
+	return []string{"text"}
+}
+
+// Binary names the tools in use, for status.
+func (f *FallbackClient) Binary() string {
+	return "wl-paste + " + f.writer + " (fallback)"
+}
+
+// Close does nothing: no process outlives a call.
+func (f *FallbackClient) Close() error {
+	return nil
+}
+
+// hasLine reports whether text has a line equal to want.
+func hasLine(text, want string) bool {
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// utf16LE encodes s as UTF-16LE with a byte order mark, which clip.exe
+// needs to keep non-ASCII characters.
+func utf16LE(s string) []byte {
+	out := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
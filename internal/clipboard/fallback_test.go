@@ -0,0 +1,70 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeTools installs shell scripts named like the fallback's tools in a
+// temp dir that becomes the whole PATH, so real tools on a WSL machine are
+// not picked up. The scripts call other commands by absolute path.
+func fakeTools(t *testing.T, scripts map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	return dir
+}
+
+func TestFallbackClient(t *testing.T) {
+	dir := t.TempDir()
+	img := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(img, []byte("png bytes"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tools := fakeTools(t, map[string]string{
+		"wl-paste": `if [ "$1" = --list-types ]; then [ -f ` + img + ` ] && echo image/png; echo text/plain; else /bin/cat ` + img + `; fi`,
+		"clip.exe": `/bin/cat > "${0%/*}/clip.out"`,
+	})
+
+	client, err := NewFallbackClient(testLogger(t))
+	if err != nil {
+		t.Fatalf("NewFallbackClient() error: %v", err)
+	}
+	if data, err := client.Check(); string(data) != "png bytes" || err != nil {
+		t.Errorf("Check() = %q, %v; want the image", data, err)
+	}
+	if err := client.UpdateClipboard("/tmp/shot é.png", `C:\shot.png`); err != nil {
+		t.Fatalf("UpdateClipboard() error: %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(tools, "clip.out"))
+	if want := utf16LE("/tmp/shot é.png"); string(got) != string(want) {
+		t.Errorf("clip.exe got %q, want UTF-16LE %q", got, want)
+	}
+	if f := client.UpdatableFormats(); len(f) != 1 || f[0] != "text" {
+		t.Errorf("UpdatableFormats() = %v, want [text]", f)
+	}
+
+	os.Remove(img)
+	if data, err := client.Check(); data != nil || err != nil {
+		t.Errorf("Check() without an image = %q, %v; want nil, nil", data, err)
+	}
+}
+
+func TestFallbackClient_MissingTools(t *testing.T) {
+	fakeTools(t, map[string]string{"wl-paste": "true"})
+	if _, err := NewFallbackClient(testLogger(t)); !errors.Is(err, ErrNoFallback) {
+		t.Errorf("NewFallbackClient() without a text writer = %v, want ErrNoFallback", err)
+	}
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if err := CheckBackend(BackendFallback); !errors.Is(err, ErrNoFallback) {
+		t.Errorf("CheckBackend(fallback) without WSLg = %v, want ErrNoFallback", err)
+	}
+}
@@ -0,0 +1,104 @@
+package clipboard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Backends a Client can run, for Options.Backend.
+const (
+	BackendPowerShell = "powershell" // clipboard.ps1 under PowerShell
+	BackendNative     = "native"     // the compiled helper in native/
+)
+
+// ErrNoNativeHelper is returned for the native backend by builds that don't
+// embed the helper.
+var ErrNoNativeHelper = errors.New("this build does not include the native helper (build it with make build-native)")
+
+// nativeHelperName is the helper's file name on the Windows side.
+const nativeHelperName = "wsl-screenshot-helper.exe"
+
+// CheckBackend reports whether backend, an Options.Backend, can be used.
+func CheckBackend(backend string) error {
+	switch backend {
+	case "", BackendPowerShell:
+		return nil
+	case BackendNative:
+		if len(nativeHelper) == 0 {
+			return ErrNoNativeHelper
+		}
+		return nil
+	case BackendFallback:
+		_, err := fallbackWriter()
+		return err
+	default:
+		return fmt.Errorf("unknown backend %q (want %s, %s or %s)", backend, BackendPowerShell, BackendNative, BackendFallback)
+	}
+}
+
+// nativeDir returns the WSL path of the directory the helper is extracted
+// to, %LOCALAPPDATA%\wsl-screenshot-cli\helper. The helper must live on the
+// Windows filesystem: started from \\wsl.localhost\ it loads slowly, and
+// some endpoint protection refuses to run executables from there at all.
+// Declared as a var so tests can use a temp dir.
+var nativeDir = func() (string, error) {
+	out, err := exec.Command("cmd.exe", "/c", "echo %LOCALAPPDATA%").Output()
+	if err != nil {
+		return "", fmt.Errorf("cmd.exe: %w", err)
+	}
+	appData := strings.TrimSpace(string(out))
+	if appData == "" || strings.Contains(appData, "%") {
+		return "", errors.New("LOCALAPPDATA is not set on the Windows side")
+	}
+	dir, err := exec.Command("wslpath", "-u", appData).Output() // #nosec G204 -- argv-separated (no shell)
+	if err != nil {
+		return "", fmt.Errorf("wslpath -u %q: %w", appData, err)
+	}
+	return filepath.Join(strings.TrimSpace(string(dir)), "wsl-screenshot-cli", "helper"), nil
+}
+
+// newNativeCommand creates the exec.Cmd for the helper extracted to path.
+// The marker argument, which the helper ignores, lets the daemon recognise
+// its backends like PowerShell ones. Declared as a var so tests can
+// override it with a fake process.
+var newNativeCommand = func(path string) *exec.Cmd {
+	return exec.Command(path, backendMarker) // #nosec G204 -- path is where installNativeHelper wrote the embedded helper
+}
+
+// installNativeHelper extracts the embedded helper to the Windows side on
+// first use and returns its path. Each build goes to a directory named
+// after the helper's hash, so upgrading never overwrites a helper an older
+// daemon is still running.
+func installNativeHelper() (string, error) {
+	if len(nativeHelper) == 0 {
+		return "", ErrNoNativeHelper
+	}
+	dir, err := nativeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(nativeHelper)
+	path := filepath.Join(dir, hex.EncodeToString(sum[:6]), nativeHelperName)
+	if info, err := os.Stat(path); err == nil && info.Size() == int64(len(nativeHelper)) {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, nativeHelper, 0700); err != nil { // #nosec G306 -- the helper must be executable
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
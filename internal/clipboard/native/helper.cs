@@ -0,0 +1,525 @@
+// wsl-screenshot-cli clipboard backend, native helper.
+//
+// A compiled stand-in for clipboard.ps1, selected with start --backend
+// native. It speaks the same line protocol over stdin/stdout, verb for verb,
+// so the Go client can't tell the two apart; see clipboard.ps1 for what each
+// verb means. Built against .NET Framework 4.8, which every supported Windows
+// ships with, it starts in milliseconds and uses a fraction of PowerShell's
+// memory, so a restart after a circuit-breaker trip costs next to nothing.
+//
+// Keep it in step with clipboard.ps1: a protocol change must land in both,
+// together with protocolVersion in clipboard.go.
+
+using System;
+using System.Collections.Concurrent;
+using System.Collections.Generic;
+using System.Collections.Specialized;
+using System.Diagnostics;
+using System.Drawing;
+using System.Drawing.Imaging;
+using System.IO;
+using System.Runtime.InteropServices;
+using System.Security.Cryptography;
+using System.Text;
+using System.Threading;
+using System.Windows.Forms;
+
+static class Helper
+{
+    const int ProtocolVersion = 14;
+
+    // Payload frames: see $frameBytes in clipboard.ps1. Must stay a multiple
+    // of 3.
+    const int FrameBytes = 49152;
+
+    const int BusyRetries = 5;
+    const int BusyRetryMs = 10;
+    const int DelayedRenderWaitMs = 150;
+
+    [DllImport("user32.dll")] static extern uint GetClipboardSequenceNumber();
+    [DllImport("user32.dll")] static extern IntPtr GetClipboardOwner();
+    [DllImport("user32.dll")] static extern uint GetWindowThreadProcessId(IntPtr hwnd, out uint pid);
+    [DllImport("user32.dll")] static extern IntPtr GetForegroundWindow();
+    [DllImport("user32.dll", CharSet = CharSet.Unicode)] static extern int GetWindowText(IntPtr hwnd, StringBuilder text, int max);
+    [DllImport("user32.dll")] static extern bool AddClipboardFormatListener(IntPtr hwnd);
+    [DllImport("user32.dll")] static extern uint MsgWaitForMultipleObjects(uint count, IntPtr handles, bool waitAll, uint ms, uint wakeMask);
+    [DllImport("user32.dll")] static extern IntPtr OpenInputDesktop(uint flags, bool inherit, uint access);
+    [DllImport("user32.dll")] static extern bool CloseDesktop(IntPtr desktop);
+
+    static TextWriter output;
+    static bool listening;
+    static byte[] held; // PNG announced by HASH, until FETCH or the next command
+
+    [STAThread]
+    static void Main()
+    {
+        output = new StreamWriter(Console.OpenStandardOutput(), new UTF8Encoding(false)) { NewLine = "\n" };
+
+        // Read stdin on a worker thread so this (STA) thread keeps pumping
+        // messages between commands, like DoEvents() in the script.
+        var lines = new BlockingCollection<string>();
+        var reader = new Thread(() =>
+        {
+            var stdin = new StreamReader(Console.OpenStandardInput(), new UTF8Encoding(false));
+            string l;
+            while ((l = stdin.ReadLine()) != null) lines.Add(l);
+            lines.CompleteAdding();
+        }) { IsBackground = true };
+        reader.Start();
+
+        using (var listener = new Form())
+        {
+            try { listening = AddClipboardFormatListener(listener.Handle); } catch { listening = false; }
+
+            Reply("READY");
+            while (true)
+            {
+                Application.DoEvents();
+                string line;
+                if (!lines.TryTake(out line, 10))
+                {
+                    if (lines.IsCompleted) break;
+                    continue;
+                }
+                if (line == "EXIT") break;
+                Handle(line);
+                output.Flush();
+            }
+        }
+    }
+
+    static void Reply(string line)
+    {
+        output.WriteLine(line);
+        output.Flush();
+    }
+
+    static void Handle(string line)
+    {
+        if (line == "FETCH")
+        {
+            if (held == null) output.WriteLine("NONE");
+            else WritePayload("IMAGE", held, null);
+            held = null;
+            return;
+        }
+        held = null;
+
+        string[] parts = line.Split('|');
+        switch (parts[0])
+        {
+            case "CHECK": Check(line); break;
+            case "VERSION": output.WriteLine("VERSION|" + ProtocolVersion); break;
+            case "PING": output.WriteLine("PONG"); break;
+            case "WAIT": Wait(uint.Parse(parts[1]), int.Parse(parts[2])); break;
+            case "SESSION": output.WriteLine(SessionLocked() ? "SESSION|locked" : "SESSION|active"); break;
+            case "SEQ": output.WriteLine("SEQ|" + GetClipboardSequenceNumber()); break;
+            case "OWNER": Owner(); break;
+            case "STATS": Stats(); break;
+            case "HTML": Html(); break;
+            case "UPDATE":
+                UpdateClipboard(Uri.UnescapeDataString(parts[1]), Uri.UnescapeDataString(parts[2]), data =>
+                {
+                    Image img = Image.FromFile(Uri.UnescapeDataString(parts[2]));
+                    data.SetImage(img);
+                    return img;
+                }, UpdateFormats(parts.Length > 3 ? parts[3] : null));
+                break;
+        }
+    }
+
+    // See Test-SessionLocked in clipboard.ps1.
+    static bool SessionLocked()
+    {
+        IntPtr desktop = OpenInputDesktop(0, false, 0x0100); // DESKTOP_SWITCHDESKTOP
+        if (desktop == IntPtr.Zero) return true;
+        CloseDesktop(desktop);
+        return false;
+    }
+
+    static void Check(string line)
+    {
+        uint seq = GetClipboardSequenceNumber();
+        output.WriteLine("SEQ|" + seq);
+        if (SessionLocked())
+        {
+            output.WriteLine("LOCKED");
+            return;
+        }
+        if (seq != 0 && line == "CHECK|" + seq)
+        {
+            output.WriteLine("SAME");
+            return;
+        }
+        try
+        {
+            CheckImage(line.EndsWith("|all"));
+        }
+        catch (Exception ex)
+        {
+            output.WriteLine(IsBusy(ex) ? "BUSY" : "NONE");
+        }
+    }
+
+    // The body of CHECK in clipboard.ps1, in the same order and with the
+    // same exclusions, which anyImage (CHECK|0|all) skips.
+    static void CheckImage(bool anyImage)
+    {
+        IDataObject dataObj = Retry(() => Clipboard.GetDataObject());
+        string[] formats = dataObj != null ? dataObj.GetFormats() : null;
+        if (!anyImage && formats != null && Has(formats, OwnFormat))
+        {
+            output.WriteLine("NONE"); // our own update, whatever formats it set
+            return;
+        }
+        bool hasPng = formats != null && Array.Exists(PngFormats, f => Has(formats, f));
+
+        if (!hasPng && !Retry(() => Clipboard.ContainsImage()))
+        {
+            byte[] dropped = DroppedImage();
+            if (dropped == null) output.WriteLine("NONE");
+            else Hold(dropped, "FileDrop");
+            return;
+        }
+
+        if (!anyImage && formats != null &&
+            (Has(formats, "XML Spreadsheet") || Has(formats, "Csv") ||
+             (Has(formats, "HTML Format") && Clipboard.ContainsText())))
+        {
+            output.WriteLine("NONE");
+            return;
+        }
+        if (!anyImage && Clipboard.ContainsText() && Clipboard.ContainsFileDropList())
+        {
+            output.WriteLine("NONE"); // our own enriched write
+            return;
+        }
+
+        byte[] png = hasPng ? ClipboardPng(dataObj, formats) : null;
+        if (png != null)
+        {
+            Hold(png, "PNG");
+            return;
+        }
+
+        byte[] dib = null;
+        string dibSource = null;
+        foreach (string fmt in new[] { "Format17", DataFormats.Dib })
+        {
+            if (formats == null || !Has(formats, fmt)) continue;
+            var stream = dataObj.GetData(fmt) as MemoryStream;
+            if (stream == null) continue;
+            dib = stream.ToArray();
+            dibSource = fmt == "Format17" ? "DIBV5" : "DIB";
+            break;
+        }
+        if (dib != null && dib.Length >= 40 && BitConverter.ToUInt16(dib, 14) <= 16)
+        {
+            Foreground();
+            WritePayload("DIB", dib, dibSource);
+            return;
+        }
+
+        Image img = Retry(() => Clipboard.GetImage());
+        if (img == null && formats != null &&
+            (Has(formats, "Bitmap") || Has(formats, "DeviceIndependentBitmap") || Has(formats, "Format17")))
+        {
+            DateTime deadline = DateTime.UtcNow.AddMilliseconds(DelayedRenderWaitMs);
+            while (DateTime.UtcNow < deadline)
+            {
+                Application.DoEvents();
+                Thread.Sleep(10);
+            }
+            img = Retry(() => Clipboard.GetImage());
+        }
+        if (img == null && dib != null && dib.Length >= 40)
+        {
+            Foreground();
+            WritePayload("DIB", dib, dibSource);
+            return;
+        }
+        if (img == null)
+        {
+            output.WriteLine("NONE");
+            return;
+        }
+        using (img)
+        {
+            Hold(ToPng(img), "Bitmap");
+        }
+    }
+
+    // See $pngFormats and Get-ClipboardPng in clipboard.ps1.
+    static readonly string[] PngFormats = { "PNG", "image/png" };
+
+    static byte[] ClipboardPng(IDataObject dataObj, string[] formats)
+    {
+        foreach (string fmt in PngFormats)
+        {
+            if (!Has(formats, fmt)) continue;
+            var stream = dataObj.GetData(fmt) as MemoryStream;
+            if (stream == null) continue;
+            byte[] bytes = stream.ToArray();
+            if (bytes.Length > 8 && bytes[0] == 0x89 && bytes[1] == 0x50 && bytes[2] == 0x4E && bytes[3] == 0x47) return bytes;
+        }
+        return null;
+    }
+
+    // See Get-DroppedImage in clipboard.ps1.
+    static readonly string[] DropExtensions = { ".png", ".jpg", ".jpeg", ".bmp", ".gif", ".tif", ".tiff" };
+    const long MaxDropBytes = 64L << 20;
+
+    static byte[] DroppedImage()
+    {
+        if (!Retry(() => Clipboard.ContainsFileDropList())) return null;
+        if (Clipboard.ContainsText()) return null; // our own enriched write
+        StringCollection files = Retry(() => Clipboard.GetFileDropList());
+        foreach (string f in files)
+        {
+            string ext = Path.GetExtension(f).ToLowerInvariant();
+            if (Array.IndexOf(DropExtensions, ext) < 0) continue;
+            var info = new FileInfo(f);
+            if (!info.Exists || info.Length > MaxDropBytes) continue;
+            if (ext == ".png") return File.ReadAllBytes(f);
+            using (Image img = Image.FromFile(f))
+            {
+                return ToPng(img);
+            }
+        }
+        return null;
+    }
+
+    static byte[] ToPng(Image img)
+    {
+        using (var ms = new MemoryStream())
+        {
+            img.Save(ms, ImageFormat.Png);
+            return ms.ToArray();
+        }
+    }
+
+    // See Write-Held in clipboard.ps1.
+    static void Hold(byte[] png, string source)
+    {
+        held = png;
+        Foreground();
+        using (var sha = SHA256.Create())
+        {
+            string hash = BitConverter.ToString(sha.ComputeHash(png)).Replace("-", "").ToLowerInvariant();
+            output.WriteLine("HASH|" + hash + "|" + png.Length + "|" + source);
+        }
+    }
+
+    static bool Has(string[] formats, string name)
+    {
+        return Array.IndexOf(formats, name) >= 0;
+    }
+
+    static void Wait(uint since, int ms)
+    {
+        DateTime deadline = DateTime.UtcNow.AddMilliseconds(ms);
+        uint seq = GetClipboardSequenceNumber();
+        while (seq == since && DateTime.UtcNow < deadline)
+        {
+            if (listening) MsgWaitForMultipleObjects(0, IntPtr.Zero, false, 100, 0x04FF);
+            else Thread.Sleep(10);
+            Application.DoEvents();
+            seq = GetClipboardSequenceNumber();
+        }
+        output.WriteLine((seq != since ? "CHANGED|" : "TIMEOUT|") + seq);
+    }
+
+    // See Write-Foreground in clipboard.ps1.
+    static void Foreground()
+    {
+        IntPtr hwnd = GetForegroundWindow();
+        if (hwnd == IntPtr.Zero) return;
+        uint pid;
+        GetWindowThreadProcessId(hwnd, out pid);
+        string name = "";
+        try
+        {
+            using (var p = Process.GetProcessById((int)pid)) name = p.ProcessName;
+        }
+        catch (ArgumentException) { } // exited meanwhile
+        var title = new StringBuilder(512);
+        GetWindowText(hwnd, title, title.Capacity);
+        output.WriteLine("WINDOW|" + name + "|" + title.ToString().Replace("\r", " ").Replace("\n", " "));
+    }
+
+    static void Owner()
+    {
+        uint pid = 0;
+        string name = "";
+        IntPtr hwnd = GetClipboardOwner();
+        if (hwnd != IntPtr.Zero)
+        {
+            GetWindowThreadProcessId(hwnd, out pid);
+            try
+            {
+                using (var p = Process.GetProcessById((int)pid)) name = p.ProcessName;
+            }
+            catch (ArgumentException) { } // exited meanwhile
+        }
+        output.WriteLine("OWNER|" + pid + "|" + name);
+    }
+
+    // See HTML in clipboard.ps1.
+    static void Html()
+    {
+        try
+        {
+            if (Retry(() => Clipboard.ContainsText(TextDataFormat.Html)))
+                WritePayload("HTML", Encoding.UTF8.GetBytes(Retry(() => Clipboard.GetText(TextDataFormat.Html))), null);
+            else
+                output.WriteLine("NONE");
+        }
+        catch (Exception ex)
+        {
+            output.WriteLine(IsBusy(ex) ? "BUSY" : "ERR|" + FormatErr(ex));
+        }
+    }
+
+    static void Stats()
+    {
+        using (var self = Process.GetCurrentProcess())
+        {
+            output.WriteLine("STATS|ws=" + self.WorkingSet64 + "|private=" + self.PrivateMemorySize64 + "|handles=" + self.HandleCount);
+        }
+    }
+
+    // See $ownFormat, $keptImageFormats and Get-UpdateFormats in
+    // clipboard.ps1.
+    const string OwnFormat = "WslScreenshotCli.Update";
+    static readonly string[] KeptImageFormats = { "PNG", "image/png", "Format17", "DeviceIndependentBitmap", "Bitmap" };
+    static readonly string[] AllFormats = { "text", "image", "filedrop" };
+
+    static string[] UpdateFormats(string options)
+    {
+        if (options != null)
+        {
+            foreach (string opt in options.Split(';'))
+            {
+                string[] kv = opt.Split(new[] { '=' }, 2);
+                if (kv[0] == "formats" && kv.Length == 2) return kv[1].Split(',');
+            }
+        }
+        return AllFormats;
+    }
+
+    // See Update-Clipboard in clipboard.ps1: each format is set on its own,
+    // and the reply lists which ones made it.
+    static void UpdateClipboard(string text, string winPath, Func<DataObject, IDisposable> setImage, string[] formats)
+    {
+        var data = new DataObject();
+        var set = new List<string>();
+        var done = new HashSet<string>();
+        foreach (string f in AllFormats) if (Array.IndexOf(formats, f) >= 0) set.Add(f);
+        Exception first = null;
+        IDisposable disposable = null;
+
+        if (set.Contains("image"))
+        {
+            try { disposable = setImage(data); done.Add("image"); } catch (Exception ex) { first = first ?? ex; }
+        }
+        else
+        {
+            try
+            {
+                IDataObject current = Retry(() => Clipboard.GetDataObject());
+                string[] present = current != null ? current.GetFormats() : new string[0];
+                foreach (string f in KeptImageFormats)
+                    if (Has(present, f)) data.SetData(f, current.GetData(f));
+            }
+            catch { } // the source image is a courtesy; the path still goes out
+        }
+        if (set.Contains("text"))
+        {
+            try { data.SetText(text, TextDataFormat.UnicodeText); done.Add("text"); } catch (Exception ex) { first = first ?? ex; }
+        }
+        if (set.Contains("filedrop"))
+        {
+            try
+            {
+                var files = new StringCollection { winPath };
+                data.SetFileDropList(files);
+                done.Add("filedrop");
+            }
+            catch (Exception ex) { first = first ?? ex; }
+        }
+        data.SetData(OwnFormat, "1");
+
+        try
+        {
+            if (done.Count == 0)
+            {
+                output.WriteLine(first == null ? "ERR|UNKNOWN|no clipboard format to set" : "ERR|" + FormatErr(first));
+                return;
+            }
+            Retry(() => { Clipboard.SetDataObject(data, true); return true; });
+            string line = "OK|" + string.Join(",", set.ConvertAll(f => f + "=" + (done.Contains(f) ? 1 : 0)));
+            if (first != null) line += "|" + FormatErr(first);
+            output.WriteLine(line);
+        }
+        catch (Exception ex)
+        {
+            output.WriteLine(IsBusy(ex) ? "BUSY" : "ERR|" + FormatErr(ex));
+        }
+        finally
+        {
+            if (disposable != null) disposable.Dispose();
+        }
+    }
+
+    // See Invoke-ClipboardRetry in clipboard.ps1.
+    static T Retry<T>(Func<T> op)
+    {
+        int delay = BusyRetryMs;
+        for (int i = 1; ; i++)
+        {
+            try
+            {
+                return op();
+            }
+            catch (ExternalException) when (i < BusyRetries)
+            {
+            }
+            DateTime deadline = DateTime.UtcNow.AddMilliseconds(delay);
+            while (DateTime.UtcNow < deadline)
+            {
+                Application.DoEvents();
+                Thread.Sleep(5);
+            }
+            delay *= 2;
+        }
+    }
+
+    static bool IsBusy(Exception ex)
+    {
+        return FormatErr(ex).StartsWith("CLIPBOARD_BUSY|");
+    }
+
+    // See Format-Err in clipboard.ps1: the code comes from the exception
+    // type, never from its localized message.
+    static string FormatErr(Exception ex)
+    {
+        string code;
+        if (ex is FileNotFoundException || ex is DirectoryNotFoundException) code = "NOT_FOUND";
+        else if (ex is UnauthorizedAccessException) code = "ACCESS_DENIED";
+        else if (ex is OutOfMemoryException || ex is FormatException) code = "INVALID_IMAGE"; // GDI+ reports bad image files as OOM
+        else if (ex is ExternalException) code = "CLIPBOARD_BUSY";
+        else code = "UNKNOWN";
+        string detail = (ex.GetType().FullName + ": " + ex.Message).Replace('\r', ' ').Replace('\n', ' ').Replace('|', ' ');
+        return code + "|" + detail;
+    }
+
+    static void WritePayload(string kind, byte[] bytes, string source)
+    {
+        output.WriteLine(kind + "|" + bytes.Length + (source != null ? "|" + source : ""));
+        for (int i = 0; i < bytes.Length; i += FrameBytes)
+        {
+            output.WriteLine(Convert.ToBase64String(bytes, i, Math.Min(FrameBytes, bytes.Length - i)));
+        }
+        output.WriteLine("END");
+    }
+}
@@ -0,0 +1,10 @@
+//go:build nativehelper
+
+package clipboard
+
+import _ "embed"
+
+// nativeHelper is the compiled helper, built by make helper.
+//
+//go:embed native/wsl-screenshot-helper.exe
+var nativeHelper []byte
@@ -0,0 +1,7 @@
+//go:build !nativehelper
+
+package clipboard
+
+// nativeHelper is empty in builds without the nativehelper tag; the native
+// backend is unavailable then.
+var nativeHelper []byte
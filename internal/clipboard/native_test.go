@@ -0,0 +1,78 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallNativeHelper(t *testing.T) {
+	origHelper, origDir := nativeHelper, nativeDir
+	defer func() { nativeHelper, nativeDir = origHelper, origDir }()
+	dir := t.TempDir()
+	nativeDir = func() (string, error) { return dir, nil }
+
+	nativeHelper = nil
+	if _, err := installNativeHelper(); !errors.Is(err, ErrNoNativeHelper) {
+		t.Fatalf("installNativeHelper() without a helper = %v, want ErrNoNativeHelper", err)
+	}
+	if err := CheckBackend(BackendNative); !errors.Is(err, ErrNoNativeHelper) {
+		t.Errorf("CheckBackend(native) = %v, want ErrNoNativeHelper", err)
+	}
+
+	nativeHelper = []byte("MZ helper v1")
+	path, err := installNativeHelper()
+	if err != nil {
+		t.Fatalf("installNativeHelper() error: %v", err)
+	}
+	if filepath.Base(path) != nativeHelperName || filepath.Dir(filepath.Dir(path)) != dir {
+		t.Errorf("installed to %s, want %s/<hash>/%s", path, dir, nativeHelperName)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "MZ helper v1" {
+		t.Errorf("%s = %q, %v", path, data, err)
+	}
+	if again, err := installNativeHelper(); again != path || err != nil {
+		t.Errorf("second install = %s, %v; want the same path", again, err)
+	}
+
+	// A new build goes next to the old one, which may still be running.
+	nativeHelper = []byte("MZ helper v2")
+	upgraded, err := installNativeHelper()
+	if err != nil || upgraded == path {
+		t.Errorf("upgrade installed to %s, %v; want a new path", upgraded, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("old helper removed: %v", err)
+	}
+	if err := CheckBackend("java"); err == nil {
+		t.Error("CheckBackend(java) should fail")
+	}
+}
+
+func TestNewClient_NativeBackend(t *testing.T) {
+	origHelper, origDir, origCmd := nativeHelper, nativeDir, newNativeCommand
+	defer func() { nativeHelper, nativeDir, newNativeCommand = origHelper, origDir, origCmd }()
+	dir := t.TempDir()
+	nativeDir = func() (string, error) { return dir, nil }
+	nativeHelper = []byte("MZ helper")
+	var started string
+	helper := helperCommand(t)
+	newNativeCommand = func(path string) *exec.Cmd {
+		started = path
+		return helper(path)
+	}
+
+	client, err := NewClient(testLogger(t), Options{Backend: BackendNative})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+	if filepath.Base(started) != nativeHelperName || client.Binary() != nativeHelperName {
+		t.Errorf("started %q, Binary() = %q; want the extracted helper", started, client.Binary())
+	}
+	if data, err := client.Check(); data != nil || err != nil {
+		t.Errorf("Check() = %q, %v; want an empty clipboard", data, err)
+	}
+}
@@ -0,0 +1,58 @@
+package clipboard
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxRecentLine caps how much of a protocol line Recent keeps. Image payloads
+// run to megabytes of base64; their first bytes are enough to tell what was
+// going on.
+const maxRecentLine = 256
+
+// Recent keeps the last few protocol lines in memory so a crash report can
+// show what the client was doing when things went wrong. Unlike a trace it is
+// always on, and it abbreviates large payloads. A nil *Recent records nothing.
+// Several clients may share one Recent, so the exchanges leading up to a
+// circuit-breaker restart stay visible.
+type Recent struct {
+	mu      sync.Mutex
+	entries []TraceEntry
+	next    int
+	full    bool
+}
+
+// NewRecent returns a Recent that keeps the last n lines.
+func NewRecent(n int) *Recent {
+	return &Recent{entries: make([]TraceEntry, max(n, 1))}
+}
+
+func (r *Recent) record(dir, line string) {
+	if r == nil {
+		return
+	}
+	if len(line) > maxRecentLine {
+		line = fmt.Sprintf("%s... (%d bytes)", line[:maxRecentLine], len(line))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = TraceEntry{Time: time.Now(), Dir: dir, Line: line}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Entries returns the recorded lines, oldest first.
+func (r *Recent) Entries() []TraceEntry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]TraceEntry(nil), r.entries[:r.next]...)
+	}
+	return append(append([]TraceEntry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
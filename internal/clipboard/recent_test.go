@@ -0,0 +1,37 @@
+package clipboard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecent_KeepsLastLinesInOrder(t *testing.T) {
+	r := NewRecent(3)
+	for _, line := range []string{"a", "b", "c", "d", "e"} {
+		r.record(dirSend, line)
+	}
+	var got []string
+	for _, e := range r.Entries() {
+		got = append(got, e.Line)
+	}
+	if strings.Join(got, ",") != "c,d,e" {
+		t.Errorf("Entries() = %q, want c,d,e", got)
+	}
+}
+
+func TestRecent_AbbreviatesLargePayloads(t *testing.T) {
+	r := NewRecent(1)
+	r.record(dirRecv, "IMAGE:"+strings.Repeat("A", 10000))
+	line := r.Entries()[0].Line
+	if len(line) > maxRecentLine+32 || !strings.HasSuffix(line, "... (10006 bytes)") {
+		t.Errorf("line = %q", line)
+	}
+}
+
+func TestRecent_NilRecordsNothing(t *testing.T) {
+	var r *Recent
+	r.record(dirSend, "CHECK")
+	if r.Entries() != nil {
+		t.Error("nil Recent returned entries")
+	}
+}
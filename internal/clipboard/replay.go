@@ -0,0 +1,167 @@
+package clipboard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Replay simulates the PowerShell side of the protocol from a recorded trace.
+// Each command the client sends consumes the next recorded command and
+// answers with the responses PowerShell gave at the time. When the trace is
+// exhausted the simulated process exits, just like a crashed powershell.exe.
+// The VERSION handshake is answered with the current protocol version rather
+// than replayed, so traces recorded by older clients still replay.
+type Replay struct {
+	entries []TraceEntry
+
+	mu          sync.Mutex
+	pos         int
+	exchanges   int
+	divergences []string
+	done        chan struct{}
+}
+
+// NewReplayClient returns a Client backed by a Replay of entries instead of
+// a real powershell.exe, so recorded sessions can be reproduced offline.
+func NewReplayClient(entries []TraceEntry, logger *slog.Logger, opts Options) (*Client, *Replay, error) {
+	r := &Replay{entries: entries, done: make(chan struct{})}
+
+	goIn, psOut := io.Pipe() // Go reads what the simulated PowerShell writes
+	psIn, goOut := io.Pipe() // simulated PowerShell reads what Go writes
+	go r.serve(psIn, psOut)
+
+	c := newClient(goOut, goIn, func() error {
+		<-r.done
+		return nil
+	}, logger, opts)
+	if err := c.handshake(); err != nil {
+		_ = goOut.Close()
+		return nil, nil, err
+	}
+	return c, r, nil
+}
+
+// Exhausted reports whether every recorded command has been replayed.
+func (r *Replay) Exhausted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.nextSend() < 0
+}
+
+// Exchanges returns how many commands have been replayed so far.
+func (r *Replay) Exchanges() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exchanges
+}
+
+// Divergences describes every point where the client sent a different
+// command than the one recorded in the trace.
+func (r *Replay) Divergences() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.divergences...)
+}
+
+// nextSend returns the index of the next recorded command at or after pos,
+// or -1 if there is none. EXIT and VERSION are not replayed: one only marks
+// where the original client shut down, the other is answered by serve.
+// Callers must hold r.mu.
+func (r *Replay) nextSend() int {
+	for i := r.pos; i < len(r.entries); i++ {
+		if l := r.entries[i].Line; r.entries[i].Dir == dirSend && l != "EXIT" && l != "VERSION" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Replay) serve(in io.ReadCloser, out io.WriteCloser) {
+	defer close(r.done)
+	defer in.Close() // unblocks client writes once the simulated process is gone
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	emit := func(lines []string) bool {
+		for _, l := range lines {
+			if _, err := fmt.Fprintln(w, l); err != nil {
+				return false
+			}
+		}
+		return w.Flush() == nil
+	}
+
+	// The first recorded responses are the startup READY.
+	r.mu.Lock()
+	banner := r.responsesLocked()
+	r.mu.Unlock()
+	if !emit(banner) {
+		return
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "EXIT" {
+			return
+		}
+		if line == "VERSION" {
+			if !emit([]string{"VERSION|" + strconv.Itoa(protocolVersion)}) {
+				return
+			}
+			continue
+		}
+
+		responses, ok := r.consume(line)
+		if !ok {
+			return // trace exhausted: behave like a dead process
+		}
+		if !emit(responses) {
+			return
+		}
+	}
+}
+
+// consume matches a command sent by the client against the next recorded
+// command and returns the recorded responses to it.
+func (r *Replay) consume(line string) ([]string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.nextSend()
+	if i < 0 {
+		return nil, false
+	}
+	r.exchanges++
+	if got, want := verb(line), verb(r.entries[i].Line); got != want {
+		r.divergences = append(r.divergences, fmt.Sprintf("exchange %d: client sent %s, trace recorded %s", r.exchanges, got, want))
+	}
+	r.pos = i + 1
+	return r.responsesLocked(), true
+}
+
+// responsesLocked collects recv lines from pos up to the next send, skipping
+// READY banners left by client restarts in the original session.
+func (r *Replay) responsesLocked() []string {
+	var lines []string
+	first := r.pos == 0
+	for r.pos < len(r.entries) && r.entries[r.pos].Dir == dirRecv {
+		if l := r.entries[r.pos].Line; l != "READY" || first {
+			lines = append(lines, l)
+		}
+		r.pos++
+	}
+	return lines
+}
+
+// verb returns the command name of a protocol line ("UPDATE|a|b" -> "UPDATE").
+func verb(line string) string {
+	v, _, _ := strings.Cut(line, "|")
+	return v
+}
@@ -0,0 +1,136 @@
+package clipboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// recordTrace runs a short session against the helper process with tracing on.
+func recordTrace(t *testing.T) []TraceEntry {
+	t.Helper()
+	orig := newPSCommand
+	defer func() { newPSCommand = orig }()
+	newPSCommand = helperCommand(t, "HELPER_CHECK_BEHAVIOR=IMAGE")
+
+	var buf bytes.Buffer
+	client, err := NewClient(testLogger(t), Options{Trace: &buf})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	if _, err := client.Check(); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if err := client.UpdateClipboard("/tmp/a.png", `C:\a.png`); err != nil {
+		t.Fatalf("UpdateClipboard() error: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	entries, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatalf("ReadTrace() error: %v", err)
+	}
+	return entries
+}
+
+func TestTrace_RecordsProtocol(t *testing.T) {
+	entries := recordTrace(t)
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Dir+":"+verb(e.Line))
+	}
+	want := []string{
+		"recv:READY", "send:VERSION", "recv:VERSION",
+		"send:CHECK", "recv:SEQ", "recv:WINDOW", "recv:HASH", "send:FETCH", "recv:IMAGE",
+		"recv:" + entries[9].Line, "recv:" + entries[10].Line, "recv:" + entries[11].Line, "recv:" + entries[12].Line, // base64 frames
+		"recv:END",
+		"send:UPDATE", "recv:OK",
+		"send:EXIT",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("trace = %v, want %v", got, want)
+	}
+	for _, e := range entries {
+		if e.Time.IsZero() {
+			t.Error("trace entry missing timestamp")
+		}
+	}
+}
+
+func TestReadTrace_Invalid(t *testing.T) {
+	if _, err := ReadTrace(strings.NewReader("not json\n")); err == nil {
+		t.Error("expected error for malformed trace")
+	}
+	if _, err := ReadTrace(strings.NewReader(`{"dir":"sideways","line":"x"}` + "\n")); err == nil {
+		t.Error("expected error for unknown direction")
+	}
+}
+
+func TestReplay_ReproducesSession(t *testing.T) {
+	entries := recordTrace(t)
+
+	client, replay, err := NewReplayClient(entries, testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewReplayClient() error: %v", err)
+	}
+	defer client.Close()
+
+	data, err := client.Check()
+	if err != nil {
+		t.Fatalf("replayed Check() error: %v", err)
+	}
+	if string(data) != "fake-png-data-for-test" {
+		t.Errorf("replayed Check() = %q", data)
+	}
+	// Paths differ from the recording; only the operation must match.
+	if err := client.UpdateClipboard("/elsewhere/b.png", `D:\b.png`); err != nil {
+		t.Fatalf("replayed UpdateClipboard() error: %v", err)
+	}
+
+	if !replay.Exhausted() {
+		t.Error("replay should be exhausted after the recorded commands")
+	}
+	if d := replay.Divergences(); len(d) != 0 {
+		t.Errorf("unexpected divergences: %v", d)
+	}
+	if n := replay.Exchanges(); n != 3 { // CHECK, FETCH, UPDATE
+		t.Errorf("Exchanges() = %d, want 3", n)
+	}
+}
+
+func TestReplay_ReportsDivergenceAndExhaustion(t *testing.T) {
+	entries := []TraceEntry{
+		{Dir: dirRecv, Line: "READY"},
+		{Dir: dirSend, Line: "CHECK"},
+		{Dir: dirRecv, Line: "NONE"},
+		// A client restart in the original session leaves a second READY. The
+		// trace predates the VERSION handshake, which must not matter.
+		{Dir: dirRecv, Line: "READY"},
+		{Dir: dirSend, Line: "CHECK"},
+		{Dir: dirRecv, Line: "NONE"},
+	}
+
+	client, replay, err := NewReplayClient(entries, testLogger(t), Options{})
+	if err != nil {
+		t.Fatalf("NewReplayClient() error: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.UpdateClipboard("/a.png", `C:\a.png`); err == nil {
+		t.Error("UPDATE answered with NONE should fail")
+	}
+	if data, err := client.Check(); err != nil || data != nil {
+		t.Errorf("second Check() = %v, %v; want nil, nil (restart READY skipped)", data, err)
+	}
+	if _, err := client.Check(); err == nil {
+		t.Error("Check() past the end of the trace should fail like a dead process")
+	}
+
+	d := replay.Divergences()
+	if len(d) != 1 || !strings.Contains(d[0], "client sent UPDATE, trace recorded CHECK") {
+		t.Errorf("Divergences() = %v", d)
+	}
+}
@@ -0,0 +1,75 @@
+package clipboard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// stderrLines is how many recent stderr lines are kept for error messages.
+const stderrLines = 5
+
+// stderrTail logs everything PowerShell writes to stderr and remembers the
+// last few lines, so a protocol error can say what PowerShell complained
+// about. A nil *stderrTail does nothing.
+type stderrTail struct {
+	mu    sync.Mutex
+	lines []string
+	done  chan struct{}
+}
+
+// watchStderr starts copying r (PowerShell's stderr) to logger until it
+// reaches EOF, i.e. the process exits.
+func watchStderr(r io.Reader, logger *slog.Logger) *stderrTail {
+	t := &stderrTail{done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), "\r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			logger.Warn("[ps:stderr]", "line", line)
+			t.add(line)
+		}
+		// Keep draining past an over-long line so PowerShell never blocks
+		// writing to a full pipe.
+		_, _ = io.Copy(io.Discard, r)
+	}()
+	return t
+}
+
+func (t *stderrTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > stderrLines {
+		t.lines = t.lines[len(t.lines)-stderrLines:]
+	}
+}
+
+// wait blocks until stderr is closed. exec.Cmd.Wait must not be called
+// before all reads from the pipe are done.
+func (t *stderrTail) wait() {
+	if t != nil {
+		<-t.done
+	}
+}
+
+// annotate appends the recent stderr lines, if any, to err.
+func (t *stderrTail) annotate(err error) error {
+	if t == nil || err == nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.lines) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (powershell stderr: %s)", err, strings.Join(t.lines, " | "))
+}
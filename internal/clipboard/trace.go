@@ -0,0 +1,71 @@
+package clipboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Trace directions, from the Go client's point of view.
+const (
+	dirSend = "send" // Go -> PowerShell
+	dirRecv = "recv" // PowerShell -> Go
+)
+
+// TraceEntry is one protocol line in a recorded trace (one JSON object per line).
+type TraceEntry struct {
+	Time time.Time `json:"ts"`
+	Dir  string    `json:"dir"`
+	Line string    `json:"line"`
+}
+
+// traceWriter appends TraceEntry records to an io.Writer. A nil *traceWriter
+// records nothing. Several clients (e.g. across circuit-breaker restarts) may
+// share one underlying writer, so writes are serialized.
+type traceWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newTraceWriter(w io.Writer) *traceWriter {
+	if w == nil {
+		return nil
+	}
+	return &traceWriter{enc: json.NewEncoder(w)}
+}
+
+func (t *traceWriter) record(dir, line string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_ = t.enc.Encode(TraceEntry{Time: time.Now(), Dir: dir, Line: line}) // best-effort, tracing must never break polling
+}
+
+// ReadTrace parses a recorded trace.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e TraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", n, err)
+		}
+		if e.Dir != dirSend && e.Dir != dirRecv {
+			return nil, fmt.Errorf("trace line %d: unknown direction %q", n, e.Dir)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,104 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFake_Now(t *testing.T) {
+	f := NewFake(epoch)
+	if got := f.Now(); !got.Equal(epoch) {
+		t.Errorf("Now() = %v, want %v", got, epoch)
+	}
+	f.Advance(90 * time.Second)
+	if got := f.Now(); !got.Equal(epoch.Add(90 * time.Second)) {
+		t.Errorf("Now() after Advance = %v, want %v", got, epoch.Add(90*time.Second))
+	}
+}
+
+func TestFake_TickerFiresOnAdvance(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(100 * time.Millisecond)
+	defer tk.Stop()
+
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired before Advance")
+	default:
+	}
+
+	f.Advance(100 * time.Millisecond)
+	select {
+	case got := <-tk.C():
+		if !got.Equal(epoch.Add(100 * time.Millisecond)) {
+			t.Errorf("tick time = %v, want %v", got, epoch.Add(100*time.Millisecond))
+		}
+	default:
+		t.Fatal("ticker did not fire after Advance")
+	}
+}
+
+func TestFake_TickerDropsWhenBehind(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(time.Second)
+	defer tk.Stop()
+
+	f.Advance(5 * time.Second)
+	<-tk.C()
+	select {
+	case <-tk.C():
+		t.Fatal("expected missed ticks to be dropped")
+	default:
+	}
+}
+
+func TestFake_TickerStop(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(time.Second)
+	tk.Stop()
+	f.Advance(2 * time.Second)
+	select {
+	case <-tk.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestFake_After(t *testing.T) {
+	f := NewFake(epoch)
+	ch := f.After(time.Minute)
+
+	f.Advance(59 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("After fired early")
+	default:
+	}
+
+	f.Advance(time.Second)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("After did not fire at deadline")
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	done := make(chan struct{})
+	go func() {
+		f.BlockUntil(2)
+		close(done)
+	}()
+
+	f.NewTicker(time.Second)
+	f.After(time.Second)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("BlockUntil did not return after two waiters were registered")
+	}
+}
@@ -0,0 +1,71 @@
+// Package config centralizes parsing and validation of user-supplied settings.
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration flag value. It accepts Go duration strings
+// ("250ms", "1s", "2.5s") and, for backward compatibility, bare integers,
+// which are read as milliseconds.
+type Duration time.Duration
+
+// ParseDuration parses s as a duration string or an integer millisecond count.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 250ms, 1s, or an integer in ms)", s)
+	}
+	return d, nil
+}
+
+// Set implements pflag.Value.
+func (d *Duration) Set(s string) error {
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// String implements pflag.Value.
+func (d *Duration) String() string { return time.Duration(*d).String() }
+
+// Type implements pflag.Value.
+func (d *Duration) Type() string { return "duration" }
+
+// Interval bounds. Sub-100 ms intervals are only allowed when most ticks are
+// cheap: with sequence-number checks, where an idle tick is a single read, or
+// with --wait, where the interval is only the fallback when the helper can't
+// listen for clipboard changes.
+const (
+	MinInterval    = 100 * time.Millisecond
+	MinIntervalSeq = 20 * time.Millisecond
+	MaxInterval    = 5 * time.Second
+)
+
+// ValidateInterval checks a polling interval against the bounds allowed by the
+// selected change-detection mode.
+func ValidateInterval(d time.Duration, seqCheck, wait bool) error {
+	if seqCheck || wait {
+		if d < MinIntervalSeq || d > MaxInterval {
+			return fmt.Errorf("Interval must be between %s and %s with --wait or --seq-check (got %s)", MinIntervalSeq, MaxInterval, d)
+		}
+		return nil
+	}
+	if d >= MinIntervalSeq && d < MinInterval {
+		return fmt.Errorf("Interval must be between %s and %s (got %s); intervals down to %s require --wait", MinInterval, MaxInterval, d, MinIntervalSeq)
+	}
+	if d < MinInterval || d > MaxInterval {
+		return fmt.Errorf("Interval must be between %s and %s (got %s)", MinInterval, MaxInterval, d)
+	}
+	return nil
+}
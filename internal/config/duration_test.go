@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"250", 250 * time.Millisecond, false},
+		{"250ms", 250 * time.Millisecond, false},
+		{"1s", time.Second, false},
+		{"2.5s", 2500 * time.Millisecond, false},
+		{" 1s ", time.Second, false},
+		{"fast", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDuration(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDuration_FlagValue(t *testing.T) {
+	var d Duration
+	if err := d.Set("1500"); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+	if got := d.String(); got != "1.5s" {
+		t.Errorf("String() = %q, want %q", got, "1.5s")
+	}
+	if err := d.Set("soon"); err == nil {
+		t.Error("Set() should reject garbage")
+	}
+	if got := d.String(); got != "1.5s" {
+		t.Errorf("failed Set() changed the value to %q", got)
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		d         time.Duration
+		seq, wait bool
+		wantErr   bool
+	}{
+		{250 * ms, false, false, false},
+		{100 * ms, false, false, false},
+		{50 * ms, false, false, true},
+		{50 * ms, true, false, false},
+		{50 * ms, false, true, false},
+		{20 * ms, true, false, false},
+		{20 * ms, false, true, false},
+		{19 * ms, true, false, true},
+		{19 * ms, false, true, true},
+		{5 * time.Second, true, false, false},
+		{25 * time.Second, false, false, true},
+		{25 * time.Second, false, true, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateInterval(tt.d, tt.seq, tt.wait)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateInterval(%v, seq=%v, wait=%v) error = %v, wantErr %v", tt.d, tt.seq, tt.wait, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// EnvName returns the environment variable that overrides flag name, e.g.
+// WSL_SCREENSHOT_PS_MEMORY_LIMIT for --ps-memory-limit with prefix
+// "WSL_SCREENSHOT_".
+func EnvName(prefix, name string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// ApplyEnv sets every flag in fs that was not given on the command line from
+// its environment variable, if set and non-empty. Flags named in skip are
+// never read from the environment. lookup is normally os.LookupEnv.
+func ApplyEnv(fs *pflag.FlagSet, prefix string, lookup func(string) (string, bool), skip ...string) error {
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		for _, s := range skip {
+			if f.Name == s {
+				return
+			}
+		}
+		env := EnvName(prefix, f.Name)
+		v, ok := lookup(env)
+		if !ok || v == "" {
+			return
+		}
+		if serr := fs.Set(f.Name, v); serr != nil {
+			err = fmt.Errorf("%s: %w", env, serr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("WSL_SCREENSHOT_", "ps-memory-limit"); got != "WSL_SCREENSHOT_PS_MEMORY_LIMIT" {
+		t.Errorf("EnvName() = %q", got)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"WSL_SCREENSHOT_OUTPUT":   "/tmp/env",
+		"WSL_SCREENSHOT_VERBOSE":  "1",
+		"WSL_SCREENSHOT_INTERVAL": "", // empty counts as unset
+		"WSL_SCREENSHOT_DAEMON":   "true",
+		"WSL_SCREENSHOT_TIMEZONE": "UTC",
+	}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+
+	fs := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	output := fs.String("output", "/tmp/default", "")
+	verbose := fs.Bool("verbose", false, "")
+	interval := fs.String("interval", "250ms", "")
+	daemon := fs.Bool("daemon", false, "")
+	timezone := fs.String("timezone", "Local", "")
+	if err := fs.Parse([]string{"--timezone", "Europe/Paris"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyEnv(fs, "WSL_SCREENSHOT_", lookup, "daemon"); err != nil {
+		t.Fatalf("ApplyEnv() error: %v", err)
+	}
+	if *output != "/tmp/env" || !*verbose {
+		t.Errorf("output, verbose = %q, %v; want the environment values", *output, *verbose)
+	}
+	if *interval != "250ms" {
+		t.Errorf("interval = %q, empty variable should leave the default", *interval)
+	}
+	if *daemon {
+		t.Error("daemon read from the environment despite being skipped")
+	}
+	if *timezone != "Europe/Paris" {
+		t.Errorf("timezone = %q, command line should win", *timezone)
+	}
+}
+
+func TestApplyEnv_InvalidValue(t *testing.T) {
+	fs := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	fs.Bool("quiet", false, "")
+	lookup := func(string) (string, bool) { return "sometimes", true }
+	err := ApplyEnv(fs, "WSL_SCREENSHOT_", lookup)
+	if err == nil || !strings.HasPrefix(err.Error(), "WSL_SCREENSHOT_QUIET: ") {
+		t.Errorf("ApplyEnv() error = %v, want one naming the variable", err)
+	}
+}
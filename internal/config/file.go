@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// FileName is the config file looked up in the user's config directory.
+const FileName = "config.yaml"
+
+// DefaultPath returns the config file location:
+// $XDG_CONFIG_HOME/wsl-screenshot-cli/config.yaml, or ~/.config/... when
+// XDG_CONFIG_HOME is unset.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wsl-screenshot-cli", FileName), nil
+}
+
+// Load reads the config file at path. A missing file yields an empty map and
+// no error when optional is true, so the default location never has to exist.
+func Load(path string, optional bool) (map[string]string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is the user's own config file
+	if err != nil {
+		if optional && errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	values, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Parse reads settings in a flat YAML subset: one "key: value" per line,
+// keyed by flag name. Values may be quoted, lists use flow style
+// ("[backend, disk]"), and "#" starts a comment. Nesting is not supported,
+// since every setting maps onto a single command-line flag.
+func Parse(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		raw := sc.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		if raw[0] == ' ' || raw[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested values are not supported", n)
+		}
+		key, rest, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("line %d: %q is set more than once", n, key)
+		}
+		val, err := parseValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, key, err)
+		}
+		values[key] = val
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseValue unquotes a scalar or joins a flow list with commas, the form
+// pflag's slice flags accept.
+func parseValue(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	switch s[0] {
+	case '"', '\'':
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", errors.New("unterminated quoted value")
+		}
+		if tail := strings.TrimSpace(s[end+2:]); tail != "" && !strings.HasPrefix(tail, "#") {
+			return "", fmt.Errorf("unexpected %q after quoted value", tail)
+		}
+		return s[1 : end+1], nil
+	case '[':
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return "", errors.New("unterminated list")
+		}
+		var items []string
+		for _, item := range strings.Split(s[1:end], ",") {
+			item, err := parseValue(item)
+			if err != nil {
+				return "", err
+			}
+			if item != "" {
+				items = append(items, item)
+			}
+		}
+		return strings.Join(items, ","), nil
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, nil
+}
+
+// Apply sets every flag in fs named by values that was not already set on the
+// command line, so explicit flags always win over the file. Keys that fs does
+// not define are ignored; callers validate them against the full command tree.
+func Apply(fs *pflag.FlagSet, values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f := fs.Lookup(k)
+		if f == nil || f.Changed {
+			continue
+		}
+		if err := fs.Set(k, values[k]); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// FormatValue renders v for a config file line. Slice flags are written as a
+// flow list; scalars are quoted when Parse would otherwise misread them.
+func FormatValue(v string, list bool) string {
+	if list {
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, FormatValue(item, false))
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	if v == "" || strings.TrimSpace(v) != v || strings.ContainsAny(v[:1], `"'[#`) || strings.Contains(v, " #") {
+		if strings.Contains(v, `"`) {
+			return "'" + v + "'"
+		}
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// Set writes key: value into the config file at path, replacing an existing
+// line for key or appending one, and leaves comments and other settings
+// untouched. value must already be formatted with FormatValue. The file and
+// its directory are created if needed.
+func Set(path, key, value string) error {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the user's own config file
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	line := key + ": " + value
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	replaced := false
+	for i, l := range lines {
+		if k, _, ok := strings.Cut(l, ":"); ok && strings.TrimSpace(k) == key {
+			lines[i] = line
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		lines = append(lines, line)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
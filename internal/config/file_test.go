@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestParse(t *testing.T) {
+	in := `---
+# screenshots
+interval: 500ms
+output: "/mnt/c/Users/me/Shots"   # quoted, with a comment
+timezone: 'Europe/Paris'
+daily-dirs: true
+breaker-ignore: [backend, "disk"]
+empty:
+`
+	got, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := map[string]string{
+		"interval":       "500ms",
+		"output":         "/mnt/c/Users/me/Shots",
+		"timezone":       "Europe/Paris",
+		"daily-dirs":     "true",
+		"breaker-ignore": "backend,disk",
+		"empty":          "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"no colon", "interval 500ms\n", "line 1: expected"},
+		{"nested", "poller:\n  interval: 1s\n", "line 2: nested values"},
+		{"duplicate", "quiet: true\nquiet: false\n", `line 2: "quiet" is set more than once`},
+		{"unterminated quote", "output: \"/tmp\n", "unterminated quoted value"},
+		{"unterminated list", "breaker-ignore: [disk\n", "unterminated list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if values, err := Load(path, true); err != nil || len(values) != 0 {
+		t.Errorf("Load(optional) = %v, %v; want empty, nil", values, err)
+	}
+	if _, err := Load(path, false); !os.IsNotExist(err) {
+		t.Errorf("Load(required) error = %v, want not-exist", err)
+	}
+}
+
+func TestApply_FlagsWin(t *testing.T) {
+	fs := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	output := fs.String("output", "/tmp/default", "")
+	quiet := fs.Bool("quiet", false, "")
+	ignore := fs.StringSlice("breaker-ignore", nil, "")
+	if err := fs.Parse([]string{"--output", "/tmp/flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Apply(fs, map[string]string{
+		"output":         "/tmp/file",
+		"quiet":          "true",
+		"breaker-ignore": "backend,disk",
+		"other-command":  "x",
+	})
+	if err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if *output != "/tmp/flag" {
+		t.Errorf("output = %q, want the command-line value", *output)
+	}
+	if !*quiet {
+		t.Error("quiet not taken from the config file")
+	}
+	if !reflect.DeepEqual(*ignore, []string{"backend", "disk"}) {
+		t.Errorf("breaker-ignore = %v", *ignore)
+	}
+}
+
+func TestApply_InvalidValue(t *testing.T) {
+	fs := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	fs.Bool("quiet", false, "")
+	if err := Apply(fs, map[string]string{"quiet": "maybe"}); err == nil || !strings.HasPrefix(err.Error(), "quiet: ") {
+		t.Errorf("Apply() error = %v, want one naming the setting", err)
+	}
+}
+
+func TestSet_PreservesOtherLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.yaml")
+	if err := Set(path, "interval", "1s"); err != nil {
+		t.Fatalf("Set() on a missing file: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("# my settings\ninterval: 1s\nquiet: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "interval", "500ms"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "breaker-ignore", FormatValue("backend, disk", true)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# my settings\ninterval: 500ms\nquiet: true\nbreaker-ignore: [backend, disk]\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+	values, err := Load(path, false)
+	if err != nil || values["breaker-ignore"] != "backend,disk" {
+		t.Errorf("Load() = %v, %v; written list should parse back", values, err)
+	}
+}
+
+func TestFormatValue_RoundTrips(t *testing.T) {
+	for _, v := range []string{"plain", "", " padded", `say "hi"`, "[not a list", "a #b", "#x", "C:\\Users"} {
+		values, err := Parse(strings.NewReader("k: " + FormatValue(v, false) + "\n"))
+		if err != nil {
+			t.Errorf("%q: %v", v, err)
+			continue
+		}
+		if values["k"] != v {
+			t.Errorf("FormatValue(%q) parsed back as %q", v, values["k"])
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileMode is a permission-bits flag value, written in octal ("0600", "700").
+type FileMode os.FileMode
+
+// ParseFileMode parses s as octal permission bits, at most 0777.
+func ParseFileMode(s string) (os.FileMode, error) {
+	s = strings.TrimSpace(s)
+	m, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("invalid mode %q (use octal permission bits, e.g. 0600)", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// Set implements pflag.Value.
+func (m *FileMode) Set(s string) error {
+	v, err := ParseFileMode(s)
+	if err != nil {
+		return err
+	}
+	*m = FileMode(v)
+	return nil
+}
+
+// String implements pflag.Value.
+func (m *FileMode) String() string { return fmt.Sprintf("%04o", uint32(*m)) }
+
+// Type implements pflag.Value.
+func (m *FileMode) Type() string { return "mode" }
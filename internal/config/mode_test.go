@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0600", 0o600, false},
+		{"700", 0o700, false},
+		{"0o644", 0o644, false},
+		{"0800", 0, true},
+		{"01777", 0, true},
+		{"rw-------", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFileMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFileMode(%q) = %o, %v; want %o, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	m := FileMode(0o640)
+	if got := m.String(); got != "0640" {
+		t.Errorf("String() = %q, want 0640", got)
+	}
+}
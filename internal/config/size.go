@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits maps size suffixes to their multiplier. Units are binary, as in
+// the sizes the commands print ("1.5 MB" is 1.5 MiB).
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ParseSize parses a byte size such as "500MB", "1.5G" or a bare byte count.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	num := s[:strings.LastIndexAny(s, "0123456789.")+1]
+	mult, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[len(num):]))]
+	v, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500MB, 2GB or a byte count)", s)
+	}
+	return int64(v * float64(mult)), nil
+}
+
+// ParseAge parses an age such as "7d", "2w" or any Go duration string
+// ("36h", "90m"). Days and weeks are 24 and 168 hours.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		if v, err := strconv.ParseFloat(s[:n-1], 64); err == nil && v >= 0 {
+			unit := 24 * time.Hour
+			if s[n-1] == 'w' {
+				unit *= 7
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 7d, 2w or 36h)", s)
+	}
+	return d, nil
+}
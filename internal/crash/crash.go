@@ -0,0 +1,182 @@
+// Package crash turns panics in the daemon's long-running loops into crash
+// reports and restarts, instead of a process that silently disappears.
+package crash
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clipboard"
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+// Report is everything known about one panic.
+type Report struct {
+	Time      time.Time
+	PID       int
+	Component string
+	Value     any    // what was passed to panic
+	Stack     []byte // of the panicking goroutine
+	Exchanges []clipboard.TraceEntry
+}
+
+// WriteTo writes r in a plain text layout meant to be read by a human or
+// attached to a bug report.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "wsl-screenshot-cli crash report\n\n")
+	fmt.Fprintf(&b, "Time:      %s\n", r.Time.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "PID:       %d\n", r.PID)
+	fmt.Fprintf(&b, "Component: %s\n", r.Component)
+	fmt.Fprintf(&b, "Panic:     %v\n\n", r.Value)
+	fmt.Fprintf(&b, "Stack:\n%s\n", strings.TrimRight(string(r.Stack), "\n"))
+	fmt.Fprintf(&b, "\nRecent protocol exchanges (oldest first):\n")
+	if len(r.Exchanges) == 0 {
+		fmt.Fprintf(&b, "(none)\n")
+	}
+	for _, e := range r.Exchanges {
+		fmt.Fprintf(&b, "%s %s %s\n", e.Time.UTC().Format("15:04:05.000"), e.Dir, e.Line)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Save writes r to path, replacing any file there.
+func (r Report) Save(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Restart policy defaults.
+const (
+	DefaultRestartDelay = time.Second
+	DefaultMaxCrashes   = 5
+	DefaultCrashWindow  = 10 * time.Minute
+)
+
+// Supervisor runs a loop, recovering from panics in it: each panic is saved
+// as a Report, logged, and followed by a restart after a delay that doubles
+// with every crash inside CrashWindow. A loop that keeps crashing is given
+// up on rather than restarted forever.
+type Supervisor struct {
+	Component string
+	Logger    *slog.Logger
+	Clock     clock.Clock // nil means the wall clock
+
+	// Path names the report file for a crash at t.
+	Path func(t time.Time) string
+
+	// Exchanges, if set, supplies the recent protocol lines for reports.
+	Exchanges func() []clipboard.TraceEntry
+
+	// OnCrash, if set, is called after each crash with the panic value and
+	// the path the report was saved to ("" if saving failed).
+	OnCrash func(value any, path string)
+
+	RestartDelay time.Duration // zero means DefaultRestartDelay
+	MaxCrashes   int           // zero means DefaultMaxCrashes
+	CrashWindow  time.Duration // zero means DefaultCrashWindow
+}
+
+// Run calls fn until it returns without panicking, ctx is cancelled, or the
+// loop has crashed MaxCrashes times within CrashWindow. It returns fn's error,
+// ctx's error, or an error describing the last crash.
+func (s *Supervisor) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	clk := s.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	delay := s.RestartDelay
+	if delay <= 0 {
+		delay = DefaultRestartDelay
+	}
+	maxCrashes := s.MaxCrashes
+	if maxCrashes <= 0 {
+		maxCrashes = DefaultMaxCrashes
+	}
+	window := s.CrashWindow
+	if window <= 0 {
+		window = DefaultCrashWindow
+	}
+
+	var crashes []time.Time
+	for {
+		value, stack, err := call(ctx, fn)
+		if stack == nil {
+			return err
+		}
+
+		now := clk.Now()
+		path := s.save(Report{
+			Time:      now,
+			PID:       os.Getpid(),
+			Component: s.Component,
+			Value:     value,
+			Stack:     stack,
+			Exchanges: s.exchanges(),
+		})
+		if s.OnCrash != nil {
+			s.OnCrash(value, path)
+		}
+
+		crashes = append(crashes, now)
+		for len(crashes) > 0 && now.Sub(crashes[0]) >= window {
+			crashes = crashes[1:]
+		}
+		if len(crashes) >= maxCrashes {
+			s.Logger.Error("Giving up after repeated panics", "panic", fmt.Sprint(value), "crashes", len(crashes), "window", window, "report", path)
+			return fmt.Errorf("%s panicked %d times within %s, last: %v", s.Component, len(crashes), window, value)
+		}
+
+		wait := delay << (len(crashes) - 1)
+		s.Logger.Error("Recovered from panic, restarting", "panic", fmt.Sprint(value), "report", path, "in", wait)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clk.After(wait):
+		}
+	}
+}
+
+// call runs fn, turning a panic into its value and stack trace.
+func call(ctx context.Context, fn func(ctx context.Context) error) (value any, stack []byte, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			value, stack = v, debug.Stack()
+		}
+	}()
+	return nil, nil, fn(ctx)
+}
+
+func (s *Supervisor) exchanges() []clipboard.TraceEntry {
+	if s.Exchanges == nil {
+		return nil
+	}
+	return s.Exchanges()
+}
+
+// save writes r where Path says and returns the path, or "" if that failed.
+func (s *Supervisor) save(r Report) string {
+	if s.Path == nil {
+		return ""
+	}
+	path := s.Path(r.Time)
+	if err := r.Save(path); err != nil {
+		s.Logger.Error("Could not write crash report", "path", path, "err", err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,126 @@
+package crash
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clipboard"
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRun_RestartsAfterPanicAndWritesReport(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	var crashed []string
+	s := &Supervisor{
+		Component: "poller",
+		Logger:    testLogger(),
+		Clock:     clk,
+		Path:      func(time.Time) string { return filepath.Join(dir, "crash") },
+		Exchanges: func() []clipboard.TraceEntry {
+			return []clipboard.TraceEntry{{Time: clk.Now(), Dir: "send", Line: "CHECK"}}
+		},
+		OnCrash: func(value any, path string) { crashed = append(crashed, path) },
+	}
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			return errors.New("stopped")
+		})
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(DefaultRestartDelay)
+	if err := <-done; err == nil || err.Error() != "stopped" {
+		t.Fatalf("Run() = %v, want the second run's error", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if len(crashed) != 1 || crashed[0] != filepath.Join(dir, "crash") {
+		t.Errorf("OnCrash paths = %q", crashed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "crash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{"Component: poller", "Panic:     boom", "crash_test.go", "12:00:00.000 send CHECK"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRun_GivesUpAfterRepeatedPanics(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	s := &Supervisor{Component: "poller", Logger: testLogger(), Clock: clk, MaxCrashes: 3}
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(context.Background(), func(ctx context.Context) error {
+			calls++
+			panic("boom")
+		})
+	}()
+
+	// The delay doubles with each crash.
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Second)
+
+	err := <-done
+	if err == nil || !strings.Contains(err.Error(), "panicked 3 times") {
+		t.Fatalf("Run() = %v, want a give-up error", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRun_StopsWaitingOnCancel(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	s := &Supervisor{Component: "poller", Logger: testLogger(), Clock: clk}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, func(ctx context.Context) error { panic("boom") })
+	}()
+	clk.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v, want context.Canceled", err)
+	}
+}
+
+func TestRun_NoPanicRunsOnce(t *testing.T) {
+	s := &Supervisor{Component: "poller", Logger: testLogger()}
+	calls := 0
+	if err := s.Run(context.Background(), func(ctx context.Context) error { calls++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
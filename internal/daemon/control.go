@@ -0,0 +1,219 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/paths"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// SocketFile is the daemon's control socket. Clients send one JSON request
+// per connection and read one JSON response, which is race-free where PID
+// files and signals are not, and can carry rich runtime state.
+var SocketFile = defaultSocketFile()
+
+// defaultSocketFile is in the per-user runtime directory, which
+// paths.RuntimeDir resolves to a private /tmp directory where there is none
+// (WSL without systemd).
+func defaultSocketFile() string {
+	return filepath.Join(paths.RuntimeDir(), "wsl-screenshot-cli.sock")
+}
+
+// controlTimeout bounds a whole control exchange, connect included.
+const controlTimeout = 5 * time.Second
+
+// ErrNoDaemon is returned by Call when no daemon is listening on SocketFile.
+var ErrNoDaemon = errors.New("no daemon is listening on the control socket")
+
+// Request is a control command.
+type Request struct {
+	Command string          `json:"command"`
+	Args    json.RawMessage `json:"args,omitempty"`
+}
+
+// Response answers a Request. Data holds the command's result when OK is set,
+// Error the reason otherwise.
+type Response struct {
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// Handler serves one control command. The result is encoded as the
+// response's data.
+type Handler func(args json.RawMessage) (any, error)
+
+var (
+	handlersMu sync.Mutex
+	handlers   = map[string]Handler{}
+)
+
+// Handle registers h for command on the control socket of daemons started
+// afterwards. The built-in commands are ping, status, config and stop.
+func Handle(command string, h Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[command] = h
+}
+
+// Call sends command with args to the running daemon and decodes the result
+// into reply, which may be nil. It returns ErrNoDaemon when nothing listens
+// on the socket, so callers can fall back to the PID file.
+func Call(command string, args, reply any) error {
+	conn, err := net.DialTimeout("unix", SocketFile, controlTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNoDaemon, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	req := Request{Command: command}
+	if args != nil {
+		if req.Args, err = json.Marshal(args); err != nil {
+			return err
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("send %s: %w", command, err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("read %s response: %w", command, err)
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	if reply == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, reply)
+}
+
+// controlServer answers requests on SocketFile for the running daemon.
+type controlServer struct {
+	ln       net.Listener
+	handlers map[string]Handler
+	logger   *slog.Logger
+	wg       sync.WaitGroup
+}
+
+// listenControl binds SocketFile, replacing a stale socket left by a daemon
+// that crashed (Run has already checked that none is running).
+func listenControl(builtins map[string]Handler, logger *slog.Logger) (*controlServer, error) {
+	_ = os.Remove(SocketFile)
+	ln, err := net.Listen("unix", SocketFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(SocketFile, 0600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+
+	s := &controlServer{ln: ln, handlers: map[string]Handler{}, logger: logger}
+	handlersMu.Lock()
+	for name, h := range handlers {
+		s.handlers[name] = h
+	}
+	handlersMu.Unlock()
+	for name, h := range builtins {
+		s.handlers[name] = h
+	}
+	return s, nil
+}
+
+// serve accepts connections until the listener is closed.
+func (s *controlServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *controlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	var req Request
+	resp := Response{}
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("bad request: %v", err)
+	} else if h, ok := s.handlers[req.Command]; !ok {
+		resp.Error = fmt.Sprintf("unknown command %q", req.Command)
+	} else if data, err := h(req.Args); err != nil {
+		resp.Error = err.Error()
+	} else if resp.Data, err = json.Marshal(data); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.OK = true
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		s.logger.Warn("Control socket reply failed", "command", req.Command, "err", err)
+	}
+}
+
+// close stops accepting, waits for in-flight requests (so a stop reply is
+// delivered before the process exits) and removes the socket.
+func (s *controlServer) close() {
+	_ = s.ln.Close()
+	s.wg.Wait()
+	_ = os.Remove(SocketFile)
+}
+
+// LiveHeartbeat asks the running daemon for a heartbeat built on the spot,
+// falling back to the last one it wrote to HeartbeatFile.
+func LiveHeartbeat() (*Heartbeat, error) {
+	var hb Heartbeat
+	if err := Call("status", nil, &hb); err == nil {
+		return &hb, nil
+	}
+	return ReadHeartbeat()
+}
+
+// stopReply is the data of a stop response.
+type stopReply struct {
+	PID             int           `json:"pid"`
+	ShutdownTimeout time.Duration `json:"shutdown_timeout,omitempty"`
+}
+
+// runControl starts the control socket for a daemon started at startedAt,
+// with built-in ping/status/config/stop commands. stop cancels the daemon through
+// cancel. It returns a function that shuts the socket down. A socket that
+// can't be created is logged; signals and the PID file still work.
+func runControl(cancel func(), startedAt time.Time, counters *stats.Counters, logger *slog.Logger) func() {
+	s, err := listenControl(map[string]Handler{
+		"ping": func(json.RawMessage) (any, error) { return "pong", nil },
+		"status": func(json.RawMessage) (any, error) {
+			return currentHeartbeat(startedAt, counters), nil
+		},
+		"config": func(json.RawMessage) (any, error) {
+			return currentState(), nil
+		},
+		"stop": func(json.RawMessage) (any, error) {
+			logger.Info("Stop requested over the control socket")
+			cancel()
+			return stopReply{PID: os.Getpid(), ShutdownTimeout: ShutdownTimeout}, nil
+		},
+	}, logger)
+	if err != nil {
+		logger.Warn("Control socket unavailable, using the PID file only", "err", err)
+		return func() {}
+	}
+	go s.serve()
+	return s.close
+}
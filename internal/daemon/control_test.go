@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// runControlled starts Run with a poll function that blocks until the daemon
+// is stopped, and waits for the control socket to answer.
+func runControlled(t *testing.T) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(context.Background(), 250, t.TempDir(), func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for Call("ping", nil, nil) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("control socket never answered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return done
+}
+
+func TestControl_StatusAndStop(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	StartSettings = map[string]string{"interval": "250ms"}
+	defer func() { StartSettings = nil }()
+	Handle("echo", func(args json.RawMessage) (any, error) {
+		var s string
+		err := json.Unmarshal(args, &s)
+		return s, err
+	})
+	defer func() { handlersMu.Lock(); delete(handlers, "echo"); handlersMu.Unlock() }()
+
+	done := runControlled(t)
+
+	var hb Heartbeat
+	if err := Call("status", nil, &hb); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if hb.PID != os.Getpid() {
+		t.Errorf("status PID = %d, want %d", hb.PID, os.Getpid())
+	}
+
+	var st State
+	if err := Call("config", nil, &st); err != nil || st.Settings["interval"] != "250ms" {
+		t.Errorf("config = %+v, %v; want the daemon's settings", st, err)
+	}
+
+	var echoed string
+	if err := Call("echo", "hi", &echoed); err != nil || echoed != "hi" {
+		t.Errorf("echo = %q, %v; want the registered handler's result", echoed, err)
+	}
+	if err := Call("bogus", nil, nil); err == nil {
+		t.Error("an unknown command should fail")
+	}
+
+	Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop over the control socket did not end Run")
+	}
+	if _, err := os.Stat(SocketFile); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed on exit, stat err = %v", err)
+	}
+}
+
+func TestCall_NoDaemon(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	if err := Call("status", nil, nil); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("Call() = %v, want ErrNoDaemon", err)
+	}
+}
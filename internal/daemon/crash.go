@@ -0,0 +1,12 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// CrashFile names the crash report for a panic at t. It sits next to the log
+// so whoever goes looking there finds it too.
+func CrashFile(t time.Time) string {
+	return fmt.Sprintf("%s.crash-%s", LogFile, t.UTC().Format("20060102T150405Z"))
+}
@@ -4,35 +4,69 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/logging"
+	"github.com/nailuu/wsl-screenshot-cli/internal/paths"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
 )
 
 // Output is the writer for user-facing messages. Tests can set it to io.Discard.
 var Output io.Writer = os.Stdout
 
-var PidFile = "/tmp/.wsl-screenshot-cli.pid"
-var LogFile = "/tmp/.wsl-screenshot-cli.log"
-var StateFile = "/tmp/.wsl-screenshot-cli.state"
-var DefaultOutputDir = "/tmp/.wsl-screenshot-cli/"
+// The daemon's files, resolved by package paths: what only matters while it
+// runs in the runtime directory, its log and history in the state
+// directory.
+var PidFile = filepath.Join(paths.RuntimeDir(), "wsl-screenshot-cli.pid")
+var LogFile = filepath.Join(paths.StateDir(), "wsl-screenshot-cli.log")
+var StateFile = filepath.Join(paths.StateDir(), "wsl-screenshot-cli.state")
+var HeartbeatFile = filepath.Join(paths.RuntimeDir(), "wsl-screenshot-cli.heartbeat")
+var EventsFile = filepath.Join(paths.StateDir(), "wsl-screenshot-cli.events")
 
-// readOutputDir reads the persisted output directory from the state file,
-// falling back to DefaultOutputDir if the file is missing or empty.
-func readOutputDir() string {
-	data, err := os.ReadFile(StateFile)
-	if err != nil {
-		return DefaultOutputDir
+// DefaultOutputDir is in the user's data directory, so screenshots of
+// different users on one machine never share a directory.
+var DefaultOutputDir = filepath.Join(paths.DataDir(), "screenshots") + "/"
+
+// ensureDirs creates the directories of the daemon's files.
+func ensureDirs() error {
+	for _, file := range []string{PidFile, LogFile, StateFile, HeartbeatFile, EventsFile, SocketFile} {
+		if err := paths.EnsureDir(filepath.Dir(file)); err != nil {
+			return fmt.Errorf("Failed to create the daemon's directory: %w", err)
+		}
 	}
-	dir := strings.TrimSpace(string(data))
-	if dir == "" {
+	return nil
+}
+
+// LogLevel is the minimum level the daemon logs at. It can change while the
+// daemon runs (e.g. --verbose toggled by a reload).
+var LogLevel = new(slog.LevelVar)
+
+// LogFormat is the daemon log's format, logging.FormatText or FormatJSON.
+var LogFormat = logging.FormatText
+
+// Clock is the time source for the daemon's periodic work. Tests replace it
+// with a clock.Fake.
+var Clock clock.Clock = clock.Real{}
+
+// ReadOutputDir reads the persisted output directory from the state file,
+// falling back to DefaultOutputDir if the file is missing or empty.
+func ReadOutputDir() string {
+	st, err := ReadState()
+	if err != nil || st.OutputDir == "" {
 		return DefaultOutputDir
 	}
-	return dir
+	return st.OutputDir
 }
 
 // RunningPID returns the PID of the running process, or 0 if not running.
@@ -65,39 +99,35 @@ func RunningPID() int {
 }
 
 // newDaemonCmd builds the exec.Cmd for the re-exec daemon process.
+// args are the flags forwarded to the child's foreground "start" command.
 // Declared as a var so tests can override it with a fake process.
-var newDaemonCmd = func(interval int, outputDir string, verbose bool) (*exec.Cmd, error) {
+var newDaemonCmd = func(args []string) (*exec.Cmd, error) {
 	exe, err := os.Executable()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get executable path: %w", err)
 	}
 
-	outputDir = filepath.Clean(outputDir)
-	args := []string{"start",
-		"--interval", strconv.Itoa(interval),
-		"--output", outputDir,
-	}
-	if verbose {
-		args = append(args, "--verbose")
-	}
-
-	cmd := exec.Command(exe, args...) // #nosec G204 -- exe from os.Executable(), args are argv-separated (no shell)
+	cmd := exec.Command(exe, append([]string{"start"}, args...)...) // #nosec G204 -- exe from os.Executable(), args are argv-separated (no shell)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	return cmd, nil
 }
 
-// Daemonize launches a detached background process via re-exec.
-func Daemonize(interval int, outputDir string, verbose bool) error {
+// Daemonize launches a detached background process via re-exec. args are the
+// "start" flags the child runs with (everything except --daemon itself).
+func Daemonize(args []string) error {
 	if pid := RunningPID(); pid != 0 {
-		fmt.Fprintf(Output, "Polling process is already running (PID %d)\n", pid)
+		fmt.Fprintln(Output, i18n.T("daemon.already_running", pid))
 		return nil
 	}
 
-	child, err := newDaemonCmd(interval, outputDir, verbose)
+	child, err := newDaemonCmd(args)
 	if err != nil {
 		return err
 	}
 
+	if err := ensureDirs(); err != nil {
+		return err
+	}
 	logF, err := os.OpenFile(LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 	if err != nil {
 		return fmt.Errorf("Failed to open log file: %w", err)
@@ -111,60 +141,220 @@ func Daemonize(interval int, outputDir string, verbose bool) error {
 	}
 	_ = logF.Close()
 
-	fmt.Fprintf(Output, "Polling process started (PID %d). Run 'wsl-screenshot-cli status' to check status.\n", child.Process.Pid)
+	fmt.Fprintln(Output, i18n.T("daemon.started", child.Process.Pid))
 	return nil
 }
 
-// Run writes the PID file, runs pollFn, and cleans up on exit.
-func Run(ctx context.Context, interval int, outputDir string, pollFn func(ctx context.Context, logger *log.Logger) error) error {
+// PollFunc runs the poll loop until ctx is cancelled, recording activity in
+// counters. logger is the daemon's base logger, for the poll loop to tag with
+// its own components. reload receives a value each time the daemon is asked to re-read
+// its configuration (SIGHUP).
+type PollFunc func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error
+
+// Run writes the PID file, runs pollFn while keeping the heartbeat file fresh
+// and serving the control socket, and cleans up on exit.
+func Run(ctx context.Context, interval int, outputDir string, pollFn PollFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	if pid := RunningPID(); pid != 0 {
-		fmt.Fprintf(Output, "Polling process is already running (PID %d)\n", pid)
+		fmt.Fprintln(Output, i18n.T("daemon.already_running", pid))
 		return nil
 	}
 
+	if err := ensureDirs(); err != nil {
+		return err
+	}
 	if err := os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0600); err != nil {
 		return fmt.Errorf("Failed to write PID file: %w", err)
 	}
-	defer os.Remove(PidFile)
+	defer removePidFile()
+
+	if err := writeState(State{
+		OutputDir:       outputDir,
+		Interval:        time.Duration(interval) * time.Millisecond,
+		Verbose:         StartVerbose,
+		Args:            StartArgs,
+		Settings:        StartSettings,
+		Sources:         StartSources,
+		CaseInsensitive: StartCaseInsensitive,
+	}); err != nil {
+		return err
+	}
+	keepState := false
+	defer func() {
+		if !keepState {
+			_ = os.Remove(StateFile)
+		}
+	}()
+
+	startedAt := Clock.Now()
+	if err := register(Instance{
+		Name:          InstanceName,
+		PID:           os.Getpid(),
+		StartedAt:     startedAt,
+		OutputDir:     outputDir,
+		LogFile:       LogFile,
+		HeartbeatFile: HeartbeatFile,
+		Args:          StartArgs,
+	}); err != nil {
+		return fmt.Errorf("Failed to register instance: %w", err)
+	}
+	defer unregister(InstanceName)
+	left := cleanLeftovers(outputDir)
+
+	counters := &stats.Counters{}
+	hbCtx, stopHeartbeat := context.WithCancel(ctx)
+	var hbDone sync.WaitGroup
+	hbDone.Add(1)
+	go func() {
+		defer hbDone.Done()
+		runHeartbeat(hbCtx, startedAt, counters)
+	}()
+	defer os.Remove(HeartbeatFile)
+	defer hbDone.Wait()
+	defer stopHeartbeat()
 
-	if err := os.WriteFile(StateFile, []byte(outputDir), 0600); err != nil {
-		return fmt.Errorf("Failed to write state file: %w", err)
+	logOut := Output
+	if rot := openRotatingLog(); rot != nil {
+		defer rot.Close()
+		logOut = rot
 	}
-	defer os.Remove(StateFile)
+	base := logging.New(logOut, LogFormat, LogLevel)
+	logger := logging.Component(base, "daemon")
+	left.log(logger)
+	defer runControl(cancel, startedAt, counters, logger)()
 
-	logger := log.New(Output, "", log.LstdFlags|log.Lmicroseconds)
-	logger.Printf("Polling process started successfully (PID %d)", os.Getpid())
-	return pollFn(ctx, logger)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	reload := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("Received SIGHUP, reloading configuration")
+				select {
+				case reload <- struct{}{}:
+				default: // a reload is already pending
+				}
+			}
+		}
+	}()
+
+	logger.Info("Polling process started", "pid", os.Getpid())
+	err := runPoll(ctx, logger, func() error { return pollFn(ctx, base, counters, reload) })
+	if err != nil && ctx.Err() == nil {
+		// Nobody asked it to stop: keep the state file saying why.
+		keepState = recordExit(err) == nil
+	}
+	return err
 }
 
-// Stop sends SIGTERM to the running daemon and cleans up the PID file.
+// stopGrace is how much longer than its shutdown timeout Stop gives a daemon
+// to exit before killing it.
+const stopGrace = 5 * time.Second
+
+// ShutdownTimeout bounds how long Run waits, once asked to stop, for the poll
+// loop to finish in-flight work and close its PowerShell client. Past it the
+// backends are killed and Run returns regardless. Zero waits forever.
+var ShutdownTimeout = 10 * time.Second
+
+// forcedExitGrace is how long Run still waits for the poll loop after killing
+// the backends, which normally unblocks whatever call it was stuck in.
+var forcedExitGrace = 2 * time.Second
+
+// runPoll runs poll until it returns. Once ctx is cancelled it gives poll
+// ShutdownTimeout to return, then kills the clipboard backends and gives up
+// on it forcedExitGrace later, so a hung clipboard call can't keep the
+// daemon alive after stop.
+func runPoll(ctx context.Context, logger *slog.Logger, poll func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- poll() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if ShutdownTimeout <= 0 {
+		return <-done
+	}
+	start := Clock.Now()
+	select {
+	case err := <-done:
+		logger.Info("Shutdown complete", "took", Clock.Now().Sub(start).Round(time.Millisecond))
+		return err
+	case <-Clock.After(ShutdownTimeout):
+	}
+
+	killed := killBackends(os.Getpid())
+	logger.Error("Shutdown timed out, killed the PowerShell backend", "timeout", ShutdownTimeout, "killed", killed)
+	select {
+	case err := <-done:
+		logger.Warn("Shutdown forced", "took", Clock.Now().Sub(start).Round(time.Millisecond))
+		return err
+	case <-Clock.After(forcedExitGrace):
+		logger.Error("Poll loop still stuck after killing the backend, exiting without it")
+		return fmt.Errorf("shutdown timed out after %s", ShutdownTimeout)
+	}
+}
+
+// removePidFile deletes PidFile if it still names this process. A replacement
+// daemon may already have written its own PID there while this one shut down.
+func removePidFile() {
+	data, err := os.ReadFile(PidFile)
+	if err == nil && strings.TrimSpace(string(data)) == strconv.Itoa(os.Getpid()) {
+		_ = os.Remove(PidFile)
+	}
+}
+
+// Stop asks the running daemon to shut down over the control socket and waits
+// for it to exit, killing it if it overruns its shutdown timeout. When the
+// socket doesn't answer it sends SIGTERM and cleans up the PID file instead.
 func Stop() {
+	var reply stopReply
+	if err := Call("stop", nil, &reply); err == nil {
+		// Wait for the exit the daemon has promised, rather than report a
+		// stop that a hung shutdown never completes. A daemon serving from
+		// this very process (tests) can't be waited for.
+		if limit := reply.ShutdownTimeout + stopGrace; reply.ShutdownTimeout > 0 && reply.PID != os.Getpid() && !WaitForExit(reply.PID, limit) {
+			_ = killProcess(reply.PID, syscall.SIGKILL)
+			fmt.Fprintln(Output, i18n.T("daemon.killed", reply.PID, limit))
+			return
+		}
+		fmt.Fprintln(Output, i18n.T("daemon.stopped", reply.PID))
+		return
+	}
+
 	data, err := os.ReadFile(PidFile)
 	if err != nil {
-		fmt.Fprintln(Output, "Polling process is not running")
+		fmt.Fprintln(Output, i18n.T("daemon.not_running"))
 		return
 	}
 
 	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
 	if err != nil {
 		_ = os.Remove(PidFile) // best-effort cleanup
-		fmt.Fprintln(Output, "Polling process is not running. Cleaned up corrupt PID file.")
+		fmt.Fprintln(Output, i18n.T("daemon.corrupt_pid_file"))
 		return
 	}
 
 	proc, err := os.FindProcess(pid)
 	if err != nil {
 		_ = os.Remove(PidFile) // best-effort cleanup
-		fmt.Fprintln(Output, "Polling process is not running. Cleaned up stale PID file.")
+		fmt.Fprintln(Output, i18n.T("daemon.stale_pid_file"))
 		return
 	}
 
 	if err := proc.Signal(syscall.SIGTERM); err != nil {
 		_ = os.Remove(PidFile) // best-effort cleanup
-		fmt.Fprintf(Output, "Polling process was not running (PID %d). Cleaned up stale PID file.\n", pid)
+		fmt.Fprintln(Output, i18n.T("daemon.stale_pid", pid))
 		return
 	}
 
 	_ = os.Remove(PidFile) // best-effort cleanup
-	fmt.Fprintf(Output, "Polling process stopped successfully (PID %d)\n", pid)
+	fmt.Fprintln(Output, i18n.T("daemon.stopped", pid))
 }
@@ -3,8 +3,9 @@ package daemon
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +14,10 @@ import (
 	"syscall"
 	"testing"
 	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
 )
 
 // setTestPaths overrides package-level vars to use a temp dir for isolation.
@@ -23,19 +28,36 @@ func setTestPaths(t *testing.T) func() {
 	origPid := PidFile
 	origLog := LogFile
 	origState := StateFile
+	origHeartbeat := HeartbeatFile
+	origEvents := EventsFile
+	origSocket := SocketFile
+	origHistory := HistoryFile
+	origRegistry := RegistryDir
 	origDefault := DefaultOutputDir
 	origOutput := Output
 
 	PidFile = filepath.Join(tmp, "test.pid")
 	LogFile = filepath.Join(tmp, "test.log")
 	StateFile = filepath.Join(tmp, "test.state")
+	HeartbeatFile = filepath.Join(tmp, "test.heartbeat")
+	EventsFile = filepath.Join(tmp, "test.events")
+	SocketFile = filepath.Join(tmp, "test.sock")
+	HistoryFile = filepath.Join(tmp, "history.json")
+	RegistryDir = filepath.Join(tmp, "registry")
 	DefaultOutputDir = filepath.Join(tmp, "output") + "/"
 	Output = io.Discard
+	restoreLang := i18n.SetLanguage("en") // tests match English messages
 
 	return func() {
+		restoreLang()
 		PidFile = origPid
 		LogFile = origLog
 		StateFile = origState
+		HeartbeatFile = origHeartbeat
+		EventsFile = origEvents
+		SocketFile = origSocket
+		HistoryFile = origHistory
+		RegistryDir = origRegistry
 		DefaultOutputDir = origDefault
 		Output = origOutput
 	}
@@ -64,6 +86,18 @@ func TestCountScreenshots(t *testing.T) {
 			t.Errorf("countScreenshots(mixed) = %d, want 2", got)
 		}
 	})
+
+	t.Run("daily_subdirs", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "a.png"), []byte("x"), 0644)
+		for _, day := range []string{"2024-05-01", "2024-05-02"} {
+			os.MkdirAll(filepath.Join(dir, day), 0755)
+			os.WriteFile(filepath.Join(dir, day, "b.png"), []byte("x"), 0644)
+		}
+		if got := countScreenshots(dir); got != 3 {
+			t.Errorf("countScreenshots(daily) = %d, want 3", got)
+		}
+	})
 }
 
 func TestReadOutputDir(t *testing.T) {
@@ -72,22 +106,22 @@ func TestReadOutputDir(t *testing.T) {
 
 	t.Run("missing_state_file", func(t *testing.T) {
 		os.Remove(StateFile)
-		if got := readOutputDir(); got != DefaultOutputDir {
-			t.Errorf("readOutputDir() = %q, want %q", got, DefaultOutputDir)
+		if got := ReadOutputDir(); got != DefaultOutputDir {
+			t.Errorf("ReadOutputDir() = %q, want %q", got, DefaultOutputDir)
 		}
 	})
 
 	t.Run("empty_state_file", func(t *testing.T) {
 		os.WriteFile(StateFile, []byte("  \n"), 0644)
-		if got := readOutputDir(); got != DefaultOutputDir {
-			t.Errorf("readOutputDir() = %q, want %q", got, DefaultOutputDir)
+		if got := ReadOutputDir(); got != DefaultOutputDir {
+			t.Errorf("ReadOutputDir() = %q, want %q", got, DefaultOutputDir)
 		}
 	})
 
 	t.Run("valid_state_file", func(t *testing.T) {
 		os.WriteFile(StateFile, []byte("/custom/path"), 0644)
-		if got := readOutputDir(); got != "/custom/path" {
-			t.Errorf("readOutputDir() = %q, want %q", got, "/custom/path")
+		if got := ReadOutputDir(); got != "/custom/path" {
+			t.Errorf("ReadOutputDir() = %q, want %q", got, "/custom/path")
 		}
 	})
 }
@@ -149,7 +183,7 @@ func TestRun_PidAndStateLifecycle(t *testing.T) {
 	done := make(chan error, 1)
 
 	go func() {
-		done <- Run(ctx, 250, outputDir, func(ctx context.Context, logger *log.Logger) error {
+		done <- Run(ctx, 250, outputDir, func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
 			close(pollStarted)
 			<-ctx.Done()
 			return nil
@@ -191,6 +225,75 @@ func TestRun_PidAndStateLifecycle(t *testing.T) {
 	}
 }
 
+func TestRun_RecordsWhyPollingGaveUp(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	gaveUp := &ExitError{Code: 3, Err: errors.New("too many restarts")}
+	err := Run(context.Background(), 250, t.TempDir(), func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+		return gaveUp
+	})
+	if !errors.Is(err, gaveUp) {
+		t.Fatalf("Run() = %v, want the poll error", err)
+	}
+	exit := LastExit()
+	if exit == nil {
+		t.Fatal("LastExit() = nil, want the recorded exit")
+	}
+	if exit.Code != 3 || exit.Reason != "too many restarts" || exit.Time.IsZero() {
+		t.Errorf("LastExit() = %+v, want status 3 with the reason", exit)
+	}
+
+	// A clean stop leaves nothing to explain.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Run(ctx, 250, t.TempDir(), func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if exit := LastExit(); exit != nil {
+		t.Errorf("LastExit() after a clean stop = %+v, want nil", exit)
+	}
+}
+
+func TestRun_SIGHUPTriggersReload(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Run(ctx, 250, t.TempDir(), func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+				return err
+			}
+			select {
+			case <-reload:
+				close(reloaded)
+			case <-time.After(5 * time.Second):
+			}
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	select {
+	case <-reloaded:
+	case err := <-done:
+		t.Fatalf("Run exited early: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP was not delivered on the reload channel")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+}
+
 func TestRun_AlreadyRunning(t *testing.T) {
 	cleanup := setTestPaths(t)
 	defer cleanup()
@@ -199,7 +302,7 @@ func TestRun_AlreadyRunning(t *testing.T) {
 	os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
 
 	pollCalled := false
-	err := Run(context.Background(), 250, t.TempDir(), func(ctx context.Context, logger *log.Logger) error {
+	err := Run(context.Background(), 250, t.TempDir(), func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
 		pollCalled = true
 		return nil
 	})
@@ -273,9 +376,9 @@ func TestHelperProcess(t *testing.T) {
 
 // helperDaemonCmd returns a newDaemonCmd override that spawns a TestHelperProcess
 // instead of re-execing the real binary.
-func helperDaemonCmd(t *testing.T) func(int, string, bool) (*exec.Cmd, error) {
+func helperDaemonCmd(t *testing.T) func([]string) (*exec.Cmd, error) {
 	t.Helper()
-	return func(interval int, outputDir string, verbose bool) (*exec.Cmd, error) {
+	return func(args []string) (*exec.Cmd, error) {
 		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
 		cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
 		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
@@ -294,7 +397,7 @@ func TestDaemonize_StartsProcess(t *testing.T) {
 	var buf bytes.Buffer
 	Output = &buf
 
-	err := Daemonize(250, t.TempDir(), false)
+	err := Daemonize([]string{"--interval", "250", "--output", t.TempDir()})
 	if err != nil {
 		t.Fatalf("Daemonize() error: %v", err)
 	}
@@ -320,7 +423,7 @@ func TestDaemonize_AlreadyRunning(t *testing.T) {
 	var buf bytes.Buffer
 	Output = &buf
 
-	err := Daemonize(250, t.TempDir(), false)
+	err := Daemonize([]string{"--interval", "250", "--output", t.TempDir()})
 	if err != nil {
 		t.Fatalf("Daemonize() error: %v", err)
 	}
@@ -329,3 +432,113 @@ func TestDaemonize_AlreadyRunning(t *testing.T) {
 		t.Errorf("expected 'already running' message, got: %q", buf.String())
 	}
 }
+
+func TestRemovePidFile_KeepsReplacementsPid(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	os.WriteFile(PidFile, []byte("999999"), 0600)
+	removePidFile()
+	if _, err := os.Stat(PidFile); err != nil {
+		t.Error("PID file of another process was removed")
+	}
+
+	os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0600)
+	removePidFile()
+	if _, err := os.Stat(PidFile); !os.IsNotExist(err) {
+		t.Error("own PID file was not removed")
+	}
+}
+
+// overrideShutdown runs runPoll tests against a fake clock and /proc, with
+// one live backend (PID 101) and one zombie (PID 102) owned by this process.
+// kill is called instead of signalling.
+func overrideShutdown(t *testing.T, kill func(pid int)) *clock.Fake {
+	t.Helper()
+	root := t.TempDir()
+	overrideProcRoot(t, root)
+	writeProc(t, root, 101, os.Getpid(), "S", "powershell.exe", "powershell.exe MARKER")
+	writeProc(t, root, 102, os.Getpid(), "Z", "powershell.exe", "")
+
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	origClock, origKill, origTimeout := Clock, killProcess, ShutdownTimeout
+	Clock, ShutdownTimeout = clk, 10*time.Second
+	killProcess = func(pid int, sig syscall.Signal) error {
+		kill(pid)
+		return nil
+	}
+	t.Cleanup(func() { Clock, killProcess, ShutdownTimeout = origClock, origKill, origTimeout })
+	return clk
+}
+
+func TestRunPoll_CleanShutdown(t *testing.T) {
+	overrideShutdown(t, func(pid int) { t.Errorf("killed PID %d on a clean shutdown", pid) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := runPoll(ctx, slog.New(slog.DiscardHandler), func() error {
+		<-ctx.Done()
+		return nil
+	})
+	if err != nil {
+		t.Errorf("runPoll() = %v", err)
+	}
+}
+
+func TestRunPoll_KillsBackendOfHungLoop(t *testing.T) {
+	unblock := make(chan struct{})
+	var killed []int
+	clk := overrideShutdown(t, func(pid int) {
+		killed = append(killed, pid)
+		close(unblock) // the hung clipboard call fails once its backend dies
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runPoll(ctx, slog.New(slog.DiscardHandler), func() error {
+			<-unblock
+			return nil
+		})
+	}()
+	cancel()
+	clk.BlockUntil(1)
+	clk.Advance(9 * time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("runPoll returned before the shutdown timeout: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	clk.Advance(time.Second)
+
+	if err := <-done; err != nil {
+		t.Errorf("runPoll() = %v, want the loop's own result", err)
+	}
+	if len(killed) != 1 || killed[0] != 101 {
+		t.Errorf("killed = %v, want only the live backend 101", killed)
+	}
+}
+
+func TestRunPoll_GivesUpOnStuckLoop(t *testing.T) {
+	clk := overrideShutdown(t, func(int) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stuck := make(chan struct{})
+	defer close(stuck)
+	done := make(chan error, 1)
+	go func() {
+		done <- runPoll(ctx, slog.New(slog.DiscardHandler), func() error {
+			<-stuck
+			return nil
+		})
+	}()
+	cancel()
+	clk.BlockUntil(1)
+	clk.Advance(ShutdownTimeout)
+	clk.BlockUntil(1)
+	clk.Advance(forcedExitGrace)
+
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "shutdown timed out after 10s") {
+		t.Errorf("runPoll() = %v, want a shutdown timeout error", err)
+	}
+}
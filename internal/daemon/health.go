@@ -0,0 +1,77 @@
+package daemon
+
+import "time"
+
+// HealthState is how far along the daemon is towards doing useful work. Each
+// state implies the ones before it.
+type HealthState int
+
+const (
+	// HealthDown: no daemon process is running.
+	HealthDown HealthState = iota
+	// HealthAlive: the process runs, but its clipboard backend is not (yet)
+	// serving requests, or no fresh heartbeat says otherwise.
+	HealthAlive
+	// HealthReady: the backend is up, but the last poll failed or no poll
+	// has completed yet.
+	HealthReady
+	// HealthHealthy: the last poll succeeded.
+	HealthHealthy
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthAlive:
+		return "alive"
+	case HealthReady:
+		return "ready"
+	case HealthHealthy:
+		return "healthy"
+	default:
+		return "down"
+	}
+}
+
+// Health separates the three questions probes ask: is the process alive, is
+// the clipboard backend ready, and did the last poll succeed.
+type Health struct {
+	Alive        bool      `json:"alive"`
+	BackendReady bool      `json:"backend_ready"`
+	LastPollOK   bool      `json:"last_poll_ok"`
+	LastPoll     time.Time `json:"last_poll,omitzero"`
+}
+
+// State folds the three checks into the furthest state reached.
+func (h Health) State() HealthState {
+	switch {
+	case !h.Alive:
+		return HealthDown
+	case !h.BackendReady:
+		return HealthAlive
+	case !h.LastPollOK:
+		return HealthReady
+	default:
+		return HealthHealthy
+	}
+}
+
+// CheckHealth reports the running daemon's health. Readiness and poll results
+// come from the live heartbeat (see Status) and are only trusted while it is
+// fresh and belongs to the running PID.
+func CheckHealth() Health {
+	pid := RunningPID()
+	if pid == 0 {
+		return Health{}
+	}
+	h := Health{Alive: true}
+	if hb, err := LiveHeartbeat(); err == nil && hb.PID == pid && hb.Fresh(Clock.Now()) {
+		h.fromHeartbeat(hb)
+	}
+	return h
+}
+
+func (h *Health) fromHeartbeat(hb *Heartbeat) {
+	h.BackendReady = hb.Stats.BackendReady
+	h.LastPollOK = hb.Stats.BackendReady && hb.Stats.LastPollOK
+	h.LastPoll = hb.Stats.LastPoll
+}
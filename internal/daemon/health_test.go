@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+func TestHealth_State(t *testing.T) {
+	tests := []struct {
+		h    Health
+		want HealthState
+	}{
+		{Health{}, HealthDown},
+		{Health{Alive: true}, HealthAlive},
+		{Health{Alive: true, LastPollOK: true}, HealthAlive}, // a poll result without a backend is stale
+		{Health{Alive: true, BackendReady: true}, HealthReady},
+		{Health{Alive: true, BackendReady: true, LastPollOK: true}, HealthHealthy},
+	}
+	for _, tt := range tests {
+		if got := tt.h.State(); got != tt.want {
+			t.Errorf("%+v.State() = %v, want %v", tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useFakeClock(t, now)
+
+	if got := CheckHealth().State(); got != HealthDown {
+		t.Errorf("no daemon: state = %v, want down", got)
+	}
+
+	os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
+	if got := CheckHealth().State(); got != HealthAlive {
+		t.Errorf("no heartbeat: state = %v, want alive", got)
+	}
+
+	beat := func(updated time.Time, s stats.Snapshot) {
+		t.Helper()
+		if err := writeHeartbeat(Heartbeat{PID: os.Getpid(), UpdatedAt: updated, Stats: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	beat(now, stats.Snapshot{BackendReady: true, LastPoll: now.Add(-time.Second)})
+	if got := CheckHealth().State(); got != HealthReady {
+		t.Errorf("last poll failed: state = %v, want ready", got)
+	}
+
+	beat(now, stats.Snapshot{BackendReady: true, LastPoll: now.Add(-time.Second), LastPollOK: true})
+	h := CheckHealth()
+	if h.State() != HealthHealthy || !h.LastPoll.Equal(now.Add(-time.Second)) {
+		t.Errorf("healthy heartbeat: got %+v", h)
+	}
+
+	beat(now.Add(-time.Hour), stats.Snapshot{BackendReady: true, LastPollOK: true})
+	if got := CheckHealth().State(); got != HealthAlive {
+		t.Errorf("stale heartbeat: state = %v, want alive", got)
+	}
+}
@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// heartbeatInterval is how often the running daemon refreshes HeartbeatFile.
+const heartbeatInterval = 5 * time.Second
+
+// heartbeatStaleAfter is how old a heartbeat may get before readers treat the
+// daemon as down. A few missed beats are tolerated for a busy or paused VM.
+const heartbeatStaleAfter = 3 * heartbeatInterval
+
+// Heartbeat is the snapshot the daemon periodically writes to HeartbeatFile.
+// It lets latency-sensitive readers (status --prompt) report liveness and
+// counters from a single small file, without /proc parsing.
+type Heartbeat struct {
+	PID       int            `json:"pid"`
+	StartedAt time.Time      `json:"started_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Runtime   RuntimeStats   `json:"runtime"`
+	Stats     stats.Snapshot `json:"stats"`
+}
+
+// RuntimeStats are process metrics the daemon measures about itself, without
+// procfs, so status still has real numbers when /proc is restricted.
+type RuntimeStats struct {
+	CPUSeconds float64 `json:"cpu_seconds"` // user+system, from getrusage
+	MemoryKB   int64   `json:"memory_kb"`   // memory obtained from the OS by the Go runtime
+}
+
+// readRuntimeStats measures the current process.
+func readRuntimeStats() RuntimeStats {
+	var rs RuntimeStats
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err == nil {
+		rs.CPUSeconds = timevalSeconds(ru.Utime) + timevalSeconds(ru.Stime)
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	rs.MemoryKB = int64(ms.Sys / 1024) // #nosec G115 -- Sys is far below MaxInt64
+	return rs
+}
+
+func timevalSeconds(tv syscall.Timeval) float64 {
+	return float64(tv.Sec) + float64(tv.Usec)/1e6
+}
+
+// Fresh reports whether the heartbeat was updated recently enough, relative
+// to now, for the daemon to be considered alive.
+func (h *Heartbeat) Fresh(now time.Time) bool {
+	return now.Sub(h.UpdatedAt) <= heartbeatStaleAfter
+}
+
+// ReadHeartbeat loads the last heartbeat written by the daemon.
+func ReadHeartbeat() (*Heartbeat, error) {
+	return readHeartbeatFile(HeartbeatFile)
+}
+
+func readHeartbeatFile(path string) (*Heartbeat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h Heartbeat
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("parse heartbeat: %w", err)
+	}
+	return &h, nil
+}
+
+// writeHeartbeat atomically replaces HeartbeatFile so concurrent readers never
+// observe a partially written file.
+func writeHeartbeat(h Heartbeat) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp := HeartbeatFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, HeartbeatFile)
+}
+
+// currentHeartbeat builds a heartbeat for this process as of now.
+func currentHeartbeat(startedAt time.Time, counters *stats.Counters) Heartbeat {
+	return Heartbeat{
+		PID:       os.Getpid(),
+		StartedAt: startedAt,
+		UpdatedAt: Clock.Now(),
+		Runtime:   readRuntimeStats(),
+		Stats:     counters.Snapshot(),
+	}
+}
+
+// runHeartbeat writes a heartbeat immediately and then every heartbeatInterval
+// until ctx is cancelled. Every sweepEvery beats it also sweeps for zombie
+// and orphaned clipboard backends, and every historyEvery beats (and on
+// exit) it rolls the counters up into HistoryFile.
+func runHeartbeat(ctx context.Context, startedAt time.Time, counters *stats.Counters) {
+	beat := func() {
+		_ = writeHeartbeat(currentHeartbeat(startedAt, counters)) // best-effort, readers treat a missing file as down
+	}
+
+	var history historyRollup
+	defer history.flush(counters)
+	sweep := newBackendSweep()
+	sweepBackends(sweep, counters)
+	beat()
+	ticker := Clock.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for n := 1; ; n++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if n%sweepEvery == 0 {
+				sweepBackends(sweep, counters)
+			}
+			if n%historyEvery == 0 {
+				history.flush(counters)
+			}
+			beat()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// useFakeClock swaps the package Clock for a fake for the duration of a test.
+func useFakeClock(t *testing.T, start time.Time) *clock.Fake {
+	t.Helper()
+	orig := Clock
+	fake := clock.NewFake(start)
+	Clock = fake
+	t.Cleanup(func() { Clock = orig })
+	return fake
+}
+
+// waitFor polls cond until it returns true or the deadline passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRun_WritesHeartbeat(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := useFakeClock(t, start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	countersCh := make(chan *stats.Counters, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, 250, t.TempDir(), func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+			countersCh <- counters
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	counters := <-countersCh
+	waitFor(t, "initial heartbeat", func() bool {
+		_, err := ReadHeartbeat()
+		return err == nil
+	})
+
+	hb, err := ReadHeartbeat()
+	if err != nil {
+		t.Fatalf("ReadHeartbeat() error: %v", err)
+	}
+	if hb.PID != os.Getpid() {
+		t.Errorf("heartbeat PID = %d, want %d", hb.PID, os.Getpid())
+	}
+	if !hb.StartedAt.Equal(start) {
+		t.Errorf("heartbeat StartedAt = %v, want %v", hb.StartedAt, start)
+	}
+
+	counters.RecordCapture(start, 1)
+	clk.BlockUntil(1)
+	clk.Advance(heartbeatInterval)
+	waitFor(t, "refreshed heartbeat", func() bool {
+		hb, err := ReadHeartbeat()
+		return err == nil && hb.Stats.Captures == 1 && hb.UpdatedAt.Equal(start.Add(heartbeatInterval))
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if _, err := os.Stat(HeartbeatFile); !os.IsNotExist(err) {
+		t.Error("heartbeat file should be removed after Run exits")
+	}
+
+	h, err := stats.LoadHistory(HistoryFile)
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	if len(h.Days) != 1 || h.Days[0].Date != "2024-05-01" || h.Days[0].Captures != 1 || h.Days[0].Bytes != 1 {
+		t.Errorf("history = %+v, want the capture rolled up under 2024-05-01 on exit", h.Days)
+	}
+}
+
+func TestHeartbeat_Fresh(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		age  time.Duration
+		want bool
+	}{
+		{"just_written", 0, true},
+		{"one_missed_beat", 2 * heartbeatInterval, true},
+		{"stale", heartbeatStaleAfter + time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hb := &Heartbeat{UpdatedAt: now.Add(-tt.age)}
+			if got := hb.Fresh(now); got != tt.want {
+				t.Errorf("Fresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHeartbeat_Missing(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	if _, err := ReadHeartbeat(); err == nil {
+		t.Error("expected error for missing heartbeat file")
+	}
+}
@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"path/filepath"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/paths"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// HistoryFile holds the daily rollups of poll activity. It lives in the
+// state directory, so stats export still has the data after a reboot.
+var HistoryFile = filepath.Join(paths.StateDir(), "history.json")
+
+// historyEvery is how many heartbeats pass between history updates.
+const historyEvery = 12
+
+// historyRollup folds the counters into HistoryFile's daily rows. Counters
+// restart from zero with the process, so only what changed since the last
+// flush is added.
+type historyRollup struct {
+	prev stats.Snapshot
+}
+
+// flush adds the activity since the last flush to today's row.
+func (r *historyRollup) flush(counters *stats.Counters) {
+	cur := counters.Snapshot()
+	delta := stats.Since(r.prev, cur)
+	r.prev = cur
+
+	h, err := stats.LoadHistory(HistoryFile)
+	if err != nil {
+		return // best-effort: a corrupt file is left for the user to inspect
+	}
+	if h.Add(Clock.Now().Format("2006-01-02"), delta) {
+		_ = stats.SaveHistory(HistoryFile, h)
+	}
+}
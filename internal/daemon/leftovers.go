@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+// leftovers is what a daemon that did not shut down cleanly (killed, or WSL
+// terminated under it) left behind, as found and removed by cleanLeftovers.
+type leftovers struct {
+	staleSocket    bool
+	staleHeartbeat bool
+	stagingFiles   int   // partial screenshots from interrupted writes
+	backends       []int // orphaned powershell.exe processes killed
+	stagingDir     string
+	stagingErr     error
+}
+
+// cleanLeftovers removes the artifacts of an unclean previous run: its
+// control socket, its heartbeat, half-written screenshots in outputDir's
+// staging directory, and clipboard backends whose daemon died. Run
+// calls it once it holds the PID file, so none of them can belong to a live
+// daemon of this profile.
+func cleanLeftovers(outputDir string) leftovers {
+	var l leftovers
+	if _, err := os.Lstat(SocketFile); err == nil {
+		l.staleSocket = os.Remove(SocketFile) == nil
+	}
+	// Any heartbeat predates this process; a crash mid-write leaves the
+	// temporary file too.
+	if hb, err := readHeartbeatFile(HeartbeatFile); err == nil && hb.PID != os.Getpid() {
+		l.staleHeartbeat = true
+	}
+	_ = os.Remove(HeartbeatFile)
+	_ = os.Remove(HeartbeatFile + ".tmp")
+
+	l.stagingDir = store.StagingDir(outputDir)
+	l.stagingFiles, l.stagingErr = store.CleanStaging(outputDir)
+
+	owners := backendOwners()
+	for _, p := range backendProcs() {
+		if isOrphan(p, owners) && reparented(p) && killProcess(p.pid, syscall.SIGKILL) == nil {
+			l.backends = append(l.backends, p.pid)
+		}
+	}
+	return l
+}
+
+// reparented reports whether p's parent is gone: p was adopted by init (PID
+// 1, or the per-session init WSL runs) or its parent no longer exists. A
+// backend of a one-shot command that is still running is left alone.
+func reparented(p procEntry) bool {
+	if p.ppid <= 1 {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(p.ppid), "stat")) // #nosec G304 -- path is under procRoot
+	if err != nil {
+		return true
+	}
+	parent, err := parseStat(string(data))
+	return err == nil && parent.comm == "init"
+}
+
+// found reports whether anything was cleaned up.
+func (l leftovers) found() bool {
+	return l.staleSocket || l.staleHeartbeat || l.stagingFiles > 0 || len(l.backends) > 0
+}
+
+// log summarizes the cleanup in one line, or says nothing after a clean
+// shutdown.
+func (l leftovers) log(logger *slog.Logger) {
+	if l.stagingErr != nil {
+		logger.Warn("Could not clean the staging directory", "dir", l.stagingDir, "err", l.stagingErr)
+	}
+	if !l.found() {
+		return
+	}
+	logger.Warn("Previous run did not shut down cleanly, removed its leftovers",
+		"stale_socket", l.staleSocket,
+		"stale_heartbeat", l.staleHeartbeat,
+		"staging_files", l.stagingFiles,
+		"orphaned_backends", l.backends,
+	)
+}
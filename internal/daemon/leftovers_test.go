@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCleanLeftovers(t *testing.T) {
+	defer setTestPaths(t)()
+	root := t.TempDir()
+	overrideProcRoot(t, root)
+	origBackend, origKill := IsBackend, killProcess
+	IsBackend = func(cmdline string) bool { return strings.Contains(cmdline, "MARKER") }
+	var killed []int
+	killProcess = func(pid int, sig syscall.Signal) error {
+		killed = append(killed, pid)
+		return nil
+	}
+	t.Cleanup(func() { IsBackend, killProcess = origBackend, origKill })
+
+	writeProc(t, root, 500, 1, "S", "wsl-screenshot-", "wsl-screenshot-cli replay")
+	writeProc(t, root, 600, 1, "S", "init", "/init")
+	writeProc(t, root, 301, 1, "S", "powershell.exe", "powershell.exe MARKER")           // adopted by init
+	writeProc(t, root, 302, 400, "S", "powershell.exe", "powershell.exe MARKER")         // parent gone
+	writeProc(t, root, 303, 500, "S", "powershell.exe", "powershell.exe MARKER")         // a one-shot command's client
+	writeProc(t, root, 304, 600, "S", "powershell.exe", "powershell.exe MARKER")         // adopted by WSL's session init
+	writeProc(t, root, 305, os.Getpid(), "S", "powershell.exe", "powershell.exe MARKER") // ours
+	writeProc(t, root, 306, 1, "S", "powershell.exe", "powershell.exe -File user.ps1")   // not a backend
+
+	if err := os.WriteFile(SocketFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeHeartbeat(Heartbeat{PID: 999999}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(HeartbeatFile+".tmp", []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	staging := filepath.Join(out, ".staging")
+	if err := os.Mkdir(staging, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(staging, "a.png.1.part"), []byte("half"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := cleanLeftovers(out)
+	if !l.staleSocket || !l.staleHeartbeat || l.stagingFiles != 1 {
+		t.Errorf("leftovers = %+v, want a stale socket, heartbeat and one staging file", l)
+	}
+	slices.Sort(killed)
+	if want := []int{301, 302, 304}; !slices.Equal(killed, want) || !slices.Equal(l.backends, killed) {
+		t.Errorf("killed %v (reported %v), want %v", killed, l.backends, want)
+	}
+	for _, path := range []string{SocketFile, HeartbeatFile, HeartbeatFile + ".tmp"} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", path)
+		}
+	}
+
+	var buf bytes.Buffer
+	l.log(slog.New(slog.NewTextHandler(&buf, nil)))
+	if !strings.Contains(buf.String(), "did not shut down cleanly") || !strings.Contains(buf.String(), "staging_files=1") {
+		t.Errorf("summary = %q", buf.String())
+	}
+
+	// After a clean shutdown there is nothing to find or report.
+	killed = nil
+	overrideProcRoot(t, t.TempDir())
+	l = cleanLeftovers(out)
+	buf.Reset()
+	l.log(slog.New(slog.NewTextHandler(&buf, nil)))
+	if l.found() || len(killed) != 0 || buf.Len() != 0 {
+		t.Errorf("second cleanup = %+v, log %q; want nothing", l, buf.String())
+	}
+}
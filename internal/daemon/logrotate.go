@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/logfile"
+)
+
+// LogMaxSize and LogMaxBackups cap the log of a daemonized process: once
+// LogFile would grow past LogMaxSize bytes it is renamed to LogFile.1 (older
+// copies shift up to LogMaxBackups) and a new one is started. A LogMaxSize of
+// 0 disables rotation.
+var (
+	LogMaxSize    int64 = 10 << 20
+	LogMaxBackups       = 3
+)
+
+// openRotatingLog takes over rotation of LogFile when this process is a
+// daemon whose stdout Daemonize pointed at it, and returns nil otherwise (a
+// foreground run logs to the terminal or wherever the user redirected it,
+// and --quiet discards the log altogether).
+func openRotatingLog() *logfile.Rotator {
+	if LogMaxSize <= 0 || Output != io.Writer(os.Stdout) || !stdoutIsLogFile() {
+		return nil
+	}
+	rot, err := logfile.Open(LogFile, LogMaxSize, LogMaxBackups)
+	if err != nil {
+		return nil // keep logging to stdout, just without a cap
+	}
+	rot.OnRotate = redirectStdio
+	return rot
+}
+
+func stdoutIsLogFile() bool {
+	out, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	log, err := os.Stat(LogFile)
+	return err == nil && os.SameFile(out, log)
+}
+
+// redirectStdio points stdout and stderr at f, so panics and anything child
+// processes print also land in the current log rather than a rotated one.
+var redirectStdio = func(f *os.File) error {
+	for _, fd := range []int{1, 2} {
+		if err := syscall.Dup3(int(f.Fd()), fd, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestOpenRotatingLog(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	// Foreground runs (stdout is not LogFile) leave logging alone.
+	if rot := openRotatingLog(); rot != nil {
+		t.Fatal("openRotatingLog() should not rotate when stdout is not the log file")
+	}
+
+	// A daemonized child writes to LogFile through stdout, as set up by Daemonize.
+	f, err := os.OpenFile(LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	origStdout, origSize, origRedirect := os.Stdout, LogMaxSize, redirectStdio
+	os.Stdout, Output, LogMaxSize = f, f, 64
+	var redirected int
+	redirectStdio = func(*os.File) error { redirected++; return nil }
+	defer func() { os.Stdout, LogMaxSize, redirectStdio = origStdout, origSize, origRedirect }()
+
+	rot := openRotatingLog()
+	if rot == nil {
+		t.Fatal("openRotatingLog() = nil, want a rotator for a daemon's log")
+	}
+	defer rot.Close()
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(rot, "log line %d\n", i)
+	}
+	if _, err := os.Stat(LogFile + ".1"); err != nil {
+		t.Errorf("log should have been rotated to .1: %v", err)
+	}
+	if redirected == 0 {
+		t.Error("stdout and stderr should follow the log to its new file")
+	}
+}
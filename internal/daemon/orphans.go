@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// backendComms are the process names a clipboard backend shows in /proc:
+// one per PowerShell it may run under, and the native helper
+// (wsl-screenshot-helper.exe, cut to the 15 characters /proc keeps).
+var backendComms = []string{"powershell.exe", "pwsh.exe", "wsl-screenshot-"}
+
+// sweepEvery is how many heartbeats pass between backend sweeps (one minute).
+const sweepEvery = 12
+
+// IsBackend reports whether a /proc cmdline (NUL-separated) belongs to a
+// clipboard backend started by this tool. The start command sets it; nil
+// disables orphan detection (zombies are still reaped).
+var IsBackend func(cmdline string) bool
+
+// procEntry is the part of /proc/<pid>/stat the sweep needs.
+type procEntry struct {
+	pid   int
+	ppid  int
+	state byte
+	comm  string
+}
+
+// parseStat parses a /proc/<pid>/stat line. comm is parenthesized and may
+// itself contain spaces or parentheses, so fields are split after the last ')'.
+func parseStat(data string) (procEntry, error) {
+	open, end := strings.IndexByte(data, '('), strings.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return procEntry{}, fmt.Errorf("malformed stat %q", data)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(data[:open]))
+	if err != nil {
+		return procEntry{}, err
+	}
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 2 || len(fields[0]) != 1 {
+		return procEntry{}, fmt.Errorf("malformed stat %q", data)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procEntry{}, err
+	}
+	return procEntry{pid: pid, ppid: ppid, state: fields[0][0], comm: data[open+1 : end]}, nil
+}
+
+// backendProcs lists the processes in procRoot named like a clipboard backend.
+func backendProcs() []procEntry {
+	paths, _ := filepath.Glob(filepath.Join(procRoot, "[0-9]*", "stat"))
+	var out []procEntry
+	for _, path := range paths {
+		data, err := os.ReadFile(path) // #nosec G304 -- path is under procRoot
+		if err != nil {
+			continue // the process exited mid-scan
+		}
+		p, err := parseStat(string(data))
+		if err == nil && slices.Contains(backendComms, p.comm) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// backendSweep reaps powershell.exe zombies left by this daemon and counts
+// orphaned backends: ones whose owning daemon died without stopping them.
+// Every code path that drops a client is meant to Wait for it, so both are a
+// safety net for long-running daemons rather than the normal cleanup.
+type backendSweep struct {
+	// zombies seen in the previous sweep. A zombie is only reaped once it has
+	// outlived a whole sweep interval, so a concurrent cmd.Wait in Close gets
+	// to reap its own child first.
+	zombies map[int]bool
+
+	// reap waits for a zombie child. Declared as a field so tests can stub it.
+	reap func(pid int) error
+}
+
+func newBackendSweep() *backendSweep {
+	return &backendSweep{
+		zombies: map[int]bool{},
+		reap: func(pid int) error {
+			var ws syscall.WaitStatus
+			_, err := syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+			return err
+		},
+	}
+}
+
+// run performs one sweep. owners are the PIDs allowed to own a backend (the
+// registered daemons of every profile, including self). It returns the number
+// of orphans found and zombies reaped.
+func (s *backendSweep) run(self int, owners map[int]bool) (orphans, reaped int) {
+	zombies := map[int]bool{}
+	for _, p := range backendProcs() {
+		switch {
+		case p.state == 'Z':
+			if p.ppid != self {
+				continue
+			}
+			if s.zombies[p.pid] && s.reap(p.pid) == nil {
+				reaped++
+				continue
+			}
+			zombies[p.pid] = true
+		case isOrphan(p, owners):
+			orphans++
+		}
+	}
+	s.zombies = zombies
+	return orphans, reaped
+}
+
+// isOrphan reports whether p is a live clipboard backend whose parent is not
+// one of owners. It is always false while IsBackend is nil.
+func isOrphan(p procEntry, owners map[int]bool) bool {
+	if p.state == 'Z' || owners[p.ppid] || IsBackend == nil {
+		return false
+	}
+	cmdline, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(p.pid), "cmdline"))
+	return err == nil && IsBackend(string(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '})))
+}
+
+// backendOwners returns the PIDs allowed to own a backend: this process and
+// the registered daemons of every profile.
+func backendOwners() map[int]bool {
+	owners := map[int]bool{os.Getpid(): true}
+	for _, inst := range Instances() {
+		owners[inst.PID] = true
+	}
+	return owners
+}
+
+// sweepBackends runs one sweep for the current process and reports orphans
+// to counters.
+func sweepBackends(s *backendSweep, counters *stats.Counters) {
+	orphans, _ := s.run(os.Getpid(), backendOwners())
+	counters.SetOrphanedBackends(orphans)
+}
+
+// killProcess sends sig to pid. Declared as a var so tests can record kills
+// instead of signalling real processes.
+var killProcess = syscall.Kill
+
+// killBackends SIGKILLs the live clipboard backends that are children of
+// self, for a shutdown that has run out of time, and returns how many it
+// signalled.
+func killBackends(self int) int {
+	n := 0
+	for _, p := range backendProcs() {
+		if p.ppid == self && p.state != 'Z' && killProcess(p.pid, syscall.SIGKILL) == nil {
+			n++
+		}
+	}
+	return n
+}
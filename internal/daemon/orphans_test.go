@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeProc adds a fake /proc/<pid> entry with the given stat fields and cmdline.
+func writeProc(t *testing.T, root string, pid, ppid int, state, comm, cmdline string) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := strconv.Itoa(pid) + " (" + comm + ") " + state + " " + strconv.Itoa(ppid) + " 1 1 0 -1"
+	os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644)
+	os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.ReplaceAll(cmdline, " ", "\x00")), 0644)
+}
+
+func TestParseStat(t *testing.T) {
+	p, err := parseStat("42 (odd) name)) S 7 42 42 0 -1")
+	if err != nil {
+		t.Fatalf("parseStat() error: %v", err)
+	}
+	if p.pid != 42 || p.ppid != 7 || p.state != 'S' || p.comm != "odd) name)" {
+		t.Errorf("parseStat() = %+v", p)
+	}
+	if _, err := parseStat("garbage"); err == nil {
+		t.Error("parseStat(garbage) should fail")
+	}
+}
+
+func TestBackendSweep(t *testing.T) {
+	root := t.TempDir()
+	overrideProcRoot(t, root)
+	orig := IsBackend
+	IsBackend = func(cmdline string) bool { return strings.Contains(cmdline, "MARKER") }
+	t.Cleanup(func() { IsBackend = orig })
+
+	const self, otherDaemon = 100, 200
+	writeProc(t, root, 101, self, "S", "powershell.exe", "powershell.exe MARKER")        // our live client
+	writeProc(t, root, 201, otherDaemon, "S", "powershell.exe", "powershell.exe MARKER") // another profile's client
+	writeProc(t, root, 301, 1, "S", "powershell.exe", "powershell.exe MARKER")           // orphan
+	writeProc(t, root, 302, 1, "S", "powershell.exe", "powershell.exe Get-Date")         // the user's own
+	writeProc(t, root, 303, 1, "S", "bash", "bash MARKER")                               // not a backend
+	writeProc(t, root, 102, self, "Z", "powershell.exe", "")                             // our zombie
+
+	var reaped []int
+	s := newBackendSweep()
+	s.reap = func(pid int) error { reaped = append(reaped, pid); return nil }
+	owners := map[int]bool{self: true, otherDaemon: true}
+
+	orphans, n := s.run(self, owners)
+	if orphans != 1 {
+		t.Errorf("orphans = %d, want 1", orphans)
+	}
+	if n != 0 || len(reaped) != 0 {
+		t.Errorf("a zombie seen for the first time was reaped: %v", reaped)
+	}
+
+	if _, n = s.run(self, owners); n != 1 || len(reaped) != 1 || reaped[0] != 102 {
+		t.Errorf("second sweep reaped %v (%d), want [102]", reaped, n)
+	}
+}
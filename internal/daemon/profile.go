@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultProfile is the profile that keeps the original, unsuffixed file names.
+const DefaultProfile = "default"
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// UseProfile gives this process the named profile's PID, log, state,
+// heartbeat, event and history files, control socket and default output
+// directory, so several daemons (e.g. "work" and "personal") can run side by
+// side. Each file name gets a "-<name>" suffix: wsl-screenshot-cli-work.pid.
+// The registry directory stays shared so status --all sees every profile.
+// "" and "default" keep the current paths. Call it once, before any other
+// daemon function.
+func UseProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		return nil
+	}
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("Invalid profile name %q (use letters, digits, '-' and '_')", name)
+	}
+	PidFile = profilePath(PidFile, name)
+	LogFile = profilePath(LogFile, name)
+	StateFile = profilePath(StateFile, name)
+	HeartbeatFile = profilePath(HeartbeatFile, name)
+	EventsFile = profilePath(EventsFile, name)
+	SocketFile = profilePath(SocketFile, name)
+	HistoryFile = profilePath(HistoryFile, name)
+	DefaultOutputDir = strings.TrimSuffix(DefaultOutputDir, "/") + "-" + name + "/"
+	InstanceName = name
+	return nil
+}
+
+// profilePath inserts "-name" before path's extension.
+func profilePath(path, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + name + ext
+}
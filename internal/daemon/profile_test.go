@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUseProfile(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	origName := InstanceName
+	defer func() { InstanceName = origName }()
+
+	dir := filepath.Dir(PidFile)
+	if err := UseProfile("default"); err != nil || PidFile != filepath.Join(dir, "test.pid") {
+		t.Fatalf("UseProfile(default) changed PidFile to %q (err %v)", PidFile, err)
+	}
+
+	if err := UseProfile("work"); err != nil {
+		t.Fatalf("UseProfile(work) error: %v", err)
+	}
+	want := map[string]string{
+		"PidFile":          filepath.Join(dir, "test-work.pid"),
+		"LogFile":          filepath.Join(dir, "test-work.log"),
+		"StateFile":        filepath.Join(dir, "test-work.state"),
+		"HeartbeatFile":    filepath.Join(dir, "test-work.heartbeat"),
+		"EventsFile":       filepath.Join(dir, "test-work.events"),
+		"DefaultOutputDir": filepath.Join(dir, "output-work") + "/",
+		"InstanceName":     "work",
+	}
+	got := map[string]string{
+		"PidFile":          PidFile,
+		"LogFile":          LogFile,
+		"StateFile":        StateFile,
+		"HeartbeatFile":    HeartbeatFile,
+		"EventsFile":       EventsFile,
+		"DefaultOutputDir": DefaultOutputDir,
+		"InstanceName":     InstanceName,
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s = %q, want %q", k, got[k], w)
+		}
+	}
+}
+
+func TestUseProfile_InvalidName(t *testing.T) {
+	for _, name := range []string{"../etc", "-work", "a b", "work/x"} {
+		if err := UseProfile(name); err == nil {
+			t.Errorf("UseProfile(%q) should fail", name)
+		}
+	}
+}
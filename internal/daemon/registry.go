@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/paths"
+)
+
+// RegistryDir holds one JSON file per running instance so every daemon can be
+// discovered without knowing its name up front.
+var RegistryDir = filepath.Join(paths.RuntimeDir(), "instances")
+
+// InstanceName identifies this daemon in the registry. It is the profile name.
+var InstanceName = DefaultProfile
+
+// StartArgs are the "start" flags this daemon was launched with, recorded so
+// tools like migrate-output can relaunch it with adjusted settings.
+var StartArgs []string
+
+// Instance is the registry record a running daemon writes about itself.
+type Instance struct {
+	Name          string    `json:"name"`
+	PID           int       `json:"pid"`
+	StartedAt     time.Time `json:"started_at"`
+	OutputDir     string    `json:"output_dir"`
+	LogFile       string    `json:"log_file"`
+	HeartbeatFile string    `json:"heartbeat_file"`
+	Args          []string  `json:"args,omitempty"`
+}
+
+// InstanceStatus is a registered instance plus its live counters.
+type InstanceStatus struct {
+	Instance
+	Uptime   time.Duration
+	Captures int64
+	Healthy  bool // heartbeat is fresh and the last poll succeeded
+}
+
+func registryPath(name string) string {
+	return filepath.Join(RegistryDir, name+".json")
+}
+
+// register records the current process in the registry.
+func register(inst Instance) error {
+	if err := os.MkdirAll(RegistryDir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	// Written aside and renamed, so Instances never reads half an entry.
+	path := registryPath(inst.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readInstance loads one registry entry.
+func readInstance(path string) (Instance, error) {
+	var inst Instance
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return inst, err
+	}
+	err = json.Unmarshal(data, &inst)
+	return inst, err
+}
+
+// unregister removes name from the registry.
+func unregister(name string) {
+	_ = os.Remove(registryPath(name)) // best-effort cleanup
+}
+
+// Instances returns every registered daemon that is still alive, sorted by
+// name. Entries left behind by dead processes (e.g. after a WSL restart) are
+// removed along the way; unreadable ones are only skipped, since they can't
+// tell whether their process is alive.
+func Instances() []InstanceStatus {
+	paths, err := filepath.Glob(filepath.Join(RegistryDir, "*.json"))
+	if err != nil {
+		return nil
+	}
+
+	now := Clock.Now()
+	var out []InstanceStatus
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var inst Instance
+		if err := json.Unmarshal(data, &inst); err != nil {
+			continue
+		}
+		if !processAlive(inst.PID) {
+			_ = os.Remove(path) // stale entry, clean up
+			continue
+		}
+		if inst.Name == "" {
+			inst.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+
+		st := InstanceStatus{Instance: inst, Uptime: now.Sub(inst.StartedAt)}
+		if hb, err := readHeartbeatFile(inst.HeartbeatFile); err == nil && hb.PID == inst.PID {
+			st.Captures = hb.Stats.Captures
+			st.Healthy = hb.Fresh(now) && hb.Stats.ConsecutiveErrors == 0
+		}
+		out = append(out, st)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LookupInstance returns the registered, still-running instance called name.
+func LookupInstance(name string) (Instance, bool) {
+	for _, st := range Instances() {
+		if st.Name == name {
+			return st.Instance, true
+		}
+	}
+	return Instance{}, false
+}
+
+// WaitForExit polls until pid has exited or timeout elapses, reporting
+// whether the process is gone.
+func WaitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
+
+// processAlive reports whether pid refers to a running process.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+func TestRun_RegistersInstance(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useFakeClock(t, start)
+	outDir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, 250, outDir, func(ctx context.Context, logger *slog.Logger, counters *stats.Counters, reload <-chan struct{}) error {
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	waitFor(t, "registry entry", func() bool {
+		_, err := os.Stat(registryPath(InstanceName))
+		return err == nil
+	})
+
+	instances := Instances()
+	if len(instances) != 1 {
+		t.Fatalf("Instances() = %d entries, want 1", len(instances))
+	}
+	got := instances[0]
+	if got.Name != InstanceName || got.PID != os.Getpid() || got.OutputDir != outDir {
+		t.Errorf("unexpected instance: %+v", got.Instance)
+	}
+	if !got.StartedAt.Equal(start) {
+		t.Errorf("StartedAt = %v, want %v", got.StartedAt, start)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if _, err := os.Stat(registryPath(InstanceName)); !os.IsNotExist(err) {
+		t.Errorf("registry entry should be removed on exit, stat err = %v", err)
+	}
+}
+
+func TestInstances_RemovesStaleEntries(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	useFakeClock(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	if err := register(Instance{Name: "dead", PID: 99999999}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RegistryDir, "corrupt.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := register(Instance{Name: "alive", PID: os.Getpid()}); err != nil {
+		t.Fatal(err)
+	}
+
+	instances := Instances()
+	if len(instances) != 1 || instances[0].Name != "alive" {
+		t.Fatalf("Instances() = %+v, want only 'alive'", instances)
+	}
+	if _, err := os.Stat(registryPath("dead")); !os.IsNotExist(err) {
+		t.Error("the entry of a dead process should be removed")
+	}
+	// Unparseable, as while being written: skipped, but not removed, since
+	// its daemon may well be alive.
+	if _, err := os.Stat(registryPath("corrupt")); err != nil {
+		t.Errorf("an unreadable entry should be left alone: %v", err)
+	}
+}
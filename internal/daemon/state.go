@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StartVerbose records whether the daemon was started with --verbose, for
+// the state file.
+var StartVerbose bool
+
+// StartCaseInsensitive records whether the output directory folds case in
+// file names (a drvfs mount such as /mnt/c), for the state file.
+var StartCaseInsensitive bool
+
+// StartSettings records the effective value of every start setting, for the
+// state file, so start --ensure can tell whether a running daemon matches.
+var StartSettings map[string]string
+
+// StartSources records where each of StartSettings came from ("command line",
+// "environment", "config file" or "default"), for show-config.
+var StartSources map[string]string
+
+// State is what StateFile records about the running daemon: its effective
+// settings, after flags, environment and config file were resolved, and the
+// flags it was started with. restart relaunches a daemon from it.
+type State struct {
+	OutputDir string        `json:"output_dir"`
+	Interval  time.Duration `json:"interval"`
+	Verbose   bool          `json:"verbose"`
+	Args      []string      `json:"args,omitempty"`
+
+	// CaseInsensitive is set when OutputDir folds case in file names.
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+
+	// Settings holds the effective value of every start setting by flag name.
+	Settings map[string]string `json:"settings,omitempty"`
+	// Sources holds where each setting came from, by flag name.
+	Sources map[string]string `json:"sources,omitempty"`
+
+	// Exit is set when polling failed for good and the daemon exited on its
+	// own. The state file is then left behind for status to explain it.
+	Exit *Exit `json:"exit,omitempty"`
+}
+
+// Exit records why a daemon stopped on its own.
+type Exit struct {
+	Time   time.Time `json:"time"`
+	Code   int       `json:"code"` // the process exit status
+	Reason string    `json:"reason"`
+}
+
+// ExitError carries the exit status a daemon should end with when polling
+// fails for good, so scripts and status can tell causes apart.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// exitCode returns the exit status for a polling failure: an ExitError's
+// code, or 1.
+func exitCode(err error) int {
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		return ee.Code
+	}
+	return 1
+}
+
+// LastExit returns why the last daemon stopped on its own, or nil when one
+// is running or the last one was stopped normally.
+func LastExit() *Exit {
+	if RunningPID() != 0 {
+		return nil
+	}
+	st, err := ReadState()
+	if err != nil {
+		return nil
+	}
+	return st.Exit
+}
+
+// recordExit notes in StateFile why polling ended on its own.
+func recordExit(err error) error {
+	st := currentState()
+	st.Exit = &Exit{Time: Clock.Now(), Code: exitCode(err), Reason: err.Error()}
+	return writeState(st)
+}
+
+var (
+	stateMu sync.Mutex
+	// current is the state this process last wrote, served over the control
+	// socket so show-config reports what the daemon uses even if StateFile
+	// was edited or removed.
+	current State
+)
+
+// currentState returns the state this process last wrote.
+func currentState() State {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return current
+}
+
+// ReadState loads StateFile. A state file from an older version holds only
+// the output directory as plain text; it is returned with just OutputDir set.
+func ReadState() (State, error) {
+	data, err := os.ReadFile(StateFile)
+	if err != nil {
+		return State{}, err
+	}
+	text := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(text, "{") {
+		return State{OutputDir: text}, nil
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return State{}, fmt.Errorf("parse state file: %w", err)
+	}
+	return st, nil
+}
+
+func writeState(st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(StateFile, data, 0600); err != nil {
+		return fmt.Errorf("Failed to write state file: %w", err)
+	}
+	stateMu.Lock()
+	current = st
+	stateMu.Unlock()
+	return nil
+}
+
+// UpdateState applies update to the running daemon's state file and keeps
+// its registry entry's output directory in step, after a live config reload
+// changed its settings.
+func UpdateState(update func(*State)) error {
+	st, err := ReadState()
+	if err != nil {
+		return fmt.Errorf("Failed to read state file: %w", err)
+	}
+	update(&st)
+	if err := writeState(st); err != nil {
+		return err
+	}
+	if inst, err := readInstance(registryPath(InstanceName)); err == nil && inst.OutputDir != st.OutputDir {
+		inst.OutputDir = st.OutputDir
+		return register(inst)
+	}
+	return nil
+}
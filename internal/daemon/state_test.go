@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadState(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	t.Run("legacy_plain_text", func(t *testing.T) {
+		os.WriteFile(StateFile, []byte("/custom/path\n"), 0644)
+		st, err := ReadState()
+		if err != nil || !reflect.DeepEqual(st, State{OutputDir: "/custom/path"}) {
+			t.Errorf("ReadState() = %+v, %v; want only the output dir", st, err)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		want := State{OutputDir: "/shots", Interval: time.Second, Verbose: true, Args: []string{"--seq-check=true"}}
+		if err := writeState(want); err != nil {
+			t.Fatal(err)
+		}
+		st, err := ReadState()
+		if err != nil || !reflect.DeepEqual(st, want) {
+			t.Errorf("ReadState() = %+v, %v; want %+v", st, err, want)
+		}
+	})
+}
+
+func TestUpdateState(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+
+	writeState(State{OutputDir: "/old", Interval: time.Second, Args: []string{"--daily-dirs=true"}})
+	if err := register(Instance{Name: InstanceName, PID: os.Getpid(), OutputDir: "/old"}); err != nil {
+		t.Fatal(err)
+	}
+	err := UpdateState(func(st *State) {
+		st.OutputDir, st.Interval, st.Verbose = "/new", 2*time.Second, true
+	})
+	if err != nil {
+		t.Fatalf("UpdateState() error: %v", err)
+	}
+
+	st, _ := ReadState()
+	want := State{OutputDir: "/new", Interval: 2 * time.Second, Verbose: true, Args: []string{"--daily-dirs=true"}}
+	if !reflect.DeepEqual(st, want) {
+		t.Errorf("state = %+v, want %+v", st, want)
+	}
+	inst, err := readInstance(registryPath(InstanceName))
+	if err != nil || inst.OutputDir != "/new" {
+		t.Errorf("registry entry = %+v, %v; want output dir /new", inst, err)
+	}
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,15 +10,96 @@ import (
 	"time"
 )
 
-// ProcessInfo holds diagnostic information about the running daemon.
+// procRoot is the procfs mount point. Declared as a var so tests can point it
+// at a fake or missing tree to exercise the heartbeat fallback.
+var procRoot = "/proc"
+
+// Sources for the process metrics in ProcessInfo.
+const (
+	SourceProc      = "proc"      // parsed from /proc/<pid>
+	SourceHeartbeat = "heartbeat" // self-reported by the daemon
+)
+
+// ProcessInfo holds diagnostic information about the running daemon. Its
+// JSON form (status --json) adds the uptime in seconds, the CPU percentage and
+// the health state name.
 type ProcessInfo struct {
-	PID         int
-	Uptime      time.Duration
-	CPUTime     float64 // total user+system CPU seconds
-	MemoryRSSKB int64   // resident set size in KB
-	Screenshots int
-	OutputDir   string
-	LogFile     string
+	PID         int           `json:"pid"`
+	Profile     string        `json:"profile"`
+	Uptime      time.Duration `json:"-"`
+	CPUTime     float64       `json:"cpu_seconds"`   // total user+system CPU seconds
+	MemoryRSSKB int64         `json:"memory_rss_kb"` // resident set size in KB
+	Screenshots int           `json:"screenshots"`
+	OutputDir   string        `json:"output_dir"`
+	LogFile     string        `json:"log_file"`
+
+	// OutputCaseInsensitive is set when OutputDir is on a mount that folds
+	// case in file names (drvfs), as probed when the daemon started.
+	OutputCaseInsensitive bool `json:"output_case_insensitive"`
+
+	// MetricsSource says where Uptime, CPUTime and MemoryRSSKB came from, or
+	// is empty when neither /proc nor a heartbeat was available.
+	MetricsSource string `json:"metrics_source,omitempty"`
+
+	// BackendMemoryKB is powershell.exe's working set as last reported in the
+	// heartbeat, or 0 if unknown.
+	BackendMemoryKB int64 `json:"backend_memory_kb"`
+
+	// BackendBinary is the PowerShell executable running the backend
+	// (pwsh.exe or powershell.exe), from the heartbeat.
+	BackendBinary string `json:"backend_binary,omitempty"`
+
+	// LastPing, LastPingOK and PingMs describe the last PING sent to
+	// powershell.exe between polls: when, whether it answered, and the
+	// round trip it took. From the heartbeat.
+	LastPing   time.Time `json:"last_ping,omitzero"`
+	LastPingOK bool      `json:"last_ping_ok"`
+	PingMs     float64   `json:"ping_ms,omitempty"`
+
+	// ClipboardSeq is the Windows clipboard sequence number the poller last
+	// read, 0 if unknown.
+	ClipboardSeq uint32 `json:"clipboard_seq,omitempty"`
+
+	// SessionLocked is true while polling is paused for a locked Windows
+	// session.
+	SessionLocked bool `json:"session_locked,omitempty"`
+
+	// Overwrites counts third-party clipboard overwrites shortly after our
+	// updates; LastOverwriter names the latest offender. From the heartbeat.
+	Overwrites     int64  `json:"overwrites"`
+	LastOverwriter string `json:"last_overwriter,omitempty"`
+
+	// OrphanedBackends counts powershell.exe backends left behind by dead
+	// daemons, from the last sweep reported in the heartbeat.
+	OrphanedBackends int64 `json:"orphaned_backends"`
+
+	// Polls, Captures, DedupHits, Errors, InjectedErrors (simulated by
+	// --chaos), ConsecutiveErrors and Restarts (of the clipboard backend) are
+	// the poll counters since the daemon started, and LastCapture the time of
+	// the newest screenshot it saved. From the heartbeat.
+	Polls             int64     `json:"polls"`
+	Captures          int64     `json:"captures"`
+	DedupHits         int64     `json:"dedup_hits"`
+	Restarts          int64     `json:"restarts"`
+	Errors            int64     `json:"errors"`
+	InjectedErrors    int64     `json:"injected_errors,omitempty"`
+	ConsecutiveErrors int64     `json:"consecutive_errors"`
+	LastCapture       time.Time `json:"last_capture,omitzero"`
+
+	// Health separates liveness, backend readiness and the last poll result.
+	Health Health `json:"health"`
+}
+
+// MarshalJSON adds the derived fields a script would otherwise recompute.
+func (p *ProcessInfo) MarshalJSON() ([]byte, error) {
+	type plain ProcessInfo
+	return json.Marshal(struct {
+		Running bool `json:"running"`
+		*plain
+		UptimeSeconds float64 `json:"uptime_seconds"`
+		CPUPercent    float64 `json:"cpu_percent"`
+		HealthState   string  `json:"health_state"`
+	}{true, (*plain)(p), p.Uptime.Seconds(), p.CPUPercent(), p.Health.State().String()})
 }
 
 // CPUPercent returns the average CPU usage as a percentage over the process lifetime.
@@ -30,64 +112,135 @@ func (p *ProcessInfo) CPUPercent() float64 {
 }
 
 // Status returns process diagnostics if the daemon is running, or nil if not.
+// Process metrics come from /proc when readable; on hardened kernels (hidepid,
+// restricted procfs) they fall back to the values the daemon reports about
+// itself in its heartbeat, which is requested over the control socket and read
+// from HeartbeatFile if that fails.
 func Status() *ProcessInfo {
 	pid := RunningPID()
 	if pid == 0 {
 		return nil
 	}
 
-	outputDir := readOutputDir()
+	outputDir := DefaultOutputDir
+	st, err := ReadState()
+	if err == nil && st.OutputDir != "" {
+		outputDir = st.OutputDir
+	}
 
 	info := &ProcessInfo{
 		PID:       pid,
+		Profile:   InstanceName,
 		OutputDir: outputDir,
 		LogFile:   LogFile,
+
+		OutputCaseInsensitive: st.CaseInsensitive,
 	}
 
-	info.Uptime = parseUptime(pid)
-	info.CPUTime = parseCPUTime(pid)
-	info.MemoryRSSKB = parseVmRSS(pid)
+	hb, err := LiveHeartbeat()
+	if err != nil || hb.PID != pid {
+		hb = nil
+	}
+	if err := readProcMetrics(pid, info); err == nil {
+		info.MetricsSource = SourceProc
+	} else if hb != nil {
+		info.Uptime = Clock.Now().Sub(hb.StartedAt)
+		info.CPUTime = hb.Runtime.CPUSeconds
+		info.MemoryRSSKB = hb.Runtime.MemoryKB
+		info.MetricsSource = SourceHeartbeat
+	}
+	info.Health.Alive = true
+	if hb != nil {
+		if hb.Fresh(Clock.Now()) {
+			info.Health.fromHeartbeat(hb)
+		}
+		info.BackendMemoryKB = hb.Stats.BackendMemoryKB
+		info.BackendBinary = hb.Stats.BackendBinary
+		info.LastPing = hb.Stats.LastPing
+		info.LastPingOK = hb.Stats.LastPingOK
+		info.PingMs = hb.Stats.PingMs
+		info.ClipboardSeq = hb.Stats.ClipboardSeq
+		info.SessionLocked = hb.Stats.SessionLocked
+		info.Overwrites = hb.Stats.Overwrites
+		info.LastOverwriter = hb.Stats.LastOverwriter
+		info.OrphanedBackends = hb.Stats.OrphanedBackends
+		info.Polls = hb.Stats.Polls
+		info.Captures = hb.Stats.Captures
+		info.DedupHits = hb.Stats.DedupHits
+		info.Restarts = hb.Stats.Restarts
+		info.Errors = hb.Stats.Errors
+		info.InjectedErrors = hb.Stats.InjectedErrors
+		info.ConsecutiveErrors = hb.Stats.ConsecutiveErrors
+		info.LastCapture = hb.Stats.LastCapture
+	}
 	info.Screenshots = countScreenshots(outputDir)
 
 	return info
 }
 
+// readProcMetrics fills the uptime, CPU and memory fields of info from /proc.
+// It fails as a whole if any of them can't be read, so callers never mix a
+// real value with a zero placeholder.
+func readProcMetrics(pid int, info *ProcessInfo) error {
+	uptime, err := parseUptime(pid)
+	if err != nil {
+		return err
+	}
+	cpu, err := parseCPUTime(pid)
+	if err != nil {
+		return err
+	}
+	rss, err := parseVmRSS(pid)
+	if err != nil {
+		return err
+	}
+	info.Uptime, info.CPUTime, info.MemoryRSSKB = uptime, cpu, rss
+	return nil
+}
+
+// readStatFields returns the fields of /proc/<pid>/stat that follow the comm
+// field (which is in parens and may contain spaces). rest[0] is field 3 (state).
+func readStatFields(pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	closeParen := strings.LastIndex(string(data), ")")
+	if closeParen < 0 || closeParen+2 > len(data) {
+		return nil, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	return strings.Fields(string(data)[closeParen+2:]), nil // skip ") "
+}
+
 // parseUptime calculates how long the process has been running by comparing
 // its start time (from /proc/<pid>/stat field 22) against system uptime.
-func parseUptime(pid int) time.Duration {
+func parseUptime(pid int) (time.Duration, error) {
 	// Read system uptime
-	data, err := os.ReadFile("/proc/uptime")
+	data, err := os.ReadFile(filepath.Join(procRoot, "uptime"))
 	if err != nil {
-		return 0
+		return 0, err
 	}
 	fields := strings.Fields(string(data))
 	if len(fields) < 1 {
-		return 0
+		return 0, fmt.Errorf("malformed /proc/uptime")
 	}
 	systemUptime, err := strconv.ParseFloat(fields[0], 64)
 	if err != nil {
-		return 0
+		return 0, fmt.Errorf("parse /proc/uptime: %w", err)
 	}
 
 	// Read process start time (field 22 in /proc/<pid>/stat, 1-indexed)
-	data, err = os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	rest, err := readStatFields(pid)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	// Fields after the comm field (which is in parens and may contain spaces)
-	// Find the closing paren, then split the rest
-	closeParen := strings.LastIndex(string(data), ")")
-	if closeParen < 0 {
-		return 0
-	}
-	rest := strings.Fields(string(data)[closeParen+2:]) // skip ") "
 	// rest[0] = field 3 (state), so field 22 = rest[19]
 	if len(rest) < 20 {
-		return 0
+		return 0, fmt.Errorf("short /proc/%d/stat", pid)
 	}
 	startTicks, err := strconv.ParseInt(rest[19], 10, 64)
 	if err != nil {
-		return 0
+		return 0, fmt.Errorf("parse start time: %w", err)
 	}
 
 	clkTck := int64(100) // sysconf(_SC_CLK_TCK), 100 on virtually all Linux
@@ -95,40 +248,35 @@ func parseUptime(pid int) time.Duration {
 	uptimeSec := systemUptime - processStartSec
 
 	if uptimeSec < 0 {
-		return 0
+		return 0, nil
 	}
-	return time.Duration(uptimeSec * float64(time.Second))
+	return time.Duration(uptimeSec * float64(time.Second)), nil
 }
 
 // parseCPUTime returns total user+system CPU time in seconds from /proc/<pid>/stat.
-func parseCPUTime(pid int) float64 {
-	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+func parseCPUTime(pid int) (float64, error) {
+	rest, err := readStatFields(pid)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	closeParen := strings.LastIndex(string(data), ")")
-	if closeParen < 0 {
-		return 0
-	}
-	rest := strings.Fields(string(data)[closeParen+2:])
 	// rest[11] = field 14 (utime), rest[12] = field 15 (stime)
 	if len(rest) < 13 {
-		return 0
+		return 0, fmt.Errorf("short /proc/%d/stat", pid)
 	}
 	utime, err1 := strconv.ParseInt(rest[11], 10, 64)
 	stime, err2 := strconv.ParseInt(rest[12], 10, 64)
 	if err1 != nil || err2 != nil {
-		return 0
+		return 0, fmt.Errorf("parse cpu times in /proc/%d/stat", pid)
 	}
 	clkTck := int64(100)
-	return float64(utime+stime) / float64(clkTck)
+	return float64(utime+stime) / float64(clkTck), nil
 }
 
 // parseVmRSS reads the VmRSS line from /proc/<pid>/status and returns the value in KB.
-func parseVmRSS(pid int) int64 {
-	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+func parseVmRSS(pid int) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "status"))
 	if err != nil {
-		return 0
+		return 0, err
 	}
 	for _, line := range strings.Split(string(data), "\n") {
 		if strings.HasPrefix(line, "VmRSS:") {
@@ -136,19 +284,28 @@ func parseVmRSS(pid int) int64 {
 			if len(fields) >= 2 {
 				val, err := strconv.ParseInt(fields[1], 10, 64)
 				if err == nil {
-					return val
+					return val, nil
 				}
 			}
 		}
 	}
-	return 0
+	return 0, fmt.Errorf("no VmRSS in /proc/%d/status", pid)
 }
 
-// countScreenshots counts .png files in the given directory.
+// countScreenshots counts .png files (any case) in the given directory, including those
+// stored one level down in daily subdirectories. Symlinks are not counted.
 func countScreenshots(dir string) int {
-	matches, err := filepath.Glob(filepath.Join(dir, "*.png"))
-	if err != nil {
-		return 0
+	count := 0
+	for _, pattern := range []string{"*.[pP][nN][gG]", "*/*.[pP][nN][gG]"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return 0
+		}
+		for _, path := range matches {
+			if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+				count++ // not latest.png, a symlink to one of the others
+			}
+		}
 	}
-	return len(matches)
+	return count
 }
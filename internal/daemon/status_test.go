@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// overrideProcRoot points procfs lookups at dir for the duration of a test.
+func overrideProcRoot(t *testing.T, dir string) {
+	t.Helper()
+	orig := procRoot
+	procRoot = dir
+	t.Cleanup(func() { procRoot = orig })
+}
+
+func TestStatus_UsesProcWhenAvailable(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("procfs not available")
+	}
+
+	os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
+
+	info := Status()
+	if info == nil {
+		t.Fatal("Status() = nil, want info for running process")
+	}
+	if info.MetricsSource != SourceProc {
+		t.Errorf("MetricsSource = %q, want %q", info.MetricsSource, SourceProc)
+	}
+	if info.MemoryRSSKB <= 0 {
+		t.Errorf("MemoryRSSKB = %d, want > 0", info.MemoryRSSKB)
+	}
+}
+
+func TestStatus_FallsBackToHeartbeat(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	overrideProcRoot(t, t.TempDir())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useFakeClock(t, now)
+
+	os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
+	if err := writeHeartbeat(Heartbeat{
+		PID:       os.Getpid(),
+		StartedAt: now.Add(-90 * time.Minute),
+		UpdatedAt: now,
+		Runtime:   RuntimeStats{CPUSeconds: 12.5, MemoryKB: 20480},
+		Stats:     stats.Snapshot{BackendMemoryKB: 150 * 1024, Overwrites: 2, LastOverwriter: "Ditto", Captures: 7, Errors: 3, LastCapture: now.Add(-time.Minute)},
+	}); err != nil {
+		t.Fatalf("writeHeartbeat: %v", err)
+	}
+
+	info := Status()
+	if info == nil {
+		t.Fatal("Status() = nil, want info for running process")
+	}
+	if info.MetricsSource != SourceHeartbeat {
+		t.Errorf("MetricsSource = %q, want %q", info.MetricsSource, SourceHeartbeat)
+	}
+	if info.Uptime != 90*time.Minute {
+		t.Errorf("Uptime = %v, want 90m", info.Uptime)
+	}
+	if info.CPUTime != 12.5 || info.MemoryRSSKB != 20480 {
+		t.Errorf("CPUTime/MemoryRSSKB = %v/%d, want 12.5/20480", info.CPUTime, info.MemoryRSSKB)
+	}
+	if info.BackendMemoryKB != 150*1024 {
+		t.Errorf("BackendMemoryKB = %d, want %d", info.BackendMemoryKB, 150*1024)
+	}
+	if info.Overwrites != 2 || info.LastOverwriter != "Ditto" {
+		t.Errorf("Overwrites = %d (last %q), want 2 (last Ditto)", info.Overwrites, info.LastOverwriter)
+	}
+	if info.Captures != 7 || info.Errors != 3 || !info.LastCapture.Equal(now.Add(-time.Minute)) {
+		t.Errorf("Captures/Errors/LastCapture = %d/%d/%v, want the heartbeat's counters", info.Captures, info.Errors, info.LastCapture)
+	}
+}
+
+func TestProcessInfo_MarshalJSON(t *testing.T) {
+	info := &ProcessInfo{PID: 42, Uptime: 100 * time.Second, CPUTime: 5, Health: Health{Alive: true, BackendReady: true}}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["running"] != true || got["pid"] != 42.0 || got["uptime_seconds"] != 100.0 || got["cpu_percent"] != 5.0 || got["health_state"] != "ready" {
+		t.Errorf("JSON = %s", data)
+	}
+}
+
+func TestStatus_NoMetricsSource(t *testing.T) {
+	cleanup := setTestPaths(t)
+	defer cleanup()
+	overrideProcRoot(t, t.TempDir())
+
+	os.WriteFile(PidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
+
+	info := Status()
+	if info == nil {
+		t.Fatal("Status() = nil, want info for running process")
+	}
+	if info.MetricsSource != "" {
+		t.Errorf("MetricsSource = %q, want empty without /proc or heartbeat", info.MetricsSource)
+	}
+}
+
+func TestReadRuntimeStats(t *testing.T) {
+	rs := readRuntimeStats()
+	if rs.MemoryKB <= 0 {
+		t.Errorf("MemoryKB = %d, want > 0", rs.MemoryKB)
+	}
+	if rs.CPUSeconds < 0 {
+		t.Errorf("CPUSeconds = %v, want >= 0", rs.CPUSeconds)
+	}
+}
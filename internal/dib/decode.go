@@ -0,0 +1,193 @@
+// Package dib decodes the device-independent bitmap layouts the Windows
+// clipboard uses for CF_DIB and CF_DIBV5: a BITMAPINFOHEADER (or its V4/V5
+// extensions), optional masks and palette, then the pixel rows.
+package dib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/bits"
+)
+
+// headerSize is sizeof(BITMAPINFOHEADER).
+const headerSize = 40
+
+// biRGB is the BI_RGB (uncompressed) compression value.
+const biRGB = 0
+
+// v4HeaderSize is sizeof(BITMAPV4HEADER). V4 and V5 headers carry the color
+// masks inline, followed by color space data Decode ignores.
+const v4HeaderSize = 108
+
+// biBitfields is the BI_BITFIELDS compression value: pixels are unpacked with
+// explicit red/green/blue (and optionally alpha) masks.
+const biBitfields = 3
+
+// maxPixels bounds the decoded image so a corrupt header can't make Decode
+// allocate gigabytes. 100 megapixels covers any real multi-monitor capture.
+const maxPixels = 100_000_000
+
+// ErrUnsupported is returned for DIB layouts Decode does not handle, such as
+// RLE or JPEG/PNG-compressed bitmaps.
+var ErrUnsupported = errors.New("unsupported DIB format")
+
+// masks describes how packed 16- and 32-bit pixels map to channels.
+type masks struct{ r, g, b, a uint32 }
+
+// Default masks for BI_RGB: 16-bit is 5-5-5 (the top bit unused), 32-bit is
+// BGRX with the high byte ignored.
+var (
+	rgb555 = masks{r: 0x7c00, g: 0x03e0, b: 0x001f}
+	rgb888 = masks{r: 0x00ff0000, g: 0x0000ff00, b: 0x000000ff}
+)
+
+// Decode parses a CF_DIB or CF_DIBV5 payload: a BITMAPINFOHEADER,
+// BITMAPV4HEADER or BITMAPV5HEADER, then optional color masks and palette,
+// then the pixel rows. It handles palettized 1/4/8-bit bitmaps, 16-bit 5-5-5
+// and masked (e.g. 5-6-5) bitmaps, and 24/32-bit bitmaps, bottom-up or
+// top-down.
+func Decode(data []byte) (image.Image, error) {
+	le := binary.LittleEndian
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("DIB too short (%d bytes)", len(data))
+	}
+	hdr := int(le.Uint32(data[0:]))
+	if hdr < headerSize || hdr > len(data) {
+		return nil, fmt.Errorf("invalid DIB header size %d", hdr)
+	}
+	w := int(int32(le.Uint32(data[4:]))) // #nosec G115 -- LONG field, sign is meaningful
+	h := int(int32(le.Uint32(data[8:]))) // #nosec G115 -- negative height = top-down rows
+	bpp := int(le.Uint16(data[14:]))
+	compression := le.Uint32(data[16:])
+	clrUsed := int(le.Uint32(data[32:]))
+
+	topDown := h < 0
+	if topDown {
+		h = -h
+	}
+	if w <= 0 || h <= 0 || w*h > maxPixels {
+		return nil, fmt.Errorf("invalid DIB dimensions %dx%d", w, h)
+	}
+
+	offset := hdr
+	var m masks
+	switch {
+	case compression == biRGB && bpp == 16:
+		m = rgb555
+	case compression == biRGB && bpp == 32:
+		m = rgb888
+	case compression == biBitfields && (bpp == 16 || bpp == 32):
+		if hdr >= v4HeaderSize {
+			m = masks{le.Uint32(data[40:]), le.Uint32(data[44:]), le.Uint32(data[48:]), le.Uint32(data[52:])}
+		} else {
+			// A plain BITMAPINFOHEADER is followed by three DWORD masks.
+			if len(data) < offset+12 {
+				return nil, errors.New("DIB truncated in color masks")
+			}
+			m = masks{r: le.Uint32(data[offset:]), g: le.Uint32(data[offset+4:]), b: le.Uint32(data[offset+8:])}
+			offset += 12
+		}
+	case compression == biRGB && (bpp == 1 || bpp == 4 || bpp == 8 || bpp == 24):
+	default:
+		return nil, fmt.Errorf("%w: %d bpp, compression %d", ErrUnsupported, bpp, compression)
+	}
+
+	var palette color.Palette
+	if bpp <= 8 {
+		n := clrUsed
+		if n == 0 || n > 1<<bpp {
+			n = 1 << bpp
+		}
+		if len(data) < offset+n*4 {
+			return nil, errors.New("DIB truncated in color table")
+		}
+		palette = make(color.Palette, n)
+		for i := range palette {
+			q := data[offset+i*4:] // RGBQUAD: blue, green, red, reserved
+			palette[i] = color.RGBA{R: q[2], G: q[1], B: q[0], A: 0xff}
+		}
+		offset += n * 4
+	}
+
+	stride := (w*bpp + 31) / 32 * 4
+	if len(data) < offset+stride*h {
+		return nil, fmt.Errorf("DIB truncated: need %d bytes of pixels, have %d", stride*h, len(data)-offset)
+	}
+	pixels := data[offset:]
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	anyAlpha := false
+	for y := 0; y < h; y++ {
+		srcY := h - 1 - y
+		if topDown {
+			srcY = y
+		}
+		row := pixels[srcY*stride : (srcY+1)*stride]
+		for x := 0; x < w; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 1, 4, 8:
+				idx := int(row[x*bpp/8]>>(8-bpp-(x*bpp)%8)) & (1<<bpp - 1)
+				if idx >= len(palette) {
+					idx = 0 // out-of-range index in a short color table
+				}
+				c = color.NRGBAModel.Convert(palette[idx]).(color.NRGBA)
+			case 16:
+				c = m.unpack(uint32(le.Uint16(row[x*2:])))
+			case 24:
+				c = color.NRGBA{R: row[x*3+2], G: row[x*3+1], B: row[x*3], A: 0xff}
+			case 32:
+				c = m.unpack(le.Uint32(row[x*4:]))
+			}
+			img.SetNRGBA(x, y, c)
+			anyAlpha = anyAlpha || c.A != 0
+		}
+	}
+	if !anyAlpha {
+		// Many apps advertise an alpha mask but leave it zeroed. A fully
+		// transparent capture is never what they meant.
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	}
+	return img, nil
+}
+
+// unpack extracts each masked channel and scales it to 8 bits. Scaling (rather
+// than shifting) maps a 5-bit 31 to 255 instead of 248, which is what keeps
+// 16-bit captures from coming out dim and color-shifted.
+func (m masks) unpack(p uint32) color.NRGBA {
+	c := color.NRGBA{R: channel(p, m.r), G: channel(p, m.g), B: channel(p, m.b), A: 0xff}
+	if m.a != 0 {
+		c.A = channel(p, m.a)
+	}
+	return c
+}
+
+func channel(p, mask uint32) byte {
+	if mask == 0 {
+		return 0
+	}
+	shift := bits.TrailingZeros32(mask)
+	max := uint64(mask >> shift)
+	v := uint64((p & mask) >> shift)
+	return byte((v*255 + max/2) / max)
+}
+
+// ToPNG decodes a CF_DIB/CF_DIBV5 payload and re-encodes it as PNG.
+func ToPNG(data []byte) ([]byte, error) {
+	img, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,179 @@
+package dib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.png golden files")
+
+// TestDecode_Golden decodes every testdata/*.dib and compares the pixels with
+// the matching .png. Run with -update after an intended decoder change and
+// review the new PNGs before committing them.
+func TestDecode_Golden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.dib"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no golden inputs: %v", err)
+	}
+	for _, in := range inputs {
+		name := strings.TrimSuffix(filepath.Base(in), ".dib")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(data)
+			if err != nil {
+				t.Fatalf("Decode() error: %v", err)
+			}
+
+			golden := strings.TrimSuffix(in, ".dib") + ".png"
+			if *update {
+				var buf bytes.Buffer
+				if err := png.Encode(&buf, got); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f, err := os.Open(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			want, err := png.Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comparePixels(t, got, want)
+		})
+	}
+}
+
+func comparePixels(t *testing.T, got, want image.Image) {
+	t.Helper()
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g := color.NRGBAModel.Convert(got.At(x, y))
+			w := color.NRGBAModel.Convert(want.At(x, y))
+			if g != w {
+				t.Errorf("pixel (%d,%d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+// TestDecode_ChannelScaling pins the values the golden files rely on, so a
+// bad -update can't silently bless a color shift.
+func TestDecode_ChannelScaling(t *testing.T) {
+	tests := []struct {
+		file string
+		x, y int
+		want color.NRGBA
+	}{
+		{"rgb555.dib", 0, 1, color.NRGBA{255, 255, 255, 255}}, // 5-bit 31 is full intensity, not 248
+		{"rgb555.dib", 1, 1, color.NRGBA{132, 66, 33, 255}},
+		{"rgb565.dib", 1, 0, color.NRGBA{0, 255, 0, 255}},
+		{"rgb565_v5.dib", 1, 1, color.NRGBA{82, 162, 165, 255}},
+		{"pal8.dib", 2, 0, color.NRGBA{0, 200, 100, 255}},
+		{"pal4_topdown.dib", 2, 0, color.NRGBA{255, 0, 192, 255}}, // top-down: first stored row is the top
+		{"mono.dib", 9, 0, color.NRGBA{255, 255, 255, 255}},
+		{"argb32_v5.dib", 1, 0, color.NRGBA{0, 255, 0, 128}},
+		{"xrgb32_v5_zero_alpha.dib", 0, 0, color.NRGBA{255, 0, 0, 255}}, // zeroed alpha treated as opaque
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: Decode() error: %v", tt.file, err)
+		}
+		if got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); got != tt.want {
+			t.Errorf("%s (%d,%d) = %v, want %v", tt.file, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestDecode_RoundTripsEncode(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for i := range src.Pix {
+		src.Pix[i] = byte(i * 7)
+	}
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 0xff
+	}
+	got, err := Decode(encode24(src))
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	comparePixels(t, got, src)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	valid, err := os.ReadFile(filepath.Join("testdata", "pal8.dib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rle := append([]byte(nil), valid...)
+	rle[16] = 1 // BI_RLE8
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"short", []byte{40, 0, 0}},
+		{"truncated pixels", valid[:len(valid)-4]},
+		{"truncated palette", valid[:50]},
+		{"rle", rle},
+	}
+	for _, tt := range tests {
+		if _, err := Decode(tt.data); err == nil {
+			t.Errorf("%s: Decode() should fail", tt.name)
+		}
+	}
+	if _, err := Decode(rle); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("rle: error = %v, want ErrUnsupported", err)
+	}
+}
+
+// encode24 returns img as a bottom-up 24-bit BI_RGB DIB, as Windows
+// applications put plain bitmaps on the clipboard.
+func encode24(img image.Image) []byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	stride := (w*3 + 3) &^ 3 // rows are padded to 4 bytes
+	out := make([]byte, headerSize+stride*h)
+	le := binary.LittleEndian
+	le.PutUint32(out[0:], headerSize)
+	le.PutUint32(out[4:], uint32(w))
+	le.PutUint32(out[8:], uint32(h))
+	le.PutUint16(out[12:], 1)  // planes
+	le.PutUint16(out[14:], 24) // bits per pixel
+	le.PutUint32(out[20:], uint32(stride*h))
+
+	pixels := out[headerSize:]
+	for y := 0; y < h; y++ {
+		row := pixels[(h-1-y)*stride:]
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			row[x*3], row[x*3+1], row[x*3+2] = byte(bl>>8), byte(g>>8), byte(r>>8)
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+// Package events keeps a structured journal of what the daemon did, one JSON
+// object per line, separate from the free-form log. Scripts read it through
+// the events command instead of parsing log text.
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	TypeStart   = "start"   // the daemon started polling
+	TypeStop    = "stop"    // the daemon shut down
+	TypeCapture = "capture" // a new screenshot was saved
+	TypeError   = "error"   // a poll failed
+	TypeRestart = "restart" // the clipboard backend was restarted
+)
+
+// Types lists every event type, for validating filters.
+var Types = []string{TypeStart, TypeStop, TypeCapture, TypeError, TypeRestart}
+
+// maxSize is how large the journal may grow before it is rotated to
+// "<path>.1", replacing the previous rotation.
+const maxSize = 1 << 20
+
+// Event is one journal entry.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message,omitempty"`
+	Path    string    `json:"path,omitempty"` // the screenshot, for captures
+}
+
+// Journal appends events to a file. All methods are safe for concurrent use,
+// and a nil *Journal is valid and records nothing.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open returns a journal appending to path. The file is created on the first
+// event.
+func Open(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Append writes e to the journal. Failures are returned but callers usually
+// ignore them: the journal is a convenience and must never stop polling.
+func (j *Journal) Append(e Event) error {
+	if j == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if info, err := os.Stat(j.path); err == nil && info.Size()+int64(len(data)) >= maxSize {
+		_ = os.Rename(j.path, j.path+".1") // best-effort, appending still works without it
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Filter selects events. Zero fields match everything.
+type Filter struct {
+	Types []string
+	Since time.Time
+}
+
+func (f Filter) match(e Event) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
+
+// Read returns the events in the journal at path, including its rotated
+// part, that match f, oldest first. A missing journal has no events.
+// Malformed lines, e.g. one cut short by a crash, are skipped.
+func Read(path string, f Filter) ([]Event, error) {
+	var out []Event
+	for _, p := range []string{path + ".1", path} {
+		evs, err := readFile(p, f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, evs...)
+	}
+	return out, nil
+}
+
+func readFile(path string, f Filter) ([]Event, error) {
+	file, err := os.Open(path) // #nosec G304 -- path is the daemon's own journal
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var out []Event
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		var e Event
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.Type == "" {
+			continue
+		}
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return out, nil
+}
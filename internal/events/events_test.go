@@ -0,0 +1,65 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	j := Open(path)
+	for i, typ := range []string{TypeStart, TypeCapture, TypeError, TypeCapture} {
+		if err := j.Append(Event{Time: epoch.Add(time.Duration(i) * time.Minute), Type: typ}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A torn final line from a crash is skipped.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"time":"2026-05-01T12:`)
+	f.Close()
+
+	all, err := Read(path, Filter{})
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Read() = %d events, %v; want 4", len(all), err)
+	}
+
+	got, _ := Read(path, Filter{Types: []string{TypeCapture}, Since: epoch.Add(2 * time.Minute)})
+	if len(got) != 1 || !got[0].Time.Equal(epoch.Add(3*time.Minute)) {
+		t.Errorf("filtered Read() = %+v, want the last capture", got)
+	}
+}
+
+func TestRead_MissingJournal(t *testing.T) {
+	got, err := Read(filepath.Join(t.TempDir(), "none"), Filter{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Read(missing) = %v, %v; want no events", got, err)
+	}
+}
+
+func TestAppend_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	os.WriteFile(path, []byte(strings.Repeat("{}\n", maxSize/3)), 0600)
+
+	if err := Open(path).Append(Event{Time: epoch, Type: TypeStop}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("full journal was not rotated: %v", err)
+	}
+	got, _ := Read(path, Filter{})
+	if len(got) != 1 || got[0].Type != TypeStop {
+		t.Errorf("Read() after rotation = %+v, want the new event", got)
+	}
+}
+
+func TestNilJournal(t *testing.T) {
+	var j *Journal
+	if err := j.Append(Event{Type: TypeStart}); err != nil {
+		t.Errorf("nil Append() = %v, want nil", err)
+	}
+}
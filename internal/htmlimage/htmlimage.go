@@ -0,0 +1,212 @@
+// Package htmlimage finds the image in a CF_HTML clipboard payload that holds
+// nothing but one image, as some applications' "Copy image" produce, and
+// loads it as PNG from a data: URI or over HTTP(S).
+package htmlimage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"  // registers GIF for image.Decode
+	_ "image/jpeg" // registers JPEG for image.Decode
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBytes bounds a loaded image, before conversion.
+const MaxBytes = 32 << 20
+
+// MaxPixels bounds the dimensions of a loaded image, checked before it is
+// decoded: a small file can declare an image that takes gigabytes to hold.
+const MaxPixels = 8192 * 8192
+
+// fetchTimeout bounds a download, so a slow server delays a poll by at most
+// this long.
+const fetchTimeout = 15 * time.Second
+
+// ErrUnsupportedSource is returned by Load for image sources other than data:
+// URIs and http(s) URLs, such as file: URLs, which would read local files.
+var ErrUnsupportedSource = errors.New("unsupported image source")
+
+// httpClient fetches remote images. Declared as a var so tests can point it
+// at a test server.
+var httpClient = &http.Client{Timeout: fetchTimeout}
+
+var (
+	imgTag    = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	srcAttr   = regexp.MustCompile(`(?is)\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	invisible = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+	anyTag    = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// Fragment returns the fragment of a CF_HTML payload: the part between the
+// StartFragment and EndFragment offsets of its header, or between the
+// <!--StartFragment--> and <!--EndFragment--> markers when the offsets are
+// missing or out of range, or the whole payload.
+func Fragment(cfhtml []byte) []byte {
+	start, end := -1, -1
+	sc := bufio.NewScanner(bytes.NewReader(cfhtml))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok || strings.HasPrefix(key, "<") {
+			break // end of the header
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "StartFragment":
+			start = n
+		case "EndFragment":
+			end = n
+		}
+	}
+	if 0 <= start && start <= end && end <= len(cfhtml) {
+		return cfhtml[start:end]
+	}
+	if _, rest, ok := bytes.Cut(cfhtml, []byte("<!--StartFragment-->")); ok {
+		frag, _, _ := bytes.Cut(rest, []byte("<!--EndFragment-->"))
+		return frag
+	}
+	return cfhtml
+}
+
+// ImageSource returns the src of the only <img> in fragment. ok is false
+// when the fragment has no image, several, or any visible text besides the
+// image: that is a copied document, not a copied image.
+func ImageSource(fragment []byte) (src string, ok bool) {
+	imgs := imgTag.FindAll(fragment, 2)
+	if len(imgs) != 1 {
+		return "", false
+	}
+	text := anyTag.ReplaceAll(invisible.ReplaceAll(fragment, nil), nil)
+	if strings.TrimSpace(html.UnescapeString(string(text))) != "" { // TrimSpace also drops &nbsp;
+		return "", false
+	}
+	m := srcAttr.FindSubmatch(imgs[0])
+	if m == nil {
+		return "", false
+	}
+	src = strings.TrimSpace(html.UnescapeString(string(bytes.Join(m[1:], nil))))
+	return src, src != ""
+}
+
+// Load reads the image src points to, a data: URI or an http(s) URL, and
+// returns it as PNG. PNGs are returned unchanged; GIF and JPEG images are
+// converted.
+func Load(ctx context.Context, src string) ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(strings.ToLower(src), "data:"):
+		data, err = decodeDataURI(src)
+	case strings.HasPrefix(strings.ToLower(src), "http://"), strings.HasPrefix(strings.ToLower(src), "https://"):
+		data, err = fetch(ctx, src)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, Redact(src))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toPNG(data)
+}
+
+// decodeDataURI returns the content of a data: URI, base64 or
+// percent-encoded.
+func decodeDataURI(uri string) ([]byte, error) {
+	meta, payload, ok := strings.Cut(uri[len("data:"):], ",")
+	if !ok {
+		return nil, errors.New("malformed data URI")
+	}
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		if base64.StdEncoding.DecodedLen(len(payload)) > MaxBytes {
+			return nil, fmt.Errorf("image larger than %d bytes", MaxBytes)
+		}
+		// Whitespace may wrap long URIs; the decoder does not skip it.
+		payload = strings.Join(strings.Fields(payload), "")
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	s, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed data URI: %w", err)
+	}
+	if len(s) > MaxBytes {
+		return nil, fmt.Errorf("image larger than %d bytes", MaxBytes)
+	}
+	return []byte(s), nil
+}
+
+// fetch downloads src, up to MaxBytes.
+func fetch(ctx context.Context, src string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", Redact(src), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", Redact(src), err)
+	}
+	if len(data) > MaxBytes {
+		return nil, fmt.Errorf("fetch %s: image larger than %d bytes", Redact(src), MaxBytes)
+	}
+	return data, nil
+}
+
+// toPNG returns data as PNG, converting the formats image.Decode knows.
+// Images of more than MaxPixels are rejected before they are decoded.
+func toPNG(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large (at most %d)", cfg.Width, cfg.Height, MaxPixels)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if format == "png" {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Redact shortens src for logs: data: URIs to their media type, URLs to
+// their scheme, host and path, dropping a query string that may carry
+// tokens.
+func Redact(src string) string {
+	if strings.HasPrefix(strings.ToLower(src), "data:") {
+		meta, _, _ := strings.Cut(src, ",")
+		return meta + ",..."
+	}
+	u, err := url.Parse(src)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
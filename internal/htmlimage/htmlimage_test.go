@@ -0,0 +1,155 @@
+package htmlimage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// cfHTML wraps fragment in a CF_HTML payload with a valid header.
+func cfHTML(fragment string) []byte {
+	const header = "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\nStartFragment:%010d\r\nEndFragment:%010d\r\n"
+	pre, post := "<html><body><!--StartFragment-->", "<!--EndFragment--></body></html>"
+	n := len(fmt.Sprintf(header, 0, 0, 0, 0))
+	start := n + len(pre)
+	end := start + len(fragment)
+	return []byte(fmt.Sprintf(header, n, end+len(post), start, end) + pre + fragment + post)
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	return img
+}
+
+func encodePNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFragment(t *testing.T) {
+	frag := `<img src="a.png">`
+	if got := string(Fragment(cfHTML(frag))); got != frag {
+		t.Errorf("Fragment() = %q, want %q", got, frag)
+	}
+	// Offsets out of range: fall back to the markers.
+	broken := []byte("Version:0.9\r\nStartFragment:9999\r\nEndFragment:99999\r\n<html><!--StartFragment-->" + frag + "<!--EndFragment--></html>")
+	if got := string(Fragment(broken)); got != frag {
+		t.Errorf("Fragment(bad offsets) = %q, want %q", got, frag)
+	}
+	if got := string(Fragment([]byte(frag))); got != frag {
+		t.Errorf("Fragment(no header) = %q, want the whole payload", got)
+	}
+}
+
+func TestImageSource(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     string
+		ok       bool
+	}{
+		{`<img src="https://example.com/a.png?x=1&amp;y=2">`, "https://example.com/a.png?x=1&y=2", true},
+		{`<IMG alt='cat' SRC='data:image/png;base64,AAAA' />`, "data:image/png;base64,AAAA", true},
+		{"\r\n<meta charset=utf-8><!-- note --><img src=a.gif>&nbsp;", "a.gif", true},
+		{`<p>Look at this</p><img src="a.png">`, "", false},
+		{`<img src="a.png"><img src="b.png">`, "", false},
+		{`<p>no image</p>`, "", false},
+		{`<img alt="no source">`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ImageSource([]byte(tt.fragment))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ImageSource(%q) = %q, %v; want %q, %v", tt.fragment, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoad_DataURI(t *testing.T) {
+	want := encodePNG(t)
+	got, err := Load(context.Background(), "data:image/png;base64,"+base64.StdEncoding.EncodeToString(want))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("a PNG data URI should load byte for byte")
+	}
+}
+
+func TestLoad_RejectsHugeImages(t *testing.T) {
+	// A small PNG whose header declares 60000x60000 pixels.
+	data := encodePNG(t)
+	binary.BigEndian.PutUint32(data[16:], 60000)
+	binary.BigEndian.PutUint32(data[20:], 60000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	if _, err := Load(context.Background(), "data:image/png;base64,"+base64.StdEncoding.EncodeToString(data)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Load(60000x60000) error = %v, want too large", err)
+	}
+
+	// Percent-encoded data URIs are bounded too.
+	if _, err := Load(context.Background(), "data:image/png,"+strings.Repeat("a", MaxBytes+1)); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Load(oversized percent-encoded URI) error = %v, want larger than", err)
+	}
+}
+
+func TestLoad_FetchesAndConvertsJPEG(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cat.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(jpg.Bytes())
+	}))
+	defer srv.Close()
+
+	got, err := Load(context.Background(), srv.URL+"/cat.jpg")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if _, format, err := image.Decode(bytes.NewReader(got)); err != nil || format != "png" {
+		t.Errorf("Load() returned %s (%v), want png", format, err)
+	}
+
+	if _, err := Load(context.Background(), srv.URL+"/missing.png"); err == nil {
+		t.Error("Load() of a 404 should fail")
+	}
+}
+
+func TestLoad_RejectsOtherSchemes(t *testing.T) {
+	for _, src := range []string{"file:///etc/passwd", "C:\\Users\\me\\a.png", "a.png"} {
+		if _, err := Load(context.Background(), src); !errors.Is(err, ErrUnsupportedSource) {
+			t.Errorf("Load(%q) error = %v, want ErrUnsupportedSource", src, err)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := map[string]string{
+		"https://cdn.example.com/a.png?token=secret": "https://cdn.example.com/a.png",
+		"data:image/png;base64,AAAA":                 "data:image/png;base64,...",
+	}
+	for src, want := range tests {
+		if got := Redact(src); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package i18n
+
+// english is the reference catalog: every message ID must be here.
+var english = Catalog{
+	// Daemon lifecycle (start, stop).
+	"daemon.already_running":  "Polling process is already running (PID %d)",
+	"daemon.started":          "Polling process started (PID %d). Run 'wsl-screenshot-cli status' to check status.",
+	"daemon.stopped":          "Polling process stopped successfully (PID %d)",
+	"daemon.killed":           "Polling process (PID %d) did not stop within %s and was killed",
+	"daemon.not_running":      "Polling process is not running",
+	"daemon.corrupt_pid_file": "Polling process is not running. Cleaned up corrupt PID file.",
+	"daemon.stale_pid_file":   "Polling process is not running. Cleaned up stale PID file.",
+	"daemon.stale_pid":        "Polling process was not running (PID %d). Cleaned up stale PID file.",
+
+	// Environment checks.
+	"platform.not_wsl":          "This CLI is meant to be run only inside a WSL instance with access to powershell.exe",
+	"platform.interop_disabled": "WSL interoperability is disabled. Enable it in /etc/wsl.conf, see https://learn.microsoft.com/en-us/windows/wsl/wsl-config#example-wslconf-file for details.",
+
+	// Command guidance.
+	"cmd.output_not_writable": "Output directory is not writable: %w",
+	"cmd.case_insensitive": "Warning: %s is case-insensitive (a Windows drive mount). Screenshots are named by lower-case hash, so dedup stays correct, " +
+		"but files that differ only in case are the same file there. A Linux directory such as ~/screenshots is faster and avoids this.",
+	"cmd.restart_not_running": "Polling process is not running, use 'wsl-screenshot-cli start --daemon'",
+	"cmd.no_screenshots":      "No screenshots found in %s",
+	"cmd.no_clipboard_image":  "No image on the clipboard",
+	"cmd.no_log_file":         "No log file at %s (has the daemon been started?)",
+	"cmd.status_not_running":  "Status:  not running",
+	"cmd.status_last_exit":    "Last exit:    %s ago, status %d: %s",
+	"cmd.no_events":           "No events",
+	"cmd.no_instances":        "No running instances",
+	"cmd.update_stopping":     "Stopping running daemon before update...",
+	"cmd.migrated":            "Moved %d screenshots from %s to %s",
+	"cmd.migrate_not_stopped": "Polling process (PID %d) did not exit, no files were moved",
+	"cmd.restart_not_stopped": "Polling process (PID %d) did not exit, not restarting",
+
+	// Common errors.
+	"cmd.invalid_flag":            "Invalid --%s: %w",
+	"cmd.flag_negative":           "Invalid --%s %d (must be 0 or more)",
+	"cmd.invalid_timezone":        "Invalid timezone %q: %w",
+	"cmd.invalid_env_override":    "Invalid environment override %w",
+	"cmd.unknown_setting":         "Unknown setting %q",
+	"cmd.invalid_setting_value":   "Invalid value for %s: %w",
+	"cmd.config_read_failed":      "Failed to read config file: %w",
+	"cmd.config_write_failed":     "Failed to write config file: %w",
+	"cmd.unsupported_shell":       "Unsupported shell %q (supported: %s)",
+	"cmd.clipboard_client_failed": "Failed to start clipboard client: %w",
+	"cmd.no_match":                "No screenshot in %s matches %q",
+	"cmd.copy_failed":             "Failed to copy %s: %w",
+
+	// Daemon output.
+	"daemon.settings_changed": "Settings changed, restarting polling process (PID %d)",
+	"daemon.failing":          "Polling process (PID %d) is failing to poll, restarting it",
+
+	// completion doctor.
+	"doctor.wslpath_unavailable":   "Cannot run wslpath, path conversion is unavailable: %w",
+	"doctor.windows":               "Windows: %s",
+	"doctor.version_unknown":       "unknown (cmd.exe ver failed)",
+	"doctor.header":                "CASE\tWINDOWS PATH\tROUND-TRIP\tWINDOWS OPENS IT",
+	"doctor.case_error":            "error: %v",
+	"doctor.ok":                    "ok",
+	"doctor.failed":                "FAILED",
+	"doctor.yes":                   "yes",
+	"doctor.no":                    "no",
+	"doctor.file_drops":            "File drops:",
+	"doctor.drive_ok":              `Drive paths (C:\...) work. Every application accepts these.`,
+	"doctor.drive_failed":          `Drive paths (C:\...) do not work. Check that the Windows drive is mounted under /mnt.`,
+	"doctor.unc_ok":                "%s paths work.",
+	"doctor.unc_failed":            "%s paths do not work.",
+	"doctor.wsl_localhost_too_old": `\\wsl.localhost\ paths do not work: Windows build %d predates them (%d+). Use \\wsl$\ or a drive path.`,
+	"doctor.name_failed":           "Paths with %s do not survive conversion. Avoid them in --output.",
+	"doctor.unc_hint": "Screenshots under the Linux filesystem are pasted as UNC paths. If pasting as a file fails in one application only, " +
+		"it probably can't open UNC paths: use an --output directory under /mnt/c.",
+	"doctor.nothing_checked": "No path form could be checked.",
+}
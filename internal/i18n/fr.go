@@ -0,0 +1,69 @@
+package i18n
+
+// french translates the English catalog. Missing IDs fall back to English.
+var french = Catalog{
+	"daemon.already_running":  "Le processus de surveillance est déjà en cours d'exécution (PID %d)",
+	"daemon.started":          "Processus de surveillance démarré (PID %d). Lancez 'wsl-screenshot-cli status' pour vérifier son état.",
+	"daemon.stopped":          "Processus de surveillance arrêté (PID %d)",
+	"daemon.killed":           "Le processus de surveillance (PID %d) ne s'est pas arrêté en %s et a été tué",
+	"daemon.not_running":      "Le processus de surveillance n'est pas en cours d'exécution",
+	"daemon.corrupt_pid_file": "Le processus de surveillance n'est pas en cours d'exécution. Fichier PID corrompu supprimé.",
+	"daemon.stale_pid_file":   "Le processus de surveillance n'est pas en cours d'exécution. Fichier PID obsolète supprimé.",
+	"daemon.stale_pid":        "Le processus de surveillance ne tournait plus (PID %d). Fichier PID obsolète supprimé.",
+
+	"platform.not_wsl":          "Cet outil ne fonctionne que dans une instance WSL ayant accès à powershell.exe",
+	"platform.interop_disabled": "L'interopérabilité WSL est désactivée. Activez-la dans /etc/wsl.conf, voir https://learn.microsoft.com/fr-fr/windows/wsl/wsl-config#example-wslconf-file pour plus de détails.",
+
+	"cmd.output_not_writable": "Impossible d'écrire dans le dossier de sortie : %w",
+	"cmd.case_insensitive": "Attention : %s ne distingue pas la casse (lecteur Windows monté). Les captures sont nommées par hachage en minuscules, la déduplication reste donc correcte, " +
+		"mais deux fichiers ne différant que par la casse y sont le même fichier. Un dossier Linux comme ~/screenshots est plus rapide et évite ce problème.",
+	"cmd.restart_not_running": "Le processus de surveillance n'est pas en cours d'exécution, utilisez 'wsl-screenshot-cli start --daemon'",
+	"cmd.no_screenshots":      "Aucune capture trouvée dans %s",
+	"cmd.no_clipboard_image":  "Aucune image dans le presse-papiers",
+	"cmd.no_log_file":         "Aucun journal à %s (le démon a-t-il été démarré ?)",
+	"cmd.status_not_running":  "État :  arrêté",
+	"cmd.status_last_exit":    "Dernier arrêt : il y a %s, code %d : %s",
+	"cmd.no_events":           "Aucun événement",
+	"cmd.no_instances":        "Aucune instance en cours d'exécution",
+	"cmd.update_stopping":     "Arrêt du démon avant la mise à jour...",
+	"cmd.migrated":            "%d captures déplacées de %s vers %s",
+	"cmd.migrate_not_stopped": "Le processus de surveillance (PID %d) ne s'est pas arrêté, aucun fichier n'a été déplacé",
+	"cmd.restart_not_stopped": "Le processus de surveillance (PID %d) ne s'est pas arrêté, pas de redémarrage",
+
+	"cmd.invalid_flag":            "--%s invalide : %w",
+	"cmd.flag_negative":           "--%s %d invalide (doit valoir 0 ou plus)",
+	"cmd.invalid_timezone":        "Fuseau horaire %q invalide : %w",
+	"cmd.invalid_env_override":    "Variable d'environnement invalide : %w",
+	"cmd.unknown_setting":         "Paramètre inconnu %q",
+	"cmd.invalid_setting_value":   "Valeur invalide pour %s : %w",
+	"cmd.config_read_failed":      "Impossible de lire le fichier de configuration : %w",
+	"cmd.config_write_failed":     "Impossible d'écrire le fichier de configuration : %w",
+	"cmd.unsupported_shell":       "Shell %q non pris en charge (pris en charge : %s)",
+	"cmd.clipboard_client_failed": "Impossible de démarrer le client du presse-papiers : %w",
+	"cmd.no_match":                "Aucune capture dans %s ne correspond à %q",
+	"cmd.copy_failed":             "Impossible de copier %s : %w",
+
+	"daemon.settings_changed": "Paramètres modifiés, redémarrage du processus de surveillance (PID %d)",
+	"daemon.failing":          "Le processus de surveillance (PID %d) n'arrive plus à interroger le presse-papiers, redémarrage",
+
+	"doctor.wslpath_unavailable": "Impossible de lancer wslpath, la conversion des chemins n'est pas disponible : %w",
+	"doctor.windows":             "Windows : %s",
+	"doctor.version_unknown":     "inconnu (échec de cmd.exe ver)",
+	"doctor.header":              "CAS\tCHEMIN WINDOWS\tALLER-RETOUR\tWINDOWS L'OUVRE",
+	"doctor.case_error":          "erreur : %v",
+	"doctor.ok":                  "ok",
+	"doctor.failed":              "ÉCHEC",
+	"doctor.yes":                 "oui",
+	"doctor.no":                  "non",
+	"doctor.file_drops":          "Dépôt de fichiers :",
+	"doctor.drive_ok":            `Les chemins de lecteur (C:\...) fonctionnent. Toutes les applications les acceptent.`,
+	"doctor.drive_failed":        `Les chemins de lecteur (C:\...) ne fonctionnent pas. Vérifiez que le lecteur Windows est monté sous /mnt.`,
+	"doctor.unc_ok":              "Les chemins %s fonctionnent.",
+	"doctor.unc_failed":          "Les chemins %s ne fonctionnent pas.",
+	"doctor.wsl_localhost_too_old": `Les chemins \\wsl.localhost\ ne fonctionnent pas : la version %d de Windows est antérieure à leur arrivée (%d+). ` +
+		`Utilisez \\wsl$\ ou un chemin de lecteur.`,
+	"doctor.name_failed": "Les chemins avec « %s » ne survivent pas à la conversion. Évitez-les dans --output.",
+	"doctor.unc_hint": "Les captures stockées dans le système de fichiers Linux sont collées sous forme de chemins UNC. Si le collage en tant que fichier " +
+		"n'échoue que dans une application, elle ne sait sans doute pas ouvrir les chemins UNC : utilisez un dossier --output sous /mnt/c.",
+	"doctor.nothing_checked": "Aucune forme de chemin n'a pu être vérifiée.",
+}
@@ -0,0 +1,83 @@
+// Package i18n translates user-facing messages. Messages are looked up by ID
+// in the catalog of the user's language (from LC_ALL, LC_MESSAGES or LANG)
+// and fall back to English, so a missing translation never hides a message.
+//
+// Adding a language means adding a catalog file like fr.go and registering
+// it in catalogs; TestCatalogs checks it against the English one.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Catalog maps message IDs to fmt templates in one language.
+type Catalog map[string]string
+
+// catalogs holds every supported language by its ISO 639-1 code.
+var catalogs = map[string]Catalog{
+	"en": english,
+	"fr": french,
+}
+
+// lang is the language messages are rendered in.
+var lang = Detect(os.Getenv)
+
+// Detect picks the language from the POSIX locale variables, in their usual
+// precedence. "fr_FR.UTF-8" selects "fr"; C, POSIX and unsupported languages
+// select English.
+func Detect(getenv func(string) string) string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := getenv(key)
+		if v == "" {
+			continue
+		}
+		code := strings.ToLower(v)
+		if i := strings.IndexAny(code, "_.@-"); i >= 0 {
+			code = code[:i]
+		}
+		if _, ok := catalogs[code]; ok {
+			return code
+		}
+		return "en"
+	}
+	return "en"
+}
+
+// Language returns the language messages are rendered in.
+func Language() string { return lang }
+
+// SetLanguage switches the message language (tests, mostly) and returns a
+// function that restores the previous one. Unsupported codes select English.
+func SetLanguage(code string) (restore func()) {
+	prev := lang
+	if _, ok := catalogs[code]; ok {
+		lang = code
+	} else {
+		lang = "en"
+	}
+	return func() { lang = prev }
+}
+
+// template returns the message for id in the current language, falling back
+// to English and then to the ID itself.
+func template(id string) string {
+	if s, ok := catalogs[lang][id]; ok {
+		return s
+	}
+	if s, ok := english[id]; ok {
+		return s
+	}
+	return id
+}
+
+// T renders message id with args.
+func T(id string, args ...any) string {
+	return fmt.Sprintf(template(id), args...)
+}
+
+// Errorf renders message id with args as an error; %w wraps as in fmt.Errorf.
+func Errorf(id string, args ...any) error {
+	return fmt.Errorf(template(id), args...)
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{nil, "en"},
+		{map[string]string{"LANG": "fr_FR.UTF-8"}, "fr"},
+		{map[string]string{"LANG": "C.UTF-8"}, "en"},
+		{map[string]string{"LANG": "ja_JP.UTF-8"}, "en"},
+		{map[string]string{"LANG": "fr_FR.UTF-8", "LC_MESSAGES": "POSIX"}, "en"},
+		{map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "fr_CA"}, "fr"},
+	}
+	for _, tt := range tests {
+		if got := Detect(func(k string) string { return tt.env[k] }); got != tt.want {
+			t.Errorf("Detect(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestT_Fallback(t *testing.T) {
+	defer SetLanguage("fr")()
+	if got := T("daemon.stopped", 42); got != "Processus de surveillance arrêté (PID 42)" {
+		t.Errorf("T(fr) = %q", got)
+	}
+
+	english["test.only_english"] = "only %s"
+	defer delete(english, "test.only_english")
+	if got := T("test.only_english", "english"); got != "only english" {
+		t.Errorf("missing translation = %q, want the English message", got)
+	}
+	if got := T("no.such.id"); got != "no.such.id" {
+		t.Errorf("unknown ID = %q, want the ID itself", got)
+	}
+
+	inner := errors.New("permission denied")
+	if err := Errorf("cmd.output_not_writable", inner); !errors.Is(err, inner) {
+		t.Errorf("Errorf should wrap %%w arguments, got %v", err)
+	}
+}
+
+var verb = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogs checks that every translation has an English original that
+// takes the same arguments in the same order.
+func TestCatalogs(t *testing.T) {
+	for code, c := range catalogs {
+		for id, msg := range c {
+			en, ok := english[id]
+			if !ok {
+				t.Errorf("%s: %q has no English message", code, id)
+				continue
+			}
+			if got, want := verb.FindAllString(msg, -1), verb.FindAllString(en, -1); !slices.Equal(got, want) {
+				t.Errorf("%s: %q uses %v, English uses %v", code, id, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package logfile provides a size-capped, rotating log file writer.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Rotator appends to a log file and rotates it once a write would grow it
+// past MaxSize: path.N-1 becomes path.N and so on, path becomes path.1, and
+// backups beyond MaxBackups are deleted. All methods are safe for concurrent
+// use.
+type Rotator struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	// OnRotate, if set, is called with the fresh file after each rotation,
+	// e.g. to point stdout and stderr at it. Rotator keeps ownership of f.
+	OnRotate func(f *os.File) error
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens (or creates) the log file at path for appending. maxSize <= 0
+// disables rotation; maxBackups < 0 is treated as 0, keeping no old data.
+func Open(path string, maxSize int64, maxBackups int) (*Rotator, error) {
+	r := &Rotator{path: path, maxSize: maxSize, maxBackups: max(maxBackups, 0)}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rotator) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// File returns the file currently written to.
+func (r *Rotator) File() *os.File {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f
+}
+
+// Write appends p, rotating first if p would push the file past the limit.
+// A file that is empty is never rotated, so a single huge write still lands.
+// A failed rotation is noted in the log and writing continues to the current
+// file: losing the cap beats losing the log.
+func (r *Rotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info, err := r.f.Stat(); err == nil {
+		r.size = info.Size() // includes what others wrote to the same file, e.g. via stderr
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			msg := fmt.Sprintf("Warning: log rotation failed, still writing to %s: %v\n", r.path, err)
+			n, _ := r.f.WriteString(msg)
+			r.size += int64(n)
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups and starts a new file. r.mu must be held.
+func (r *Rotator) rotate() error {
+	if r.maxBackups == 0 {
+		_ = os.Remove(r.path)
+	} else {
+		_ = os.Remove(backup(r.path, r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(backup(r.path, i), backup(r.path, i+1)) // gaps are fine
+		}
+		if err := os.Rename(r.path, backup(r.path, 1)); err != nil {
+			return err
+		}
+	}
+
+	old := r.f
+	if err := r.open(); err != nil {
+		r.f = old // keep writing to the renamed file
+		return err
+	}
+	_ = old.Close()
+	if r.OnRotate != nil {
+		return r.OnRotate(r.f)
+	}
+	return nil
+}
+
+// Close closes the current file.
+func (r *Rotator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// backup names the i-th rotated copy of path.
+func backup(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
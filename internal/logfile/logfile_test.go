@@ -0,0 +1,93 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "<missing>"
+		}
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	r, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var rotated []*os.File
+	r.OnRotate = func(f *os.File) error {
+		rotated = append(rotated, f)
+		return nil
+	}
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "gggg\n",
+		path + ".1": "eeee\nffff\n",
+		path + ".2": "cccc\ndddd\n",
+		path + ".3": "<missing>", // beyond maxBackups
+	}
+	for p, w := range want {
+		if got := readFile(t, p); got != w {
+			t.Errorf("%s = %q, want %q", filepath.Base(p), got, w)
+		}
+	}
+	if len(rotated) != 3 || rotated[2] != r.File() {
+		t.Errorf("OnRotate called %d times, want 3 with the current file last", len(rotated))
+	}
+}
+
+func TestRotator_Limits(t *testing.T) {
+	dir := t.TempDir()
+
+	// An oversized write into an empty file lands instead of rotating forever.
+	path := filepath.Join(dir, "big.log")
+	r, err := Open(path, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = r.Write([]byte("0123456789\n"))
+	_ = r.Close()
+	if got := readFile(t, path); got != "0123456789\n" || readFile(t, path+".1") != "<missing>" {
+		t.Errorf("oversized first write: %q, want it kept whole", got)
+	}
+
+	// No backups: the old data is simply dropped.
+	path = filepath.Join(dir, "nobackups.log")
+	r, _ = Open(path, 6, 0)
+	_, _ = r.Write([]byte("old\n"))
+	_, _ = r.Write([]byte("new\n"))
+	_ = r.Close()
+	if got := readFile(t, path); got != "new\n" || readFile(t, path+".1") != "<missing>" {
+		t.Errorf("maxBackups 0: %s = %q, want only the new line", filepath.Base(path), got)
+	}
+
+	// maxSize 0 disables rotation; existing content counts towards the size.
+	path = filepath.Join(dir, "unlimited.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = Open(path, 0, 3)
+	_, _ = r.Write([]byte(strings.Repeat("x", 100) + "\n"))
+	_ = r.Close()
+	if got := readFile(t, path); !strings.HasPrefix(got, "existing\n") || readFile(t, path+".1") != "<missing>" {
+		t.Errorf("maxSize 0 should append without rotating, got %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+// Package logging builds the slog loggers the daemon and its components log
+// through, in a human-readable text or machine-readable JSON format.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formats accepted by New.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ComponentKey is the attribute naming the part of the program a record
+// comes from: "daemon", "poller", "clipboard" or "maintenance".
+const ComponentKey = "component"
+
+// ParseLevel parses a --log-level value: debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+// ParseFormat validates a --log-format value: text or json.
+func ParseFormat(s string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(s)); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q (want text or json)", s)
+}
+
+// New returns a logger writing records at or above level to w in format,
+// which must have passed ParseFormat (anything but json is text).
+func New(w io.Writer, format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Component tags every record of logger with the component name.
+func Component(logger *slog.Logger, name string) *slog.Logger {
+	return logger.With(ComponentKey, name)
+}
+
+// Discard returns a logger that drops everything, for tests and callers
+// that don't care.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
@@ -0,0 +1,148 @@
+// Package logtail prints the end of a log file and follows it as it grows,
+// like tail -F, without depending on a tail binary being installed.
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+// PollInterval is how often Follow checks the file for new data. The daemon
+// logs a few lines a minute, so polling costs nothing measurable and also
+// works on drvfs and other filesystems where inotify events never arrive.
+var PollInterval = 250 * time.Millisecond
+
+// chunkSize is how much Last reads per step backwards from the end.
+const chunkSize = 4096
+
+// Last returns the last n lines of the file at path and the offset just past
+// them, where Follow should pick up. n <= 0 returns nothing.
+func Last(path string, n int) ([]byte, int64, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is the daemon's own log file
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	if n <= 0 || size == 0 {
+		return nil, size, nil
+	}
+
+	// Walk back a chunk at a time until the buffer holds n line breaks
+	// (not counting the one ending the file) or the start is reached.
+	var buf []byte
+	pos := size
+	for pos > 0 {
+		step := min(int64(chunkSize), pos)
+		pos -= step
+		chunk := make([]byte, step)
+		if _, err := f.ReadAt(chunk, pos); err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		buf = append(chunk, buf...)
+		if bytes.Count(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) >= n {
+			break
+		}
+	}
+
+	body := bytes.TrimSuffix(buf, []byte("\n"))
+	for i := len(body) - 1; i >= 0; i-- {
+		if body[i] != '\n' {
+			continue
+		}
+		if n--; n == 0 {
+			buf = buf[i+1:]
+			break
+		}
+	}
+	return buf, size, nil
+}
+
+// Follow copies whatever is appended to the file at path after offset to w
+// until ctx is done. A file that shrinks (truncated) is read again from the
+// start; one that is replaced (rotated) is drained and then the new file is
+// followed from its start. A missing file is waited for.
+func Follow(ctx context.Context, path string, offset int64, w io.Writer, clk clock.Clock) error {
+	var f *os.File
+	defer func() {
+		if f != nil {
+			_ = f.Close()
+		}
+	}()
+
+	ticker := clk.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		if f == nil {
+			var err error
+			if f, err = os.Open(path); err == nil { // #nosec G304 -- path is the daemon's own log file
+				if _, err := f.Seek(offset, io.SeekStart); err != nil {
+					return err
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if f != nil {
+			if err := drain(f, &offset, w); err != nil {
+				return err
+			}
+			if replaced(f, path) {
+				// Pick up what was written between the drain and the rename.
+				if err := drain(f, &offset, w); err != nil {
+					return err
+				}
+				_ = f.Close()
+				f, offset = nil, 0
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
+
+// drain copies f from *offset to its end, starting over when the file has
+// been truncated below *offset.
+func drain(f *os.File, offset *int64, w io.Writer) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < *offset {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		*offset = 0
+	}
+	n, err := io.Copy(w, f)
+	*offset += n
+	return err
+}
+
+// replaced reports whether path no longer names the open file f.
+func replaced(f *os.File, path string) bool {
+	open, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	cur, err := os.Stat(path)
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	return !os.SameFile(open, cur)
+}
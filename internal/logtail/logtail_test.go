@@ -0,0 +1,160 @@
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+func TestLast(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	var all strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&all, "line %d\n", i) // spans several chunks
+	}
+	if err := os.WriteFile(path, []byte(all.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, ""},
+		{1, "line 1000\n"},
+		{3, "line 998\nline 999\nline 1000\n"},
+		{5000, all.String()},
+	}
+	for _, tt := range tests {
+		got, offset, err := Last(path, tt.n)
+		if err != nil {
+			t.Fatalf("Last(%d) error: %v", tt.n, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Last(%d) = %q, want %q", tt.n, shorten(string(got)), shorten(tt.want))
+		}
+		if offset != int64(all.Len()) {
+			t.Errorf("Last(%d) offset = %d, want %d", tt.n, offset, all.Len())
+		}
+	}
+
+	// A last line without a trailing newline still counts.
+	if err := os.WriteFile(path, []byte("a\nb\nc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := Last(path, 2); string(got) != "b\nc" {
+		t.Errorf("Last(2) = %q, want %q", got, "b\nc")
+	}
+}
+
+func shorten(s string) string {
+	if len(s) > 40 {
+		return s[:20] + "..." + s[len(s)-20:]
+	}
+	return s
+}
+
+// syncBuffer is a bytes.Buffer safe to read while Follow writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	appendFile(t, path, "old\n")
+
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- Follow(ctx, path, 4, &out, clk) }()
+	clk.BlockUntil(1)
+
+	// waitOutput advances the fake clock until out reads want.
+	waitOutput := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for out.String() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("output = %q, want %q", out.String(), want)
+			}
+			clk.Advance(PollInterval)
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	appendFile(t, path, "first\n")
+	waitOutput("first\n")
+
+	// Rotation: the old file is renamed away and a new one created.
+	appendFile(t, path, "last before rotation\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "after rotation\n")
+	waitOutput("first\nlast before rotation\nafter rotation\n")
+
+	// Truncation starts over from the beginning.
+	if err := os.WriteFile(path, []byte("fresh\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitOutput("first\nlast before rotation\nafter rotation\nfresh\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Follow() error: %v", err)
+	}
+}
+
+func TestFollow_WaitsForFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	go func() { _ = Follow(ctx, path, 0, &out, clk) }()
+	clk.BlockUntil(1)
+
+	appendFile(t, path, "started\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != "started\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("output = %q, want the new file's contents", out.String())
+		}
+		clk.Advance(PollInterval)
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+// Janitor enforces a retention policy on the output directory every
+// Interval, so a long-running daemon never fills its disk. Unlike the daily
+// tasks, pruning is cheap and can't wait for the window: a busy day alone
+// may exceed a size bound.
+type Janitor struct {
+	Retention store.Retention
+	Interval  time.Duration
+	Dir       func() string // the current output directory
+	Protect   func() string // the screenshot the clipboard refers to, or ""
+	Clock     clock.Clock   // nil means the wall clock
+}
+
+// Run prunes once right away, then every Interval until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context, logger *slog.Logger) {
+	clk := j.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	ticker := clk.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		j.Sweep(logger, clk.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Sweep deletes the screenshots the retention policy no longer keeps at now,
+// never the one Protect returns. Failures are logged; the next sweep retries.
+func (j *Janitor) Sweep(logger *slog.Logger, now time.Time) {
+	var protect []string
+	if j.Protect != nil {
+		if path := j.Protect(); path != "" {
+			protect = append(protect, path)
+		}
+	}
+	dir := j.Dir()
+	removed, err := store.Prune(dir, j.Retention, now, protect...)
+	if err != nil {
+		logger.Error("Retention sweep failed", "dir", dir, "removed", len(removed), "err", err)
+		return
+	}
+	if len(removed) > 0 {
+		var bytes int64
+		for _, f := range removed {
+			bytes += f.Size
+		}
+		logger.Info("Retention sweep removed old screenshots", "dir", dir, "removed", len(removed), "bytes", bytes)
+	}
+}
@@ -0,0 +1,74 @@
+package maintenance
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestJanitor_PrunesEveryInterval(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	write := func(name string, mod time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	clipboard := write("clipboard.png", start.Add(-48*time.Hour))
+	old := write("old.png", start.Add(-47*time.Hour))
+	fresh := write("fresh.png", start.Add(-time.Hour))
+
+	clk := clock.NewFake(start)
+	j := &Janitor{
+		Retention: store.Retention{MaxAge: 24 * time.Hour},
+		Interval:  10 * time.Minute,
+		Dir:       func() string { return dir },
+		Protect:   func() string { return clipboard },
+		Clock:     clk,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		j.Run(ctx, slog.New(slog.DiscardHandler))
+		close(done)
+	}()
+
+	clk.BlockUntil(1) // the first sweep has run
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("old.png not pruned at start: %v", err)
+	}
+	if _, err := os.Stat(clipboard); err != nil {
+		t.Errorf("the clipboard's screenshot was pruned: %v", err)
+	}
+
+	// Within the bound now, past it after the next tick.
+	stale := write("stale.png", start.Add(-23*time.Hour-55*time.Minute))
+	clk.Advance(10 * time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(stale); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale.png not pruned by the next sweep")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("fresh.png pruned: %v", err)
+	}
+
+	cancel()
+	<-done
+}
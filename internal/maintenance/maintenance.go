@@ -0,0 +1,104 @@
+// Package maintenance runs housekeeping tasks (integrity checks) once a day
+// at a quiet time, so they never compete with polling for disk or CPU, and
+// the retention janitor that prunes old screenshots throughout the day.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+// Task is one unit of housekeeping work.
+type Task struct {
+	Name string
+	Run  func(ctx context.Context, logger *slog.Logger) error
+}
+
+// Window is the daily time of day at which maintenance runs.
+type Window struct {
+	Hour, Minute int
+}
+
+// ParseWindow parses an "HH:MM" time of day. "off" (or an empty string)
+// returns ok=false, meaning scheduled maintenance is disabled.
+func ParseWindow(s string) (w Window, ok bool, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return Window{}, false, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return Window{}, false, fmt.Errorf("invalid time of day %q (want HH:MM or off)", s)
+	}
+	return Window{Hour: t.Hour(), Minute: t.Minute()}, true, nil
+}
+
+// String returns the window as HH:MM.
+func (w Window) String() string {
+	return fmt.Sprintf("%02d:%02d", w.Hour, w.Minute)
+}
+
+// Next returns the first occurrence of the window strictly after t, in loc.
+// The date is computed on the calendar, so DST shifts move the run with the
+// wall clock rather than drifting by an hour.
+func (w Window) Next(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), w.Hour, w.Minute, 0, 0, loc)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, w.Hour, w.Minute, 0, 0, loc)
+	}
+	return next
+}
+
+// Scheduler runs Tasks once a day at Window.
+type Scheduler struct {
+	Window   Window
+	Location *time.Location // nil means local time
+	Clock    clock.Clock    // nil means the wall clock
+	Tasks    []Task
+}
+
+// Run waits for each maintenance window and runs every task in order, until
+// ctx is cancelled. A failing task is logged and does not stop the others.
+func (s *Scheduler) Run(ctx context.Context, logger *slog.Logger) {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	clk := s.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+
+	for {
+		now := clk.Now()
+		next := s.Window.Next(now, loc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-clk.After(next.Sub(now)):
+		}
+		s.RunTasks(ctx, logger)
+	}
+}
+
+// RunTasks runs every task once, immediately.
+func (s *Scheduler) RunTasks(ctx context.Context, logger *slog.Logger) {
+	logger.Info("Maintenance window started", "tasks", len(s.Tasks))
+	for _, task := range s.Tasks {
+		if ctx.Err() != nil {
+			return
+		}
+		start := time.Now()
+		if err := task.Run(ctx, logger); err != nil {
+			logger.Error("Maintenance task failed", "task", task.Name, "err", err)
+			continue
+		}
+		logger.Info("Maintenance task finished", "task", task.Name, "duration", time.Since(start).Round(time.Millisecond))
+	}
+}
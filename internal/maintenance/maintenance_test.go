@@ -0,0 +1,111 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Window
+		ok      bool
+		wantErr bool
+	}{
+		{"03:30", Window{3, 30}, true, false},
+		{"23:05", Window{23, 5}, true, false},
+		{"off", Window{}, false, false},
+		{"", Window{}, false, false},
+		{"25:00", Window{}, false, true},
+		{"3am", Window{}, false, true},
+	}
+
+	for _, tt := range tests {
+		got, ok, err := ParseWindow(tt.in)
+		if (err != nil) != tt.wantErr || ok != tt.ok || got != tt.want {
+			t.Errorf("ParseWindow(%q) = %v, %v, %v; want %v, %v, err=%v", tt.in, got, ok, err, tt.want, tt.ok, tt.wantErr)
+		}
+	}
+}
+
+func TestWindow_Next(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	w := Window{Hour: 3, Minute: 30}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"later today", time.Date(2024, 5, 1, 1, 0, 0, 0, paris), time.Date(2024, 5, 1, 3, 30, 0, 0, paris)},
+		{"already passed", time.Date(2024, 5, 1, 4, 0, 0, 0, paris), time.Date(2024, 5, 2, 3, 30, 0, 0, paris)},
+		{"exactly now", time.Date(2024, 5, 1, 3, 30, 0, 0, paris), time.Date(2024, 5, 2, 3, 30, 0, 0, paris)},
+		{"across DST", time.Date(2024, 3, 30, 12, 0, 0, 0, paris), time.Date(2024, 3, 31, 3, 30, 0, 0, paris)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Next(tt.now, paris); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduler_RunsTasksAtWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC))
+	ran := make(chan string, 4)
+	s := &Scheduler{
+		Window:   Window{Hour: 3, Minute: 0},
+		Location: time.UTC,
+		Clock:    clk,
+		Tasks: []Task{
+			{Name: "broken", Run: func(context.Context, *slog.Logger) error {
+				ran <- "broken"
+				return errors.New("boom")
+			}},
+			{Name: "fsck", Run: func(context.Context, *slog.Logger) error {
+				ran <- "fsck"
+				return nil
+			}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, slog.New(slog.DiscardHandler))
+		close(done)
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(59 * time.Minute)
+	select {
+	case name := <-ran:
+		t.Fatalf("task %s ran before the window", name)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clk.Advance(time.Minute)
+	for _, want := range []string{"broken", "fsck"} {
+		select {
+		case got := <-ran:
+			if got != want {
+				t.Errorf("ran %s, want %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("task %s did not run at the window", want)
+		}
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,65 @@
+// Package notify shows Windows toast notifications from WSL, for problems
+// the user should hear about even when nobody reads the daemon log.
+package notify
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+)
+
+// appID is the application the toast is shown for. Windows only displays
+// toasts from registered applications; Windows PowerShell always is.
+const appID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// toastScript shows a toast with the ToastGeneric template. TITLE and
+// MESSAGE are replaced by the XML-escaped, PowerShell-quoted strings. It needs
+// Windows PowerShell: pwsh can't load WinRT types this way.
+const toastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] > $null
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml("<toast><visual><binding template='ToastGeneric'><text>" + TITLE + "</text><text>" + MESSAGE + "</text></binding></visual></toast>")
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('` + appID + `').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// newCommand creates the powershell.exe process that shows a toast.
+// Declared as a var so tests can inspect the command without running it.
+var newCommand = func(script string) *exec.Cmd {
+	return exec.Command("powershell.exe", "-NoLogo", "-NoProfile", "-NonInteractive", "-EncodedCommand", encode(script)) // #nosec G204 -- fixed binary, the script is built from toastScript with quoted strings
+}
+
+// Toast shows a Windows notification with title and message. It returns once
+// powershell.exe has started, without waiting for it to exit, so a slow
+// Windows side never holds up the caller.
+func Toast(title, message string) error {
+	r := strings.NewReplacer("TITLE", quote(escapeXML(title)), "MESSAGE", quote(escapeXML(message)))
+	cmd := newCommand(r.Replace(toastScript))
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// escapeXML escapes s for XML text content.
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;").Replace(s)
+}
+
+// quote returns s as a PowerShell single-quoted string, in which nothing is
+// expanded and a quote is written twice.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// encode returns script in the form -EncodedCommand takes: base64 of its
+// UTF-16LE bytes. It sidesteps every quoting rule between WSL and Windows.
+func encode(script string) string {
+	units := utf16.Encode([]rune(script))
+	buf := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[2*i:], u)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
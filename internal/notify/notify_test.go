@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestToast_QuotesAndEncodes(t *testing.T) {
+	var script string
+	orig := newCommand
+	newCommand = func(s string) *exec.Cmd {
+		script = s
+		return exec.Command("true")
+	}
+	defer func() { newCommand = orig }()
+
+	if err := Toast("Disk <full>", "Can't save to /tmp & co"); err != nil {
+		t.Fatalf("Toast() error: %v", err)
+	}
+	for _, want := range []string{"'Disk &lt;full&gt;'", "'Can&apos;t save to /tmp &amp; co'", appID} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script does not contain %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	raw, err := base64.StdEncoding.DecodeString(encode("Write-Host 'é'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(raw[2*i:])
+	}
+	if got := string(utf16.Decode(units)); got != "Write-Host 'é'" {
+		t.Errorf("encode() decodes to %q", got)
+	}
+}
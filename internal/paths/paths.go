@@ -0,0 +1,82 @@
+// Package paths resolves where wsl-screenshot-cli keeps its files, following
+// the XDG base directory specification, so that every command finds the
+// daemon's files in the same place and users sharing a machine never share
+// (or fight over) them.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// App is the directory name used under each base directory.
+const App = "wsl-screenshot-cli"
+
+// getenv and getuid read the environment. Declared as vars for tests.
+var (
+	getenv = os.Getenv
+	getuid = os.Getuid
+)
+
+// RuntimeDir holds what only matters while a daemon runs: its PID file,
+// control socket, heartbeat and the instance registry. It is
+// $XDG_RUNTIME_DIR/wsl-screenshot-cli, or under /run/user/$UID when that
+// exists without the variable being set (WSL without a login session), or
+// /tmp/wsl-screenshot-cli-$UID.
+func RuntimeDir() string {
+	if dir := getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, App)
+	}
+	run := fmt.Sprintf("/run/user/%d", getuid())
+	if info, err := os.Stat(run); err == nil && info.IsDir() {
+		return filepath.Join(run, App)
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d", App, getuid()))
+}
+
+// StateDir holds what should survive a restart but is not worth backing up:
+// the log, state file, event journal and stats history. It is
+// $XDG_STATE_HOME/wsl-screenshot-cli, or ~/.local/state/wsl-screenshot-cli.
+func StateDir() string {
+	return baseDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// DataDir holds the user's data: the screenshots. It is
+// $XDG_DATA_HOME/wsl-screenshot-cli, or ~/.local/share/wsl-screenshot-cli.
+func DataDir() string {
+	return baseDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// baseDir returns App under the directory in the environment variable env,
+// or under home/rel when it is unset or relative (which the specification
+// says to ignore). Without a home directory it falls back to RuntimeDir.
+func baseDir(env, rel string) string {
+	if dir := getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, App)
+	}
+	home := getenv("HOME")
+	if !filepath.IsAbs(home) {
+		return RuntimeDir()
+	}
+	return filepath.Join(home, rel, App)
+}
+
+// EnsureDir creates dir, and its parents, for files only the user should
+// see. A dir that already exists must belong to the user: in the /tmp
+// fallback, another user could have created it first to read or replace the
+// daemon's files.
+func EnsureDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != getuid() {
+		return fmt.Errorf("%s belongs to another user (uid %d)", dir, st.Uid)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func overrideEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	orig := getenv
+	getenv = func(key string) string { return env[key] }
+	t.Cleanup(func() { getenv = orig })
+}
+
+func TestBaseDirs(t *testing.T) {
+	overrideEnv(t, map[string]string{
+		"XDG_RUNTIME_DIR": "/run/user/1000",
+		"XDG_STATE_HOME":  "/home/me/state",
+		"XDG_DATA_HOME":   "relative/is/ignored",
+		"HOME":            "/home/me",
+	})
+	tests := map[string][2]string{
+		"RuntimeDir": {RuntimeDir(), "/run/user/1000/wsl-screenshot-cli"},
+		"StateDir":   {StateDir(), "/home/me/state/wsl-screenshot-cli"},
+		"DataDir":    {DataDir(), "/home/me/.local/share/wsl-screenshot-cli"},
+	}
+	for name, tt := range tests {
+		if tt[0] != tt[1] {
+			t.Errorf("%s() = %q, want %q", name, tt[0], tt[1])
+		}
+	}
+}
+
+func TestBaseDirs_NoHome(t *testing.T) {
+	overrideEnv(t, map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"})
+	if got := StateDir(); got != RuntimeDir() {
+		t.Errorf("StateDir() without HOME = %q, want the runtime dir %q", got, RuntimeDir())
+	}
+}
+
+func TestEnsureDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	if err := EnsureDir(dir); err != nil {
+		t.Fatalf("EnsureDir() error: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("EnsureDir() left %v (%v), want a 0700 directory", info, err)
+	}
+
+	orig := getuid
+	getuid = func() int { return os.Getuid() + 1 }
+	defer func() { getuid = orig }()
+	if err := EnsureDir(dir); err == nil {
+		t.Error("EnsureDir() accepted a directory owned by another user")
+	}
+}
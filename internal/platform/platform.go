@@ -1,13 +1,13 @@
 package platform
 
 import (
-	"fmt"
+	"errors"
 	"os"
 	"os/exec"
 	"strings"
-)
 
-const wslErrorMessage = "This CLI is meant to be run only inside a WSL instance with access to powershell.exe"
+	"github.com/nailuu/wsl-screenshot-cli/internal/i18n"
+)
 
 // CheckWSLEnvironment verifies we're running inside WSL and that powershell.exe is accessible.
 // Declared as a var so tests can override it without needing real WSL binaries.
@@ -26,14 +26,14 @@ var CheckWSLEnvironment = func() error {
 			return nil
 		}
 	}
-	return fmt.Errorf("%s", wslErrorMessage)
+	return errors.New(i18n.T("platform.not_wsl"))
 }
 
 // CheckWSLInterop verifies that WSL interop is enabled by checking the WSL_INTEROP environment variable.
 // Declared as a var so tests can override it.
 var CheckWSLInterop = func() error {
 	if os.Getenv("WSL_INTEROP") == "" {
-		return fmt.Errorf("WSL interoperability is disabled. Enable it in /etc/wsl.conf, see https://learn.microsoft.com/en-us/windows/wsl/wsl-config#example-wslconf-file for details.")
+		return errors.New(i18n.T("platform.interop_disabled"))
 	}
 	return nil
 }
@@ -0,0 +1,96 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// OwnerReporter is implemented by clients that can name the process owning
+// the clipboard.
+type OwnerReporter interface {
+	Owner() (pid int, name string, err error)
+}
+
+// defaultOverwriteWindow is how long after an update a clipboard change is
+// attributed to a third-party overwrite when Config leaves it unset.
+const defaultOverwriteWindow = 5 * time.Second
+
+// overwriteAudit notices when another application replaces the clipboard
+// shortly after one of our updates, the usual cause of "my pasted path
+// disappears immediately" (clipboard managers, password managers, RDP).
+// It needs a Sequencer client; other clients are never audited.
+//
+// A change is only a suspect until the poll that follows it: when that poll
+// saves a new screenshot, the change was the user taking another one, not an
+// overwrite.
+type overwriteAudit struct {
+	window  time.Duration
+	seq     uint32 // sequence number right after our update; 0 = not armed
+	armedAt time.Time
+	suspect *overwrite // a change check found, for settle to report
+}
+
+// overwrite is a clipboard change after our update: who made it, and when.
+type overwrite struct {
+	by    string // "" if unknown
+	after time.Duration
+}
+
+// arm records the sequence number our update produced.
+func (a *overwriteAudit) arm(client Clipboard, now time.Time) {
+	seqr, ok := client.(Sequencer)
+	if !ok || a.window <= 0 {
+		return
+	}
+	seq, err := seqr.Sequence()
+	if err != nil {
+		return
+	}
+	a.seq, a.armedAt = seq, now
+}
+
+// check looks for a clipboard change since arm and, within the window, holds
+// it as a suspect along with the offending process, for settle.
+func (a *overwriteAudit) check(client Clipboard, now time.Time) {
+	if a.seq == 0 {
+		return
+	}
+	if now.Sub(a.armedAt) > a.window {
+		a.seq = 0
+		return
+	}
+	seq, err := client.(Sequencer).Sequence()
+	if err != nil || seq == a.seq {
+		return
+	}
+	a.seq = 0
+
+	a.suspect = &overwrite{after: now.Sub(a.armedAt)}
+	if or, ok := client.(OwnerReporter); ok {
+		if _, n, err := or.Owner(); err == nil {
+			a.suspect.by = n
+		}
+	}
+}
+
+// captured clears the suspect: the poll after the change saved a new
+// screenshot.
+func (a *overwriteAudit) captured() {
+	a.suspect = nil
+}
+
+// settle records the suspect check found, if the poll since did not clear it.
+func (a *overwriteAudit) settle(logger pollLogger, counters *stats.Counters) {
+	if a.suspect == nil {
+		return
+	}
+	o := a.suspect
+	a.suspect = nil
+	counters.RecordOverwrite(o.by)
+	name := o.by
+	if name == "" {
+		name = "unknown process"
+	}
+	logger.Warn("Clipboard overwritten after our update", "by", name, "after", o.after.Round(time.Millisecond))
+}
@@ -0,0 +1,104 @@
+package poller
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// auditClipboard reports a settable sequence number and a fixed owner.
+type auditClipboard struct {
+	mockClipboard
+	seq uint32
+}
+
+func (a *auditClipboard) Sequence() (uint32, error)   { return a.seq, nil }
+func (a *auditClipboard) Owner() (int, string, error) { return 4242, "Ditto", nil }
+
+func TestOverwriteAudit(t *testing.T) {
+	client := &auditClipboard{seq: 10}
+	counters := &stats.Counters{}
+	audit := overwriteAudit{window: 5 * time.Second}
+
+	audit.arm(client, testEpoch)
+	audit.check(client, testEpoch.Add(time.Second))
+	audit.settle(testLogger(), counters)
+	if n := counters.Snapshot().Overwrites; n != 0 {
+		t.Fatalf("Overwrites = %d with unchanged clipboard, want 0", n)
+	}
+
+	client.seq = 11
+	audit.check(client, testEpoch.Add(2*time.Second))
+	audit.settle(testLogger(), counters)
+	s := counters.Snapshot()
+	if s.Overwrites != 1 || s.LastOverwriter != "Ditto" {
+		t.Fatalf("Overwrites = %d (last %q), want 1 (last Ditto)", s.Overwrites, s.LastOverwriter)
+	}
+
+	// Disarmed after reporting: further changes are not attributed.
+	client.seq = 12
+	audit.check(client, testEpoch.Add(3*time.Second))
+	audit.settle(testLogger(), counters)
+	if n := counters.Snapshot().Overwrites; n != 1 {
+		t.Errorf("Overwrites = %d after disarm, want 1", n)
+	}
+}
+
+func TestOverwriteAudit_OutsideWindow(t *testing.T) {
+	client := &auditClipboard{seq: 10}
+	counters := &stats.Counters{}
+	audit := overwriteAudit{window: 5 * time.Second}
+
+	audit.arm(client, testEpoch)
+	client.seq = 11
+	audit.check(client, testEpoch.Add(6*time.Second))
+	audit.settle(testLogger(), counters)
+	if n := counters.Snapshot().Overwrites; n != 0 {
+		t.Errorf("Overwrites = %d for a change outside the window, want 0", n)
+	}
+}
+
+func TestOverwriteAudit_BackToBackCaptures(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	var second bytes.Buffer
+	if err := png.Encode(&second, image.NewRGBA(image.Rect(0, 0, 3, 3))); err != nil {
+		t.Fatal(err)
+	}
+	client := &auditClipboard{seq: 10}
+	counters := &stats.Counters{}
+	audit := overwriteAudit{window: 5 * time.Second}
+	now := testEpoch
+	cfg := Config{
+		OutputDir: t.TempDir(),
+		onUpdate:  func() { client.seq++; audit.arm(client, now) },
+		onCapture: audit.captured,
+	}
+	// tick is one poll, audited as Run audits it.
+	tick := func(img []byte, seq uint32) {
+		t.Helper()
+		now = now.Add(time.Second)
+		client.seq = seq
+		client.checkFunc = func() ([]byte, error) { return img, nil }
+		audit.check(client, now)
+		if err := poll(client, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+		audit.settle(testLogger(), counters)
+	}
+
+	tick(testPNG(t), 20)
+	tick(second.Bytes(), 30) // another snip a second later
+	if n := counters.Snapshot().Overwrites; n != 0 {
+		t.Fatalf("Overwrites = %d after two captures in a row, want 0", n)
+	}
+
+	// The same screenshot put back by a clipboard manager is an overwrite.
+	tick(second.Bytes(), 40)
+	if s := counters.Snapshot(); s.Overwrites != 1 || s.LastOverwriter != "Ditto" {
+		t.Errorf("Overwrites = %d (last %q), want 1 (last Ditto)", s.Overwrites, s.LastOverwriter)
+	}
+}
@@ -0,0 +1,210 @@
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultMaxConsecutiveErrors is the breaker threshold used when the policy
+// leaves MaxConsecutiveErrors unset.
+const defaultMaxConsecutiveErrors = 5
+
+// Restart backoff defaults, used when the policy leaves them unset.
+const (
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// backoffJitter is how far, as a fraction, a restart delay is randomized
+// either way, so daemons hitting the same contention don't restart in step.
+const backoffJitter = 0.2
+
+// TripAction is what the poll loop does when the circuit breaker trips.
+type TripAction string
+
+const (
+	// TripRestart replaces the clipboard client and keeps polling.
+	TripRestart TripAction = "restart"
+	// TripExit stops polling and returns an error, for fail-fast setups.
+	TripExit TripAction = "exit"
+)
+
+// ErrorClass categorizes poll failures so a policy can decide which ones
+// count toward the breaker.
+type ErrorClass string
+
+const (
+	// ClassBackend covers failures talking to the clipboard backend (CHECK).
+	ClassBackend ErrorClass = "backend"
+	// ClassDisk covers failures saving screenshots to the output directory.
+	ClassDisk ErrorClass = "disk"
+)
+
+// BreakerPolicy configures the poll loop's circuit breaker.
+type BreakerPolicy struct {
+	// MaxConsecutiveErrors is how many counted failures in a row trip the
+	// breaker. Zero means defaultMaxConsecutiveErrors.
+	MaxConsecutiveErrors int
+
+	// Action is taken when the breaker trips. Empty means TripRestart.
+	Action TripAction
+
+	// Ignore lists error classes that are logged but never count toward
+	// the breaker (nor reset it).
+	Ignore []ErrorClass
+
+	// Backoff is the delay before the second of two client restarts with
+	// no successful poll in between; each further one doubles it, up to
+	// MaxBackoff. The first restart is immediate. Zero means defaultBackoff
+	// and defaultMaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// MaxRestartsPerHour makes Run give up with ErrRestartLimit rather than
+	// restart the client more often than this within an hour. Zero means
+	// no limit.
+	MaxRestartsPerHour int
+}
+
+// ErrRestartLimit is returned by Run when the client needed more restarts
+// than BreakerPolicy.MaxRestartsPerHour allows: the backend is beyond what
+// restarting can fix.
+var ErrRestartLimit = errors.New("too many PowerShell client restarts")
+
+func (p BreakerPolicy) withDefaults() BreakerPolicy {
+	if p.MaxConsecutiveErrors <= 0 {
+		p.MaxConsecutiveErrors = defaultMaxConsecutiveErrors
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = defaultBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	p.MaxBackoff = max(p.MaxBackoff, p.Backoff)
+	if p.Action == "" {
+		p.Action = TripRestart
+	}
+	return p
+}
+
+// counts reports whether err should count toward the breaker.
+func (p BreakerPolicy) counts(err error) bool {
+	class := classOf(err)
+	for _, ignored := range p.Ignore {
+		if class == ignored {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTripAction validates a trip action name from the command line.
+func ParseTripAction(s string) (TripAction, error) {
+	switch a := TripAction(strings.ToLower(strings.TrimSpace(s))); a {
+	case TripRestart, TripExit:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown breaker action %q (expected restart or exit)", s)
+	}
+}
+
+// ParseErrorClasses validates a list of error class names from the command line.
+func ParseErrorClasses(names []string) ([]ErrorClass, error) {
+	classes := make([]ErrorClass, 0, len(names))
+	for _, name := range names {
+		switch c := ErrorClass(strings.ToLower(strings.TrimSpace(name))); c {
+		case ClassBackend, ClassDisk:
+			classes = append(classes, c)
+		default:
+			return nil, fmt.Errorf("unknown error class %q (expected backend or disk)", name)
+		}
+	}
+	return classes, nil
+}
+
+// backoffRand draws the restart jitter. Declared as a var so tests can make
+// delays exact.
+var backoffRand = rand.Float64
+
+// restartBackoff spaces out client restarts that follow each other without
+// a successful poll in between. Contention that outlasts a restart, like an
+// RDP session or clipboard manager holding the clipboard, then slows the
+// restarts down instead of turning into a restart storm.
+type restartBackoff struct {
+	base, max time.Duration
+	restarts  int       // since the last successful poll
+	next      time.Time // earliest time for the next restart
+}
+
+func newRestartBackoff(p BreakerPolicy) *restartBackoff {
+	return &restartBackoff{base: p.Backoff, max: p.MaxBackoff}
+}
+
+// wait returns how long a restart at now still has to wait; zero or less
+// means it may go ahead.
+func (b *restartBackoff) wait(now time.Time) time.Duration {
+	return b.next.Sub(now)
+}
+
+// restarted records a restart at now and schedules the earliest next one.
+func (b *restartBackoff) restarted(now time.Time) {
+	d := b.max
+	if b.restarts < 30 {
+		d = min(b.base<<b.restarts, b.max)
+	}
+	b.restarts++
+	jitter := (backoffRand()*2 - 1) * backoffJitter * float64(d)
+	b.next = now.Add(d + time.Duration(jitter))
+}
+
+// restartLimit enforces BreakerPolicy.MaxRestartsPerHour.
+type restartLimit struct {
+	max   int
+	times []time.Time // restarts within the last hour
+}
+
+// allow records a restart at now, or returns ErrRestartLimit when it would
+// exceed the limit.
+func (l *restartLimit) allow(now time.Time) error {
+	if l.max <= 0 {
+		return nil
+	}
+	l.times = slices.DeleteFunc(l.times, func(t time.Time) bool { return now.Sub(t) >= time.Hour })
+	if len(l.times) >= l.max {
+		return fmt.Errorf("%w: %d in the last hour (limit %d)", ErrRestartLimit, len(l.times), l.max)
+	}
+	l.times = append(l.times, now)
+	return nil
+}
+
+// reset forgets past restarts after a successful poll.
+func (b *restartBackoff) reset() {
+	b.restarts, b.next = 0, time.Time{}
+}
+
+// classifiedError tags a poll failure with its ErrorClass.
+type classifiedError struct {
+	class ErrorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+
+func classify(class ErrorClass, err error) error {
+	return &classifiedError{class: class, err: err}
+}
+
+// classOf returns the class of a poll error, defaulting to ClassBackend.
+func classOf(err error) ErrorClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	return ClassBackend
+}
@@ -0,0 +1,275 @@
+package poller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+func TestParseTripAction(t *testing.T) {
+	for _, in := range []string{"restart", "EXIT", " exit "} {
+		if _, err := ParseTripAction(in); err != nil {
+			t.Errorf("ParseTripAction(%q) error: %v", in, err)
+		}
+	}
+	if _, err := ParseTripAction("explode"); err == nil {
+		t.Error("ParseTripAction(explode) should fail")
+	}
+}
+
+func TestParseErrorClasses(t *testing.T) {
+	got, err := ParseErrorClasses([]string{"disk", "Backend"})
+	if err != nil {
+		t.Fatalf("ParseErrorClasses error: %v", err)
+	}
+	if len(got) != 2 || got[0] != ClassDisk || got[1] != ClassBackend {
+		t.Errorf("ParseErrorClasses = %v", got)
+	}
+	if _, err := ParseErrorClasses([]string{"network"}); err == nil {
+		t.Error("ParseErrorClasses(network) should fail")
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	if got := classOf(errors.New("plain")); got != ClassBackend {
+		t.Errorf("classOf(plain) = %q, want backend", got)
+	}
+	wrapped := classify(ClassDisk, os.ErrPermission)
+	if got := classOf(wrapped); got != ClassDisk {
+		t.Errorf("classOf(disk) = %q, want disk", got)
+	}
+	if !errors.Is(wrapped, os.ErrPermission) {
+		t.Error("classified error should unwrap to the original")
+	}
+}
+
+func TestPoll_WriteErrorIsDiskClass(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return []byte("img"), nil }}
+	missing := filepath.Join(t.TempDir(), "does", "not", "exist")
+
+	err := poll(mock, testLogger(), Config{OutputDir: missing})
+	if err == nil {
+		t.Fatal("expected write error, got nil")
+	}
+	if got := classOf(err); got != ClassDisk {
+		t.Errorf("classOf(write error) = %q, want disk", got)
+	}
+}
+
+func TestRun_BreakerExitAction(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	polled := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
+
+	factory := func() (Clipboard, error) {
+		factoryCalls.Add(1)
+		return &mockClipboard{checkFunc: func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, errors.New("backend down")
+		}}, nil
+	}
+
+	cfg := Config{OutputDir: t.TempDir(), Breaker: BreakerPolicy{MaxConsecutiveErrors: 2, Action: TripExit}}
+	stop := startRun(t, clk, cfg, factory)
+	tick(t, clk, polled)
+	tick(t, clk, polled)
+
+	err := stop()
+	if err == nil || !strings.Contains(err.Error(), "circuit breaker tripped after 2 consecutive errors") {
+		t.Errorf("Run error = %v, want breaker trip error", err)
+	}
+	if calls := factoryCalls.Load(); calls != 1 {
+		t.Errorf("factory called %d times, want 1 (exit must not restart)", calls)
+	}
+}
+
+// brokenClipboard is a client whose backend was killed after a timeout.
+type brokenClipboard struct {
+	mockClipboard
+}
+
+func (b *brokenClipboard) Broken() bool { return true }
+
+func TestRun_RestartsBrokenClientImmediately(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+
+	polled := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
+	factory := func() (Clipboard, error) {
+		if factoryCalls.Add(1) == 1 {
+			return &brokenClipboard{mockClipboard{checkFunc: func() ([]byte, error) {
+				polled <- struct{}{}
+				return nil, errors.New("CHECK: powershell did not respond in time after 10s")
+			}}}, nil
+		}
+		return &mockClipboard{checkFunc: func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, nil
+		}}, nil
+	}
+
+	counters := &stats.Counters{}
+	// Even when backend errors never count toward the breaker.
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters, Breaker: BreakerPolicy{Ignore: []ErrorClass{ClassBackend}}}, factory)
+	tick(t, clk, polled)
+	tick(t, clk, polled)
+
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := factoryCalls.Load(); calls != 2 {
+		t.Errorf("factory called %d times, want 2 (a broken client is replaced at once)", calls)
+	}
+	if s := counters.Snapshot(); s.Restarts != 1 {
+		t.Errorf("Restarts = %d, want 1", s.Restarts)
+	}
+}
+
+func TestRun_BrokenClientWithExitAction(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	polled := make(chan struct{}, 1)
+
+	factory := func() (Clipboard, error) {
+		return &brokenClipboard{mockClipboard{checkFunc: func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, errors.New("timed out")
+		}}}, nil
+	}
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Breaker: BreakerPolicy{Action: TripExit}}, factory)
+	tick(t, clk, polled)
+
+	if err := stop(); err == nil || !strings.Contains(err.Error(), "clipboard backend stopped responding") {
+		t.Errorf("Run error = %v, want a stopped responding error", err)
+	}
+}
+
+func TestRun_BreakerIgnoresClass(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	polled := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
+
+	// Every poll fails to save because the output dir doesn't exist.
+	missing := filepath.Join(t.TempDir(), "missing")
+	factory := func() (Clipboard, error) {
+		factoryCalls.Add(1)
+		return &mockClipboard{checkFunc: func() ([]byte, error) {
+			polled <- struct{}{}
+			return []byte("img"), nil
+		}}, nil
+	}
+
+	cfg := Config{OutputDir: missing, Breaker: BreakerPolicy{MaxConsecutiveErrors: 2, Ignore: []ErrorClass{ClassDisk}}}
+	stop := startRun(t, clk, cfg, factory)
+	for i := 0; i < 5; i++ {
+		tick(t, clk, polled)
+	}
+	// One extra tick so the last failing poll has been fully handled.
+	tick(t, clk, polled)
+
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := factoryCalls.Load(); calls != 1 {
+		t.Errorf("factory called %d times, want 1 (ignored class must not trip)", calls)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	orig := backoffRand
+	defer func() { backoffRand = orig }()
+	backoffRand = func() float64 { return 0.5 } // no jitter
+
+	b := newRestartBackoff(BreakerPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second})
+	now := testEpoch
+	if w := b.wait(now); w > 0 {
+		t.Fatalf("first restart waits %s, want none", w)
+	}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		b.restarted(now)
+		if w := b.wait(now); w != want {
+			t.Errorf("after restart %d: wait %s, want %s", b.restarts, w, want)
+		}
+	}
+	b.reset()
+	if w := b.wait(now); w > 0 {
+		t.Errorf("after a successful poll: wait %s, want none", w)
+	}
+
+	backoffRand = func() float64 { return 0 } // the most jitter allowed, downwards
+	b.restarted(now)
+	if w := b.wait(now); w != 800*time.Millisecond {
+		t.Errorf("jittered wait = %s, want 800ms", w)
+	}
+}
+
+func TestRun_BacksOffRepeatedRestarts(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	orig := backoffRand
+	defer func() { backoffRand = orig }()
+	backoffRand = func() float64 { return 0.5 }
+	clk := clock.NewFake(testEpoch)
+
+	polled := make(chan struct{}, 1)
+	created := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
+	factory := func() (Clipboard, error) {
+		if factoryCalls.Add(1) > 1 {
+			created <- struct{}{}
+		}
+		return &mockClipboard{checkFunc: func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, errors.New("clipboard held by RDP")
+		}}, nil
+	}
+
+	counters := &stats.Counters{}
+	policy := BreakerPolicy{MaxConsecutiveErrors: 1, Backoff: 2 * testInterval}
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters, Breaker: policy}, factory)
+	tick(t, clk, polled) // trips: the first restart is immediate
+	<-created
+	tick(t, clk, polled)  // trips again, too soon: the restart waits
+	tick(t, clk, created) // backoff over: restarted without polling
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if s := counters.Snapshot(); s.Restarts != 2 || s.Errors != 2 {
+		t.Errorf("stats = %+v, want 2 restarts after 2 errors", s)
+	}
+}
+
+func TestRun_GivesUpAfterRestartLimit(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	polled := make(chan struct{}, 1)
+	factory := func() (Clipboard, error) {
+		return &mockClipboard{checkFunc: func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, errors.New("clipboard held by RDP")
+		}}, nil
+	}
+
+	policy := BreakerPolicy{MaxConsecutiveErrors: 1, Backoff: testInterval / 2, MaxRestartsPerHour: 1}
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Breaker: policy}, factory)
+	tick(t, clk, polled) // first restart: within the limit
+	tick(t, clk, polled) // second: over it
+	err := stop()
+	if !errors.Is(err, ErrRestartLimit) {
+		t.Fatalf("Run() = %v, want ErrRestartLimit", err)
+	}
+	if !strings.Contains(err.Error(), "1 consecutive errors") {
+		t.Errorf("error %q should say what the restart was for", err)
+	}
+}
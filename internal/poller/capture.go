@@ -0,0 +1,290 @@
+package poller
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+// StreamChecker is implemented by clients that can write the clipboard image
+// to w as it arrives instead of returning it in one piece. found reports
+// whether the clipboard held an image. Run streams such captures straight
+// into the staging directory, hashing them on the way, so a screenshot is
+// never held in memory just to be saved.
+type StreamChecker interface {
+	CheckTo(w io.Writer) (found bool, err error)
+}
+
+// SequenceChecker is implemented by clients whose CHECK also returns the
+// clipboard sequence number, and skips reading the clipboard while it still
+// equals since (found is false then). Run passes the number of the last
+// successful poll, so an unchanged clipboard costs one short round trip
+// instead of an image render, whether or not SeqCheck is on.
+type SequenceChecker interface {
+	CheckSince(since uint32, w io.Writer) (seq uint32, found bool, err error)
+}
+
+// HashChecker is a SequenceChecker whose backend announces each image by its
+// SHA256 before sending it. When known(sha) returns true, the image is not
+// transferred: found is true but nothing is written to w. sha is "" for
+// images sent without a hash.
+type HashChecker interface {
+	CheckKnown(since uint32, known func(sha string) bool, w io.Writer) (seq uint32, sha string, found bool, err error)
+}
+
+// SourceReporter is implemented by clients that can tell which clipboard
+// format (PNG, DIBV5, Bitmap, ...) the image of their last check was read
+// from. The name is logged with each capture it saves.
+type SourceReporter interface {
+	Source() string
+}
+
+// ForegroundReporter is implemented by clients that can name the window in
+// the foreground when their last check read its image: the process and the
+// title, recorded in the index with the capture.
+type ForegroundReporter interface {
+	Foreground() (process, title string)
+}
+
+// maxKnownImages bounds knownImages. Reaching it starts over empty; the
+// images that matter are the few recent ones going round the clipboard.
+const maxKnownImages = 256
+
+// knownImages remembers where Run's recent captures were saved, by the SHA256
+// the backend reported for them, so that an image already on disk is not
+// transferred again only to be deduplicated.
+type knownImages struct {
+	paths map[string]string
+}
+
+func newKnownImages() *knownImages {
+	return &knownImages{paths: make(map[string]string)}
+}
+
+// add records that the image with backend hash sha is saved at path.
+func (k *knownImages) add(sha, path string) {
+	if k == nil || sha == "" {
+		return
+	}
+	if len(k.paths) >= maxKnownImages {
+		clear(k.paths)
+	}
+	k.paths[sha] = path
+}
+
+// lookup returns where the image with backend hash sha was saved, provided
+// the file is still there and in dir, where it would be saved now. It
+// returns "" otherwise.
+func (k *knownImages) lookup(sha, dir string) string {
+	if k == nil {
+		return ""
+	}
+	path, ok := k.paths[sha]
+	if !ok || filepath.Dir(path) != dir {
+		return ""
+	}
+	if _, err := os.Stat(path); err != nil {
+		delete(k.paths, sha) // deleted or cleaned up: fetch and save it again
+		return ""
+	}
+	return path
+}
+
+// clipSeq tracks clipboard sequence numbers across the polls of Run. 0 means
+// unknown.
+type clipSeq struct {
+	last uint32 // at the last successful poll
+	seen uint32 // reported by the current poll
+
+	// queued is set when the current poll's capture went to the write
+	// queue: last stays put, so a later poll reads it again and updates
+	// the clipboard once the file exists.
+	queued bool
+}
+
+// since returns the number to pass to CheckSince.
+func (s *clipSeq) since() uint32 {
+	if s == nil {
+		return 0
+	}
+	return s.last
+}
+
+// hold keeps the current poll from counting as read: see queued.
+func (s *clipSeq) hold() {
+	if s != nil {
+		s.queued = true
+	}
+}
+
+// capture is a clipboard image on its way to the output directory: either
+// read into memory, or streamed into a staged file as it arrived.
+type capture struct {
+	hash   string // hex name hash, see nameHash
+	size   int64
+	data   []byte        // the PNG, for captures read into memory
+	staged *store.Staged // the PNG, for streamed captures
+
+	sha    string // the PNG's SHA256 as reported by the backend, "" if not
+	saved  string // for images the backend did not send again: where they are
+	source string // the clipboard format it was read from, "" if unknown
+	window string // the foreground process when it was read, "" if unknown
+	title  string // that window's title, "" if unknown
+	seq    int    // its number in the output directory's index, 0 until known
+}
+
+// newCapture wraps an image read into memory.
+func newCapture(data, key []byte) *capture {
+	return &capture{hash: nameHash(data, key), size: int64(len(data)), data: data}
+}
+
+// open returns the capture's content.
+func (c *capture) open() (io.ReadCloser, error) {
+	if c.staged == nil {
+		return io.NopCloser(bytes.NewReader(c.data)), nil
+	}
+	return os.Open(c.staged.Name())
+}
+
+// discard removes a streamed capture's staged file unless it was saved.
+func (c *capture) discard() {
+	if c.staged != nil {
+		c.staged.Discard()
+	}
+}
+
+// readClipboard checks the clipboard and returns its image, or nil when it
+// holds none. Clients that can stream have the image written to a staged
+// file; the others, and every capture while the write throttle may queue it,
+// are read into memory. An image the backend recognised as already saved
+// comes back with saved set and no content.
+func readClipboard(client Clipboard, cfg Config) (*capture, error) {
+	var staged *store.Staged
+	if canStream(client) && cfg.throttle == nil {
+		// Without a staging directory (say the output dir is gone), fall
+		// back to memory and let the save report the disk error.
+		staged, _ = store.Stage(cfg.OutputDir, "capture.png")
+	}
+	if staged == nil {
+		var buf bytes.Buffer
+		res, err := checkTo(client, cfg, &buf)
+		if err != nil || !res.found {
+			return nil, err
+		}
+		if res.saved != "" {
+			return res.describe(&capture{}), nil
+		}
+		return res.describe(newCapture(buf.Bytes(), cfg.NameKey)), nil
+	}
+
+	h := newNameHash(cfg.NameKey)
+	res, err := checkTo(client, cfg, io.MultiWriter(staged, h))
+	if err != nil || !res.found || res.saved != "" {
+		staged.Discard()
+		if err != nil || !res.found {
+			return nil, err
+		}
+		return res.describe(&capture{}), nil
+	}
+	size, err := staged.Seek(0, io.SeekCurrent)
+	if err != nil {
+		staged.Discard()
+		return nil, err
+	}
+	return res.describe(&capture{hash: hex.EncodeToString(h.Sum(nil)), size: size, staged: staged}), nil
+}
+
+// canStream reports whether client can write the image to an io.Writer.
+func canStream(client Clipboard) bool {
+	switch client.(type) {
+	case HashChecker, SequenceChecker, StreamChecker:
+		return true
+	}
+	return false
+}
+
+// checkResult is what checkTo learned about the clipboard.
+type checkResult struct {
+	found  bool   // it held an image
+	sha    string // the image's SHA256 as reported by the backend, if it did
+	saved  string // where the image already is, if it was not transferred
+	source string // the clipboard format it was read from, if reported
+	window string // the foreground process at the time, if reported
+	title  string // and its window title
+}
+
+// describe copies what the check reported about the image to img.
+func (res checkResult) describe(img *capture) *capture {
+	img.sha, img.saved, img.source = res.sha, res.saved, res.source
+	img.window, img.title = res.window, res.title
+	return img
+}
+
+// checkTo runs the richest CHECK client supports, writing the image to w.
+// A reported sequence number goes to cfg.seq and Stats.
+func checkTo(client Clipboard, cfg Config, w io.Writer) (checkResult, error) {
+	var res checkResult
+	var seq uint32
+	var err error
+	switch c := client.(type) {
+	case HashChecker:
+		dir := targetDir(cfg)
+		known := func(sha string) bool {
+			res.saved = cfg.known.lookup(sha, dir)
+			return res.saved != ""
+		}
+		seq, res.sha, res.found, err = c.CheckKnown(cfg.seq.since(), known, w)
+	case SequenceChecker:
+		seq, res.found, err = c.CheckSince(cfg.seq.since(), w)
+	case StreamChecker:
+		res.found, err = c.CheckTo(w)
+	default:
+		var data []byte
+		if data, err = client.Check(); err == nil && data != nil {
+			res.found = true
+			_, err = w.Write(data)
+		}
+	}
+	if sr, ok := client.(SourceReporter); ok && res.found {
+		res.source = sr.Source()
+	}
+	if fr, ok := client.(ForegroundReporter); ok && res.found {
+		res.window, res.title = fr.Foreground()
+	}
+	if err == nil && seq != 0 {
+		if cfg.seq != nil {
+			cfg.seq.seen = seq
+		}
+		cfg.Stats.SetClipboardSequence(seq)
+	}
+	return res, err
+}
+
+// hashBytes returns the lowercase hex SHA256 of data.
+func hashBytes(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// nameHash returns the hex name for data: its SHA256, or its HMAC-SHA256
+// under key when one is set.
+func nameHash(data, key []byte) string {
+	h := newNameHash(key)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newNameHash returns the hash nameHash uses under key.
+func newNameHash(key []byte) hash.Hash {
+	if key == nil {
+		return sha256.New()
+	}
+	return hmac.New(sha256.New, key)
+}
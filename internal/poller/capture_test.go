@@ -0,0 +1,259 @@
+package poller
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+// streamClipboard is a mockClipboard that streams its image in small writes
+// and fails if the poller asks for it in one piece.
+type streamClipboard struct {
+	mockClipboard
+	image   []byte
+	checkTo func(w io.Writer) (bool, error) // overrides image when set
+}
+
+func (s *streamClipboard) Check() ([]byte, error) {
+	return nil, errors.New("Check called on a streaming client")
+}
+
+func (s *streamClipboard) CheckTo(w io.Writer) (bool, error) {
+	if s.checkTo != nil {
+		return s.checkTo(w)
+	}
+	if s.image == nil {
+		return false, nil
+	}
+	for data := s.image; len(data) > 0; data = data[min(3, len(data)):] {
+		if _, err := w.Write(data[:min(3, len(data))]); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func TestPoll_StreamsCaptureToDisk(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	img := []byte("streamed-png-data")
+	client := &streamClipboard{image: img}
+	counters := &stats.Counters{}
+	cfg := Config{OutputDir: dir, Stats: counters}
+
+	// The second poll is a dedup hit, which drops its staged copy.
+	for i := 0; i < 2; i++ {
+		if err := poll(client, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+	}
+
+	saved, err := os.ReadFile(filepath.Join(dir, hashBytes(img)+".png"))
+	if err != nil || string(saved) != string(img) {
+		t.Fatalf("saved file = %q, %v; want the streamed image under its hash", saved, err)
+	}
+	if s := counters.Snapshot(); s.Captures != 1 || s.Bytes != int64(len(img)) || s.DedupHits != 1 {
+		t.Errorf("stats = %+v, want 1 capture of %d bytes and 1 dedup hit", s, len(img))
+	}
+	if parts, _ := filepath.Glob(filepath.Join(store.StagingDir(dir), "*.part")); len(parts) != 0 {
+		t.Errorf("staged files left behind: %v", parts)
+	}
+}
+
+func TestPoll_StreamedCaptureCollision(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	img := []byte("new content")
+	// Same size, different bytes: compared in full, not by size alone.
+	taken := filepath.Join(dir, hashBytes(img)+".png")
+	if err := os.WriteFile(taken, []byte("old content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := poll(&streamClipboard{image: img}, testLogger(), Config{OutputDir: dir}); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	suffixed := strings.TrimSuffix(taken, ".png") + "-1.png"
+	if saved, err := os.ReadFile(suffixed); err != nil || string(saved) != string(img) {
+		t.Errorf("%s = %q, %v; want the new capture", suffixed, saved, err)
+	}
+}
+
+func TestPoll_FailedStreamLeavesNothing(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	client := &streamClipboard{checkTo: func(w io.Writer) (bool, error) {
+		_, _ = w.Write([]byte("half an ima"))
+		return true, errors.New("CHECK: powershell did not respond in time")
+	}}
+
+	if err := poll(client, testLogger(), Config{OutputDir: dir}); err == nil || classOf(err) != ClassBackend {
+		t.Fatalf("poll() error = %v, want a backend error", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	parts, _ := filepath.Glob(filepath.Join(store.StagingDir(dir), "*.part"))
+	if len(entries) != 1 || len(parts) != 0 {
+		t.Errorf("output dir holds %d entries and %d staged files, want only the staging dir", len(entries), len(parts))
+	}
+}
+
+// sinceClipboard is a mockClipboard whose CHECK reports a sequence number
+// and records the one it was asked to compare with.
+type sinceClipboard struct {
+	mockClipboard
+	seq    atomic.Uint32
+	busy   atomic.Bool
+	mu     sync.Mutex
+	sinces []uint32
+	polled chan struct{}
+}
+
+type errBusy struct{}
+
+func (errBusy) Error() string       { return "clipboard held open by another process" }
+func (errBusy) ClipboardBusy() bool { return true }
+
+func (s *sinceClipboard) CheckSince(since uint32, w io.Writer) (uint32, bool, error) {
+	defer func() { s.polled <- struct{}{} }()
+	s.mu.Lock()
+	s.sinces = append(s.sinces, since)
+	s.mu.Unlock()
+	if s.busy.Load() {
+		return s.seq.Load(), false, errBusy{}
+	}
+	return s.seq.Load(), false, nil
+}
+
+func TestRun_CheckSincePassesLastSequence(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+	mock := &sinceClipboard{polled: make(chan struct{}, 1)}
+	mock.seq.Store(7)
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters}, func() (Clipboard, error) {
+		return mock, nil
+	})
+	tick(t, clk, mock.polled) // nothing known yet
+	tick(t, clk, mock.polled) // compares with 7
+	mock.seq.Store(8)
+	tick(t, clk, mock.polled) // still compares with 7, learns 8
+	tick(t, clk, mock.polled)
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if want := []uint32{0, 7, 7, 8}; !slices.Equal(mock.sinces[:4], want) {
+		t.Errorf("CheckSince called with %v, want %v first", mock.sinces, want)
+	}
+	if got := counters.Snapshot().ClipboardSeq; got != 8 {
+		t.Errorf("ClipboardSeq = %d, want 8", got)
+	}
+}
+
+func TestRun_BusyClipboardIsReadAgain(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+	mock := &sinceClipboard{polled: make(chan struct{}, 1)}
+	mock.seq.Store(7)
+	var clients atomic.Int32
+
+	cfg := Config{OutputDir: t.TempDir(), Stats: counters, Breaker: BreakerPolicy{MaxConsecutiveErrors: 1}}
+	stop := startRun(t, clk, cfg, func() (Clipboard, error) {
+		clients.Add(1)
+		return mock, nil
+	})
+	tick(t, clk, mock.polled)
+	mock.seq.Store(8)
+	mock.busy.Store(true)
+	tick(t, clk, mock.polled) // busy: 8 is not taken as seen
+	tick(t, clk, mock.polled)
+	mock.busy.Store(false)
+	tick(t, clk, mock.polled)
+	tick(t, clk, mock.polled)
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if want := []uint32{0, 7, 7, 7, 8}; !slices.Equal(mock.sinces[:5], want) {
+		t.Errorf("CheckSince called with %v, want %v first", mock.sinces, want)
+	}
+	if s := counters.Snapshot(); s.Errors != 0 || clients.Load() != 1 {
+		t.Errorf("%d errors, %d clients; a busy clipboard should neither fail a poll nor restart", s.Errors, clients.Load())
+	}
+}
+
+// hashClipboard is a mockClipboard whose backend announces its image by hash
+// and counts how often it had to send it.
+type hashClipboard struct {
+	mockClipboard
+	image     []byte
+	transfers int
+	updated   []string
+}
+
+func (h *hashClipboard) CheckKnown(since uint32, known func(string) bool, w io.Writer) (uint32, string, bool, error) {
+	sha := hashBytes(h.image)
+	if known(sha) {
+		return 0, sha, true, nil
+	}
+	h.transfers++
+	_, err := w.Write(h.image)
+	return 0, sha, true, err
+}
+
+func (h *hashClipboard) UpdateClipboard(wsl, win string) error {
+	h.updated = append(h.updated, wsl)
+	return nil
+}
+
+func TestPoll_SkipsTransferOfKnownImage(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	client := &hashClipboard{image: []byte("png already saved")}
+	counters := &stats.Counters{}
+	cfg := Config{OutputDir: dir, Stats: counters, NameKey: []byte("key"), known: newKnownImages()}
+
+	for i := 0; i < 3; i++ {
+		if err := poll(client, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+	}
+	if client.transfers != 1 {
+		t.Errorf("image transferred %d times, want once", client.transfers)
+	}
+	if s := counters.Snapshot(); s.Captures != 1 || s.DedupHits != 2 {
+		t.Errorf("stats = %+v, want 1 capture and 2 dedup hits", s)
+	}
+	// Named by its HMAC, yet recognised by the backend's plain SHA256.
+	saved := filepath.Join(dir, nameHash(client.image, cfg.NameKey)+".png")
+	if len(client.updated) != 3 || client.updated[2] != saved {
+		t.Errorf("clipboard updated with %v, want %s each time", client.updated, saved)
+	}
+
+	// Once the file is gone, the image is fetched and saved again.
+	if err := os.Remove(saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := poll(client, testLogger(), cfg); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	if _, err := os.Stat(saved); err != nil || client.transfers != 2 {
+		t.Errorf("after removal: %v, %d transfers; want the image saved again", err, client.transfers)
+	}
+}
@@ -0,0 +1,64 @@
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+// ErrInjected marks failures simulated by Config.Chaos, so they are counted
+// apart from organic errors.
+var ErrInjected = errors.New("injected by --chaos")
+
+// chaosMaxDelay bounds the slow responses chaos simulates. It is well past
+// typical poll intervals, so ticks pile up the way they do behind a stuck
+// backend.
+const chaosMaxDelay = 2 * time.Second
+
+// chaos randomly fails or delays pipeline stages to exercise the circuit
+// breaker, restarts and alerting on a real system. A nil *chaos injects
+// nothing.
+type chaos struct {
+	rate  float64        // probability of a fault at each injection point
+	rand  func() float64 // uniform in [0, 1)
+	clock clock.Clock
+}
+
+// chaosRand draws chaos's random numbers. Declared as a var so tests can
+// make faults deterministic.
+var chaosRand = rand.Float64
+
+func newChaos(rate float64, clk clock.Clock) *chaos {
+	if rate <= 0 {
+		return nil
+	}
+	return &chaos{rate: rate, rand: chaosRand, clock: clk}
+}
+
+func (c *chaos) hit() bool {
+	return c != nil && c.rand() < c.rate
+}
+
+// beforeCheck runs ahead of each CHECK. A fault is either a failed CHECK or
+// a slow response of up to chaosMaxDelay, with equal odds.
+func (c *chaos) beforeCheck() error {
+	if !c.hit() {
+		return nil
+	}
+	if c.rand() < 0.5 {
+		return fmt.Errorf("CHECK failed: %w", ErrInjected)
+	}
+	<-c.clock.After(time.Duration(c.rand() * float64(chaosMaxDelay)))
+	return nil
+}
+
+// beforeWrite runs ahead of saving a screenshot and may fail the write.
+func (c *chaos) beforeWrite() error {
+	if !c.hit() {
+		return nil
+	}
+	return fmt.Errorf("disk write failed: %w", ErrInjected)
+}
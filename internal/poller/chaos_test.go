@@ -0,0 +1,95 @@
+package poller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// scriptedChaos returns a chaos whose random draws come from draws in order.
+func scriptedChaos(rate float64, clk clock.Clock, draws ...float64) *chaos {
+	return &chaos{rate: rate, clock: clk, rand: func() float64 {
+		d := draws[0]
+		draws = draws[1:]
+		return d
+	}}
+}
+
+func TestChaos_FailsCheck(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	checked := false
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { checked = true; return []byte("png"), nil }}
+
+	cfg := Config{OutputDir: t.TempDir(), chaos: scriptedChaos(0.1, clock.Real{}, 0.05, 0.2)}
+	err := poll(mock, testLogger(), cfg)
+	if !errors.Is(err, ErrInjected) {
+		t.Fatalf("poll() = %v, want an injected error", err)
+	}
+	if classOf(err) != ClassBackend {
+		t.Errorf("class = %s, want backend like a real CHECK failure", classOf(err))
+	}
+	if checked {
+		t.Error("the backend should not be asked after an injected CHECK failure")
+	}
+}
+
+func TestChaos_SlowsCheck(t *testing.T) {
+	clk := clock.NewFake(testEpoch)
+	c := scriptedChaos(0.1, clk, 0.05, 0.7, 0.5)
+	done := make(chan error, 1)
+	go func() { done <- c.beforeCheck() }()
+
+	clk.BlockUntil(1)
+	clk.Advance(chaosMaxDelay / 2)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("beforeCheck() = %v, want a delay, not an error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow response never finished")
+	}
+}
+
+func TestChaos_FailsWrite(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return []byte("png"), nil }}
+
+	cfg := Config{OutputDir: dir, chaos: scriptedChaos(0.1, clock.Real{}, 0.5, 0.05)}
+	if err := poll(mock, testLogger(), cfg); !errors.Is(err, ErrInjected) || classOf(err) != ClassDisk {
+		t.Fatalf("poll() = %v, want an injected disk error", err)
+	}
+	if entries, _ := outputEntries(dir); len(entries) != 0 {
+		t.Errorf("an injected write failure should not save a file, found %d", len(entries))
+	}
+}
+
+func TestRun_CountsInjectedErrorsSeparately(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+	orig := chaosRand
+	chaosRand = func() float64 { return 0.1 } // every CHECK fails, none is slowed
+	defer func() { chaosRand = orig }()
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters, Chaos: 1}, func() (Clipboard, error) {
+		return &mockClipboard{}, nil
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for counters.Snapshot().InjectedErrors == 0 && time.Now().Before(deadline) {
+		clk.Advance(testInterval)
+		time.Sleep(time.Millisecond)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	s := counters.Snapshot()
+	if s.InjectedErrors == 0 || s.Errors != 0 {
+		t.Errorf("InjectedErrors/Errors = %d/%d, want injected errors only", s.InjectedErrors, s.Errors)
+	}
+}
@@ -0,0 +1,72 @@
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+// resolveSavePath picks where img should live given its preferred path.
+// It returns the path the capture ends up at and whether it still has to be
+// written; an empty path means the skip policy dropped it.
+func resolveSavePath(path string, img *capture, policy store.CollisionPolicy) (target string, write bool, err error) {
+	same, exists, err := compareFile(path, img)
+	switch {
+	case err != nil:
+		return "", false, err
+	case !exists:
+		return path, true, nil
+	case same:
+		return path, false, nil
+	}
+
+	switch policy {
+	case store.CollisionOverwrite:
+		return path, true, nil
+	case store.CollisionSkip:
+		return "", false, nil
+	}
+
+	for i := 1; i <= store.MaxCollisionSuffix; i++ {
+		candidate := store.CollisionName(path, i)
+		same, exists, err := compareFile(candidate, img)
+		switch {
+		case err != nil:
+			return "", false, err
+		case !exists:
+			return candidate, true, nil
+		case same:
+			return candidate, false, nil
+		}
+	}
+	return "", false, fmt.Errorf("no free name for %s after %d suffixes", filepath.Base(path), store.MaxCollisionSuffix)
+}
+
+// compareFile reports whether path exists and, if so, whether it holds img.
+func compareFile(path string, img *capture) (same, exists bool, err error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if info.Size() != img.size {
+		return false, true, nil
+	}
+	existing, err := os.Open(path) // #nosec G304 -- path is built from the cleaned output dir
+	if err != nil {
+		return false, true, err
+	}
+	defer existing.Close()
+	content, err := img.open()
+	if err != nil {
+		return false, true, err
+	}
+	defer content.Close()
+	same, err = store.SameContent(existing, content)
+	return same, true, err
+}
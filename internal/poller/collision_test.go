@@ -0,0 +1,78 @@
+package poller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestResolveSavePath(t *testing.T) {
+	data := []byte("new capture")
+
+	tests := []struct {
+		name      string
+		existing  map[string]string // file name -> content
+		policy    store.CollisionPolicy
+		want      string
+		wantWrite bool
+	}{
+		{"free name", nil, store.CollisionSuffix, "shot.png", true},
+		{"identical content", map[string]string{"shot.png": "new capture"}, store.CollisionSkip, "shot.png", false},
+		{"suffix", map[string]string{"shot.png": "other"}, store.CollisionSuffix, "shot-1.png", true},
+		{"suffix skips taken", map[string]string{"shot.png": "other", "shot-1.png": "another"}, store.CollisionSuffix, "shot-2.png", true},
+		{"suffix finds earlier copy", map[string]string{"shot.png": "other", "shot-1.png": "new capture"}, store.CollisionSuffix, "shot-1.png", false},
+		{"overwrite", map[string]string{"shot.png": "other"}, store.CollisionOverwrite, "shot.png", true},
+		{"skip", map[string]string{"shot.png": "other"}, store.CollisionSkip, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, write, err := resolveSavePath(filepath.Join(dir, "shot.png"), newCapture(data, nil), tt.policy)
+			if err != nil {
+				t.Fatalf("resolveSavePath() error: %v", err)
+			}
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(dir, tt.want)
+			}
+			if got != want || write != tt.wantWrite {
+				t.Errorf("resolveSavePath() = %q, %v; want %q, %v", got, write, want, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestPoll_CollisionSkipKeepsExistingFile(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	imgData := []byte("fake-png-data")
+	existing := filepath.Join(dir, hashBytes(imgData)+".png")
+	if err := os.WriteFile(existing, []byte("truncated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := false
+	mock := &mockClipboard{
+		checkFunc:  func() ([]byte, error) { return imgData, nil },
+		updateFunc: func(wsl, win string) error { updated = true; return nil },
+	}
+
+	if err := poll(mock, testLogger(), Config{OutputDir: dir, OnCollision: store.CollisionSkip}); err != nil {
+		t.Fatalf("poll() returned error: %v", err)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "truncated" {
+		t.Errorf("existing file was modified: %q", got)
+	}
+	if updated {
+		t.Error("clipboard should not be updated for a skipped capture")
+	}
+}
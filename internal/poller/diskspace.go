@@ -0,0 +1,69 @@
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/events"
+	"github.com/nailuu/wsl-screenshot-cli/internal/notify"
+)
+
+// errLowDiskSpace is returned by save when writing a screenshot would leave
+// less than Config.MinFreeSpace free on the output filesystem.
+var errLowDiskSpace = errors.New("not enough free disk space")
+
+// freeSpace returns the bytes available to unprivileged users on the
+// filesystem holding dir. Declared as a var so tests can fill the disk.
+var freeSpace = func(dir string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * st.Bsize, nil // #nosec G115 -- free blocks fit in an int64
+}
+
+// showToast shows a Windows notification. Declared as a var so tests don't
+// start powershell.exe.
+var showToast = notify.Toast
+
+// diskGuard remembers whether Run last found the output filesystem short of
+// space, so the error, event and toast come once when space runs out rather
+// than with every capture it costs.
+type diskGuard struct {
+	low bool
+}
+
+// checkDiskSpace returns errLowDiskSpace when saving size more bytes in dir
+// would leave less than cfg.MinFreeSpace free. Free space that can't be read
+// lets the save go ahead: the write itself will tell.
+func checkDiskSpace(dir string, size int64, logger pollLogger, cfg Config) error {
+	if cfg.MinFreeSpace <= 0 {
+		return nil
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		return nil
+	}
+	if free-size >= cfg.MinFreeSpace {
+		if cfg.disk != nil && cfg.disk.low {
+			cfg.disk.low = false
+			logger.Info("Disk space recovered, saving screenshots again", "dir", dir, "free_bytes", free)
+		}
+		return nil
+	}
+	if cfg.disk == nil || !cfg.disk.low {
+		if cfg.disk != nil {
+			cfg.disk.low = true
+		}
+		msg := fmt.Sprintf("Low disk space: %d MB free in %s, below the %d MB minimum; screenshots are not saved", free>>20, dir, cfg.MinFreeSpace>>20)
+		logger.Error("Low disk space, screenshots are not saved until space is freed", "dir", dir, "free_bytes", free, "min_free_bytes", cfg.MinFreeSpace)
+		_ = cfg.Events.Append(events.Event{Time: cfg.Clock.Now(), Type: events.TypeError, Message: msg})
+		if cfg.LowDiskToast {
+			if err := showToast("wsl-screenshot-cli", msg); err != nil {
+				logger.Warn("Could not show the low disk space notification", "err", err)
+			}
+		}
+	}
+	return errLowDiskSpace
+}
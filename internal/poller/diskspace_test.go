@@ -0,0 +1,55 @@
+package poller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPoll_SkipsSaveOnLowDiskSpace(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	free := int64(10 << 20)
+	origFree, origToast := freeSpace, showToast
+	freeSpace = func(string) (int64, error) { return free, nil }
+	var toasts []string
+	showToast = func(title, msg string) error {
+		toasts = append(toasts, msg)
+		return nil
+	}
+	t.Cleanup(func() { freeSpace, showToast = origFree, origToast })
+
+	dir := t.TempDir()
+	imgData := []byte("big-image")
+	updated := false
+	mock := &mockClipboard{
+		checkFunc:  func() ([]byte, error) { return imgData, nil },
+		updateFunc: func(string, string) error { updated = true; return nil },
+	}
+	cfg := Config{OutputDir: dir, MinFreeSpace: 64 << 20, LowDiskToast: true, disk: &diskGuard{}}
+	for range 2 {
+		if err := poll(mock, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error = %v, want the capture skipped without an error", err)
+		}
+	}
+	path := filepath.Join(dir, hashBytes(imgData)+".png")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("screenshot saved with too little free space: %v", err)
+	}
+	if updated {
+		t.Error("clipboard updated although nothing was saved")
+	}
+	if len(toasts) != 1 {
+		t.Errorf("%d toasts for one low-space episode, want 1", len(toasts))
+	}
+
+	free = 1 << 30
+	if err := poll(mock, testLogger(), cfg); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("screenshot not saved once space was freed: %v", err)
+	}
+	if cfg.disk.low {
+		t.Error("still flagged as low on space after a save")
+	}
+}
@@ -0,0 +1,92 @@
+package poller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// shortHashLen is how many hex digits of the name hash {shorthash} keeps.
+const shortHashLen = 6
+
+// filenameToken matches a {token} in a filename template.
+var filenameToken = regexp.MustCompile(`\{[^{}]*\}`)
+
+// unsafeNameChars matches what a window name may not bring into a file name.
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FilenameData is what a FilenameTemplate is rendered with.
+type FilenameData struct {
+	Time   time.Time // the capture time, in Config.Location
+	Seq    int       // 1 for the first capture recorded in the output directory
+	Hash   string    // the name hash, as a hash-named file would be called
+	Window string    // the foreground process at capture time, "" if unknown
+}
+
+// FilenameTemplate names screenshots from tokens instead of their hash:
+//
+//	{date}       capture date, 2006-01-02
+//	{time}       capture time, 15-04-05
+//	{seq}        capture number in the output directory, 0001
+//	{hash}       the full name hash
+//	{shorthash}  its first six hex digits
+//	{window}     the foreground process when the image was captured
+//
+// The .png extension is added. Dedup goes through the store's index then,
+// since the name no longer identifies the content.
+type FilenameTemplate struct {
+	text       string
+	usesWindow bool // whether it has a {window} token
+}
+
+// ParseFilenameTemplate validates a --filename-template.
+func ParseFilenameTemplate(text string) (*FilenameTemplate, error) {
+	text = strings.TrimSuffix(strings.TrimSpace(text), ".png")
+	if text == "" {
+		return nil, fmt.Errorf("empty filename template")
+	}
+	if strings.ContainsAny(text, `/\`) {
+		return nil, fmt.Errorf("filename template %q must not contain a path separator", text)
+	}
+	t := &FilenameTemplate{text: text}
+	for _, tok := range filenameToken.FindAllString(text, -1) {
+		switch tok {
+		case "{date}", "{time}", "{seq}", "{hash}", "{shorthash}":
+		case "{window}":
+			t.usesWindow = true
+		default:
+			return nil, fmt.Errorf("unknown token %s in filename template (expected {date}, {time}, {seq}, {hash}, {shorthash} or {window})", tok)
+		}
+	}
+	if rest := filenameToken.ReplaceAllString(text, ""); strings.ContainsAny(rest, "{}") {
+		return nil, fmt.Errorf("unbalanced brace in filename template %q", text)
+	}
+	return t, nil
+}
+
+// String returns the template as given, without the extension.
+func (t *FilenameTemplate) String() string {
+	return t.text
+}
+
+// Render returns the file name for d, extension included.
+func (t *FilenameTemplate) Render(d FilenameData) string {
+	short := d.Hash
+	if len(short) > shortHashLen {
+		short = short[:shortHashLen]
+	}
+	window := strings.Trim(unsafeNameChars.ReplaceAllString(d.Window, "_"), "._")
+	if window == "" {
+		window = "unknown"
+	}
+	r := strings.NewReplacer(
+		"{date}", d.Time.Format("2006-01-02"),
+		"{time}", d.Time.Format("15-04-05"),
+		"{seq}", fmt.Sprintf("%04d", d.Seq),
+		"{hash}", d.Hash,
+		"{shorthash}", short,
+		"{window}", window,
+	)
+	return r.Replace(t.text) + ".png"
+}
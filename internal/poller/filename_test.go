@@ -0,0 +1,97 @@
+package poller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
+
+func TestParseFilenameTemplate(t *testing.T) {
+	for _, text := range []string{"{date}_{time}_{shorthash}", "shot-{seq}.png", "{window}-{hash}"} {
+		if _, err := ParseFilenameTemplate(text); err != nil {
+			t.Errorf("ParseFilenameTemplate(%q) error: %v", text, err)
+		}
+	}
+	for _, text := range []string{"", ".png", "{date}/{time}", "{nope}", "{date"} {
+		if _, err := ParseFilenameTemplate(text); err == nil {
+			t.Errorf("ParseFilenameTemplate(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestFilenameTemplate_Render(t *testing.T) {
+	tmpl, err := ParseFilenameTemplate("{date}_{time}_{shorthash}-{seq}-{window}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := FilenameData{
+		Time:   time.Date(2024, 5, 1, 14, 32, 7, 0, time.UTC),
+		Seq:    7,
+		Hash:   "ab12cd34ef",
+		Window: "Snipping Tool.exe",
+	}
+	if got, want := tmpl.Render(d), "2024-05-01_14-32-07_ab12cd-0007-Snipping_Tool.exe.png"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+	d.Window = "../"
+	if got, want := tmpl.Render(d), "2024-05-01_14-32-07_ab12cd-0007-unknown.png"; got != want {
+		t.Errorf("Render() with an unusable window = %q, want %q", got, want)
+	}
+}
+
+func TestPoll_FilenameTemplate(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	imgData := testPNG(t)
+	client := &auditClipboard{}
+	client.checkFunc = func() ([]byte, error) { return imgData, nil }
+	tmpl, err := ParseFilenameTemplate("{date}_{time}_{seq}_{window}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewFake(time.Date(2024, 5, 1, 14, 32, 7, 0, time.UTC))
+	dir := t.TempDir()
+	cfg := Config{OutputDir: dir, FilenameTemplate: tmpl, Clock: clk, Location: time.UTC}
+
+	path, err := pollPath(client, testLogger(), cfg)
+	if err != nil {
+		t.Fatalf("pollPath() error: %v", err)
+	}
+	if want := filepath.Join(dir, "2024-05-01_14-32-07_0001_Ditto.png"); path != want {
+		t.Fatalf("saved to %s, want %s", path, want)
+	}
+
+	// A minute later the same image renders another name, but the index
+	// knows it is saved already.
+	clk.Advance(time.Minute)
+	again, err := pollPath(client, testLogger(), cfg)
+	if err != nil {
+		t.Fatalf("pollPath() error: %v", err)
+	}
+	if again != path {
+		t.Errorf("same image saved again as %s, want dedup to %s", again, path)
+	}
+
+	// Once the file is gone, the image is saved anew with the next number.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	again, err = pollPath(client, testLogger(), cfg)
+	if err != nil {
+		t.Fatalf("pollPath() error: %v", err)
+	}
+	want := filepath.Join(dir, "2024-05-01_14-33-07_0002_Ditto.png")
+	if again != want {
+		t.Errorf("saved to %s after removal, want %s", again, want)
+	}
+	ix, err := store.ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, got := ix.NextSeq(), ix.Lookup(hashBytes(imgData)); next != 3 || got != want {
+		t.Errorf("index continues at %d and maps the image to %s, want 3 and %s", next, got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package poller
+
+import (
+	"context"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/htmlimage"
+)
+
+// HTMLReader is implemented by clients that can read the clipboard's CF_HTML
+// format. With Config.HTMLImages, a poll that finds no image looks for one
+// in the HTML instead.
+type HTMLReader interface {
+	HTML() ([]byte, error)
+}
+
+// htmlSeen remembers the image source Run last found in the clipboard HTML,
+// so that an image the clipboard keeps offering is loaded once, not on every
+// tick, by clients that can't tell when the clipboard changed. ctx is Run's,
+// so that stopping the daemon cancels a download in progress.
+type htmlSeen struct {
+	ctx context.Context
+	src string
+}
+
+// loadHTMLImage loads the image an HTML clipboard points to, as PNG.
+// Declared as a var so tests can stay off the network.
+var loadHTMLImage = htmlimage.Load
+
+// readHTMLImage returns the image in the clipboard's HTML, when that HTML is
+// nothing but one image (a data: URI or an http(s) URL), or nil. An image
+// that fails to load is logged and skipped rather than failing the poll: the
+// clipboard itself was read fine.
+func readHTMLImage(client Clipboard, logger pollLogger, cfg Config) (*capture, error) {
+	hr, ok := client.(HTMLReader)
+	if !ok {
+		return nil, nil
+	}
+	if cfg.seq != nil && cfg.seq.seen != 0 && cfg.seq.seen == cfg.seq.last {
+		return nil, nil // unchanged since the last poll
+	}
+	raw, err := hr.HTML()
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	src, ok := htmlimage.ImageSource(htmlimage.Fragment(raw))
+	if cfg.html != nil {
+		if ok && src == cfg.html.src {
+			return nil, nil
+		}
+		cfg.html.src = src
+	}
+	if !ok {
+		return nil, nil
+	}
+	ctx := context.Background()
+	if cfg.html != nil && cfg.html.ctx != nil {
+		ctx = cfg.html.ctx
+	}
+	data, err := loadHTMLImage(ctx, src)
+	if err != nil {
+		logger.Warn("Could not load the image in the clipboard HTML", "src", htmlimage.Redact(src), "err", err)
+		return nil, nil
+	}
+	img := newCapture(data, cfg.NameKey)
+	img.source = "HTML"
+	return img, nil
+}
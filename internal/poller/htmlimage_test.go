@@ -0,0 +1,101 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// htmlClipboard holds HTML and no image.
+type htmlClipboard struct {
+	mockClipboard
+	html  string
+	reads int
+}
+
+func (m *htmlClipboard) HTML() ([]byte, error) {
+	m.reads++
+	return []byte(m.html), nil
+}
+
+func overrideHTMLLoad(t *testing.T, fn func(context.Context, string) ([]byte, error)) {
+	t.Helper()
+	orig := loadHTMLImage
+	loadHTMLImage = fn
+	t.Cleanup(func() { loadHTMLImage = orig })
+}
+
+func TestPoll_HTMLImage(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	imgData := testPNG(t)
+	var loaded []string
+	var loadCtx context.Context
+	overrideHTMLLoad(t, func(ctx context.Context, src string) ([]byte, error) {
+		loaded = append(loaded, src)
+		loadCtx = ctx
+		return imgData, nil
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &htmlClipboard{html: `<!--StartFragment--><img src="https://example.com/cat.png"><!--EndFragment-->`}
+	updated := false
+	mock.updateFunc = func(string, string) error { updated = true; return nil }
+
+	dir := t.TempDir()
+	if err := poll(mock, logger, Config{OutputDir: dir}); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	if mock.reads != 0 {
+		t.Error("HTML was read without HTMLImages")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg := Config{OutputDir: dir, HTMLImages: true, html: &htmlSeen{ctx: ctx}}
+	if err := poll(mock, logger, cfg); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0] != "https://example.com/cat.png" {
+		t.Fatalf("loaded %v, want the image URL", loaded)
+	}
+	if loadCtx != ctx {
+		t.Error("the image was not loaded with Run's context, so stopping could not cancel it")
+	}
+	if _, err := os.Stat(filepath.Join(dir, hashBytes(imgData)+".png")); err != nil {
+		t.Errorf("HTML image not saved: %v", err)
+	}
+	if !updated {
+		t.Error("clipboard not updated after saving the HTML image")
+	}
+
+	// Still on the clipboard: not loaded again.
+	if err := poll(mock, logger, cfg); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Errorf("the same HTML image was loaded %d times", len(loaded))
+	}
+}
+
+func TestPoll_HTMLImageIgnoresDocumentsAndFailures(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	overrideHTMLLoad(t, func(context.Context, string) ([]byte, error) { return nil, errors.New("connection refused") })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	for _, html := range []string{
+		`<p>Some text</p><img src="https://example.com/a.png">`,
+		`<img src="https://example.com/down.png">`,
+	} {
+		mock := &htmlClipboard{html: html}
+		if err := poll(mock, logger, Config{OutputDir: dir, HTMLImages: true}); err != nil {
+			t.Errorf("poll(%q) error = %v, want nil", html, err)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("output dir has %d entries, want none", len(entries))
+	}
+}
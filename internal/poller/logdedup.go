@@ -0,0 +1,118 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+// dedupWindow is how long identical log records are suppressed after the
+// first occurrence before a repeat count is written.
+const dedupWindow = time.Minute
+
+// pollLogger is the subset of *slog.Logger used by poll, so it can be handed
+// either a plain logger (tests) or a dedupLogger (Run).
+type pollLogger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// dedupLogger collapses identical log records (same level, message and
+// attributes). The first occurrence is written immediately; repeats within
+// the window are counted and summarized once the window expires, as the same
+// record with "repeated" and "window" attributes, so a warning firing on
+// every tick costs one record per minute instead of four per second. Not
+// safe for concurrent use.
+type dedupLogger struct {
+	logger  *slog.Logger
+	clock   clock.Clock
+	window  time.Duration
+	entries map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	level      slog.Level
+	msg        string
+	args       []any
+	since      time.Time // start of the current suppression window
+	suppressed int
+}
+
+func newDedupLogger(logger *slog.Logger, clk clock.Clock, window time.Duration) *dedupLogger {
+	return &dedupLogger{
+		logger:  logger,
+		clock:   clk,
+		window:  window,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+func (d *dedupLogger) Info(msg string, args ...any)  { d.emit(slog.LevelInfo, msg, args) }
+func (d *dedupLogger) Warn(msg string, args ...any)  { d.emit(slog.LevelWarn, msg, args) }
+func (d *dedupLogger) Error(msg string, args ...any) { d.emit(slog.LevelError, msg, args) }
+
+func (d *dedupLogger) emit(level slog.Level, msg string, args []any) {
+	key := fmt.Sprint(level, msg, args)
+	now := d.clock.Now()
+	if e, ok := d.entries[key]; ok {
+		if now.Sub(e.since) < d.window {
+			e.suppressed++
+			return
+		}
+		d.summarize(e)
+	}
+	d.entries[key] = &dedupEntry{level: level, msg: msg, args: args, since: now}
+	d.logger.Log(context.Background(), level, msg, args...)
+}
+
+// flush writes summaries for every entry whose window has expired and forgets
+// them, so a warning that stops repeating still gets its count reported.
+func (d *dedupLogger) flush() {
+	d.flushBefore(d.clock.Now().Add(-d.window))
+}
+
+// flushAll writes summaries for every pending entry regardless of its window.
+// Called on shutdown so no suppressed counts are lost.
+func (d *dedupLogger) flushAll() {
+	d.flushBefore(time.Time{})
+}
+
+func (d *dedupLogger) flushBefore(cutoff time.Time) {
+	keys := make([]string, 0, len(d.entries))
+	for key, e := range d.entries {
+		if cutoff.IsZero() || !e.since.After(cutoff) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		d.summarize(d.entries[key])
+		delete(d.entries, key)
+	}
+}
+
+func (d *dedupLogger) summarize(e *dedupEntry) {
+	if e.suppressed == 0 {
+		return
+	}
+	args := append(e.args[:len(e.args):len(e.args)], "repeated", e.suppressed, "window", shortDuration(d.clock.Now().Sub(e.since)))
+	d.logger.Log(context.Background(), e.level, e.msg, args...)
+}
+
+// shortDuration formats d without trailing zero units ("1m" instead of "1m0s").
+func shortDuration(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
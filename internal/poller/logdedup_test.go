@@ -0,0 +1,136 @@
+package poller
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+func newTestDedup(clk clock.Clock) (*dedupLogger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+			return slog.Attr{} // keep test lines stable and short
+		}
+		return a
+	}})
+	return newDedupLogger(slog.New(h), clk, time.Minute), &buf
+}
+
+func logLines(buf *bytes.Buffer) []string {
+	out := strings.TrimSpace(buf.String())
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+func TestDedupLogger_SuppressesRepeatsWithinWindow(t *testing.T) {
+	clk := clock.NewFake(testEpoch)
+	d, buf := newTestDedup(clk)
+
+	for i := 0; i < 240; i++ {
+		d.Warn("wslpath failed", "err", "boom")
+		clk.Advance(250 * time.Millisecond)
+	}
+
+	lines := logLines(buf)
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line within the window, got %d: %q", len(lines), lines)
+	}
+
+	d.flush()
+	lines = logLines(buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected a summary after the window, got %q", lines)
+	}
+	want := `msg="wslpath failed" err=boom repeated=239 window=1m`
+	if lines[1] != want {
+		t.Errorf("summary = %q, want %q", lines[1], want)
+	}
+}
+
+func TestDedupLogger_DistinctMessagesPassThrough(t *testing.T) {
+	clk := clock.NewFake(testEpoch)
+	d, buf := newTestDedup(clk)
+
+	d.Info("first")
+	d.Info("second")
+	d.Info("third", "n", 3)
+	d.Info("third", "n", 4)
+	d.Warn("first")
+
+	if got := logLines(buf); len(got) != 5 {
+		t.Errorf("expected 5 distinct records (message, attributes or level differ), got %q", got)
+	}
+}
+
+func TestDedupLogger_RepeatAfterWindowIsLoggedAgain(t *testing.T) {
+	clk := clock.NewFake(testEpoch)
+	d, buf := newTestDedup(clk)
+
+	d.Warn("update failed")
+	d.Warn("update failed")
+	clk.Advance(time.Minute)
+	d.Warn("update failed")
+
+	lines := logLines(buf)
+	want := []string{
+		`msg="update failed"`,
+		`msg="update failed" repeated=1 window=1m`,
+		`msg="update failed"`,
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestDedupLogger_FlushSkipsSingleOccurrences(t *testing.T) {
+	clk := clock.NewFake(testEpoch)
+	d, buf := newTestDedup(clk)
+
+	d.Info("once")
+	clk.Advance(2 * time.Minute)
+	d.flush()
+
+	if got := logLines(buf); len(got) != 1 {
+		t.Errorf("expected no summary for a single occurrence, got %q", got)
+	}
+}
+
+func TestDedupLogger_FlushAllReportsPendingCounts(t *testing.T) {
+	clk := clock.NewFake(testEpoch)
+	d, buf := newTestDedup(clk)
+
+	d.Info("busy")
+	clk.Advance(10 * time.Second)
+	d.Info("busy")
+	d.flushAll()
+
+	lines := logLines(buf)
+	if len(lines) != 2 || lines[1] != "msg=busy repeated=1 window=10s" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestShortDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "10s"},
+		{time.Minute, "1m"},
+		{90 * time.Second, "1m30s"},
+		{time.Hour, "1h"},
+		{time.Hour + 30*time.Second, "1h0m30s"},
+	}
+	for _, tt := range tests {
+		if got := shortDuration(tt.d); got != tt.want {
+			t.Errorf("shortDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -2,115 +2,981 @@ package poller
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
-	"log"
+	"image/png"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
-)
 
-const maxConsecutiveErrors = 5
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/events"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
+)
 
 // Clipboard abstracts clipboard operations for testability.
 type Clipboard interface {
 	Check() ([]byte, error)
-	UpdateClipboard(wslPath, winPath string) error
+	// UpdateClipboard sets text (the saved file's WSL path, or
+	// Config.TextTemplate rendered for it) and the image at winPath.
+	UpdateClipboard(text, winPath string) error
 	Close() error
 }
 
+// Sequencer is implemented by clients that can report the Windows clipboard
+// sequence number, a counter bumped on every clipboard change. Reading it is
+// far cheaper than a full CHECK.
+type Sequencer interface {
+	Sequence() (uint32, error)
+}
+
+// FormatLimiter is implemented by clients whose UpdateClipboard may not set
+// every format (text, image, filedrop), because the backend can't or the
+// user left some out. UpdatableFormats lists the ones it does set; Run logs
+// what is missing.
+type FormatLimiter interface {
+	UpdatableFormats() []string
+}
+
+// PartialUpdate is implemented by UpdateClipboard errors reporting that only
+// some clipboard formats could be set. The clipboard still holds the others,
+// so the poller logs it and counts the update as done.
+type PartialUpdate interface {
+	error
+	FailedFormats() []string
+}
+
+// SessionLock is implemented by check errors reporting that the Windows
+// session is locked, when the clipboard can't be read. Run pauses polling
+// until the session is unlocked instead of counting them as failures.
+type SessionLock interface {
+	error
+	SessionLocked() bool
+}
+
+// ClipboardBusy is implemented by check errors reporting that another
+// process kept the clipboard open. Run counts such a poll as "no change" and
+// reads the clipboard again next tick, without tripping the breaker.
+type ClipboardBusy interface {
+	error
+	ClipboardBusy() bool
+}
+
+// BinaryReporter is implemented by clients that know which PowerShell
+// executable runs their backend, for status.
+type BinaryReporter interface {
+	Binary() string
+}
+
+// SessionWatcher is implemented by clients that can tell whether the Windows
+// session is locked without reading the clipboard. Run asks it on every tick
+// while paused; without it, paused ticks poll and see for themselves.
+type SessionWatcher interface {
+	SessionLocked() (bool, error)
+}
+
+// MemoryReporter is implemented by clients that can report the memory used by
+// their backend process.
+type MemoryReporter interface {
+	BackendMemory() (int64, error) // working set in bytes
+}
+
+// memoryCheckInterval is how often Run samples backend memory.
+const memoryCheckInterval = 30 * time.Second
+
+// VerboseSetter is implemented by clients whose protocol logging can be
+// switched on and off while running.
+type VerboseSetter interface {
+	SetVerbose(verbose bool)
+}
+
+// Breakable is implemented by clients that can tell they are no longer
+// usable, e.g. because their backend was killed for not answering in time.
+// Run replaces a broken client right away instead of waiting for the breaker
+// to count enough failures.
+type Breakable interface {
+	Broken() bool
+}
+
+// Pinger is implemented by clients that can check their backend still
+// answers without touching the clipboard. Run pings between polls to catch a
+// wedged backend before a real CHECK hangs on it.
+type Pinger interface {
+	Ping() (time.Duration, error) // round trip time
+}
+
+// pingInterval is how often Run pings the backend.
+const pingInterval = 10 * time.Second
+
+// Settings are the parts of Config that can change while Run is polling.
+type Settings struct {
+	Interval  time.Duration
+	OutputDir string
+	Verbose   bool
+}
+
+// CopyRequest asks Run to set the clipboard to the screenshot saved at Path,
+// with the client it polls with, like Copy. The result is sent on Done, which
+// should be buffered so Run never waits for the requester.
+type CopyRequest struct {
+	Path string
+	Done chan<- error
+}
+
 // ClientFactory creates a new Clipboard client.
 type ClientFactory func() (Clipboard, error)
 
-// Run polls the clipboard at the given interval until the context is cancelled.
-func Run(ctx context.Context, logger *log.Logger, interval int, outputDir string, newClient ClientFactory) error {
+// Config holds the settings for a polling session.
+type Config struct {
+	Interval  time.Duration
+	OutputDir string
+
+	// DailyDirs saves each screenshot under a YYYY-MM-DD subdirectory of
+	// OutputDir, named after the capture date in Location.
+	DailyDirs bool
+
+	// Location is the timezone used for all date-derived paths. Nil means local time.
+	Location *time.Location
+
+	// Clock drives the poll ticker and supplies capture times. Nil means the wall clock.
+	Clock clock.Clock
+
+	// Stats receives capture and error counts. Nil disables reporting.
+	Stats *stats.Counters
+
+	// Events receives capture, error and restart events. Nil disables the journal.
+	Events *events.Journal
+
+	// SeqCheck skips the full clipboard check on ticks where the clipboard
+	// sequence number has not changed. Only takes effect when the client
+	// implements Sequencer.
+	SeqCheck bool
+
+	// Wait replaces the fixed ticker with blocking waits for clipboard
+	// changes, bounded by a safety timeout, when the client implements
+	// Waiter. A client that can't wait is polled every Interval as usual.
+	Wait bool
+
+	// MaxBackendMemory restarts the client when its backend's working set
+	// exceeds this many bytes. Zero disables the limit; memory is still
+	// sampled and reported to Stats when the client supports it.
+	MaxBackendMemory int64
+
+	// OnCollision decides what to do when the file name is taken by different
+	// content. Empty means store.CollisionSuffix.
+	OnCollision store.CollisionPolicy
+
+	// OverwriteWindow is how long after an update a clipboard change by
+	// another application counts as an overwrite in Stats. Zero means
+	// defaultOverwriteWindow; negative disables the audit.
+	OverwriteWindow time.Duration
+
+	// Breaker controls when repeated failures restart the client or stop polling.
+	Breaker BreakerPolicy
+
+	// MaxWritesPerSecond limits how many new screenshots are saved per
+	// second. Captures over the limit are queued and saved on later ticks;
+	// the clipboard is updated once they are on disk. Zero disables it.
+	MaxWritesPerSecond int
+
+	// HTMLImages makes a poll that finds no image look in the clipboard's
+	// HTML, when the client implements HTMLReader, and save the image that
+	// HTML holds when it holds nothing else: an embedded data: URI, or a
+	// remote image, which is downloaded. Off by default because of the
+	// downloads.
+	HTMLImages bool
+
+	// FileMode is the permission bits of saved screenshots, and DirMode
+	// those of the daily directories created for them. Zero means 0600 and
+	// 0700: screenshots may show secrets, and only the daemon's user (whom
+	// Windows apps reading them through WSL act as) needs them.
+	FileMode os.FileMode
+	DirMode  os.FileMode
+
+	// MinFreeSpace is how many bytes must stay free on the output
+	// filesystem after a save. A capture that would go below it is not
+	// saved, rather than failing halfway through the write. Zero disables
+	// the check.
+	MinFreeSpace int64
+
+	// LowDiskToast shows a Windows notification when captures start being
+	// skipped for MinFreeSpace.
+	LowDiskToast bool
+
+	// LatestLink keeps a latest.png symlink in OutputDir pointing at the
+	// screenshot of the newest capture, and LatestPathFile, with it, a
+	// "latest" file holding that screenshot's path.
+	LatestLink     bool
+	LatestPathFile bool
+
+	// NoClipboardUpdate saves screenshots without touching the clipboard
+	// afterwards: what the user copied stays as it was.
+	NoClipboardUpdate bool
+
+	// TextMode picks the path clipboard updates set as text. Empty means
+	// TextWSL.
+	TextMode TextMode
+
+	// TextTemplate, when set, renders the text clipboard updates set, from
+	// a TextData, instead of the path TextMode picks. See ParseTextTemplate.
+	TextTemplate *template.Template
+
+	// NameKey, when set, names screenshots by the HMAC-SHA256 of their
+	// content under this key instead of the plain SHA256, so names don't
+	// reveal content hashes to other local users. Dedup is unaffected: an
+	// image always gets the same name under the same key.
+	NameKey []byte
+
+	// FilenameTemplate, when set, names screenshots from the capture time,
+	// sequence number, hash and source window instead of the hash alone.
+	// Dedup then looks images up in the store's index. Nil names them
+	// <hash>.png.
+	FilenameTemplate *FilenameTemplate
+
+	// Chaos is the probability, from 0 to 1, of injecting a simulated fault
+	// (failed CHECK, slow response, failed write) at each point of the
+	// pipeline. Injected errors wrap ErrInjected and are counted separately
+	// in Stats. Zero disables it.
+	Chaos float64
+
+	// Reload delivers settings to apply live, e.g. after the daemon re-reads
+	// its config file on SIGHUP. Nil means settings never change.
+	Reload <-chan Settings
+
+	// Copies delivers requests to put earlier screenshots back on the
+	// clipboard. Nil means none arrive.
+	Copies <-chan CopyRequest
+
+	// onUpdate, when set, runs after every successful clipboard update.
+	onUpdate func()
+
+	// onCapture, when set, runs when a poll finds a new screenshot, before
+	// it is saved.
+	onCapture func()
+
+	// throttle, when set, rate-limits saves (see MaxWritesPerSecond).
+	throttle *writeThrottle
+
+	// chaos, when set, injects faults (see Chaos).
+	chaos *chaos
+
+	// seq, when set, carries clipboard sequence numbers between polls for
+	// SequenceChecker clients.
+	seq *clipSeq
+
+	// known, when set, remembers recent captures for HashChecker clients.
+	known *knownImages
+
+	// html, when set, remembers the last image found in the clipboard HTML.
+	html *htmlSeen
+
+	// kept, when set, remembers the last image NoClipboardUpdate left on
+	// the clipboard.
+	kept *keptImage
+
+	// disk, when set, remembers whether the output filesystem is short of
+	// space (see MinFreeSpace).
+	disk *diskGuard
+
+	// ToWinPath converts a saved file's WSL path to a Windows path. Nil means
+	// wslpath -w; offline tools (replay) substitute a fake.
+	ToWinPath func(wslPath string) (string, error)
+}
+
+// withDefaults fills in nil fields with their production defaults.
+func (c Config) withDefaults() Config {
+	if c.Location == nil {
+		c.Location = time.Local
+	}
+	if c.Clock == nil {
+		c.Clock = clock.Real{}
+	}
+	if c.OverwriteWindow == 0 {
+		c.OverwriteWindow = defaultOverwriteWindow
+	}
+	if c.OnCollision == "" {
+		c.OnCollision = store.CollisionSuffix
+	}
+	c.Breaker = c.Breaker.withDefaults()
+	if c.ToWinPath == nil {
+		c.ToWinPath = wslToWinPath
+	}
+	if c.FileMode == 0 {
+		c.FileMode = 0600
+	}
+	if c.DirMode == 0 {
+		c.DirMode = 0700
+	}
+	return c
+}
+
+// Run polls the clipboard at the configured interval, or on every change with
+// Wait, until the context is cancelled.
+func Run(ctx context.Context, baseLogger *slog.Logger, cfg Config, newClient ClientFactory) error {
+	cfg = cfg.withDefaults()
+	logger := newDedupLogger(baseLogger, cfg.Clock, dedupWindow)
+	defer logger.flushAll()
+
 	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("start clipboard client: %w", err)
 	}
-	defer func() { _ = client.Close() }()
+	cfg.Stats.SetBackendReady(true)
+	reportBinary(client, cfg)
+	if cfg.NoClipboardUpdate {
+		logger.Info("Clipboard updates disabled, screenshots are only saved")
+		cfg.kept = &keptImage{}
+	} else if missing := missingFormats(client); len(missing) > 0 {
+		logger.Info("Clipboard updates leave out some formats", "missing", strings.Join(missing, ", "))
+	}
+	defer func() {
+		cfg.Stats.SetBackendReady(false)
+		_ = client.Close()
+	}()
 
-	ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
-	defer ticker.Stop()
+	ticker := cfg.Clock.NewTicker(cfg.Interval)
+	defer func() { ticker.Stop() }()
+	var verbose *bool // set by a reload; reapplied to restarted clients
 
 	consecutiveErrors := 0
+	locked := false // the Windows session is locked: polling is paused
+	cfg.seq = &clipSeq{}
+	cfg.known = newKnownImages()
+	cfg.html = &htmlSeen{ctx: ctx}
+	cfg.disk = &diskGuard{}
+	lastMemCheck := cfg.Clock.Now()
+	lastPing := cfg.Clock.Now()
+	audit := overwriteAudit{window: cfg.OverwriteWindow}
+	cfg.onUpdate = func() { audit.arm(client, cfg.Clock.Now()) }
+	cfg.onCapture = audit.captured
+	if cfg.MaxWritesPerSecond > 0 {
+		cfg.throttle = newWriteThrottle(cfg.MaxWritesPerSecond, cfg.Clock.Now())
+		cfg.throttle.counters = cfg.Stats
+		defer func() {
+			cfg.throttle.drain(cfg.Clock.Now(), true, func(w pendingWrite) { saveQueued(w, logger, cfg) })
+		}()
+	}
+
+	if cfg.Chaos > 0 {
+		cfg.chaos = newChaos(cfg.Chaos, cfg.Clock)
+		logger.Warn("Chaos mode: injecting faults", "rate", cfg.Chaos)
+	}
+
+	backoff := newRestartBackoff(cfg.Breaker)
+	limit := restartLimit{max: cfg.Breaker.MaxRestartsPerHour}
+	pending := "" // reason for a restart waiting out the backoff
+	restart := func(reason string) error {
+		if wait := backoff.wait(cfg.Clock.Now()); wait > 0 {
+			if pending == "" {
+				logger.Warn("PowerShell client restarting again too soon, backing off", "reason", reason, "delay", wait.Round(time.Millisecond))
+			}
+			pending = reason
+			return nil
+		}
+		pending = ""
+		if err := limit.allow(cfg.Clock.Now()); err != nil {
+			logger.Error("PowerShell client keeps failing, giving up", "reason", reason, "err", err)
+			return fmt.Errorf("%w; latest cause: %s", err, reason)
+		}
+		backoff.restarted(cfg.Clock.Now())
+		_ = cfg.Events.Append(events.Event{Time: cfg.Clock.Now(), Type: events.TypeRestart, Message: reason})
+		cfg.Stats.RecordRestart()
+		cfg.Stats.SetBackendReady(false)
+		_ = client.Close()
+		c, err := newClient()
+		if err != nil {
+			return fmt.Errorf("restart clipboard client: %w", err)
+		}
+		client = c
+		cfg.Stats.SetBackendReady(true)
+		reportBinary(client, cfg)
+		if vs, ok := client.(VerboseSetter); ok && verbose != nil {
+			vs.SetVerbose(*verbose)
+		}
+		consecutiveErrors = 0
+		*cfg.seq = clipSeq{}
+		return nil
+	}
+
+	// tick runs one poll cycle, with the housekeeping that comes before it.
+	tick := func() error {
+		logger.flush()
+		if pending != "" {
+			// No polls against a client that is due for a restart.
+			return restart(pending)
+		}
+		if cfg.throttle != nil {
+			cfg.throttle.drain(cfg.Clock.Now(), false, func(w pendingWrite) { saveQueued(w, logger, cfg) })
+		}
+		audit.check(client, cfg.Clock.Now())
+		defer audit.settle(logger, cfg.Stats) // once this tick's poll could clear it
+		if now := cfg.Clock.Now(); now.Sub(lastMemCheck) >= memoryCheckInterval {
+			lastMemCheck = now
+			if overMemoryLimit(client, logger, cfg) {
+				logger.Warn("PowerShell client exceeded its memory limit, restarting", "limit_bytes", cfg.MaxBackendMemory)
+				if err := restart("memory limit exceeded"); err != nil {
+					return err
+				}
+			}
+		}
+		if now := cfg.Clock.Now(); now.Sub(lastPing) >= pingInterval {
+			lastPing = now
+			if err := pingBackend(client, cfg); err != nil {
+				if cfg.Breaker.Action == TripExit {
+					return fmt.Errorf("clipboard backend stopped responding: %w", err)
+				}
+				logger.Error("PowerShell client did not answer PING, restarting it", "err", err)
+				if err := restart("backend did not answer PING"); err != nil {
+					return err
+				}
+				return nil
+			}
+		}
+		if locked {
+			if sessionLocked(client) {
+				return nil
+			}
+			locked = false
+			cfg.Stats.SetSessionLocked(false)
+			logger.Info("Windows session unlocked, resuming polling")
+		}
+		seq, unchanged := sequenceUnchanged(client, cfg, cfg.seq.last)
+		if unchanged {
+			cfg.Stats.RecordPoll(cfg.Clock.Now(), true)
+			return nil
+		}
+		cfg.seq.seen, cfg.seq.queued = seq, false
+		cfg.Stats.BeginJob()
+		err := poll(client, logger, cfg)
+		cfg.Stats.EndJob()
+		var lock SessionLock
+		if errors.As(err, &lock) && lock.SessionLocked() {
+			locked = true
+			cfg.Stats.SetSessionLocked(true)
+			logger.Info("Windows session locked, pausing polling until unlock")
+			return nil
+		}
+		var busy ClipboardBusy
+		if errors.As(err, &busy) && busy.ClipboardBusy() {
+			cfg.Stats.RecordPoll(cfg.Clock.Now(), true)
+			cfg.seq.seen = cfg.seq.last // not read yet: a wait must not skip it
+			logger.Info("Clipboard held open by another process, reading it again next tick", "err", err)
+			return nil
+		}
+		cfg.Stats.RecordPoll(cfg.Clock.Now(), err == nil)
+		if err != nil {
+			if errors.Is(err, ErrInjected) {
+				cfg.Stats.RecordInjectedError()
+			} else {
+				cfg.Stats.RecordError()
+			}
+			_ = cfg.Events.Append(events.Event{Time: cfg.Clock.Now(), Type: events.TypeError, Message: err.Error()})
+			if b, ok := client.(Breakable); ok && b.Broken() {
+				// Every further call would fail too, whatever the policy counts.
+				if cfg.Breaker.Action == TripExit {
+					return fmt.Errorf("clipboard backend stopped responding: %w", err)
+				}
+				logger.Error("PowerShell client stopped responding, restarting it", "err", err)
+				if err := restart("backend stopped responding"); err != nil {
+					return err
+				}
+				return nil
+			}
+			if !cfg.Breaker.counts(err) {
+				logger.Warn("Poll error ignored by breaker", "class", classOf(err), "err", err)
+				return nil
+			}
+			consecutiveErrors++
+			logger.Warn("Poll error", "consecutive", consecutiveErrors, "threshold", cfg.Breaker.MaxConsecutiveErrors, "err", err)
+
+			if consecutiveErrors >= cfg.Breaker.MaxConsecutiveErrors {
+				if cfg.Breaker.Action == TripExit {
+					return fmt.Errorf("circuit breaker tripped after %d consecutive errors: %w", consecutiveErrors, err)
+				}
+				logger.Error("Too many consecutive errors, restarting PowerShell client", "consecutive", consecutiveErrors)
+				if err := restart(fmt.Sprintf("%d consecutive errors", consecutiveErrors)); err != nil {
+					return err
+				}
+			}
+		} else {
+			cfg.Stats.RecordSuccess()
+			consecutiveErrors = 0
+			backoff.reset()
+			if !cfg.seq.queued {
+				cfg.seq.last = cfg.seq.seen
+			}
+		}
+		return nil
+	}
+
+	var wake *waker
+	if cfg.Wait {
+		wake = newWaker()
+		defer wake.stop()
+	}
+	waiting := wake.start(client, 0)
+	tickC := ticker.C()
+	if waiting {
+		tickC = nil
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			logger.Println("Polling process shutting down...")
+			logger.Info("Polling process shutting down")
 			return nil
-		case <-ticker.C:
-			if err := poll(client, logger, outputDir); err != nil {
-				consecutiveErrors++
-				logger.Printf("Poll error (%d/%d): %v", consecutiveErrors, maxConsecutiveErrors, err)
-
-				if consecutiveErrors >= maxConsecutiveErrors {
-					logger.Println("Too many consecutive errors, restarting PowerShell client...")
-					_ = client.Close()
-
-					client, err = newClient()
-					if err != nil {
-						return fmt.Errorf("restart clipboard client: %w", err)
-					}
-					consecutiveErrors = 0
+		case s := <-cfg.Reload:
+			if s.Interval != cfg.Interval {
+				ticker.Stop()
+				ticker = cfg.Clock.NewTicker(s.Interval)
+				if !waiting {
+					tickC = ticker.C()
 				}
-			} else {
-				consecutiveErrors = 0
 			}
+			if vs, ok := client.(VerboseSetter); ok {
+				vs.SetVerbose(s.Verbose)
+			}
+			verbose = &s.Verbose
+			logger.Info("Settings reloaded", "interval", s.Interval, "output", s.OutputDir, "verbose", s.Verbose)
+			cfg.Interval, cfg.OutputDir = s.Interval, s.OutputDir
+		case req := <-cfg.Copies:
+			err := copyTo(client, logger, cfg, req.Path)
+			if err == nil {
+				logger.Info("Clipboard set to a saved screenshot", "path", req.Path)
+				cfg.Stats.SetClipboardPath(req.Path)
+				cfg.onUpdate()
+			}
+			req.Done <- err
+		case <-tickC:
+			if err := tick(); err != nil {
+				return err
+			}
+		case err := <-wake.C():
+			if err != nil {
+				if b, ok := client.(Breakable); !ok || !b.Broken() {
+					// The backend can't wait (an old script, no user32):
+					// waiting again would fail right away, over and over.
+					logger.Warn("Clipboard backend cannot wait for changes, polling every interval instead", "err", err)
+					waiting, tickC = false, ticker.C()
+				}
+			}
+			if err := tick(); err != nil {
+				return err
+			}
+			if waiting && !wake.start(client, cfg.seq.seen) {
+				waiting, tickC = false, ticker.C()
+			}
+		}
+	}
+}
+
+// overMemoryLimit samples the backend's memory, reports it to Stats, and says
+// whether it exceeds cfg.MaxBackendMemory.
+func overMemoryLimit(client Clipboard, logger pollLogger, cfg Config) bool {
+	mr, ok := client.(MemoryReporter)
+	if !ok {
+		return false
+	}
+	mem, err := mr.BackendMemory()
+	if err != nil {
+		logger.Warn("Could not read PowerShell memory", "err", err)
+		return false
+	}
+	cfg.Stats.SetBackendMemory(mem)
+	return cfg.MaxBackendMemory > 0 && mem > cfg.MaxBackendMemory
+}
+
+// missingFormats lists the clipboard formats a FormatLimiter client cannot
+// set, in protocol order. It is empty for full clients.
+func missingFormats(client Clipboard) []string {
+	fl, ok := client.(FormatLimiter)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for _, f := range []string{"text", "image", "filedrop"} {
+		if !slices.Contains(fl.UpdatableFormats(), f) {
+			missing = append(missing, f)
 		}
 	}
+	return missing
+}
+
+// reportBinary tells Stats which PowerShell executable a BinaryReporter
+// client runs.
+func reportBinary(client Clipboard, cfg Config) {
+	if br, ok := client.(BinaryReporter); ok {
+		cfg.Stats.SetBackendBinary(br.Binary())
+	}
+}
+
+// sessionLocked asks a SessionWatcher client whether the Windows session is
+// still locked. Errors and other clients count as unlocked, so the next poll
+// finds out.
+func sessionLocked(client Clipboard) bool {
+	sw, ok := client.(SessionWatcher)
+	if !ok {
+		return false
+	}
+	locked, err := sw.SessionLocked()
+	return err == nil && locked
+}
+
+// pingBackend pings the client's backend, if it supports it, and reports the
+// result to Stats.
+func pingBackend(client Clipboard, cfg Config) error {
+	p, ok := client.(Pinger)
+	if !ok {
+		return nil
+	}
+	at := cfg.Clock.Now()
+	rtt, err := p.Ping()
+	cfg.Stats.RecordPing(at, rtt, err == nil)
+	return err
+}
+
+// sequenceUnchanged reads the clipboard sequence number when SeqCheck is on
+// and reports whether it still matches last. Errors and a zero sequence
+// (no clipboard access) fall back to a full check. SequenceChecker clients
+// skip it: their CHECK compares the sequence number in the same round trip.
+func sequenceUnchanged(client Clipboard, cfg Config, last uint32) (uint32, bool) {
+	if !cfg.SeqCheck {
+		return 0, false
+	}
+	if _, ok := client.(SequenceChecker); ok {
+		return 0, false
+	}
+	seqr, ok := client.(Sequencer)
+	if !ok {
+		return 0, false
+	}
+	seq, err := seqr.Sequence()
+	if err != nil || seq == 0 {
+		return 0, false
+	}
+	cfg.Stats.SetClipboardSequence(seq)
+	return seq, seq == last
+}
+
+// PollOnce performs a single clipboard check cycle outside of Run, for
+// one-shot and offline tools.
+func PollOnce(client Clipboard, logger *slog.Logger, cfg Config) error {
+	return poll(client, logger, cfg)
+}
+
+// Grab performs a single clipboard check cycle like PollOnce and returns
+// the path of the screenshot the clipboard held, saved now or by an earlier
+// capture, or "" when there was no image or it was not saved.
+func Grab(client Clipboard, logger *slog.Logger, cfg Config) (string, error) {
+	return pollPath(client, logger, cfg)
+}
+
+// Copy sets the clipboard to the screenshot saved at path, the way a poll
+// does after saving it. path may also be a JPEG or another image format the
+// backend can load. A partial update is logged and counts as done.
+func Copy(client Clipboard, logger *slog.Logger, cfg Config, path string) error {
+	return copyTo(client, logger, cfg.withDefaults(), path)
+}
+
+// copyTo is Copy for a Config with defaults filled in.
+func copyTo(client Clipboard, logger pollLogger, cfg Config, path string) error {
+	winPath, err := cfg.ToWinPath(path)
+	if err != nil {
+		return err
+	}
+	text := clipboardText(cfg, logger, path, winPath)
+	err = client.UpdateClipboard(text, winPath)
+	var partial PartialUpdate
+	if errors.As(err, &partial) {
+		logger.Warn("Clipboard partially updated", "err", err)
+		return nil
+	}
+	return err
 }
 
 // poll performs a single clipboard check cycle: check -> hash -> dedup -> save -> update.
-func poll(client Clipboard, logger *log.Logger, outputDir string) error {
-	pngData, err := client.Check()
+func poll(client Clipboard, logger pollLogger, cfg Config) error {
+	_, err := pollPath(client, logger, cfg)
+	return err
+}
+
+// pollPath is poll, also returning where the screenshot is, like Grab.
+func pollPath(client Clipboard, logger pollLogger, cfg Config) (string, error) {
+	cfg = cfg.withDefaults()
+
+	start := cfg.Clock.Now()
+	if err := cfg.chaos.beforeCheck(); err != nil {
+		return "", classify(ClassBackend, fmt.Errorf("check clipboard: %w", err))
+	}
+	img, err := readClipboard(client, cfg)
+	if err == nil && img == nil && cfg.HTMLImages {
+		img, err = readHTMLImage(client, logger, cfg)
+	}
+	cfg.Stats.RecordStage(stats.StageCheck, cfg.Clock.Now().Sub(start))
 	if err != nil {
-		return fmt.Errorf("check clipboard: %w", err)
+		return "", classify(ClassBackend, fmt.Errorf("check clipboard: %w", err))
 	}
-	if pngData == nil {
-		return nil // no image in clipboard
+	if img == nil {
+		return "", nil // no image in clipboard
+	}
+	defer img.discard()
+	if cfg.kept != nil && img.hash != "" && img.hash == cfg.kept.hash {
+		return "", nil // saved by an earlier poll and left on the clipboard
+	}
+	if img.window == "" && cfg.FilenameTemplate != nil && cfg.FilenameTemplate.usesWindow {
+		// A backend that can't name the foreground window still knows
+		// who owns the clipboard, which is usually the same application.
+		if or, ok := client.(OwnerReporter); ok {
+			_, img.window, _ = or.Owner() // unknown on failure; the name says so
+		}
+	}
+
+	filePath := img.saved
+	if filePath != "" {
+		// The backend recognised an image saved by an earlier poll and
+		// did not send it again.
+		cfg.Stats.RecordDedupHit()
+	} else if filePath, err = place(img, logger, cfg); err != nil || filePath == "" {
+		return "", err
+	}
+	cfg.known.add(img.sha, filePath)
+	markLatest(filePath, logger, cfg)
+	if cfg.NoClipboardUpdate {
+		if cfg.kept != nil {
+			cfg.kept.hash = img.hash
+		}
+		logger.Info("Clipboard left unchanged", "path", filePath)
+		return filePath, nil
 	}
 
-	hash := hashBytes(pngData)
-	filename := hash + ".png"
-	filePath := filepath.Join(outputDir, filename)
+	start = cfg.Clock.Now()
+	defer func() { cfg.Stats.RecordStage(stats.StageUpdate, cfg.Clock.Now().Sub(start)) }()
 
-	// Only write if file doesn't already exist (content-addressable dedup).
+	winPath, err := cfg.ToWinPath(filePath)
+	if err != nil {
+		logger.Warn("wslpath failed, clipboard not updated", "err", err)
+		return filePath, nil // file saved, just can't update clipboard
+	}
+
+	text := clipboardText(cfg, logger, filePath, winPath)
+	if err := client.UpdateClipboard(text, winPath); err != nil {
+		var partial PartialUpdate
+		if !errors.As(err, &partial) {
+			logger.Warn("Clipboard update failed", "err", err)
+			return filePath, nil // file saved, just can't update clipboard
+		}
+		logger.Warn("Clipboard partially updated", "err", err)
+	}
+
+	logger.Info("Clipboard updated", "path", filePath)
+	cfg.Stats.SetClipboardPath(filePath)
+	if cfg.onUpdate != nil {
+		cfg.onUpdate()
+	}
+	return filePath, nil
+}
+
+// place finds img's file in the output directory and saves it there unless
+// an identical file already exists. It returns the file's path, or "" when
+// the capture was dropped or queued for a later save.
+func place(img *capture, logger pollLogger, cfg Config) (string, error) {
+	filename := img.hash + ".png"
+	dir := targetDir(cfg)
+	if cfg.DailyDirs {
+		if err := os.MkdirAll(dir, cfg.DirMode); err != nil {
+			return "", classify(ClassDisk, fmt.Errorf("create daily directory: %w", err))
+		}
+	}
+	if cfg.FilenameTemplate != nil {
+		existing, name, err := templateName(img, cfg)
+		if err != nil {
+			return "", classify(ClassDisk, fmt.Errorf("read index: %w", err))
+		}
+		if existing != "" {
+			cfg.Stats.RecordDedupHit()
+			return existing, nil
+		}
+		filename = name
+	}
+	filePath := filepath.Join(dir, filename)
+
+	// Only write if an identical file doesn't already exist (content-addressable dedup).
 	// We intentionally do NOT return early when the file exists because actions
 	// like Snipping Tool's Copy button or Undo button overwrite the clipboard
 	// with just CF_BITMAP, stripping our 3-format fingerprint (CF_BITMAP +
 	// CF_UNICODETEXT + CF_HDROP). The SHA256 match tells us the image is already
 	// saved locally, so we skip the write but still fall through to
-	// UpdateClipboard below to restore the useful text-path and file-drop formats.
-	if _, err := os.Stat(filePath); err != nil {
-		if err := os.WriteFile(filePath, pngData, 0644); err != nil { // #nosec G306 -- screenshots must be readable by Windows apps via WSL interop
-			return fmt.Errorf("write %s: %w", filename, err)
+	// the clipboard update in poll to restore the useful text-path and file-drop formats.
+	filePath, write, err := resolveSavePath(filePath, img, cfg.OnCollision)
+	if err != nil {
+		return "", classify(ClassDisk, fmt.Errorf("resolve name for %s: %w", filename, err))
+	}
+	if filePath == "" {
+		logger.Warn("File already exists with different content, capture skipped", "file", filename)
+		return "", nil
+	}
+	if write && cfg.onCapture != nil {
+		cfg.onCapture()
+	}
+	if write && cfg.throttle != nil {
+		w := pendingWrite{path: filePath, hash: img.hash, data: img.data}
+		if cfg.throttle.queued(w) {
+			cfg.seq.hold()
+			return "", nil // waiting for its turn to be saved
+		}
+		if !cfg.throttle.allow(cfg.Clock.Now()) {
+			// The clipboard is updated by a later poll, once the file exists.
+			cfg.throttle.enqueue(w, logger)
+			cfg.seq.hold()
+			return "", nil
+		}
+	}
+	if write {
+		if err := save(filePath, img, logger, cfg); errors.Is(err, errLowDiskSpace) {
+			logger.Warn("Screenshot not saved, low disk space", "file", filename)
+			return "", nil
+		} else if err != nil {
+			return "", classify(ClassDisk, err)
 		}
-		logger.Printf("New screenshot saved: %s (%d bytes)", filename, len(pngData))
+	} else {
+		cfg.Stats.RecordDedupHit()
 	}
+	return filePath, nil
+}
 
-	winPath, err := wslToWinPath(filePath)
+// templateName returns where the index says img is already saved, or, when
+// it is not, the name cfg.FilenameTemplate gives it.
+func templateName(img *capture, cfg Config) (existing, name string, err error) {
+	ix, err := store.ReadIndex(cfg.OutputDir)
 	if err != nil {
-		logger.Printf("Warning: wslpath failed, clipboard not updated: %v", err)
-		return nil // file saved, just can't update clipboard
+		return "", "", err
+	}
+	if path := ix.Lookup(img.hash); path != "" {
+		if same, _, err := compareFile(path, img); err == nil && same {
+			return path, "", nil
+		}
+		// Gone or replaced since: save the image again under a new name.
+	}
+	img.seq = ix.NextSeq()
+	return "", cfg.FilenameTemplate.Render(FilenameData{
+		Time:   cfg.Clock.Now().In(cfg.Location),
+		Seq:    img.seq,
+		Hash:   img.hash,
+		Window: img.window,
+	}), nil
+}
+
+// indexCapture records a newly saved capture in the output directory's
+// index. The caller holds the directory's write lock.
+func indexCapture(path string, img *capture, cfg Config) error {
+	seq := img.seq
+	if seq == 0 {
+		ix, err := store.ReadIndex(cfg.OutputDir)
+		if err != nil {
+			return err
+		}
+		seq = ix.NextSeq()
 	}
+	e := store.IndexEntry{Hash: img.hash, Time: cfg.Clock.Now(), Seq: seq, Size: img.size, Window: img.window, Title: img.title}
+	if content, err := img.open(); err == nil {
+		if dims, err := png.DecodeConfig(content); err == nil {
+			e.Width, e.Height = dims.Width, dims.Height
+		}
+		_ = content.Close()
+	}
+	return store.AppendIndex(cfg.OutputDir, path, e)
+}
+
+// markLatest points the output directory's latest.png at path, when
+// LatestLink asks for it. A failure (say, a filesystem without symlinks)
+// only costs the link.
+func markLatest(path string, logger pollLogger, cfg Config) {
+	if !cfg.LatestLink {
+		return
+	}
+	if err := store.UpdateLatest(cfg.OutputDir, path, cfg.LatestPathFile, cfg.FileMode); err != nil {
+		logger.Warn("Failed to update latest.png", "err", err)
+	}
+}
 
-	if err := client.UpdateClipboard(filePath, winPath); err != nil {
-		logger.Printf("Warning: clipboard update failed: %v", err)
-		return nil // file saved, just can't update clipboard
+// targetDir returns the directory a capture taken now is saved in.
+func targetDir(cfg Config) string {
+	if cfg.DailyDirs {
+		return filepath.Join(cfg.OutputDir, dayDir(cfg.Clock.Now(), cfg.Location))
 	}
+	return cfg.OutputDir
+}
 
-	logger.Printf("Clipboard updated (WSL: %s)", filePath)
+// save writes a new screenshot, or moves a streamed one into place, and
+// records it.
+func save(path string, img *capture, logger pollLogger, cfg Config) error {
+	need := img.size
+	if img.staged != nil {
+		need = 0 // already written to the staging dir, on the same filesystem
+	}
+	if err := checkDiskSpace(filepath.Dir(path), need, logger, cfg); err != nil {
+		return err
+	}
+	start := cfg.Clock.Now()
+	// Readers (stats, last) snapshot the output directory under the shared
+	// lock; holding it until the capture is counted keeps them consistent.
+	unlock := store.LockWrite(cfg.OutputDir)
+	defer unlock()
+	err := cfg.chaos.beforeWrite()
+	if err == nil {
+		// Staged, so a crash mid-write never leaves a truncated PNG that
+		// dedup would later take for the finished screenshot.
+		if img.staged != nil {
+			err = img.staged.Commit(path, cfg.FileMode)
+		} else {
+			err = store.WriteStaged(cfg.OutputDir, path, img.data, cfg.FileMode)
+		}
+	}
+	cfg.Stats.RecordStage(stats.StageSave, cfg.Clock.Now().Sub(start))
+	if err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	msg := fmt.Sprintf("New screenshot saved: %s (%d bytes)", filepath.Base(path), img.size)
+	attrs := []any{"file", filepath.Base(path), "bytes", img.size}
+	if img.source != "" {
+		attrs = append(attrs, "source", img.source)
+	}
+	logger.Info("New screenshot saved", attrs...)
+	if err := indexCapture(path, img, cfg); err != nil {
+		logger.Warn("Failed to record the screenshot in the index", "err", err)
+	}
+	cfg.Stats.RecordCapture(cfg.Clock.Now(), int(img.size))
+	_ = cfg.Events.Append(events.Event{Time: cfg.Clock.Now(), Type: events.TypeCapture, Message: msg, Path: path})
 	return nil
 }
 
-// hashBytes returns the lowercase hex SHA256 of data.
-func hashBytes(data []byte) string {
-	h := sha256.Sum256(data)
-	return fmt.Sprintf("%x", h)
+// saveQueued writes a capture the throttle held back. A failure only loses
+// that capture; the next poll of the same image queues it again.
+func saveQueued(w pendingWrite, logger pollLogger, cfg Config) {
+	if err := save(w.path, &capture{hash: w.hash, data: w.data, size: int64(len(w.data))}, logger, cfg); err != nil {
+		logger.Warn("Queued save failed", "err", err)
+	}
+}
+
+// keptImage is the name hash of the last image NoClipboardUpdate left on the
+// clipboard. Clients that can't tell when the clipboard changed return it on
+// every tick until something else is copied; it is skipped rather than
+// counted as a dedup hit each time.
+type keptImage struct {
+	hash string
+}
+
+// dayDir returns the daily subdirectory name for t in loc. The name is derived
+// from the calendar date rather than from truncating t to 24h multiples, so a
+// DST transition (23h or 25h day) never splits one day across two folders.
+func dayDir(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
 }
 
 // wslToWinPath converts a WSL path to a Windows path using wslpath -w.
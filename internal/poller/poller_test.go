@@ -1,18 +1,27 @@
 package poller
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
-	"io"
-	"log"
+	"image"
+	"image/png"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/events"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+	"github.com/nailuu/wsl-screenshot-cli/internal/store"
 )
 
 // mockClipboard implements the Clipboard interface for testing.
@@ -46,8 +55,17 @@ func (m *mockClipboard) Close() error {
 	return nil
 }
 
-func testLogger() *log.Logger {
-	return log.New(io.Discard, "", 0)
+// outputEntries lists dir like os.ReadDir, minus the staging directory and
+// the capture index.
+func outputEntries(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	return slices.DeleteFunc(entries, func(e os.DirEntry) bool {
+		return e.Name() == store.StagingDirName || e.Name() == store.IndexFile
+	}), err
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
 }
 
 // overrideWslPath replaces wslToWinPath for the duration of a test.
@@ -90,7 +108,7 @@ func TestPoll_NoImage(t *testing.T) {
 	overrideWslPath(t, fakeWslPath)
 	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return nil, nil }}
 
-	err := poll(mock, testLogger(), t.TempDir())
+	err := poll(mock, testLogger(), Config{OutputDir: t.TempDir()})
 	if err != nil {
 		t.Fatalf("poll() returned error: %v", err)
 	}
@@ -111,7 +129,7 @@ func TestPoll_NewScreenshot(t *testing.T) {
 		},
 	}
 
-	err := poll(mock, testLogger(), dir)
+	err := poll(mock, testLogger(), Config{OutputDir: dir})
 	if err != nil {
 		t.Fatalf("poll() returned error: %v", err)
 	}
@@ -134,6 +152,30 @@ func TestPoll_NewScreenshot(t *testing.T) {
 	}
 }
 
+func TestPoll_RecordsCapture(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	imgData := []byte("counted")
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return imgData, nil }}
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+	cfg := Config{OutputDir: t.TempDir(), Clock: clk, Stats: counters}
+
+	// The second poll is a dedup hit and must not count as a new capture.
+	for i := 0; i < 2; i++ {
+		if err := poll(mock, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() returned error: %v", err)
+		}
+	}
+
+	snap := counters.Snapshot()
+	if snap.Captures != 1 {
+		t.Errorf("Captures = %d, want 1", snap.Captures)
+	}
+	if !snap.LastCapture.Equal(testEpoch) {
+		t.Errorf("LastCapture = %v, want %v", snap.LastCapture, testEpoch)
+	}
+}
+
 func TestPoll_Dedup(t *testing.T) {
 	overrideWslPath(t, fakeWslPath)
 	dir := t.TempDir()
@@ -148,16 +190,48 @@ func TestPoll_Dedup(t *testing.T) {
 		},
 	}
 
-	if err := poll(mock, testLogger(), dir); err != nil {
+	counters := &stats.Counters{}
+	if err := poll(mock, testLogger(), Config{OutputDir: dir, Stats: counters}); err != nil {
 		t.Fatalf("first poll: %v", err)
 	}
-	if err := poll(mock, testLogger(), dir); err != nil {
+	if err := poll(mock, testLogger(), Config{OutputDir: dir, Stats: counters}); err != nil {
 		t.Fatalf("second poll: %v", err)
 	}
 
 	if updateCount != 2 {
 		t.Errorf("UpdateClipboard called %d times, want 2 (always restore clipboard formats)", updateCount)
 	}
+	if s := counters.Snapshot(); s.Captures != 1 || s.DedupHits != 1 {
+		t.Errorf("Captures/DedupHits = %d/%d, want 1/1", s.Captures, s.DedupHits)
+	}
+}
+
+func TestPoll_NoClipboardUpdate(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	imgData := []byte("archived-image")
+	mock := &mockClipboard{
+		checkFunc: func() ([]byte, error) { return imgData, nil },
+		updateFunc: func(wsl, win string) error {
+			t.Error("UpdateClipboard called with NoClipboardUpdate")
+			return nil
+		},
+	}
+
+	counters := &stats.Counters{}
+	cfg := Config{OutputDir: dir, Stats: counters, NoClipboardUpdate: true, kept: &keptImage{}}
+	for range 3 {
+		if err := poll(mock, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, hashBytes(imgData)+".png")); err != nil {
+		t.Errorf("screenshot not saved: %v", err)
+	}
+	// The image stays on the clipboard: later polls leave it alone.
+	if s := counters.Snapshot(); s.Captures != 1 || s.DedupHits != 0 {
+		t.Errorf("Captures/DedupHits = %d/%d, want 1/0", s.Captures, s.DedupHits)
+	}
 }
 
 func TestPoll_CheckError(t *testing.T) {
@@ -165,7 +239,7 @@ func TestPoll_CheckError(t *testing.T) {
 	checkErr := errors.New("powershell died")
 	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return nil, checkErr }}
 
-	err := poll(mock, testLogger(), t.TempDir())
+	err := poll(mock, testLogger(), Config{OutputDir: t.TempDir()})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -184,7 +258,7 @@ func TestPoll_WslPathFailure(t *testing.T) {
 		checkFunc: func() ([]byte, error) { return imgData, nil },
 	}
 
-	err := poll(mock, testLogger(), dir)
+	err := poll(mock, testLogger(), Config{OutputDir: dir})
 	if err != nil {
 		t.Fatalf("poll should not return error on wslpath failure: %v", err)
 	}
@@ -205,7 +279,7 @@ func TestPoll_UpdateFailure(t *testing.T) {
 		updateFunc: func(wsl, win string) error { return errors.New("update failed") },
 	}
 
-	err := poll(mock, testLogger(), dir)
+	err := poll(mock, testLogger(), Config{OutputDir: dir})
 	if err != nil {
 		t.Fatalf("poll should not return error on update failure: %v", err)
 	}
@@ -216,32 +290,289 @@ func TestPoll_UpdateFailure(t *testing.T) {
 	}
 }
 
-// --- Run tests ---
+// --- date-based organization tests ---
 
-func TestRun_ShutdownCallsClose(t *testing.T) {
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data for %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestDayDir(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{"utc_midnight", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.UTC, "2024-05-01"},
+		{"utc_converted_to_local_day_before", time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC), ny, "2024-04-30"},
+		{"local_converted_to_utc_day_after", time.Date(2024, 4, 30, 22, 0, 0, 0, ny), time.UTC, "2024-05-01"},
+		// 2024-03-10 is a 23h day in New York (clocks jump 02:00 -> 03:00).
+		{"spring_forward_before", time.Date(2024, 3, 10, 1, 59, 0, 0, ny), ny, "2024-03-10"},
+		{"spring_forward_after", time.Date(2024, 3, 10, 23, 59, 0, 0, ny), ny, "2024-03-10"},
+		// 2024-11-03 is a 25h day in New York (01:00-02:00 happens twice).
+		{"fall_back_first_0130", time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), ny, "2024-11-03"},
+		{"fall_back_second_0130", time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC), ny, "2024-11-03"},
+		{"fall_back_last_minute", time.Date(2024, 11, 3, 23, 59, 0, 0, ny), ny, "2024-11-03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dayDir(tt.t, tt.loc); got != tt.want {
+				t.Errorf("dayDir(%v, %v) = %q, want %q", tt.t, tt.loc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoll_DailyDirs(t *testing.T) {
 	overrideWslPath(t, fakeWslPath)
-	mock := &mockClipboard{}
+	dir := t.TempDir()
+	imgData := []byte("daily-image")
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return imgData, nil }}
+
+	cfg := Config{
+		OutputDir: dir,
+		DailyDirs: true,
+		Location:  time.UTC,
+		Clock:     clock.NewFake(time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)),
+	}
+	if err := poll(mock, testLogger(), cfg); err != nil {
+		t.Fatalf("poll() returned error: %v", err)
+	}
+
+	want := filepath.Join(dir, "2024-05-01", hashBytes(imgData)+".png")
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("expected screenshot at %s: %v", want, err)
+	}
+}
+
+func TestPoll_FileModes(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	for _, tt := range []struct {
+		fileMode, dirMode     os.FileMode
+		wantFile, wantDirMode os.FileMode
+	}{
+		{0, 0, 0600, 0700},
+		{0640, 0750, 0640, 0750},
+	} {
+		dir := t.TempDir()
+		imgData := []byte(fmt.Sprintf("image-%o", tt.fileMode))
+		mock := &mockClipboard{checkFunc: func() ([]byte, error) { return imgData, nil }}
+		cfg := Config{OutputDir: dir, DailyDirs: true, Location: time.UTC, Clock: clk, FileMode: tt.fileMode, DirMode: tt.dirMode}
+		if err := poll(mock, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+		day := filepath.Join(dir, "2024-05-01")
+		file, err := os.Stat(filepath.Join(day, hashBytes(imgData)+".png"))
+		if err != nil {
+			t.Fatalf("screenshot not saved: %v", err)
+		}
+		if got := file.Mode().Perm(); got != tt.wantFile {
+			t.Errorf("FileMode %o: screenshot mode = %o, want %o", tt.fileMode, got, tt.wantFile)
+		}
+		if info, err := os.Stat(day); err != nil || info.Mode().Perm() != tt.wantDirMode {
+			t.Errorf("DirMode %o: daily dir mode wrong (%v), want %o", tt.dirMode, err, tt.wantDirMode)
+		}
+	}
+}
+
+func TestPoll_LatestLink(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	images := [][]byte{[]byte("first"), []byte("second")}
+	for _, data := range images {
+		mock := &mockClipboard{checkFunc: func() ([]byte, error) { return data, nil }}
+		if err := poll(mock, testLogger(), Config{OutputDir: dir, LatestLink: true}); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, store.LatestLink))
+		if err != nil || string(got) != string(data) {
+			t.Errorf("latest.png reads %q, %v; want %q", got, err, data)
+		}
+	}
+	if entries, _ := outputEntries(dir); len(entries) != 3 {
+		t.Errorf("output dir has %d entries, want two screenshots and the link", len(entries))
+	}
+}
+
+func TestPoll_IndexesCaptures(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	imgData := testPNG(t)
+	captured := time.Date(2024, 5, 1, 14, 32, 7, 0, time.UTC)
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return imgData, nil }}
+	cfg := Config{OutputDir: dir, Clock: clock.NewFake(captured)}
+	for range 2 { // the second poll is a dedup hit, not a new entry
+		if err := poll(mock, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() error: %v", err)
+		}
+	}
+
+	ix, err := store.ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := ix.Entries()
+	want := store.IndexEntry{
+		Hash: hashBytes(imgData), Name: hashBytes(imgData) + ".png", Time: captured, Seq: 1,
+		Size: int64(len(imgData)), Width: 2, Height: 2,
+	}
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("index = %+v, want [%+v]", entries, want)
+	}
+}
 
+// foregroundClipboard reports a fixed foreground window with each image.
+type foregroundClipboard struct {
+	auditClipboard
+}
+
+func (f *foregroundClipboard) Foreground() (string, string) {
+	return "chrome", "Pull requests · GitHub - Google Chrome"
+}
+
+func TestPoll_RecordsForegroundWindow(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	imgData := testPNG(t)
+	client := &foregroundClipboard{}
+	client.checkFunc = func() ([]byte, error) { return imgData, nil }
+	tmpl, err := ParseFilenameTemplate("{window}-{seq}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{OutputDir: dir, FilenameTemplate: tmpl, Clock: clock.NewFake(testEpoch)}
+
+	path, err := pollPath(client, testLogger(), cfg)
+	if err != nil {
+		t.Fatalf("pollPath() error: %v", err)
+	}
+	// The foreground window wins over the clipboard owner (Ditto).
+	if want := filepath.Join(dir, "chrome-0001.png"); path != want {
+		t.Errorf("saved to %s, want %s", path, want)
+	}
+	ix, err := store.ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := ix.Find(path)
+	if !ok || e.Window != "chrome" || e.Title != "Pull requests · GitHub - Google Chrome" {
+		t.Errorf("index entry = %+v, want the foreground window", e)
+	}
+}
+
+func TestPoll_DailyDirsNoDuplicateOnFallBack(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	ny := mustLoadLocation(t, "America/New_York")
+	dir := t.TempDir()
+
+	// Two captures during the repeated 01:30 hour of the fall-back transition.
+	for i, instant := range []time.Time{
+		time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+		time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC), // 01:30 EST
+	} {
+		img := []byte(fmt.Sprintf("capture-%d", i))
+		mock := &mockClipboard{checkFunc: func() ([]byte, error) { return img, nil }}
+		cfg := Config{OutputDir: dir, DailyDirs: true, Location: ny, Clock: clock.NewFake(instant)}
+		if err := poll(mock, testLogger(), cfg); err != nil {
+			t.Fatalf("poll() returned error: %v", err)
+		}
+	}
+
+	entries, err := outputEntries(dir)
+	if err != nil {
+		t.Fatalf("read output dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "2024-11-03" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("expected a single 2024-11-03 folder, got %v", names)
+	}
+	files, _ := os.ReadDir(filepath.Join(dir, "2024-11-03"))
+	if len(files) != 2 {
+		t.Errorf("expected 2 screenshots in daily folder, got %d", len(files))
+	}
+}
+
+// --- Run tests ---
+
+const testInterval = 100 * time.Millisecond
+
+var testEpoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// startRun launches Run on a fake clock and waits until its ticker is
+// registered. The returned function cancels the context and waits for Run
+// to return.
+func startRun(t *testing.T, clk *clock.Fake, cfg Config, factory ClientFactory) (stop func() error) {
+	t.Helper()
 	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan error, 1)
 
+	cfg.Interval = testInterval
+	cfg.Clock = clk
 	go func() {
-		done <- Run(ctx, testLogger(), 100, t.TempDir(), func() (Clipboard, error) {
-			return mock, nil
-		})
+		done <- Run(ctx, testLogger(), cfg, factory)
 	}()
 
-	// Let it run a tick or two
-	time.Sleep(250 * time.Millisecond)
-	cancel()
-
+	registered := make(chan struct{})
+	go func() {
+		clk.BlockUntil(1)
+		close(registered)
+	}()
 	select {
+	case <-registered:
 	case err := <-done:
-		if err != nil {
-			t.Fatalf("Run returned error: %v", err)
+		cancel()
+		t.Fatalf("Run exited before starting its ticker: %v", err)
+	case <-time.After(5 * time.Second):
+		cancel()
+		t.Fatal("Run never started its ticker")
+	}
+
+	return func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not exit after context cancel")
+			return nil
 		}
+	}
+}
+
+// tick advances the fake clock by one interval and waits for the resulting
+// poll to signal on polled.
+func tick(t *testing.T, clk *clock.Fake, polled <-chan struct{}) {
+	t.Helper()
+	clk.Advance(testInterval)
+	select {
+	case <-polled:
 	case <-time.After(5 * time.Second):
-		t.Fatal("Run did not exit after context cancel")
+		t.Fatal("tick did not trigger a poll")
+	}
+}
+
+func TestRun_ShutdownCallsClose(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	mock := &mockClipboard{}
+	clk := clock.NewFake(testEpoch)
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir()}, func() (Clipboard, error) {
+		return mock, nil
+	})
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
 	}
 
 	if !mock.closeCalled.Load() {
@@ -251,54 +582,82 @@ func TestRun_ShutdownCallsClose(t *testing.T) {
 
 func TestRun_CircuitBreakerRestart(t *testing.T) {
 	overrideWslPath(t, fakeWslPath)
-	factoryCalls := 0
 	checkErr := errors.New("persistent error")
+	clk := clock.NewFake(testEpoch)
 
-	var mu sync.Mutex
-	var activeMock *mockClipboard
-
+	polled := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
 	factory := func() (Clipboard, error) {
-		mu.Lock()
-		defer mu.Unlock()
-		factoryCalls++
-		m := &mockClipboard{
-			checkFunc: func() ([]byte, error) { return nil, checkErr },
-		}
-		activeMock = m
-		return m, nil
+		factoryCalls.Add(1)
+		return &mockClipboard{
+			checkFunc: func() ([]byte, error) {
+				polled <- struct{}{}
+				return nil, checkErr
+			},
+		}, nil
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	done := make(chan error, 1)
+	counters := &stats.Counters{}
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters}, factory)
 
-	go func() {
-		done <- Run(ctx, testLogger(), 100, t.TempDir(), factory)
-	}()
+	for i := 0; i < defaultMaxConsecutiveErrors; i++ {
+		tick(t, clk, polled)
+	}
+	// The restart happens synchronously after the last failing poll, so one
+	// more tick guarantees it completed before we inspect the counter.
+	tick(t, clk, polled)
 
-	// Wait for circuit breaker to trigger (5 errors * 100ms interval + margin)
-	time.Sleep(800 * time.Millisecond)
-	cancel()
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := factoryCalls.Load(); calls != 2 {
+		t.Errorf("factory called %d times, want 2 (circuit breaker should restart once)", calls)
+	}
+	if s := counters.Snapshot(); s.Restarts != 1 || s.Polls != int64(defaultMaxConsecutiveErrors)+1 {
+		t.Errorf("Restarts/Polls = %d/%d, want 1/%d", s.Restarts, s.Polls, defaultMaxConsecutiveErrors+1)
+	}
+}
 
-	select {
-	case <-done:
-	case <-time.After(5 * time.Second):
-		t.Fatal("Run did not exit")
+func TestRun_CircuitBreakerResetsOnSuccess(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+
+	polled := make(chan struct{}, 1)
+	var checks atomic.Int32
+	var factoryCalls atomic.Int32
+	factory := func() (Clipboard, error) {
+		factoryCalls.Add(1)
+		return &mockClipboard{
+			checkFunc: func() ([]byte, error) {
+				defer func() { polled <- struct{}{} }()
+				// Every fifth check succeeds, so the breaker never reaches its threshold.
+				if checks.Add(1)%defaultMaxConsecutiveErrors == 0 {
+					return nil, nil
+				}
+				return nil, errors.New("transient")
+			},
+		}, nil
 	}
 
-	mu.Lock()
-	calls := factoryCalls
-	mu.Unlock()
-	if calls < 2 {
-		t.Errorf("factory called %d times, want >= 2 (circuit breaker should restart)", calls)
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir()}, factory)
+	for i := 0; i < 3*defaultMaxConsecutiveErrors; i++ {
+		tick(t, clk, polled)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
 	}
 
-	_ = activeMock // just verify it was assigned
+	if calls := factoryCalls.Load(); calls != 1 {
+		t.Errorf("factory called %d times, want 1 (successes should reset the breaker)", calls)
+	}
 }
 
 func TestRun_ShutdownClosesLatestClient(t *testing.T) {
 	overrideWslPath(t, fakeWslPath)
 	checkErr := errors.New("persistent error")
+	clk := clock.NewFake(testEpoch)
 
+	polled := make(chan struct{}, 1)
 	var clients []*mockClipboard
 	var mu sync.Mutex
 
@@ -306,27 +665,21 @@ func TestRun_ShutdownClosesLatestClient(t *testing.T) {
 		mu.Lock()
 		defer mu.Unlock()
 		m := &mockClipboard{
-			checkFunc: func() ([]byte, error) { return nil, checkErr },
+			checkFunc: func() ([]byte, error) {
+				polled <- struct{}{}
+				return nil, checkErr
+			},
 		}
 		clients = append(clients, m)
 		return m, nil
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	done := make(chan error, 1)
-
-	go func() {
-		done <- Run(ctx, testLogger(), 100, t.TempDir(), factory)
-	}()
-
-	// Wait for at least one circuit breaker restart
-	time.Sleep(800 * time.Millisecond)
-	cancel()
-
-	select {
-	case <-done:
-	case <-time.After(5 * time.Second):
-		t.Fatal("Run did not exit")
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir()}, factory)
+	for i := 0; i <= defaultMaxConsecutiveErrors; i++ {
+		tick(t, clk, polled)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
 	}
 
 	mu.Lock()
@@ -341,6 +694,10 @@ func TestRun_ShutdownClosesLatestClient(t *testing.T) {
 	if !last.closeCalled.Load() {
 		t.Error("Close() was not called on the latest client after shutdown")
 	}
+	// Earlier clients are closed by the circuit breaker before replacement.
+	if !clients[0].closeCalled.Load() {
+		t.Error("Close() was not called on the replaced client")
+	}
 }
 
 // --- Integration test ---
@@ -348,44 +705,425 @@ func TestRun_ShutdownClosesLatestClient(t *testing.T) {
 func TestIntegration_SignalCausesCloseAndExit(t *testing.T) {
 	overrideWslPath(t, fakeWslPath)
 	dir := t.TempDir()
+	clk := clock.NewFake(testEpoch)
 
+	polled := make(chan struct{}, 1)
 	var pollCount atomic.Int32
 	mock := &mockClipboard{
 		checkFunc: func() ([]byte, error) {
 			pollCount.Add(1)
+			polled <- struct{}{}
 			return nil, nil // no image
 		},
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	done := make(chan error, 1)
-
-	go func() {
-		done <- Run(ctx, testLogger(), 100, dir, func() (Clipboard, error) {
-			return mock, nil
-		})
-	}()
+	stop := startRun(t, clk, Config{OutputDir: dir}, func() (Clipboard, error) {
+		return mock, nil
+	})
 
 	// Let it tick a few times
-	time.Sleep(350 * time.Millisecond)
-
-	if pollCount.Load() < 2 {
-		t.Errorf("expected at least 2 polls, got %d", pollCount.Load())
+	for i := 0; i < 3; i++ {
+		tick(t, clk, polled)
+	}
+	if got := pollCount.Load(); got != 3 {
+		t.Errorf("expected 3 polls, got %d", got)
 	}
 
 	// Simulate SIGINT/SIGTERM by cancelling context
-	cancel()
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if !mock.closeCalled.Load() {
+		t.Error("Close() was not called on the active client after signal")
+	}
+}
+
+// seqClipboard is a mockClipboard that also reports a sequence number.
+type seqClipboard struct {
+	mockClipboard
+	seq       atomic.Uint32
+	sequenced chan struct{}
+}
+
+func (s *seqClipboard) Sequence() (uint32, error) {
+	defer func() { s.sequenced <- struct{}{} }()
+	return s.seq.Load(), nil
+}
+
+func TestRun_SeqCheckSkipsUnchangedClipboard(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	var checks atomic.Int32
+	mock := &seqClipboard{sequenced: make(chan struct{}, 1)}
+	mock.checkFunc = func() ([]byte, error) {
+		checks.Add(1)
+		return nil, nil
+	}
+	mock.seq.Store(7)
+	clk := clock.NewFake(testEpoch)
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), SeqCheck: true}, func() (Clipboard, error) {
+		return mock, nil
+	})
+
+	tick(t, clk, mock.sequenced) // first sequence seen: full check
+	tick(t, clk, mock.sequenced) // unchanged: skipped
+	tick(t, clk, mock.sequenced) // unchanged: skipped
+	if got := checks.Load(); got != 1 {
+		t.Errorf("checks with unchanged sequence = %d, want 1", got)
+	}
+
+	mock.seq.Store(8)
+	tick(t, clk, mock.sequenced) // changed: full check
+	tick(t, clk, mock.sequenced) // waits for the previous check to finish
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := checks.Load(); got != 2 {
+		t.Errorf("checks after sequence change = %d, want 2", got)
+	}
+}
+
+// memClipboard is a mockClipboard whose backend reports a fixed memory usage.
+type memClipboard struct {
+	mockClipboard
+	mem int64
+}
+
+func (m *memClipboard) BackendMemory() (int64, error) { return m.mem, nil }
 
+func TestRun_RestartsClientOverMemoryLimit(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+
+	polled := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
+	factory := func() (Clipboard, error) {
+		mem := int64(50 << 20)
+		if factoryCalls.Add(1) == 1 {
+			mem = 200 << 20 // first client has grown past the limit
+		}
+		c := &memClipboard{mem: mem}
+		c.checkFunc = func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, nil
+		}
+		return c, nil
+	}
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters, MaxBackendMemory: 100 << 20}, factory)
+
+	tick(t, clk, polled) // before the first sample: no memory check
+	clk.Advance(memoryCheckInterval)
 	select {
-	case err := <-done:
-		if err != nil {
-			t.Fatalf("Run returned error: %v", err)
+	case <-polled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no poll after the memory check interval")
+	}
+	tick(t, clk, polled)
+
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := factoryCalls.Load(); calls != 2 {
+		t.Errorf("factory called %d times, want 2 (client over the memory limit should restart once)", calls)
+	}
+	if got := counters.Snapshot().BackendMemoryKB; got != 200<<10 {
+		t.Errorf("BackendMemoryKB = %d, want %d", got, 200<<10)
+	}
+}
+
+// pingClipboard is a mockClipboard whose backend answers PING with pingFunc.
+type pingClipboard struct {
+	mockClipboard
+	pingFunc func() (time.Duration, error)
+}
+
+func (p *pingClipboard) Ping() (time.Duration, error) { return p.pingFunc() }
+
+func TestRun_RestartsClientFailingPing(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+
+	polled := make(chan struct{}, 1)
+	pinged := make(chan struct{}, 1)
+	var factoryCalls atomic.Int32
+	factory := func() (Clipboard, error) {
+		first := factoryCalls.Add(1) == 1
+		c := &pingClipboard{pingFunc: func() (time.Duration, error) {
+			pinged <- struct{}{}
+			if first {
+				return 0, errors.New("PING: powershell did not respond in time after 10s")
+			}
+			return 3 * time.Millisecond, nil
+		}}
+		c.checkFunc = func() ([]byte, error) {
+			polled <- struct{}{}
+			return nil, nil
 		}
+		return c, nil
+	}
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters}, factory)
+
+	tick(t, clk, polled) // before the first ping
+	clk.Advance(pingInterval)
+	select {
+	case <-pinged:
 	case <-time.After(5 * time.Second):
-		t.Fatal("Run did not exit within 5 seconds of context cancel")
+		t.Fatal("no ping after the ping interval")
 	}
+	tick(t, clk, polled) // the replacement client polls as usual
 
-	if !mock.closeCalled.Load() {
-		t.Error("Close() was not called on the active client after signal")
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := factoryCalls.Load(); calls != 2 {
+		t.Errorf("factory called %d times, want 2 (a client failing PING is replaced)", calls)
+	}
+	if s := counters.Snapshot(); s.LastPingOK || s.Restarts != 1 {
+		t.Errorf("LastPingOK = %v, Restarts = %d; want a failed ping and 1 restart", s.LastPingOK, s.Restarts)
+	}
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// textOnlyClipboard is a mockClipboard whose UPDATE can only set the text.
+type textOnlyClipboard struct{ mockClipboard }
+
+func (*textOnlyClipboard) UpdatableFormats() []string { return []string{"text"} }
+
+func TestPoll_TextOnlyClient(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	var updated string
+	mock := &textOnlyClipboard{}
+	mock.checkFunc = func() ([]byte, error) { return testPNG(t), nil }
+	mock.updateFunc = func(wsl, win string) error {
+		updated = wsl
+		return nil
+	}
+
+	if err := poll(mock, testLogger(), Config{OutputDir: t.TempDir()}); err != nil {
+		t.Fatalf("poll() returned error: %v", err)
+	}
+	if updated == "" {
+		t.Error("the path text was not set")
+	}
+	if got := missingFormats(mock); !slices.Equal(got, []string{"image", "filedrop"}) {
+		t.Errorf("missingFormats() = %v, want [image filedrop]", got)
+	}
+}
+
+// partialErr mimics the client's error for an update where some formats failed.
+type partialErr struct{ failed []string }
+
+func (e partialErr) Error() string {
+	return "clipboard partially updated, failed: " + strings.Join(e.failed, ", ")
+}
+func (e partialErr) FailedFormats() []string { return e.failed }
+
+func TestPoll_PartialUpdateCountsAsUpdated(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	imgData := testPNG(t)
+
+	for _, failed := range [][]string{{"filedrop"}, {"image"}} {
+		t.Run(strings.Join(failed, ","), func(t *testing.T) {
+			updated := false
+			mock := &mockClipboard{checkFunc: func() ([]byte, error) { return imgData, nil }}
+			mock.updateFunc = func(wsl, win string) error { return partialErr{failed} }
+			var logBuf bytes.Buffer
+			cfg := Config{OutputDir: t.TempDir(), onUpdate: func() { updated = true }}
+
+			if err := poll(mock, slog.New(slog.NewTextHandler(&logBuf, nil)), cfg); err != nil {
+				t.Fatalf("poll() returned error: %v", err)
+			}
+			if !updated {
+				t.Error("partial update should still count as a clipboard update")
+			}
+			if want := "partially updated, failed: " + failed[0]; !strings.Contains(logBuf.String(), want) {
+				t.Errorf("log should report the partial update:\n%s", logBuf.String())
+			}
+		})
+	}
+}
+
+// sourceClipboard reports the clipboard format its images come from.
+type sourceClipboard struct {
+	mockClipboard
+	source string
+}
+
+func (m *sourceClipboard) Source() string { return m.source }
+
+func TestPoll_LogsImageSource(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	imgData := testPNG(t)
+	mock := &sourceClipboard{source: "PNG"}
+	mock.checkFunc = func() ([]byte, error) { return imgData, nil }
+
+	var logBuf bytes.Buffer
+	if err := poll(mock, slog.New(slog.NewTextHandler(&logBuf, nil)), Config{OutputDir: t.TempDir()}); err != nil {
+		t.Fatalf("poll() returned error: %v", err)
+	}
+	if !strings.Contains(logBuf.String(), "source=PNG") {
+		t.Errorf("capture log should name the source format:\n%s", logBuf.String())
+	}
+}
+
+func TestRun_ReloadSwitchesOutputDir(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	polled := make(chan struct{}, 1)
+	imgData := []byte("after-reload")
+	mock := &mockClipboard{
+		checkFunc: func() ([]byte, error) { return imgData, nil },
+		updateFunc: func(wsl, win string) error {
+			polled <- struct{}{}
+			return nil
+		},
+	}
+	clk := clock.NewFake(testEpoch)
+	reload := make(chan Settings)
+	newDir := t.TempDir()
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Reload: reload}, func() (Clipboard, error) {
+		return mock, nil
+	})
+	reload <- Settings{Interval: testInterval, OutputDir: newDir}
+	tick(t, clk, polled)
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(newDir, hashBytes(imgData)+".png")); err != nil {
+		t.Errorf("capture was not saved in the reloaded output dir: %v", err)
+	}
+}
+
+func TestRun_CopiesSavedScreenshot(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	var text string
+	mock := &mockClipboard{updateFunc: func(wsl, win string) error {
+		text = wsl
+		return nil
+	}}
+	copies := make(chan CopyRequest)
+	path := filepath.Join(t.TempDir(), "old.png")
+
+	stop := startRun(t, clock.NewFake(testEpoch), Config{OutputDir: t.TempDir(), Copies: copies}, func() (Clipboard, error) {
+		return mock, nil
+	})
+	done := make(chan error, 1)
+	copies <- CopyRequest{Path: path, Done: done}
+	if err := <-done; err != nil {
+		t.Errorf("copy error: %v", err)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if text != path {
+		t.Errorf("clipboard text = %q, want %q", text, path)
+	}
+}
+
+func TestPoll_JournalsCapture(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	journal := filepath.Join(t.TempDir(), "events")
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return []byte("img"), nil }}
+
+	if err := poll(mock, testLogger(), Config{OutputDir: dir, Events: events.Open(journal)}); err != nil {
+		t.Fatal(err)
+	}
+	evs, err := events.Read(journal, events.Filter{})
+	if err != nil || len(evs) != 1 || evs[0].Type != events.TypeCapture || evs[0].Path != filepath.Join(dir, hashBytes([]byte("img"))+".png") {
+		t.Errorf("journal = %+v, %v; want one capture event with the saved path", evs, err)
+	}
+}
+
+func TestPoll_PrivateNames(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	img := []byte("secret")
+	mock := &mockClipboard{checkFunc: func() ([]byte, error) { return img, nil }}
+
+	key := []byte("0123456789abcdef")
+	if err := poll(mock, testLogger(), Config{OutputDir: dir, NameKey: key}); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	entries, _ := outputEntries(dir)
+	if len(entries) != 1 {
+		t.Fatalf("got %d files, want 1", len(entries))
+	}
+	name := entries[0].Name()
+	if name == hashBytes(img)+".png" {
+		t.Error("a keyed name should not be the plain content hash")
+	}
+	if name != nameHash(img, key)+".png" {
+		t.Errorf("name = %s, want the HMAC of the content", name)
+	}
+}
+
+// errLocked is what a client's check returns while the session is locked.
+type errLocked struct{}
+
+func (errLocked) Error() string       { return "Windows session is locked" }
+func (errLocked) SessionLocked() bool { return true }
+
+// lockClipboard is a mockClipboard for a Windows session that can be locked.
+type lockClipboard struct {
+	mockClipboard
+	locked atomic.Bool
+	probed chan struct{}
+}
+
+func (l *lockClipboard) SessionLocked() (bool, error) {
+	defer func() { l.probed <- struct{}{} }()
+	return l.locked.Load(), nil
+}
+
+func TestRun_PausesWhileSessionLocked(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	counters := &stats.Counters{}
+	polled := make(chan struct{}, 1)
+	var checks atomic.Int32
+	mock := &lockClipboard{probed: make(chan struct{}, 1)}
+	mock.locked.Store(true)
+	mock.checkFunc = func() ([]byte, error) {
+		checks.Add(1)
+		defer func() { polled <- struct{}{} }()
+		if mock.locked.Load() {
+			return nil, errLocked{}
+		}
+		return nil, nil
+	}
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Stats: counters}, func() (Clipboard, error) {
+		return mock, nil
+	})
+	tick(t, clk, polled)      // finds the session locked
+	tick(t, clk, mock.probed) // still locked: no check
+	tick(t, clk, mock.probed)
+	if s := counters.Snapshot(); !s.SessionLocked || s.Errors != 0 || checks.Load() != 1 {
+		t.Errorf("while locked: %d checks, stats %+v; want 1 check, no errors, SessionLocked", checks.Load(), s)
+	}
+
+	mock.locked.Store(false)
+	tick(t, clk, mock.probed) // unlocked: polls again
+	<-polled
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if s := counters.Snapshot(); s.SessionLocked || s.Errors != 0 || checks.Load() != 2 {
+		t.Errorf("after unlock: %d checks, stats %+v; want 2 checks, no errors", checks.Load(), s)
 	}
 }
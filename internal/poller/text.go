@@ -0,0 +1,78 @@
+package poller
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TextMode picks which path clipboard updates set as text when there is no
+// Config.TextTemplate.
+type TextMode string
+
+const (
+	TextWSL     TextMode = "wsl"     // the WSL path, for pasting in WSL terminals
+	TextWindows TextMode = "windows" // the Windows path, for Windows applications
+	TextBoth    TextMode = "both"    // the WSL path, then the Windows path on the next line
+)
+
+// ParseTextMode validates a text mode name from the command line.
+func ParseTextMode(s string) (TextMode, error) {
+	switch m := TextMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case TextWSL, TextWindows, TextBoth:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown text mode %q (expected wsl, windows or both)", s)
+	}
+}
+
+// TextData is what a Config.TextTemplate is executed with.
+type TextData struct {
+	WSLPath string // the saved screenshot, e.g. /home/me/.wsl-screenshots/<hash>.png
+	WinPath string // the same file as Windows sees it, e.g. \\wsl.localhost\...
+	Name    string // the file name alone
+}
+
+// ParseTextTemplate parses a Config.TextTemplate. The template is also
+// executed once against sample data, so that a reference to an unknown
+// field fails here rather than on every capture.
+func ParseTextTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("text").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := TextData{WSLPath: "/tmp/x.png", WinPath: `C:\x.png`, Name: "x.png"}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// clipboardText returns the text a clipboard update puts on the clipboard:
+// the path or paths cfg.TextMode picks, or cfg.TextTemplate rendered for the
+// file. A template that fails or renders nothing is logged and the plain
+// path used instead, so a capture never leaves the clipboard without text.
+func clipboardText(cfg Config, logger pollLogger, wslPath, winPath string) string {
+	if cfg.TextTemplate == nil {
+		switch cfg.TextMode {
+		case TextWindows:
+			return winPath
+		case TextBoth:
+			return wslPath + "\r\n" + winPath // CRLF, as Windows applications expect
+		default:
+			return wslPath
+		}
+	}
+	var buf strings.Builder
+	data := TextData{WSLPath: wslPath, WinPath: winPath, Name: filepath.Base(wslPath)}
+	if err := cfg.TextTemplate.Execute(&buf, data); err != nil {
+		logger.Warn("Text template failed, copying the path instead", "err", err)
+		return wslPath
+	}
+	if buf.Len() == 0 {
+		return wslPath
+	}
+	return buf.String()
+}
@@ -0,0 +1,63 @@
+package poller
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTextTemplate(t *testing.T) {
+	for _, good := range []string{"{{.WSLPath}}", "![screenshot]({{.WSLPath}})", "@{{.Name}} {{.WinPath}}"} {
+		if _, err := ParseTextTemplate(good); err != nil {
+			t.Errorf("ParseTextTemplate(%q) error: %v", good, err)
+		}
+	}
+	for _, bad := range []string{"{{.WSLPath", "{{.Path}}", "{{template \"x\"}}"} {
+		if _, err := ParseTextTemplate(bad); err == nil {
+			t.Errorf("ParseTextTemplate(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPoll_TextTemplate(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	imgData := testPNG(t)
+	tmpl, err := ParseTextTemplate("![screenshot]({{.WSLPath}})")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text, win string
+	mock := &mockClipboard{
+		checkFunc:  func() ([]byte, error) { return imgData, nil },
+		updateFunc: func(s, w string) error { text, win = s, w; return nil },
+	}
+	dir := t.TempDir()
+	if err := poll(mock, testLogger(), Config{OutputDir: dir, TextTemplate: tmpl}); err != nil {
+		t.Fatalf("poll() error: %v", err)
+	}
+	path := filepath.Join(dir, hashBytes(imgData)+".png")
+	if want := "![screenshot](" + path + ")"; text != want {
+		t.Errorf("clipboard text = %q, want %q", text, want)
+	}
+	if want, _ := fakeWslPath(path); win != want {
+		t.Errorf("Windows path = %q, want %q", win, want)
+	}
+}
+
+func TestClipboardText_Modes(t *testing.T) {
+	const wsl, win = "/tmp/a.png", `\\wsl.localhost\Ubuntu\tmp\a.png`
+	tests := map[TextMode]string{
+		"":          wsl,
+		TextWSL:     wsl,
+		TextWindows: win,
+		TextBoth:    wsl + "\r\n" + win,
+	}
+	for mode, want := range tests {
+		if got := clipboardText(Config{TextMode: mode}, testLogger(), wsl, win); got != want {
+			t.Errorf("clipboardText(%q) = %q, want %q", mode, got, want)
+		}
+	}
+	if _, err := ParseTextMode("unc"); err == nil {
+		t.Error("ParseTextMode(unc) should fail")
+	}
+}
@@ -0,0 +1,84 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+// writeQueueSize bounds how many throttled captures wait in memory. Beyond
+// it the throttle keeps only the newest one.
+const writeQueueSize = 8
+
+// pendingWrite is a capture held back by the write throttle.
+type pendingWrite struct {
+	path string
+	hash string // the capture's name hash
+	data []byte
+}
+
+// writeThrottle is a token bucket limiting saves to rate per second, with a
+// one-second burst. Captures over the limit wait in a short queue and are
+// written on later ticks, so an app cycling images through the clipboard
+// can't saturate drvfs or /tmp and stall the WSL VM.
+type writeThrottle struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	queue  []pendingWrite
+
+	counters *stats.Counters // when set, told the queue depth on every change
+}
+
+func newWriteThrottle(rate int, now time.Time) *writeThrottle {
+	return &writeThrottle{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+// allow takes a token if one is available.
+func (t *writeThrottle) allow(now time.Time) bool {
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	t.tokens = min(t.tokens, t.rate)
+	t.last = now
+	if t.tokens < 1 {
+		return false
+	}
+	t.tokens--
+	return true
+}
+
+// queued reports whether w is already waiting to be written: its path, or
+// with --filename-template, where names change with time, its hash.
+func (t *writeThrottle) queued(w pendingWrite) bool {
+	for _, q := range t.queue {
+		if q.path == w.path || w.hash != "" && q.hash == w.hash {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue holds a capture for later. When the queue is full, everything
+// waiting is dropped in favor of the new capture, the one the user most
+// likely wants.
+func (t *writeThrottle) enqueue(w pendingWrite, logger pollLogger) {
+	if t.queued(w) {
+		return
+	}
+	if len(t.queue) >= writeQueueSize {
+		logger.Warn("Write queue full, kept only the latest capture", "dropped", len(t.queue))
+		t.queue = t.queue[:0]
+	}
+	t.queue = append(t.queue, w)
+	t.counters.SetQueueDepth(len(t.queue))
+}
+
+// drain writes queued captures, oldest first, while tokens last. With force
+// set (shutdown) it ignores the limit so nothing queued is lost.
+func (t *writeThrottle) drain(now time.Time, force bool, save func(pendingWrite)) {
+	for len(t.queue) > 0 && (force || t.allow(now)) {
+		w := t.queue[0]
+		t.queue = t.queue[1:]
+		t.counters.SetQueueDepth(len(t.queue))
+		save(w)
+	}
+}
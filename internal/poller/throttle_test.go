@@ -0,0 +1,176 @@
+package poller
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+	"github.com/nailuu/wsl-screenshot-cli/internal/stats"
+)
+
+func TestWriteThrottle_Allow(t *testing.T) {
+	now := testEpoch
+	th := newWriteThrottle(2, now)
+	if !th.allow(now) || !th.allow(now) {
+		t.Fatal("a full bucket should allow a burst of rate writes")
+	}
+	if th.allow(now) {
+		t.Error("third write in the same instant should be throttled")
+	}
+	if !th.allow(now.Add(500 * time.Millisecond)) {
+		t.Error("half a second at 2/s should refill one token")
+	}
+	if th.allow(now.Add(500 * time.Millisecond)) {
+		t.Error("only one token should have been refilled")
+	}
+}
+
+func TestWriteThrottle_OverflowKeepsLatest(t *testing.T) {
+	th := newWriteThrottle(1, testEpoch)
+	for i := 0; i < writeQueueSize; i++ {
+		th.enqueue(pendingWrite{path: string(rune('a' + i))}, testLogger())
+	}
+	th.enqueue(pendingWrite{path: "a"}, testLogger()) // already queued: no-op
+	if len(th.queue) != writeQueueSize {
+		t.Fatalf("queue length = %d, want %d", len(th.queue), writeQueueSize)
+	}
+
+	th.enqueue(pendingWrite{path: "latest"}, testLogger())
+	if len(th.queue) != 1 || th.queue[0].path != "latest" {
+		t.Errorf("queue after overflow = %v, want only the latest capture", th.queue)
+	}
+}
+
+func TestWriteThrottle_Drain(t *testing.T) {
+	th := newWriteThrottle(1, testEpoch)
+	th.counters = &stats.Counters{}
+	th.allow(testEpoch) // spend the only token
+	for _, p := range []string{"a", "b", "c"} {
+		th.enqueue(pendingWrite{path: p}, testLogger())
+	}
+	if n := th.counters.Snapshot().Pipeline.QueueDepth; n != 3 {
+		t.Errorf("QueueDepth = %d with 3 captures queued, want 3", n)
+	}
+
+	var saved []string
+	collect := func(w pendingWrite) { saved = append(saved, w.path) }
+	th.drain(testEpoch.Add(time.Second), false, collect)
+	if len(saved) != 1 || saved[0] != "a" {
+		t.Errorf("drain after 1s saved %v, want [a]", saved)
+	}
+	if n := th.counters.Snapshot().Pipeline.QueueDepth; n != 2 {
+		t.Errorf("QueueDepth = %d after saving one, want 2", n)
+	}
+	th.drain(testEpoch.Add(time.Second), true, collect)
+	if len(saved) != 3 || len(th.queue) != 0 {
+		t.Errorf("forced drain saved %v, queue %d; want everything", saved, len(th.queue))
+	}
+	if n := th.counters.Snapshot().Pipeline.QueueDepth; n != 0 {
+		t.Errorf("QueueDepth = %d after draining, want 0", n)
+	}
+}
+
+func TestPoll_ThrottledCaptureIsQueued(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	dir := t.TempDir()
+	clk := clock.NewFake(testEpoch)
+	img := []byte("first")
+	updates := 0
+	mock := &mockClipboard{
+		checkFunc:  func() ([]byte, error) { return img, nil },
+		updateFunc: func(wsl, win string) error { updates++; return nil },
+	}
+	cfg := Config{OutputDir: dir, Clock: clk, throttle: newWriteThrottle(1, testEpoch)}
+
+	if err := poll(mock, testLogger(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	img = []byte("second")
+	if err := poll(mock, testLogger(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	second := filepath.Join(dir, hashBytes(img)+".png")
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Fatalf("throttled capture was written immediately (stat err %v)", err)
+	}
+	if updates != 1 {
+		t.Errorf("clipboard updates = %d, want 1 (the throttled capture waits)", updates)
+	}
+
+	clk.Advance(time.Second)
+	cfg.throttle.drain(clk.Now(), false, func(w pendingWrite) { saveQueued(w, testLogger(), cfg) })
+	if err := poll(mock, testLogger(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(second); err != nil {
+		t.Errorf("queued capture not saved after the throttle refilled: %v", err)
+	}
+	if updates != 2 {
+		t.Errorf("clipboard updates = %d, want 2 once the file exists", updates)
+	}
+}
+
+// seqImageClipboard is a mockClipboard holding an image under a sequence
+// number, answering CHECK|<since> like the real helper: nothing when the
+// clipboard has not changed since.
+type seqImageClipboard struct {
+	mockClipboard
+	mu      sync.Mutex
+	seq     uint32
+	image   []byte
+	updated []string
+	polled  chan struct{}
+}
+
+func (c *seqImageClipboard) CheckSince(since uint32, w io.Writer) (uint32, bool, error) {
+	defer func() { c.polled <- struct{}{} }()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if since == c.seq {
+		return c.seq, false, nil
+	}
+	_, err := w.Write(c.image)
+	return c.seq, true, err
+}
+
+func (c *seqImageClipboard) set(seq uint32, image []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq, c.image = seq, image
+}
+
+func TestRun_ThrottledCaptureReachesClipboard(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	dir := t.TempDir()
+	mock := &seqImageClipboard{polled: make(chan struct{}, 1)}
+	mock.updateFunc = func(wsl, win string) error {
+		mock.updated = append(mock.updated, wsl)
+		return nil
+	}
+	mock.set(1, []byte("first"))
+
+	stop := startRun(t, clk, Config{OutputDir: dir, MaxWritesPerSecond: 1}, func() (Clipboard, error) {
+		return mock, nil
+	})
+	tick(t, clk, mock.polled) // saved, using up the only token
+	mock.set(2, []byte("second"))
+	for range 15 { // queued, then saved once the bucket refills
+		tick(t, clk, mock.polled)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	second := filepath.Join(dir, hashBytes([]byte("second"))+".png")
+	if _, err := os.Stat(second); err != nil {
+		t.Fatalf("throttled capture not saved: %v", err)
+	}
+	if n := len(mock.updated); n != 2 || mock.updated[n-1] != second {
+		t.Errorf("clipboard updated with %v, want the throttled capture last", mock.updated)
+	}
+}
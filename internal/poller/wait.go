@@ -0,0 +1,63 @@
+package poller
+
+import "time"
+
+// Waiter is implemented by clients whose backend can block until the
+// clipboard sequence number differs from since, or timeout passes.
+type Waiter interface {
+	Wait(since uint32, timeout time.Duration) (seq uint32, changed bool, err error)
+}
+
+// waitTimeout bounds each wait, so Run still polls, pings and drains its
+// queues this often when no clipboard change arrives, and a missed event
+// costs at most this much latency.
+const waitTimeout = 2 * time.Second
+
+// waker runs Run's waits in the background, one at a time. Each finished
+// wait wakes the loop like a tick, and the next one only starts when the
+// loop asks for it, so a wait never holds the client while the loop uses it.
+type waker struct {
+	requests chan waitRequest
+	done     chan error
+}
+
+type waitRequest struct {
+	client Waiter
+	since  uint32
+}
+
+func newWaker() *waker {
+	w := &waker{requests: make(chan waitRequest), done: make(chan error, 1)}
+	go func() {
+		for req := range w.requests {
+			_, _, err := req.client.Wait(req.since, waitTimeout)
+			w.done <- err
+		}
+	}()
+	return w
+}
+
+// start begins a wait on client, reporting false when it can't wait.
+func (w *waker) start(client Clipboard, since uint32) bool {
+	wc, ok := client.(Waiter)
+	if w == nil || !ok {
+		return false
+	}
+	w.requests <- waitRequest{client: wc, since: since}
+	return true
+}
+
+// C delivers the outcome of each wait; nil for a nil waker.
+func (w *waker) C() <-chan error {
+	if w == nil {
+		return nil
+	}
+	return w.done
+}
+
+// stop ends the background goroutine once its current wait returns.
+func (w *waker) stop() {
+	if w != nil {
+		close(w.requests)
+	}
+}
@@ -0,0 +1,65 @@
+package poller
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nailuu/wsl-screenshot-cli/internal/clock"
+)
+
+// waitClipboard is a mockClipboard whose waits return when the test says so.
+type waitClipboard struct {
+	mockClipboard
+	changes chan error
+	waits   atomic.Int32
+}
+
+func (w *waitClipboard) Wait(since uint32, timeout time.Duration) (uint32, bool, error) {
+	w.waits.Add(1)
+	err := <-w.changes
+	return since + 1, err == nil, err
+}
+
+func TestRun_WaitsForChanges(t *testing.T) {
+	overrideWslPath(t, fakeWslPath)
+	clk := clock.NewFake(testEpoch)
+	polled := make(chan struct{}, 1)
+	mock := &waitClipboard{changes: make(chan error)}
+	mock.checkFunc = func() ([]byte, error) {
+		polled <- struct{}{}
+		return nil, nil
+	}
+
+	stop := startRun(t, clk, Config{OutputDir: t.TempDir(), Wait: true}, func() (Clipboard, error) {
+		return mock, nil
+	})
+
+	// Polls follow changes, not the clock.
+	clk.Advance(10 * testInterval)
+	select {
+	case <-polled:
+		t.Fatal("polled on a tick while waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 0; i < 2; i++ {
+		mock.changes <- nil
+		select {
+		case <-polled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("a change did not trigger a poll")
+		}
+	}
+
+	// A backend that can't wait is polled on the ticker instead.
+	mock.changes <- errors.New("ERR|UNAVAILABLE|clipboard listener unavailable")
+	<-polled
+	tick(t, clk, polled)
+	if err := stop(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := mock.waits.Load(); got != 3 {
+		t.Errorf("waits = %d, want 3", got)
+	}
+}
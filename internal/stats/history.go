@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// historyDays is how many daily rollups History keeps.
+const historyDays = 366
+
+// Day is the rollup of one calendar day's poll activity, across daemon
+// restarts.
+type Day struct {
+	Date     string `json:"date"` // YYYY-MM-DD, local time
+	Polls    int64  `json:"polls"`
+	Captures int64  `json:"captures"`
+	Bytes    int64  `json:"bytes"`
+	Errors   int64  `json:"errors"`
+
+	// Checks and CheckMs are the number and total duration of CHECK round
+	// trips, for the average latency.
+	Checks  int64   `json:"checks"`
+	CheckMs float64 `json:"check_ms"`
+}
+
+// AvgLatencyMs is the day's average CHECK round trip, or 0 without checks.
+func (d Day) AvgLatencyMs() float64 {
+	if d.Checks == 0 {
+		return 0
+	}
+	return d.CheckMs / float64(d.Checks)
+}
+
+// empty reports whether d holds no activity.
+func (d Day) empty() bool {
+	return d.Polls == 0 && d.Captures == 0 && d.Errors == 0 && d.Checks == 0
+}
+
+// History is the persisted list of daily rollups, oldest first.
+type History struct {
+	Days []Day `json:"days"`
+}
+
+// Since returns what happened between two snapshots of the same Counters as
+// an undated Day.
+func Since(prev, cur Snapshot) Day {
+	d := Day{
+		Polls:    cur.Polls - prev.Polls,
+		Captures: cur.Captures - prev.Captures,
+		Bytes:    cur.Bytes - prev.Bytes,
+		Errors:   cur.Errors - prev.Errors,
+	}
+	p, c := prev.Pipeline.Stages[StageCheck], cur.Pipeline.Stages[StageCheck]
+	d.Checks = c.Count - p.Count
+	d.CheckMs = c.AvgMs*float64(c.Count) - p.AvgMs*float64(p.Count)
+	return d
+}
+
+// Add adds delta to the rollup for date, creating it if needed, and drops
+// days beyond the retention limit. It reports whether anything changed.
+func (h *History) Add(date string, delta Day) bool {
+	if delta.empty() {
+		return false
+	}
+	i := sort.Search(len(h.Days), func(i int) bool { return h.Days[i].Date >= date })
+	if i == len(h.Days) || h.Days[i].Date != date {
+		h.Days = append(h.Days, Day{})
+		copy(h.Days[i+1:], h.Days[i:])
+		h.Days[i] = Day{Date: date}
+	}
+	d := &h.Days[i]
+	d.Polls += delta.Polls
+	d.Captures += delta.Captures
+	d.Bytes += delta.Bytes
+	d.Errors += delta.Errors
+	d.Checks += delta.Checks
+	d.CheckMs += delta.CheckMs
+	if n := len(h.Days); n > historyDays {
+		h.Days = h.Days[n-historyDays:]
+	}
+	return true
+}
+
+// LoadHistory reads the history at path. A missing file is an empty history.
+func LoadHistory(path string) (History, error) {
+	var h History
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the daemon's history file
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	err = json.Unmarshal(data, &h)
+	return h, err
+}
+
+// SaveHistory atomically replaces the history at path.
+func SaveHistory(path string, h History) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
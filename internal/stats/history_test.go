@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistory_AddMergesSortsAndTrims(t *testing.T) {
+	var h History
+	h.Add("2024-05-02", Day{Captures: 1})
+	h.Add("2024-05-01", Day{Captures: 2, Bytes: 10})
+	h.Add("2024-05-02", Day{Captures: 3, Errors: 1})
+	if h.Add("2024-05-03", Day{}) {
+		t.Error("an empty delta should not change the history")
+	}
+
+	want := []Day{
+		{Date: "2024-05-01", Captures: 2, Bytes: 10},
+		{Date: "2024-05-02", Captures: 4, Errors: 1},
+	}
+	if !reflect.DeepEqual(h.Days, want) {
+		t.Errorf("Days = %+v, want %+v", h.Days, want)
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < historyDays+5; i++ {
+		h.Add(start.AddDate(0, 0, i).Format("2006-01-02"), Day{Polls: 1})
+	}
+	if len(h.Days) != historyDays {
+		t.Fatalf("kept %d days, want %d", len(h.Days), historyDays)
+	}
+	if h.Days[len(h.Days)-1].Date != "2024-05-02" {
+		t.Errorf("newest day = %s, want the most recent date kept", h.Days[len(h.Days)-1].Date)
+	}
+}
+
+func TestSince(t *testing.T) {
+	var c Counters
+	c.RecordStage(StageCheck, 10*time.Millisecond)
+	prev := c.Snapshot()
+	c.RecordCapture(time.Now(), 100)
+	c.RecordPoll(time.Now(), true)
+	c.RecordStage(StageCheck, 30*time.Millisecond)
+	c.RecordError()
+
+	d := Since(prev, c.Snapshot())
+	if d.Captures != 1 || d.Bytes != 100 || d.Polls != 1 || d.Errors != 1 || d.Checks != 1 {
+		t.Errorf("Since() = %+v", d)
+	}
+	if got := fmt.Sprintf("%.1f", d.AvgLatencyMs()); got != "30.0" {
+		t.Errorf("AvgLatencyMs() = %s, want 30.0", got)
+	}
+}
+
+func TestHistory_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.json")
+	if h, err := LoadHistory(path); err != nil || len(h.Days) != 0 {
+		t.Fatalf("LoadHistory(missing) = %+v, %v; want empty", h, err)
+	}
+	h := History{Days: []Day{{Date: "2024-05-01", Captures: 3}}}
+	if err := SaveHistory(path, h); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadHistory(path); err != nil || !reflect.DeepEqual(got, h) {
+		t.Errorf("LoadHistory() = %+v, %v; want %+v", got, err, h)
+	}
+}
@@ -0,0 +1,401 @@
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage names for the per-stage timing of a poll cycle.
+const (
+	StageCheck  = "check"  // CHECK round trip to the clipboard backend
+	StageSave   = "save"   // writing a new screenshot to disk
+	StageUpdate = "update" // path conversion and UPDATE round trip
+)
+
+// Counters tracks poll-loop activity. The poller updates them while the
+// daemon's heartbeat goroutine reads them, so all methods are safe for
+// concurrent use. A nil *Counters is valid and records nothing.
+type Counters struct {
+	captures          atomic.Int64
+	bytes             atomic.Int64
+	errors            atomic.Int64
+	injectedErrors    atomic.Int64
+	consecutiveErrors atomic.Int64
+	lastCapture       atomic.Int64 // unix nanoseconds, 0 if none yet
+	polls             atomic.Int64
+	dedupHits         atomic.Int64
+	restarts          atomic.Int64
+
+	inFlight   atomic.Int64
+	queueDepth atomic.Int64
+
+	backendMemory    atomic.Int64 // bytes, 0 if not reported
+	overwrites       atomic.Int64
+	orphanedBackends atomic.Int64
+
+	backendReady   atomic.Bool
+	lastPoll       atomic.Int64 // unix nanoseconds, 0 if none yet
+	lastPollFailed atomic.Bool
+
+	pingRTT        atomic.Int64 // nanoseconds
+	lastPing       atomic.Int64 // unix nanoseconds, 0 if none yet
+	lastPingFailed atomic.Bool
+
+	clipboardSeq  atomic.Uint32
+	sessionLocked atomic.Bool
+
+	mu             sync.Mutex
+	stages         map[string]*stageTotals
+	lastOverwriter string
+	backendBinary  string
+	clipboardPath  string
+}
+
+type stageTotals struct {
+	count int64
+	total time.Duration
+	last  time.Duration
+	max   time.Duration
+}
+
+// StageTiming summarizes how long one pipeline stage has been taking.
+type StageTiming struct {
+	Count  int64   `json:"count"`
+	LastMs float64 `json:"last_ms"`
+	AvgMs  float64 `json:"avg_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// Pipeline reports how much work is queued or being processed, so a backlog
+// in a slow stage is visible before it eats disk or memory.
+type Pipeline struct {
+	QueueDepth int64                  `json:"queue_depth"`
+	InFlight   int64                  `json:"in_flight"`
+	Stages     map[string]StageTiming `json:"stages,omitempty"`
+}
+
+// Snapshot is a point-in-time copy of Counters, suitable for JSON encoding.
+type Snapshot struct {
+	Captures          int64     `json:"captures"`
+	Bytes             int64     `json:"bytes"` // total size of the captures
+	Errors            int64     `json:"errors"`
+	InjectedErrors    int64     `json:"injected_errors,omitempty"` // simulated by --chaos, not in Errors
+	ConsecutiveErrors int64     `json:"consecutive_errors"`
+	LastCapture       time.Time `json:"last_capture,omitzero"`
+
+	// Polls counts completed poll cycles, DedupHits captures that matched an
+	// already saved screenshot, and Restarts clipboard backend restarts.
+	Polls     int64 `json:"polls"`
+	DedupHits int64 `json:"dedup_hits"`
+	Restarts  int64 `json:"restarts"`
+
+	Pipeline Pipeline `json:"pipeline"`
+
+	// BackendMemoryKB is the clipboard backend's (powershell.exe) working set
+	// at its last report, or 0 if it has not reported yet.
+	BackendMemoryKB int64 `json:"backend_memory_kb,omitempty"`
+
+	// BackendBinary is the PowerShell executable running the clipboard
+	// backend (pwsh.exe or powershell.exe), if the client reports it.
+	BackendBinary string `json:"backend_binary,omitempty"`
+
+	// Overwrites counts how often another application replaced the clipboard
+	// shortly after one of our updates; LastOverwriter names the latest one.
+	Overwrites     int64  `json:"overwrites"`
+	LastOverwriter string `json:"last_overwriter,omitempty"`
+
+	// BackendReady is true while a clipboard backend is started and serving
+	// requests. LastPoll and LastPollOK describe the most recent poll cycle.
+	BackendReady bool      `json:"backend_ready"`
+	LastPoll     time.Time `json:"last_poll,omitzero"`
+	LastPollOK   bool      `json:"last_poll_ok"`
+
+	// OrphanedBackends counts powershell.exe backends whose owning daemon is
+	// gone, as of the last sweep.
+	OrphanedBackends int64 `json:"orphaned_backends,omitempty"`
+
+	// LastPing is when the clipboard backend was last sent a PING,
+	// LastPingOK whether it answered, and PingMs the round trip it took.
+	LastPing   time.Time `json:"last_ping,omitzero"`
+	LastPingOK bool      `json:"last_ping_ok"`
+	PingMs     float64   `json:"ping_ms,omitempty"`
+
+	// ClipboardSeq is the Windows clipboard sequence number at the last
+	// check that read it, or 0 if none has.
+	ClipboardSeq uint32 `json:"clipboard_seq,omitempty"`
+
+	// SessionLocked is true while polling is paused because the Windows
+	// session is locked.
+	SessionLocked bool `json:"session_locked,omitempty"`
+
+	// ClipboardPath is the screenshot the clipboard was last set to, which
+	// cleanups must not delete.
+	ClipboardPath string `json:"clipboard_path,omitempty"`
+}
+
+// RecordCapture counts a newly saved screenshot of size bytes taken at t.
+func (c *Counters) RecordCapture(t time.Time, size int) {
+	if c == nil {
+		return
+	}
+	c.captures.Add(1)
+	c.bytes.Add(int64(size))
+	c.lastCapture.Store(t.UnixNano())
+}
+
+// RecordDedupHit counts a capture whose image was already saved.
+func (c *Counters) RecordDedupHit() {
+	if c == nil {
+		return
+	}
+	c.dedupHits.Add(1)
+}
+
+// RecordRestart counts a restart of the clipboard backend.
+func (c *Counters) RecordRestart() {
+	if c == nil {
+		return
+	}
+	c.restarts.Add(1)
+}
+
+// RecordError counts a failed poll.
+func (c *Counters) RecordError() {
+	if c == nil {
+		return
+	}
+	c.errors.Add(1)
+	c.consecutiveErrors.Add(1)
+}
+
+// RecordInjectedError counts a poll failed on purpose by --chaos. It is kept
+// apart from organic errors but still counts towards the consecutive ones,
+// which is what the circuit breaker and health checks watch.
+func (c *Counters) RecordInjectedError() {
+	if c == nil {
+		return
+	}
+	c.injectedErrors.Add(1)
+	c.consecutiveErrors.Add(1)
+}
+
+// RecordSuccess resets the consecutive error count after a successful poll.
+func (c *Counters) RecordSuccess() {
+	if c == nil {
+		return
+	}
+	c.consecutiveErrors.Store(0)
+}
+
+// BeginJob marks a poll cycle as in flight. Pair with EndJob.
+func (c *Counters) BeginJob() {
+	if c == nil {
+		return
+	}
+	c.inFlight.Add(1)
+}
+
+// EndJob marks a poll cycle started with BeginJob as finished.
+func (c *Counters) EndJob() {
+	if c == nil {
+		return
+	}
+	c.inFlight.Add(-1)
+}
+
+// SetQueueDepth reports how many captures are waiting to be processed.
+func (c *Counters) SetQueueDepth(n int) {
+	if c == nil {
+		return
+	}
+	c.queueDepth.Store(int64(n))
+}
+
+// SetBackendMemory records the clipboard backend's latest working set in bytes.
+func (c *Counters) SetBackendMemory(bytes int64) {
+	if c == nil {
+		return
+	}
+	c.backendMemory.Store(bytes)
+}
+
+// SetClipboardSequence records the clipboard sequence number last read.
+func (c *Counters) SetClipboardSequence(seq uint32) {
+	if c == nil {
+		return
+	}
+	c.clipboardSeq.Store(seq)
+}
+
+// RecordOverwrite counts a third-party clipboard overwrite by process, which
+// may be empty when the owner is unknown.
+func (c *Counters) RecordOverwrite(process string) {
+	if c == nil {
+		return
+	}
+	c.overwrites.Add(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastOverwriter = process
+}
+
+// SetBackendBinary records which PowerShell executable runs the backend.
+func (c *Counters) SetBackendBinary(binary string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.backendBinary = binary
+}
+
+// SetClipboardPath records the screenshot the clipboard was set to.
+func (c *Counters) SetClipboardPath(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clipboardPath = path
+}
+
+// ClipboardPath returns the screenshot the clipboard was last set to, or "".
+func (c *Counters) ClipboardPath() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clipboardPath
+}
+
+// SetOrphanedBackends records how many orphaned backends the last sweep found.
+func (c *Counters) SetOrphanedBackends(n int) {
+	if c == nil {
+		return
+	}
+	c.orphanedBackends.Store(int64(n))
+}
+
+// SetBackendReady records whether the clipboard backend is up.
+func (c *Counters) SetBackendReady(ready bool) {
+	if c == nil {
+		return
+	}
+	c.backendReady.Store(ready)
+}
+
+// SetSessionLocked records whether polling is paused for a locked session.
+func (c *Counters) SetSessionLocked(locked bool) {
+	if c == nil {
+		return
+	}
+	c.sessionLocked.Store(locked)
+}
+
+// RecordPoll counts a poll cycle and records its time and outcome. Unlike
+// RecordError, it also covers failures the circuit breaker ignores.
+func (c *Counters) RecordPoll(t time.Time, ok bool) {
+	if c == nil {
+		return
+	}
+	c.polls.Add(1)
+	c.lastPollFailed.Store(!ok)
+	c.lastPoll.Store(t.UnixNano())
+}
+
+// RecordPing records a PING to the clipboard backend sent at t, and its round
+// trip time when ok.
+func (c *Counters) RecordPing(t time.Time, rtt time.Duration, ok bool) {
+	if c == nil {
+		return
+	}
+	if ok {
+		c.pingRTT.Store(int64(rtt))
+	}
+	c.lastPingFailed.Store(!ok)
+	c.lastPing.Store(t.UnixNano())
+}
+
+// RecordStage adds one timing sample for the named stage.
+func (c *Counters) RecordStage(stage string, d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stages == nil {
+		c.stages = make(map[string]*stageTotals)
+	}
+	st, ok := c.stages[stage]
+	if !ok {
+		st = &stageTotals{}
+		c.stages[stage] = st
+	}
+	st.count++
+	st.total += d
+	st.last = d
+	st.max = max(st.max, d)
+}
+
+// Snapshot returns the current counter values.
+func (c *Counters) Snapshot() Snapshot {
+	if c == nil {
+		return Snapshot{}
+	}
+	s := Snapshot{
+		Captures:          c.captures.Load(),
+		Bytes:             c.bytes.Load(),
+		Errors:            c.errors.Load(),
+		InjectedErrors:    c.injectedErrors.Load(),
+		ConsecutiveErrors: c.consecutiveErrors.Load(),
+		Polls:             c.polls.Load(),
+		DedupHits:         c.dedupHits.Load(),
+		Restarts:          c.restarts.Load(),
+		Pipeline: Pipeline{
+			QueueDepth: c.queueDepth.Load(),
+			InFlight:   c.inFlight.Load(),
+		},
+		BackendMemoryKB:  c.backendMemory.Load() / 1024,
+		Overwrites:       c.overwrites.Load(),
+		BackendReady:     c.backendReady.Load(),
+		OrphanedBackends: c.orphanedBackends.Load(),
+		ClipboardSeq:     c.clipboardSeq.Load(),
+		SessionLocked:    c.sessionLocked.Load(),
+	}
+	if ns := c.lastCapture.Load(); ns != 0 {
+		s.LastCapture = time.Unix(0, ns)
+	}
+	if ns := c.lastPoll.Load(); ns != 0 {
+		s.LastPoll = time.Unix(0, ns)
+		s.LastPollOK = !c.lastPollFailed.Load()
+	}
+	if ns := c.lastPing.Load(); ns != 0 {
+		s.LastPing = time.Unix(0, ns)
+		s.LastPingOK = !c.lastPingFailed.Load()
+		s.PingMs = millis(time.Duration(c.pingRTT.Load()))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.LastOverwriter = c.lastOverwriter
+	s.BackendBinary = c.backendBinary
+	s.ClipboardPath = c.clipboardPath
+	if len(c.stages) > 0 {
+		s.Pipeline.Stages = make(map[string]StageTiming, len(c.stages))
+		for name, st := range c.stages {
+			s.Pipeline.Stages[name] = StageTiming{
+				Count:  st.count,
+				LastMs: millis(st.last),
+				AvgMs:  millis(st.total / time.Duration(st.count)),
+				MaxMs:  millis(st.max),
+			}
+		}
+	}
+	return s
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
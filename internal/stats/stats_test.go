@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCounters_Snapshot(t *testing.T) {
+	var c Counters
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	c.RecordError()
+	c.RecordError()
+	c.RecordSuccess()
+	c.RecordCapture(at, 2048)
+	c.RecordError()
+	c.RecordInjectedError()
+	c.SetBackendMemory(80 * 1024 * 1024)
+	c.RecordOverwrite("Ditto")
+	c.RecordOverwrite("KeePass")
+	c.SetClipboardPath("/tmp/shots/a.png")
+
+	s := c.Snapshot()
+	if s.Captures != 1 {
+		t.Errorf("Captures = %d, want 1", s.Captures)
+	}
+	if s.Errors != 3 {
+		t.Errorf("Errors = %d, want 3", s.Errors)
+	}
+	if s.InjectedErrors != 1 {
+		t.Errorf("InjectedErrors = %d, want 1 (kept out of Errors)", s.InjectedErrors)
+	}
+	if s.ConsecutiveErrors != 2 {
+		t.Errorf("ConsecutiveErrors = %d, want 2", s.ConsecutiveErrors)
+	}
+	if !s.LastCapture.Equal(at) {
+		t.Errorf("LastCapture = %v, want %v", s.LastCapture, at)
+	}
+	if s.BackendMemoryKB != 80*1024 {
+		t.Errorf("BackendMemoryKB = %d, want %d", s.BackendMemoryKB, 80*1024)
+	}
+	if s.Overwrites != 2 || s.LastOverwriter != "KeePass" {
+		t.Errorf("Overwrites = %d (last %q), want 2 (last KeePass)", s.Overwrites, s.LastOverwriter)
+	}
+	if s.ClipboardPath != "/tmp/shots/a.png" {
+		t.Errorf("ClipboardPath = %q, want /tmp/shots/a.png", s.ClipboardPath)
+	}
+}
+
+func TestCounters_NilIsNoop(t *testing.T) {
+	var c *Counters
+	c.RecordCapture(time.Now(), 1)
+	c.RecordError()
+	c.RecordSuccess()
+	c.BeginJob()
+	c.RecordStage(StageCheck, time.Second)
+	c.SetBackendMemory(1)
+	c.RecordOverwrite("x")
+	if s := c.Snapshot(); !reflect.DeepEqual(s, Snapshot{}) {
+		t.Errorf("nil Snapshot() = %+v, want zero", s)
+	}
+}
+
+func TestCounters_Pipeline(t *testing.T) {
+	var c Counters
+	c.BeginJob()
+	c.SetQueueDepth(3)
+	c.RecordStage(StageCheck, 10*time.Millisecond)
+	c.RecordStage(StageCheck, 30*time.Millisecond)
+	c.RecordStage(StageSave, 5*time.Millisecond)
+
+	p := c.Snapshot().Pipeline
+	if p.InFlight != 1 || p.QueueDepth != 3 {
+		t.Errorf("InFlight/QueueDepth = %d/%d, want 1/3", p.InFlight, p.QueueDepth)
+	}
+	want := StageTiming{Count: 2, LastMs: 30, AvgMs: 20, MaxMs: 30}
+	if got := p.Stages[StageCheck]; got != want {
+		t.Errorf("check stage = %+v, want %+v", got, want)
+	}
+	if got := p.Stages[StageSave].Count; got != 1 {
+		t.Errorf("save stage count = %d, want 1", got)
+	}
+
+	c.EndJob()
+	if got := c.Snapshot().Pipeline.InFlight; got != 0 {
+		t.Errorf("InFlight after EndJob = %d, want 0", got)
+	}
+}
+
+func TestCounters_Readiness(t *testing.T) {
+	var c Counters
+	if s := c.Snapshot(); s.BackendReady || !s.LastPoll.IsZero() || s.LastPollOK {
+		t.Fatalf("fresh counters = %+v, want not ready and no poll", s)
+	}
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.SetBackendReady(true)
+	c.RecordPoll(at, false)
+	if s := c.Snapshot(); !s.BackendReady || !s.LastPoll.Equal(at) || s.LastPollOK {
+		t.Errorf("after failed poll = %+v", s)
+	}
+	c.RecordPoll(at.Add(time.Second), true)
+	if s := c.Snapshot(); !s.LastPollOK {
+		t.Errorf("after successful poll = %+v", s)
+	}
+}
+
+func TestCounters_Ping(t *testing.T) {
+	var c Counters
+	if s := c.Snapshot(); !s.LastPing.IsZero() || s.LastPingOK {
+		t.Fatalf("before any ping = %+v", s)
+	}
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.RecordPing(at, 4*time.Millisecond, true)
+	if s := c.Snapshot(); !s.LastPing.Equal(at) || !s.LastPingOK || s.PingMs != 4 {
+		t.Errorf("after answered ping = %+v", s)
+	}
+	// A failed ping keeps the last known round trip.
+	c.RecordPing(at.Add(time.Second), 0, false)
+	if s := c.Snapshot(); s.LastPingOK || s.PingMs != 4 {
+		t.Errorf("after failed ping = %+v", s)
+	}
+}
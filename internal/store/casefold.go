@@ -0,0 +1,27 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CaseInsensitive reports whether dir folds case in file names, as drvfs
+// mounts of Windows drives (/mnt/c) do by default. It probes by creating a
+// mixed-case file and looking it up in lower case.
+func CaseInsensitive(dir string) (bool, error) {
+	f, err := os.CreateTemp(dir, ".Case-Probe-*")
+	if err != nil {
+		return false, err
+	}
+	name := f.Name()
+	_ = f.Close()
+	defer os.Remove(name)
+
+	folded := filepath.Join(filepath.Dir(name), strings.ToLower(filepath.Base(name)))
+	_, err = os.Stat(folded)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCaseInsensitive(t *testing.T) {
+	dir := t.TempDir()
+	fold, err := CaseInsensitive(dir)
+	if err != nil {
+		t.Fatalf("CaseInsensitive() error: %v", err)
+	}
+
+	// Cross-check against the filesystem the test runs on.
+	probe := filepath.Join(dir, "Probe")
+	if err := os.WriteFile(probe, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, statErr := os.Stat(filepath.Join(dir, "probe"))
+	if want := statErr == nil; fold != want {
+		t.Errorf("CaseInsensitive() = %t, want %t", fold, want)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("the probe file was left behind: %d entries", len(entries))
+	}
+
+	if _, err := CaseInsensitive(filepath.Join(dir, "missing")); err == nil {
+		t.Error("probing a missing directory should fail")
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// CollisionPolicy decides what happens when a screenshot's file name is
+// already taken by a file with different content. Identical content is always
+// deduplicated, whatever the policy.
+type CollisionPolicy string
+
+const (
+	// CollisionSuffix saves under the first free name-N.ext.
+	CollisionSuffix CollisionPolicy = "suffix"
+	// CollisionOverwrite replaces the existing file.
+	CollisionOverwrite CollisionPolicy = "overwrite"
+	// CollisionSkip keeps the existing file and drops the new one with a
+	// warning.
+	CollisionSkip CollisionPolicy = "skip"
+)
+
+// MaxCollisionSuffix bounds how many name-N candidates the suffix policy
+// tries.
+const MaxCollisionSuffix = 1000
+
+// ParseCollisionPolicy validates a collision policy name from the command line.
+func ParseCollisionPolicy(s string) (CollisionPolicy, error) {
+	switch p := CollisionPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case CollisionSuffix, CollisionOverwrite, CollisionSkip:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown collision policy %q (expected suffix, overwrite or skip)", s)
+	}
+}
+
+// CollisionName returns the n-th name the suffix policy tries for path:
+// name-n.ext.
+func CollisionName(path string, n int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
+// SameContent reports whether a and b hold the same bytes, reading both a
+// chunk at a time.
+func SameContent(a, b io.Reader) (bool, error) {
+	bufA, bufB := make([]byte, 64*1024), make([]byte, 64*1024)
+	for {
+		n, errA := io.ReadFull(a, bufA)
+		m, errB := io.ReadFull(b, bufB)
+		if !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false, nil
+		}
+		doneA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		doneB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		switch {
+		case errA != nil && !doneA:
+			return false, errA
+		case errB != nil && !doneB:
+			return false, errB
+		case doneA || doneB:
+			return doneA && doneB, nil
+		}
+	}
+}
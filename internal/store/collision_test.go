@@ -0,0 +1,37 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCollisionPolicy(t *testing.T) {
+	for _, in := range []string{"suffix", "Overwrite", " skip "} {
+		if _, err := ParseCollisionPolicy(in); err != nil {
+			t.Errorf("ParseCollisionPolicy(%q) error: %v", in, err)
+		}
+	}
+	if _, err := ParseCollisionPolicy("rename"); err == nil {
+		t.Error("ParseCollisionPolicy(rename) should fail")
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"", "", true},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "abcd", false},
+		{long, long, true},
+		{long, long[:len(long)-1] + "y", false},
+	} {
+		got, err := SameContent(strings.NewReader(tc.a), strings.NewReader(tc.b))
+		if err != nil || got != tc.want {
+			t.Errorf("SameContent(%d bytes, %d bytes) = %v, %v; want %v", len(tc.a), len(tc.b), got, err, tc.want)
+		}
+	}
+}
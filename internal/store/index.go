@@ -0,0 +1,182 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// IndexFile is the name of the capture index inside an output directory. It
+// records every capture with its metadata, so commands can show where a
+// screenshot came from without decoding it, and maps hashes back to files
+// whose names don't carry them (--filename-template) for dedup.
+const IndexFile = ".index.jsonl"
+
+// IndexEntry records one capture.
+type IndexEntry struct {
+	Hash   string    `json:"hash"`             // the name hash, as a hash-named file would be called
+	Name   string    `json:"name"`             // the file, relative to the output directory
+	Time   time.Time `json:"time"`             // when it was captured
+	Seq    int       `json:"seq,omitempty"`    // 1 for the first capture in the directory
+	Size   int64     `json:"size,omitempty"`   // in bytes
+	Width  int       `json:"width,omitempty"`  // in pixels, 0 if unknown
+	Height int       `json:"height,omitempty"` // in pixels, 0 if unknown
+	Window string    `json:"window,omitempty"` // the foreground process when it was captured
+	Title  string    `json:"title,omitempty"`  // that window's title
+}
+
+// Index is an output directory's capture index. Entries are appended one
+// JSON object per line; a later entry for the same hash or file wins.
+type Index struct {
+	dir     string
+	entries []IndexEntry // in file order
+	byHash  map[string]int
+	byName  map[string]int
+	maxSeq  int
+}
+
+// ReadIndex loads the index of dir. A missing index is empty. Malformed
+// lines, e.g. one cut short by a crash, are skipped.
+func ReadIndex(dir string) (*Index, error) {
+	ix := &Index{dir: dir, byHash: make(map[string]int), byName: make(map[string]int)}
+	f, err := os.Open(filepath.Join(dir, IndexFile)) // #nosec G304 -- the index inside the output directory
+	if errors.Is(err, os.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e IndexEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.Hash == "" || e.Name == "" {
+			continue
+		}
+		ix.add(e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", IndexFile, err)
+	}
+	return ix, nil
+}
+
+func (ix *Index) add(e IndexEntry) {
+	ix.byHash[e.Hash] = len(ix.entries)
+	ix.byName[e.Name] = len(ix.entries)
+	ix.entries = append(ix.entries, e)
+	ix.maxSeq = max(ix.maxSeq, e.Seq)
+}
+
+// Lookup returns the path of the file last recorded for hash, or "" when
+// there is none.
+func (ix *Index) Lookup(hash string) string {
+	i, ok := ix.byHash[hash]
+	if !ok {
+		return ""
+	}
+	return ix.Path(ix.entries[i])
+}
+
+// Find returns the entry last recorded for the file at path.
+func (ix *Index) Find(path string) (IndexEntry, bool) {
+	name, err := filepath.Rel(ix.dir, path)
+	if err != nil {
+		return IndexEntry{}, false
+	}
+	i, ok := ix.byName[filepath.ToSlash(name)]
+	if !ok {
+		return IndexEntry{}, false
+	}
+	return ix.entries[i], true
+}
+
+// Entries returns the current entry of every recorded file, oldest first.
+// Files may have been deleted since.
+func (ix *Index) Entries() []IndexEntry {
+	var out []IndexEntry
+	for i, e := range ix.entries {
+		if ix.byName[e.Name] == i {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// NextSeq returns the sequence number of the next capture.
+func (ix *Index) NextSeq() int {
+	return ix.maxSeq + 1
+}
+
+// Path returns where the file of e is.
+func (ix *Index) Path(e IndexEntry) string {
+	return filepath.Join(ix.dir, filepath.FromSlash(e.Name))
+}
+
+// AppendIndex records e for the file at path, which must be inside dir;
+// e.Name is filled in from it. Callers hold dir's write lock.
+func AppendIndex(dir, path string, e IndexEntry) error {
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		return err
+	}
+	e.Name = filepath.ToSlash(name)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, IndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CompactIndex rewrites dir's index without the entries of files that no
+// longer exist and returns how many it dropped. Prune does this after each
+// deletion; CompactIndex also catches files removed by hand or moved aside
+// by Verify. It holds dir's write lock.
+func CompactIndex(dir string) (int, error) {
+	unlock := LockWrite(dir)
+	defer unlock()
+	return compactIndex(dir)
+}
+
+// compactIndex rewrites dir's index without the entries of files that no
+// longer exist and returns how many it dropped. The entry with the highest
+// sequence number stays either way, so numbering never goes back. Callers
+// hold dir's write lock.
+func compactIndex(dir string) (int, error) {
+	ix, err := ReadIndex(dir)
+	if err != nil || len(ix.entries) == 0 {
+		return 0, err
+	}
+	keep := slices.DeleteFunc(ix.Entries(), func(e IndexEntry) bool {
+		_, err := os.Lstat(ix.Path(e))
+		return errors.Is(err, os.ErrNotExist) && e.Seq != ix.maxSeq
+	})
+	if len(keep) == len(ix.entries) {
+		return 0, nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range keep {
+		if err := enc.Encode(e); err != nil {
+			return 0, err
+		}
+	}
+	if err := WriteStaged(dir, filepath.Join(dir, IndexFile), buf.Bytes(), 0600); err != nil {
+		return 0, err
+	}
+	return len(ix.entries) - len(keep), nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	dir := t.TempDir()
+	ix, err := ReadIndex(dir)
+	if err != nil || ix.NextSeq() != 1 || len(ix.Entries()) != 0 {
+		t.Fatalf("ReadIndex(empty) = %v, %v; want an empty index", ix, err)
+	}
+
+	captured := time.Date(2024, 5, 1, 14, 32, 7, 0, time.UTC)
+	for i, e := range []struct{ hash, path string }{
+		{"aaa", filepath.Join(dir, "first.png")},
+		{"bbb", filepath.Join(dir, "2024-05-01", "second.png")},
+		{"aaa", filepath.Join(dir, "again.png")},
+	} {
+		entry := IndexEntry{Hash: e.hash, Time: captured, Seq: i + 1, Size: 100, Width: 4, Height: 3, Window: "Ditto"}
+		if err := AppendIndex(dir, e.path, entry); err != nil {
+			t.Fatalf("AppendIndex(%s) error: %v", e.hash, err)
+		}
+	}
+	// A line cut short by a crash is skipped.
+	f, err := os.OpenFile(filepath.Join(dir, IndexFile), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"hash":"ccc","na`)
+	f.Close()
+
+	if ix, err = ReadIndex(dir); err != nil {
+		t.Fatalf("ReadIndex() error: %v", err)
+	}
+	if ix.NextSeq() != 4 || len(ix.Entries()) != 3 {
+		t.Errorf("NextSeq() = %d with %d entries, want 4 and 3", ix.NextSeq(), len(ix.Entries()))
+	}
+	if got, want := ix.Lookup("aaa"), filepath.Join(dir, "again.png"); got != want {
+		t.Errorf("Lookup(aaa) = %q, want the later entry %q", got, want)
+	}
+	if got := ix.Lookup("ccc"); got != "" {
+		t.Errorf("Lookup(ccc) = %q, want none", got)
+	}
+	e, ok := ix.Find(filepath.Join(dir, "2024-05-01", "second.png"))
+	if !ok || e.Hash != "bbb" || e.Name != "2024-05-01/second.png" || !e.Time.Equal(captured) || e.Width != 4 || e.Window != "Ditto" {
+		t.Errorf("Find(second.png) = %+v, %v; want its metadata", e, ok)
+	}
+}
+
+func TestPrune_CompactsIndex(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old", "mid", "new"} {
+		path := filepath.Join(dir, name+".png")
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(path, mod, mod)
+		if err := AppendIndex(dir, path, IndexEntry{Hash: name, Time: mod, Seq: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "new.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Prune(dir, Retention{KeepLast: 1}, now); err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	ix, err := ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []string
+	for _, e := range ix.Entries() {
+		hashes = append(hashes, e.Hash)
+	}
+	// old.png was pruned; new.png is gone too but holds the last number.
+	if len(hashes) != 2 || hashes[0] != "mid" || hashes[1] != "new" || ix.NextSeq() != 4 {
+		t.Errorf("index after Prune = %v, next %d; want [mid new], next 4", hashes, ix.NextSeq())
+	}
+}
+
+func TestCompactIndex(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"a", "b", "c", "d"} {
+		path := filepath.Join(dir, name+".png")
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := AppendIndex(dir, path, IndexEntry{Hash: name, Seq: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a", "c", "d"} {
+		if err := os.Remove(filepath.Join(dir, name+".png")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := CompactIndex(dir)
+	if err != nil || n != 2 {
+		t.Fatalf("CompactIndex() = %d, %v; want 2 dropped", n, err)
+	}
+	ix, err := ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d.png is gone too but holds the last number.
+	if got := ix.Entries(); len(got) != 2 || got[0].Hash != "b" || got[1].Hash != "d" || ix.NextSeq() != 5 {
+		t.Errorf("index after CompactIndex = %+v, next %d; want [b d], next 5", got, ix.NextSeq())
+	}
+	if n, err := CompactIndex(dir); err != nil || n != 0 {
+		t.Errorf("second CompactIndex() = %d, %v; want nothing to drop", n, err)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LatestLink is the symlink in an output directory that points at the
+// newest screenshot, and LatestPathFile the file holding its absolute path,
+// for tools that can't follow symlinks. Scans skip symlinks, so the link is
+// never counted as a screenshot of its own.
+const (
+	LatestLink     = "latest.png"
+	LatestPathFile = "latest"
+)
+
+// UpdateLatest points dir's LatestLink at path, which must be inside dir,
+// and with pathFile set also writes path to LatestPathFile with mode perm.
+// The link is created in the staging directory and renamed over the old
+// one, so readers always find either the previous or the new screenshot.
+// Nothing is written when the link already points at path.
+func UpdateLatest(dir, path string, pathFile bool, perm os.FileMode) error {
+	target, err := filepath.Rel(dir, path)
+	if err != nil {
+		return err
+	}
+	link := filepath.Join(dir, LatestLink)
+	if current, err := os.Readlink(link); err == nil && current == target {
+		return nil
+	}
+	if err := os.Mkdir(StagingDir(dir), 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+	tmp := filepath.Join(StagingDir(dir), fmt.Sprintf("%s.%d%s", LatestLink, os.Getpid(), stagingSuffix))
+	_ = os.Remove(tmp) // left by a crash
+	// Relative, so the link survives the output directory being moved.
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if !pathFile {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return WriteStaged(dir, filepath.Join(dir, LatestPathFile), []byte(abs+"\n"), perm)
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateLatest(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.png")
+	second := filepath.Join(dir, "2024-05-01", "b.png")
+	os.MkdirAll(filepath.Dir(second), 0700)
+	for _, p := range []string{first, second} {
+		if err := os.WriteFile(p, []byte(filepath.Base(p)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	link := filepath.Join(dir, LatestLink)
+	for _, path := range []string{first, second} {
+		if err := UpdateLatest(dir, path, true, 0600); err != nil {
+			t.Fatalf("UpdateLatest(%s) error: %v", path, err)
+		}
+		data, err := os.ReadFile(link)
+		if err != nil || string(data) != filepath.Base(path) {
+			t.Errorf("%s reads %q, %v; want the content of %s", LatestLink, data, err, path)
+		}
+		data, err = os.ReadFile(filepath.Join(dir, LatestPathFile))
+		if err != nil || strings.TrimSpace(string(data)) != path {
+			t.Errorf("%s holds %q, %v; want %s", LatestPathFile, data, err, path)
+		}
+	}
+	if target, _ := os.Readlink(link); filepath.IsAbs(target) {
+		t.Errorf("link target %q is absolute, want relative", target)
+	}
+
+	// The link is not a screenshot of its own.
+	snap, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := snap.Usage(); n != 2 {
+		t.Errorf("Scan() found %d screenshots with the link, want 2", n)
+	}
+}
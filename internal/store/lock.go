@@ -0,0 +1,63 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// lockName is the lock file in the staging directory that orders saves
+// against readers. Saves hold it exclusively while committing a screenshot,
+// readers share it while taking a snapshot.
+const lockName = "lock"
+
+// lockWait bounds how long either side waits for the other. Locking is
+// best-effort: a capture is never dropped, nor a command stuck, because
+// someone holds the lock for too long. Declared as a var for tests.
+var lockWait = 2 * time.Second
+
+// lockPoll is how often a blocked lock is retried.
+const lockPoll = 10 * time.Millisecond
+
+// LockWrite takes the output directory root's lock exclusively, for a save
+// that must not interleave with a reader's snapshot. The returned function
+// releases it.
+func LockWrite(root string) (unlock func()) {
+	return lock(root, syscall.LOCK_EX)
+}
+
+// lockRead takes the output directory root's lock shared.
+func lockRead(root string) (unlock func()) {
+	return lock(root, syscall.LOCK_SH)
+}
+
+// lock flocks root's lock file with how, waiting up to lockWait. When the
+// lock can't be had (no such directory, read-only, timeout) it returns a
+// no-op and the caller goes ahead unlocked.
+func lock(root string, how int) func() {
+	dir := StagingDir(root)
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return func() {}
+	}
+	f, err := os.OpenFile(filepath.Join(dir, lockName), os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return func() {}
+	}
+	deadline := time.Now().Add(lockWait)
+	for {
+		err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			return func() {
+				_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				_ = f.Close()
+			}
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) || time.Now().After(deadline) {
+			_ = f.Close()
+			return func() {}
+		}
+		time.Sleep(lockPoll)
+	}
+}
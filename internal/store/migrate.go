@@ -0,0 +1,277 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// MoveOptions tunes Move.
+type MoveOptions struct {
+	// OnCollision decides what happens to a screenshot whose name is taken
+	// in newDir by a different file. Empty means CollisionSuffix.
+	OnCollision CollisionPolicy
+	// DirMode is the permission bits of the directories Move creates in
+	// newDir. Zero means 0700.
+	DirMode os.FileMode
+}
+
+// MoveResult summarizes Move.
+type MoveResult struct {
+	Moved   int      // screenshots moved into newDir
+	Skipped []string // left in oldDir by CollisionSkip, relative to it
+}
+
+// Move relocates every screenshot under oldDir (including daily
+// subdirectories) to the same relative path under newDir. A file already at
+// the destination with the same content is kept and the source copy dropped;
+// a different file there, which --filename-template names and collision
+// suffixes make possible, is resolved by opts.OnCollision as a capture's would
+// be. Subdirectories left empty are removed. The capture index entries of the
+// moved files are appended to newDir's index under their new names, and
+// LatestLink (with LatestPathFile, if there is one) is recreated in newDir
+// rather than moved.
+func Move(oldDir, newDir string, opts MoveOptions) (MoveResult, error) {
+	var res MoveResult
+	dirMode := opts.DirMode
+	if dirMode == 0 {
+		dirMode = 0700
+	}
+	oldAbs, err := filepath.Abs(oldDir)
+	if err != nil {
+		return res, err
+	}
+	newAbs, err := filepath.Abs(newDir)
+	if err != nil {
+		return res, err
+	}
+	if oldAbs == newAbs {
+		return res, fmt.Errorf("source and destination are the same directory")
+	}
+	if strings.HasPrefix(newAbs, oldAbs+string(filepath.Separator)) {
+		return res, fmt.Errorf("destination %s is inside %s", newAbs, oldAbs)
+	}
+
+	// The link is relative and may point at a file moved before it, so it
+	// is read now and made anew at the end.
+	latest, _ := os.Readlink(filepath.Join(oldAbs, LatestLink))
+	pathFile, _ := os.Stat(filepath.Join(oldAbs, LatestPathFile))
+
+	// Where each file of oldDir is now in newDir, by slash-separated
+	// relative name: moved holds the files moved, placed those deduplicated
+	// against an identical file as well.
+	moved := make(map[string]string)
+	placed := make(map[string]string)
+	for _, pattern := range screenshotPatterns {
+		matches, err := filepath.Glob(filepath.Join(oldAbs, pattern))
+		if err != nil {
+			return res, err
+		}
+		for _, src := range matches {
+			if info, err := os.Lstat(src); err != nil || !info.Mode().IsRegular() {
+				continue // gone, or a symlink such as LatestLink
+			}
+			rel, err := filepath.Rel(oldAbs, src)
+			if err != nil {
+				return res, err
+			}
+			dst := filepath.Join(newAbs, rel)
+			if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+				return res, err
+			}
+			dst, move, err := moveTarget(src, dst, opts.OnCollision)
+			if err != nil {
+				return res, fmt.Errorf("move %s: %w", rel, err)
+			}
+			if dst == "" {
+				res.Skipped = append(res.Skipped, rel)
+				continue
+			}
+			newRel, err := filepath.Rel(newAbs, dst)
+			if err != nil {
+				return res, err
+			}
+			if move {
+				if err := moveFile(src, dst, newAbs); err != nil {
+					return res, fmt.Errorf("move %s: %w", rel, err)
+				}
+				res.Moved++
+				moved[filepath.ToSlash(rel)] = filepath.ToSlash(newRel)
+			} else if err := os.Remove(src); err != nil {
+				return res, err
+			}
+			placed[filepath.ToSlash(rel)] = filepath.ToSlash(newRel)
+		}
+	}
+
+	if err := moveIndex(oldAbs, newAbs, moved, len(res.Skipped) > 0); err != nil {
+		return res, fmt.Errorf("move %s: %w", IndexFile, err)
+	}
+	if err := moveLatest(oldAbs, newAbs, placed[filepath.ToSlash(filepath.Clean(latest))], pathFile); err != nil {
+		return res, fmt.Errorf("move %s: %w", LatestLink, err)
+	}
+
+	// Best-effort: drop now-empty daily directories, then the old root.
+	if dirs, err := filepath.Glob(filepath.Join(oldAbs, "*")); err == nil {
+		for _, d := range dirs {
+			if info, err := os.Lstat(d); err == nil && info.IsDir() {
+				_ = os.Remove(d) // fails harmlessly on non-empty dirs
+			}
+		}
+	}
+	_ = os.Remove(oldAbs)
+	return res, nil
+}
+
+// moveTarget picks where src goes given its preferred destination dst,
+// applying policy when dst is taken by different content. It returns the
+// path src ends up at and whether it still has to be moved there (not when
+// an identical file is already there); an empty path means CollisionSkip
+// leaves it where it is.
+func moveTarget(src, dst string, policy CollisionPolicy) (target string, move bool, err error) {
+	same, exists, err := sameFile(src, dst)
+	switch {
+	case err != nil:
+		return "", false, err
+	case !exists:
+		return dst, true, nil
+	case same:
+		return dst, false, nil
+	}
+
+	switch policy {
+	case CollisionOverwrite:
+		return dst, true, nil
+	case CollisionSkip:
+		return "", false, nil
+	}
+
+	for i := 1; i <= MaxCollisionSuffix; i++ {
+		candidate := CollisionName(dst, i)
+		same, exists, err := sameFile(src, candidate)
+		switch {
+		case err != nil:
+			return "", false, err
+		case !exists:
+			return candidate, true, nil
+		case same:
+			return candidate, false, nil
+		}
+	}
+	return "", false, fmt.Errorf("no free name for %s after %d suffixes", filepath.Base(dst), MaxCollisionSuffix)
+}
+
+// sameFile reports whether dst exists and, if so, whether it holds the same
+// bytes as src.
+func sameFile(src, dst string) (same, exists bool, err error) {
+	dstInfo, err := os.Stat(dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, true, err
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return false, true, nil
+	}
+	a, err := os.Open(src) // #nosec G304 -- src comes from globbing the output directory
+	if err != nil {
+		return false, true, err
+	}
+	defer a.Close()
+	b, err := os.Open(dst) // #nosec G304 -- dst is inside the destination directory
+	if err != nil {
+		return false, true, err
+	}
+	defer b.Close()
+	same, err = SameContent(a, b)
+	return same, true, err
+}
+
+// renameFile is os.Rename, declared as a var so tests can make it fail as
+// between filesystems.
+var renameFile = os.Rename
+
+// moveFile renames src to dst, falling back to a copy through root's staging
+// directory and a delete when they live on different filesystems (e.g. /tmp
+// to a /mnt/c drvfs mount), so an interrupted copy never leaves a truncated
+// screenshot at dst.
+func moveFile(src, dst, root string) error {
+	err := renameFile(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src) // #nosec G304 -- src comes from globbing the output directory
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := CopyStaged(root, dst, in, info.Mode().Perm()); err != nil {
+		return err
+	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime()) // keep Latest() ordering
+	return os.Remove(src)
+}
+
+// moveIndex appends the entries of oldDir's index for the files in moved
+// (old name to new name, slash-separated) to newDir's index under their new
+// names, then removes the old index, or, when files were left behind
+// (kept), drops only the entries of the files that are gone.
+func moveIndex(oldDir, newDir string, moved map[string]string, kept bool) error {
+	ix, err := ReadIndex(oldDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range ix.Entries() {
+		name, ok := moved[e.Name]
+		if !ok {
+			continue // gone before the move, or not moved
+		}
+		if err := AppendIndex(newDir, filepath.Join(newDir, filepath.FromSlash(name)), e); err != nil {
+			return err
+		}
+	}
+	if kept {
+		_, err := compactIndex(oldDir)
+		return err
+	}
+	err = os.Remove(filepath.Join(oldDir, IndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// moveLatest points newDir's LatestLink at target, the old link's screenshot
+// relative to newDir ("" when there was none or it stayed in oldDir), and
+// writes LatestPathFile there too when oldDir had one (pathFile, nil if
+// not). The old link and path file are removed.
+func moveLatest(oldDir, newDir, target string, pathFile os.FileInfo) error {
+	_ = os.Remove(filepath.Join(oldDir, LatestLink))
+	_ = os.Remove(filepath.Join(oldDir, LatestPathFile))
+	if target == "" {
+		return nil
+	}
+	path := filepath.Join(newDir, filepath.FromSlash(target))
+	if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+		return nil // gone since; the next capture makes a new one
+	}
+	perm := os.FileMode(0600)
+	if pathFile != nil {
+		perm = pathFile.Mode().Perm()
+	}
+	return UpdateLatest(newDir, path, pathFile != nil, perm)
+}
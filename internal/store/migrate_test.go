@@ -0,0 +1,198 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMove(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := filepath.Join(t.TempDir(), "new")
+	mod := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	writeAt(t, filepath.Join(oldDir, "a.png"), mod)
+	writeAt(t, filepath.Join(oldDir, "2024-05-01", "b.png"), mod)
+	writeAt(t, filepath.Join(oldDir, "dup.png"), mod)
+	writeAt(t, filepath.Join(newDir, "dup.png"), mod)
+	if err := AppendIndex(oldDir, filepath.Join(oldDir, "2024-05-01", "b.png"), IndexEntry{Hash: "bbb", Window: "Ditto"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Move(oldDir, newDir, MoveOptions{})
+	if err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+	if res.Moved != 2 {
+		t.Errorf("Move() moved %d files, want 2", res.Moved)
+	}
+	for _, rel := range []string{"a.png", filepath.Join("2024-05-01", "b.png"), "dup.png"} {
+		info, err := os.Stat(filepath.Join(newDir, rel))
+		if err != nil {
+			t.Errorf("%s missing from destination: %v", rel, err)
+			continue
+		}
+		if !info.ModTime().Equal(mod) {
+			t.Errorf("%s mtime = %v, want %v", rel, info.ModTime(), mod)
+		}
+	}
+	ix, err := ReadIndex(newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := ix.Find(filepath.Join(newDir, "2024-05-01", "b.png")); !ok || e.Window != "Ditto" {
+		t.Errorf("index entry of b.png after Move = %+v, %v; want it carried over", e, ok)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("old directory should be removed once empty, stat err = %v", err)
+	}
+}
+
+func TestMove_DirMode(t *testing.T) {
+	for _, want := range []os.FileMode{0, 0750} {
+		oldDir := t.TempDir()
+		newDir := filepath.Join(t.TempDir(), "new")
+		writeAt(t, filepath.Join(oldDir, "2024-05-01", "b.png"), time.Now())
+
+		if _, err := Move(oldDir, newDir, MoveOptions{DirMode: want}); err != nil {
+			t.Fatalf("Move(DirMode %04o) error: %v", want, err)
+		}
+		if want == 0 {
+			want = 0700
+		}
+		for _, dir := range []string{newDir, filepath.Join(newDir, "2024-05-01")} {
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != want {
+				t.Errorf("mode of %s = %04o, want %04o", dir, got, want)
+			}
+		}
+	}
+}
+
+func TestMove_NameTakenByDifferentFile(t *testing.T) {
+	for _, tc := range []struct {
+		policy   CollisionPolicy
+		moved    string // where the old file ends up in newDir, "" if left behind
+		existing string // what newDir's shot.png holds afterwards
+	}{
+		{"", "shot-1.png", "existing"},
+		{CollisionSuffix, "shot-1.png", "existing"},
+		{CollisionOverwrite, "shot.png", "migrated"},
+		{CollisionSkip, "", "existing"},
+	} {
+		oldDir := t.TempDir()
+		newDir := t.TempDir()
+		src := filepath.Join(oldDir, "shot.png")
+		if err := os.WriteFile(src, []byte("migrated"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(newDir, "shot.png"), []byte("existing"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := AppendIndex(oldDir, src, IndexEntry{Hash: "old", Window: "Ditto"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := AppendIndex(newDir, filepath.Join(newDir, "shot.png"), IndexEntry{Hash: "new"}); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Move(oldDir, newDir, MoveOptions{OnCollision: tc.policy})
+		if err != nil {
+			t.Fatalf("Move(%q) error: %v", tc.policy, err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(newDir, "shot.png")); string(data) != tc.existing {
+			t.Errorf("Move(%q): shot.png holds %q, want %q", tc.policy, data, tc.existing)
+		}
+		ix, err := ReadIndex(newDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.moved == "" {
+			if len(res.Skipped) != 1 || res.Moved != 0 {
+				t.Errorf("Move(%q) = %+v, want shot.png skipped", tc.policy, res)
+			}
+			if data, err := os.ReadFile(src); err != nil || string(data) != "migrated" {
+				t.Errorf("Move(%q) should leave the old file in place: %q, %v", tc.policy, data, err)
+			}
+			if e, ok := ix.Find(filepath.Join(newDir, "shot.png")); !ok || e.Hash != "new" {
+				t.Errorf("Move(%q): index entry of shot.png = %+v, want the existing file's", tc.policy, e)
+			}
+			if old, err := ReadIndex(oldDir); err != nil || old.Lookup("old") != src {
+				t.Errorf("Move(%q): the old index should keep the skipped file (err %v)", tc.policy, err)
+			}
+			continue
+		}
+		moved := filepath.Join(newDir, tc.moved)
+		if data, err := os.ReadFile(moved); err != nil || string(data) != "migrated" {
+			t.Errorf("Move(%q): %s = %q, %v; want the migrated file", tc.policy, tc.moved, data, err)
+		}
+		if e, ok := ix.Find(moved); !ok || e.Hash != "old" || e.Window != "Ditto" {
+			t.Errorf("Move(%q): index entry of %s = %+v, want the migrated file's", tc.policy, tc.moved, e)
+		}
+		if tc.moved != "shot.png" {
+			if e, ok := ix.Find(filepath.Join(newDir, "shot.png")); !ok || e.Hash != "new" {
+				t.Errorf("Move(%q): index entry of shot.png = %+v, want the existing file's", tc.policy, e)
+			}
+		}
+	}
+}
+
+func TestMove_RejectsNestedDestination(t *testing.T) {
+	dir := t.TempDir()
+	for _, dst := range []string{dir, filepath.Join(dir, "sub")} {
+		if _, err := Move(dir, dst, MoveOptions{}); err == nil {
+			t.Errorf("Move(%q, %q) should fail", dir, dst)
+		}
+	}
+}
+
+func TestMove_LatestLinkAcrossFilesystems(t *testing.T) {
+	orig := renameFile
+	defer func() { renameFile = orig }()
+	renameFile = func(string, string) error { return &os.LinkError{Op: "rename", Err: syscall.EXDEV} }
+
+	for _, daily := range []bool{false, true} {
+		oldDir := t.TempDir()
+		newDir := filepath.Join(t.TempDir(), "new")
+		mod := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		rel := "a.png"
+		if daily {
+			rel = filepath.Join("2024-05-01", "a.png")
+		}
+		shot := filepath.Join(oldDir, rel)
+		writeAt(t, shot, mod)
+		if err := AppendIndex(oldDir, shot, IndexEntry{Hash: "aaa"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateLatest(oldDir, shot, true, 0640); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Move(oldDir, newDir, MoveOptions{})
+		if err != nil {
+			t.Fatalf("Move() with daily=%v error: %v", daily, err)
+		}
+		if res.Moved != 1 {
+			t.Errorf("Move() with daily=%v moved %d files, want only the screenshot", daily, res.Moved)
+		}
+		if target, err := os.Readlink(filepath.Join(newDir, LatestLink)); err != nil || target != rel {
+			t.Errorf("%s in the new directory = %q, %v; want a link to %s", LatestLink, target, err, rel)
+		}
+		data, err := os.ReadFile(filepath.Join(newDir, LatestPathFile))
+		if err != nil || strings.TrimSpace(string(data)) != filepath.Join(newDir, rel) {
+			t.Errorf("%s in the new directory = %q, %v; want the new path", LatestPathFile, data, err)
+		}
+		if ix, err := ReadIndex(newDir); err != nil || ix.Lookup("aaa") != filepath.Join(newDir, rel) {
+			t.Errorf("index not carried over to the new directory (err %v)", err)
+		}
+		if _, err := os.Lstat(filepath.Join(oldDir, LatestLink)); !os.IsNotExist(err) {
+			t.Errorf("old %s should be removed, lstat err = %v", LatestLink, err)
+		}
+	}
+}
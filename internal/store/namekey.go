@@ -0,0 +1,66 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nameKeySize is the length of generated name keys, the HMAC-SHA256 block
+// size's worth of entropy being more than enough.
+const nameKeySize = 32
+
+// LoadOrCreateNameKey reads the secret key for private file names from path,
+// generating a random one on first use. The file holds the key hex-encoded
+// and must only be readable by its owner: anyone holding the key can tell
+// which image a name stands for.
+func LoadOrCreateNameKey(path string) ([]byte, error) {
+	key, err := ReadNameKey(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+
+	key = make([]byte, nameKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 -- path is the user's config dir
+	if errors.Is(err, os.ErrExist) {
+		return ReadNameKey(path) // another process created it first
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintln(f, hex.EncodeToString(key)); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return key, f.Close()
+}
+
+// ReadNameKey reads an existing name key from path without creating one.
+func ReadNameKey(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible to other users (mode %o), chmod 600 it", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the user's config dir
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) < 16 {
+		return nil, fmt.Errorf("%s does not hold a hex key of at least 16 bytes", path)
+	}
+	return key, nil
+}
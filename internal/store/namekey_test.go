@@ -0,0 +1,36 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOrCreateNameKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg", "name.key")
+
+	key, err := LoadOrCreateNameKey(path)
+	if err != nil {
+		t.Fatalf("first call error: %v", err)
+	}
+	if len(key) != nameKeySize {
+		t.Errorf("key length = %d, want %d", len(key), nameKeySize)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file mode = %v (err %v), want 0600", info.Mode().Perm(), err)
+	}
+
+	again, err := LoadOrCreateNameKey(path)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Errorf("second call = %x, %v; want the stored key", again, err)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateNameKey(path); err == nil {
+		t.Error("a key readable by others should be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Retention bounds what an output directory keeps. A zero field sets no
+// bound; the zero Retention keeps everything.
+type Retention struct {
+	MaxAge   time.Duration // screenshots modified longer ago go
+	KeepLast int           // only the newest KeepLast screenshots stay
+	MaxBytes int64         // the newest screenshots totalling at most MaxBytes stay
+}
+
+// IsZero reports whether r keeps everything.
+func (r Retention) IsZero() bool {
+	return r.MaxAge <= 0 && r.KeepLast <= 0 && r.MaxBytes <= 0
+}
+
+// Expired returns the screenshots in s that r does not keep at now, oldest
+// first. Files at the paths in protect (such as the screenshot the clipboard
+// refers to) are never returned, though they count against KeepLast and
+// MaxBytes like any other.
+func (r Retention) Expired(s *Snapshot, now time.Time, protect ...string) []File {
+	if r.IsZero() {
+		return nil
+	}
+	var protected []os.FileInfo
+	for _, p := range protect {
+		if info, err := os.Stat(p); err == nil {
+			protected = append(protected, info)
+		}
+	}
+	var expired []File
+	var total int64
+	for i, f := range s.Newest() {
+		total += f.Size
+		keep := (r.MaxAge <= 0 || now.Sub(f.ModTime) <= r.MaxAge) &&
+			(r.KeepLast <= 0 || i < r.KeepLast) &&
+			(r.MaxBytes <= 0 || total <= r.MaxBytes)
+		if keep || isProtected(f.Path, protected) {
+			continue
+		}
+		expired = append(expired, f)
+	}
+	slices.Reverse(expired)
+	return expired
+}
+
+// isProtected reports whether path is one of the protected files.
+func isProtected(path string, protected []os.FileInfo) bool {
+	if len(protected) == 0 {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	for _, p := range protected {
+		if os.SameFile(info, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune deletes the screenshots in dir that r does not keep at now, except
+// those at the paths in protect, and returns them. It holds the directory's
+// write lock, so a reader's snapshot never lists a half-pruned directory.
+// Daily subdirectories left empty are removed too, and the capture index
+// forgets the removed files.
+func Prune(dir string, r Retention, now time.Time, protect ...string) ([]File, error) {
+	unlock := LockWrite(dir)
+	defer unlock()
+	snap, err := scan(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []File
+	for _, f := range r.Expired(snap, now, protect...) {
+		if err := os.Remove(f.Path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // removed concurrently
+			}
+			return removed, err
+		}
+		removed = append(removed, f)
+		if sub := filepath.Dir(f.Path); sub != filepath.Clean(dir) {
+			_ = os.Remove(sub) // fails while the day still has screenshots
+		}
+	}
+	if len(removed) > 0 {
+		_, _ = compactIndex(dir) // best-effort: stale entries are only skipped
+	}
+	return removed, nil
+}
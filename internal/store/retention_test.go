@@ -0,0 +1,77 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// names returns the base names of files.
+func names(files []File) []string {
+	var out []string
+	for _, f := range files {
+		out = append(out, filepath.Base(f.Path))
+	}
+	return out
+}
+
+func TestRetention_Expired(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"a.png", "b.png", "c.png", "d.png"} { // a is the oldest
+		writeAt(t, filepath.Join(dir, name), now.Add(-time.Duration(4-i)*24*time.Hour))
+	}
+	snap, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		r    Retention
+		want []string
+	}{
+		{"zero", Retention{}, nil},
+		{"age", Retention{MaxAge: 50 * time.Hour}, []string{"a.png", "b.png"}},
+		{"count", Retention{KeepLast: 3}, []string{"a.png"}},
+		{"size", Retention{MaxBytes: 2}, []string{"a.png", "b.png"}},
+		{"any bound", Retention{MaxAge: 80 * time.Hour, KeepLast: 2}, []string{"a.png", "b.png"}},
+	}
+	for _, tt := range tests {
+		got := names(tt.r.Expired(snap, now))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Expired() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	got := names(Retention{KeepLast: 1}.Expired(snap, now, filepath.Join(dir, ".", "b.png")))
+	if !slices.Equal(got, []string{"a.png", "c.png"}) {
+		t.Errorf("Expired() with b.png protected = %v, want [a.png c.png]", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	writeAt(t, filepath.Join(dir, "2024-05-01", "old.png"), now.Add(-9*24*time.Hour))
+	writeAt(t, filepath.Join(dir, "2024-05-10", "new.png"), now)
+	writeAt(t, filepath.Join(dir, "notes.txt"), now.Add(-30*24*time.Hour))
+
+	removed, err := Prune(dir, Retention{MaxAge: 7 * 24 * time.Hour}, now)
+	if err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	if got := names(removed); len(got) != 1 || got[0] != "old.png" {
+		t.Errorf("Prune() removed %v, want [old.png]", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-05-01")); !os.IsNotExist(err) {
+		t.Errorf("emptied daily directory still there: %v", err)
+	}
+	for _, keep := range []string{"2024-05-10/new.png", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, keep)); err != nil {
+			t.Errorf("%s removed: %v", keep, err)
+		}
+	}
+}
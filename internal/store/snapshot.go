@@ -0,0 +1,93 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// File is one screenshot in a Snapshot.
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Snapshot is the set of screenshots in an output directory at one moment.
+type Snapshot struct {
+	Dir   string
+	Files []File
+}
+
+// View takes a snapshot of dir and calls fn with it while no save can
+// complete, so whatever fn reads alongside (such as the daemon's capture
+// counter) agrees with the snapshot. fn should be quick: saves wait for it.
+func View(dir string, fn func(*Snapshot) error) error {
+	unlock := lockRead(dir)
+	defer unlock()
+	snap, err := scan(dir)
+	if err != nil {
+		return err
+	}
+	return fn(snap)
+}
+
+// Scan returns a consistent snapshot of the screenshots in dir.
+func Scan(dir string) (*Snapshot, error) {
+	var snap *Snapshot
+	err := View(dir, func(s *Snapshot) error {
+		snap = s
+		return nil
+	})
+	return snap, err
+}
+
+// scan lists dir's screenshots. Staged files never match the patterns, and
+// symlinks such as LatestLink are skipped: they point at files listed anyway.
+func scan(dir string) (*Snapshot, error) {
+	snap := &Snapshot{Dir: dir}
+	for _, pattern := range screenshotPatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue // removed concurrently, a symlink or not a file
+			}
+			snap.Files = append(snap.Files, File{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+		}
+	}
+	return snap, nil
+}
+
+// Latest returns the most recently modified screenshot, or ErrEmpty.
+func (s *Snapshot) Latest() (File, error) {
+	var latest File
+	for _, f := range s.Files {
+		if latest.Path == "" || f.ModTime.After(latest.ModTime) {
+			latest = f
+		}
+	}
+	if latest.Path == "" {
+		return File{}, ErrEmpty
+	}
+	return latest, nil
+}
+
+// Newest returns the snapshot's files, most recently modified first.
+func (s *Snapshot) Newest() []File {
+	files := slices.Clone(s.Files)
+	slices.SortStableFunc(files, func(a, b File) int { return b.ModTime.Compare(a.ModTime) })
+	return files
+}
+
+// Usage returns how many screenshots the snapshot holds and their total size.
+func (s *Snapshot) Usage() (files int, bytes int64) {
+	for _, f := range s.Files {
+		bytes += f.Size
+	}
+	return len(s.Files), bytes
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	writeAt(t, filepath.Join(dir, "a.png"), base)
+	writeAt(t, filepath.Join(dir, "2024-05-02", "b.png"), base.Add(time.Hour))
+	writeAt(t, filepath.Join(StagingDir(dir), "c.png.1.part"), base.Add(2*time.Hour))
+
+	snap, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, bytes := snap.Usage(); files != 2 || bytes != 2 {
+		t.Errorf("Usage() = %d, %d; want 2 files, 2 bytes (staged files excluded)", files, bytes)
+	}
+	if latest, err := snap.Latest(); err != nil || filepath.Base(latest.Path) != "b.png" {
+		t.Errorf("Latest() = %v, %v; want b.png", latest.Path, err)
+	}
+}
+
+func TestSnapshot_Newest(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	snap := &Snapshot{Files: []File{
+		{Path: "a.png", ModTime: base},
+		{Path: "c.png", ModTime: base.Add(2 * time.Hour)},
+		{Path: "b.png", ModTime: base.Add(time.Hour)},
+	}}
+	var got []string
+	for _, f := range snap.Newest() {
+		got = append(got, f.Path)
+	}
+	if want := []string{"c.png", "b.png", "a.png"}; !slices.Equal(got, want) {
+		t.Errorf("Newest() = %v, want %v", got, want)
+	}
+}
+
+func TestView_HoldsOffSaves(t *testing.T) {
+	dir := t.TempDir()
+	var saved atomic.Bool
+	done := make(chan struct{})
+
+	err := View(dir, func(*Snapshot) error {
+		go func() {
+			defer close(done)
+			unlock := LockWrite(dir)
+			saved.Store(true)
+			unlock()
+		}()
+		time.Sleep(100 * time.Millisecond)
+		if saved.Load() {
+			t.Error("a save committed while a view was open")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if !saved.Load() {
+		t.Error("the save never went through")
+	}
+}
+
+func TestView_GivesUpOnStuckWriter(t *testing.T) {
+	orig := lockWait
+	lockWait = 50 * time.Millisecond
+	defer func() { lockWait = orig }()
+
+	dir := t.TempDir()
+	unlock := LockWrite(dir)
+	defer unlock()
+
+	start := time.Now()
+	if _, err := Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Scan() waited %s for a stuck writer", elapsed)
+	}
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// StagingDirName is the directory under an output directory where files are
+// written before being renamed into place. Being inside the output directory
+// keeps it on the same filesystem, so the final rename is atomic and a
+// screenshot is either absent or complete: never half-written in the output
+// directory, in a listing, or on the clipboard.
+const StagingDirName = ".staging"
+
+// stagingSuffix marks staged files. It keeps them out of the screenshot
+// patterns should anything ever list the staging directory.
+const stagingSuffix = ".part"
+
+// StagingDir returns the staging directory of the output directory root.
+func StagingDir(root string) string {
+	return filepath.Join(root, StagingDirName)
+}
+
+// WriteStaged writes data to dst via the staging directory of root, which
+// must be on the same filesystem as dst (dst is normally inside root).
+func WriteStaged(root, dst string, data []byte, perm os.FileMode) error {
+	return CopyStaged(root, dst, bytes.NewReader(data), perm)
+}
+
+// CopyStaged copies r to dst via the staging directory of root: the data is
+// written and synced to a temporary file there, which is then renamed to dst.
+// On failure the temporary file is removed and dst is left untouched.
+func CopyStaged(root, dst string, r io.Reader, perm os.FileMode) error {
+	f, err := Stage(root, filepath.Base(dst))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Discard()
+		return err
+	}
+	return f.Commit(dst, perm)
+}
+
+// Staged is a file being written in a staging directory, for writers that
+// only learn the final name once the data is written (e.g. a name derived
+// from a hash of the content). Commit moves it into place, Discard drops it.
+type Staged struct {
+	*os.File
+	done bool
+}
+
+// Stage creates an empty staged file in root's staging directory. name only
+// makes the temporary file recognizable.
+func Stage(root, name string) (*Staged, error) {
+	// Mkdir, not MkdirAll: a missing root (e.g. an unmounted drive) must
+	// fail the write rather than be recreated here.
+	dir := StagingDir(root)
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("create staging directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, name+".*"+stagingSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return &Staged{File: f}, nil
+}
+
+// Commit syncs the file, gives it mode perm and renames it to dst, which must
+// be on the same filesystem as the staging directory. On failure the file is
+// discarded and dst is left untouched.
+func (s *Staged) Commit(dst string, perm os.FileMode) (err error) {
+	defer func() {
+		if err != nil {
+			s.Discard()
+		}
+	}()
+	if err = s.Chmod(perm); err != nil {
+		return err
+	}
+	if err = s.Sync(); err != nil {
+		return err
+	}
+	if err = s.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(s.Name(), dst); err != nil {
+		return err
+	}
+	s.done = true
+	return nil
+}
+
+// Discard closes and removes the file. It does nothing after a successful
+// Commit, so it can be deferred.
+func (s *Staged) Discard() {
+	if s.done {
+		return
+	}
+	s.done = true
+	_ = s.Close()
+	_ = os.Remove(s.Name())
+}
+
+// CleanStaging removes whatever a crashed or killed writer left in root's
+// staging directory, except the lock file, and returns how many files it
+// removed. Staged files are incomplete by definition (a finished one has been
+// renamed away), so nothing of value is lost. Only call it while no other
+// process writes to root.
+func CleanStaging(root string) (int, error) {
+	entries, err := os.ReadDir(StagingDir(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		if e.Name() == lockName {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(StagingDir(root), e.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
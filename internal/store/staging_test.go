@@ -0,0 +1,102 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk on fire") }
+
+func TestWriteStaged(t *testing.T) {
+	root := t.TempDir()
+	dst := filepath.Join(root, "2024-05-01", "abc.png")
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteStaged(root, dst, []byte("png data"), 0644); err != nil {
+		t.Fatalf("WriteStaged() error: %v", err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// A failed copy leaves neither dst nor anything in staging.
+	failed := filepath.Join(root, "def.png")
+	if err := CopyStaged(root, failed, failingReader{}, 0644); err == nil {
+		t.Fatal("CopyStaged() with a failing reader should fail")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("%s should not exist after a failed write", failed)
+	}
+	if entries, _ := os.ReadDir(StagingDir(root)); len(entries) != 0 {
+		t.Errorf("staging should be empty, has %d entries", len(entries))
+	}
+
+	// A missing output dir is an error, not something to recreate.
+	gone := filepath.Join(root, "unmounted")
+	if err := WriteStaged(gone, filepath.Join(gone, "x.png"), nil, 0644); err == nil {
+		t.Error("WriteStaged() into a missing root should fail")
+	}
+}
+
+func TestCleanStaging(t *testing.T) {
+	root := t.TempDir()
+	if n, err := CleanStaging(root); n != 0 || err != nil {
+		t.Fatalf("CleanStaging(no staging dir) = %d, %v", n, err)
+	}
+
+	if err := os.Mkdir(StagingDir(root), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.png.123.part", "b.png.456.part", lockName} {
+		if err := os.WriteFile(filepath.Join(StagingDir(root), name), []byte("half"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := CleanStaging(root); n != 2 || err != nil {
+		t.Errorf("CleanStaging() = %d, %v; want 2 files removed", n, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(root, "*", "*.part")); len(matches) != 0 {
+		t.Errorf("leftovers remain: %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(StagingDir(root), lockName)); err != nil {
+		t.Errorf("lock file removed: %v", err)
+	}
+}
+
+func TestStage(t *testing.T) {
+	root := t.TempDir()
+	f, err := Stage(root, "shot.png")
+	if err != nil {
+		t.Fatalf("Stage() error: %v", err)
+	}
+	if _, err := f.WriteString("png data"); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(root, "named-after-the-content.png")
+	if err := f.Commit(dst, 0644); err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	f.Discard() // no-op once committed
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "png data" {
+		t.Errorf("%s = %q, %v", dst, data, err)
+	}
+
+	dropped, err := Stage(root, "shot.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped.Discard()
+	if entries, _ := os.ReadDir(StagingDir(root)); len(entries) != 0 {
+		t.Errorf("staging should be empty after Discard, has %d entries", len(entries))
+	}
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+	"image/png"
+	"os"
+)
+
+// ErrEmpty is returned when the output directory holds no screenshots.
+var ErrEmpty = errors.New("no screenshots found")
+
+// screenshotPatterns match PNGs at the top of the output directory and one
+// level down in daily subdirectories. The extension matches in any case:
+// Windows tools may save or rename files as .PNG on a /mnt/c output dir.
+var screenshotPatterns = []string{"*.[pP][nN][gG]", "*/*.[pP][nN][gG]"}
+
+// Latest returns the path of the most recently modified screenshot in dir.
+func Latest(dir string) (string, error) {
+	snap, err := Scan(dir)
+	if err != nil {
+		return "", err
+	}
+	f, err := snap.Latest()
+	return f.Path, err
+}
+
+// Usage returns how many screenshots dir holds and their total size in bytes.
+func Usage(dir string) (files int, bytes int64, err error) {
+	snap, err := Scan(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	files, bytes = snap.Usage()
+	return files, bytes, nil
+}
+
+// Dimensions returns the width and height of the PNG at path, read from its
+// header without decoding the image.
+func Dimensions(path string) (width, height int, err error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from a scan of the output dir
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	cfg, err := png.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeAt(t *testing.T, path string, mod time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	writeAt(t, filepath.Join(dir, "old.png"), base)
+	writeAt(t, filepath.Join(dir, "2024-05-02", "newest.png"), base.Add(2*time.Hour))
+	writeAt(t, filepath.Join(dir, "middle.png"), base.Add(time.Hour))
+	writeAt(t, filepath.Join(dir, "notes.txt"), base.Add(3*time.Hour))
+
+	got, err := Latest(dir)
+	if err != nil {
+		t.Fatalf("Latest() error: %v", err)
+	}
+	if want := filepath.Join(dir, "2024-05-02", "newest.png"); got != want {
+		t.Errorf("Latest() = %q, want %q", got, want)
+	}
+}
+
+func TestLatest_Empty(t *testing.T) {
+	for _, dir := range []string{t.TempDir(), "/nonexistent/path"} {
+		if _, err := Latest(dir); !errors.Is(err, ErrEmpty) {
+			t.Errorf("Latest(%q) error = %v, want ErrEmpty", dir, err)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	dir := t.TempDir()
+	mod := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	writeAt(t, filepath.Join(dir, "a.png"), mod)
+	writeAt(t, filepath.Join(dir, "2024-05-01", "b.png"), mod)
+	writeAt(t, filepath.Join(dir, "C.PNG"), mod) // saved by a Windows tool
+	writeAt(t, filepath.Join(dir, "notes.txt"), mod)
+
+	files, bytes, err := Usage(dir)
+	if err != nil {
+		t.Fatalf("Usage() error: %v", err)
+	}
+	if files != 3 || bytes != 3 {
+		t.Errorf("Usage() = %d files, %d bytes; want 3, 3", files, bytes)
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.png")
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w, h, err := Dimensions(path); err != nil || w != 3 || h != 2 {
+		t.Errorf("Dimensions() = %d, %d, %v; want 3, 2", w, h, err)
+	}
+
+	writeAt(t, path, time.Now())
+	if _, _, err := Dimensions(path); err == nil {
+		t.Error("Dimensions() of a file that isn't a PNG should fail")
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyResult summarizes an integrity sweep.
+type VerifyResult struct {
+	Checked int      // screenshots re-hashed
+	Corrupt []string // paths whose content no longer matches their hash
+}
+
+// Verify re-hashes every content-addressed screenshot (<sha256>.png) in dir
+// and renames mismatches to <name>.corrupt. Dedup trusts file names, so a
+// truncated file would otherwise count as "already captured" forever; moving
+// it aside lets the next capture of that image be saved again. Files named by
+// --filename-template are checked against the hash the index recorded for
+// them; files with other names are left alone. With a name key (private
+// names), a file also matches when named by the HMAC-SHA256 of its content
+// under key, so stores holding both kinds of names verify cleanly. On a
+// case-insensitive directory (drvfs), a name in upper case (e.g. renamed by a
+// Windows tool) is the same name to the filesystem, so it is verified against
+// its lower-case hash too. A cancelled ctx stops the sweep between files,
+// returning what was found so far.
+func Verify(ctx context.Context, dir string, key []byte) (VerifyResult, error) {
+	var res VerifyResult
+	ix, err := ReadIndex(dir)
+	if err != nil {
+		return res, err
+	}
+	fold, _ := CaseInsensitive(dir) // unknown: only exact lower-case names count
+	for _, pattern := range screenshotPatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return res, err
+		}
+		for _, path := range matches {
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+			if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+				continue // removed concurrently, or a symlink such as LatestLink
+			}
+			base := filepath.Base(path)
+			want := strings.TrimSuffix(base, filepath.Ext(base))
+			if fold {
+				want = strings.ToLower(want)
+			}
+			if !isHexHash(want) {
+				e, ok := ix.Find(path)
+				if !ok {
+					continue
+				}
+				want = e.Hash
+			}
+			plain, keyed, err := hashFile(path, key)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue // removed concurrently
+				}
+				return res, err
+			}
+			res.Checked++
+			if plain == want || keyed == want {
+				continue
+			}
+			if err := os.Rename(path, path+".corrupt"); err != nil {
+				return res, err
+			}
+			res.Corrupt = append(res.Corrupt, path)
+		}
+	}
+	return res, nil
+}
+
+// hashFile returns the hex SHA256 of path's content and, when key is set, its
+// hex HMAC-SHA256 under key (empty otherwise), reading the file once.
+func hashFile(path string, key []byte) (plain, keyed string, err error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from globbing the output directory
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	var w io.Writer = h
+	var m hash.Hash
+	if key != nil {
+		m = hmac.New(sha256.New, key)
+		w = io.MultiWriter(h, m)
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		return "", "", err
+	}
+	if m != nil {
+		keyed = hex.EncodeToString(m.Sum(nil))
+	}
+	return hex.EncodeToString(h.Sum(nil)), keyed, nil
+}
+
+func isHexHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeContent(t *testing.T, dir string, content []byte, corrupt bool) string {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	path := filepath.Join(dir, hex.EncodeToString(sum[:])+".png")
+	if corrupt {
+		content = content[:len(content)/2]
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	good := writeContent(t, dir, []byte("intact screenshot"), false)
+	bad := writeContent(t, filepath.Join(dir, "2024-05-01"), []byte("truncated screenshot"), true)
+	other := filepath.Join(dir, "custom-name.png")
+	if err := os.WriteFile(other, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Verify(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if res.Checked != 2 {
+		t.Errorf("Checked = %d, want 2 (non-hash names are skipped)", res.Checked)
+	}
+	if len(res.Corrupt) != 1 || res.Corrupt[0] != bad {
+		t.Fatalf("Corrupt = %v, want [%s]", res.Corrupt, bad)
+	}
+	if _, err := os.Stat(bad + ".corrupt"); err != nil {
+		t.Errorf("corrupt file should be moved aside: %v", err)
+	}
+	for _, p := range []string{good, other} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should be untouched: %v", p, err)
+		}
+	}
+}
+
+func TestVerify_PrivateNames(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte("0123456789abcdef")
+	content := []byte("private screenshot")
+	m := hmac.New(sha256.New, key)
+	m.Write(content)
+	keyed := filepath.Join(dir, hex.EncodeToString(m.Sum(nil))+".png")
+	if err := os.WriteFile(keyed, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain := writeContent(t, dir, []byte("older screenshot"), false)
+
+	res, err := Verify(context.Background(), dir, key)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if res.Checked != 2 || len(res.Corrupt) != 0 {
+		t.Errorf("Verify() = %+v, want both keyed and plain names to match", res)
+	}
+
+	// Without the key a keyed name looks corrupt.
+	if res, _ := Verify(context.Background(), dir, nil); len(res.Corrupt) != 1 || res.Corrupt[0] != keyed {
+		t.Errorf("Verify() without key = %+v, want only %s flagged", res, keyed)
+	}
+	if _, err := os.Stat(plain); err != nil {
+		t.Errorf("%s should be untouched: %v", plain, err)
+	}
+}
+
+func TestVerify_TemplateNames(t *testing.T) {
+	dir := t.TempDir()
+	record := func(name string, content []byte, corrupt bool) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		sum := sha256.Sum256(content)
+		if corrupt {
+			content = content[:len(content)/2]
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := AppendIndex(dir, path, IndexEntry{Hash: hex.EncodeToString(sum[:])}); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good := record("2024-05-01_120000_0001.png", []byte("intact screenshot"), false)
+	bad := record("2024-05-01_120500_0002.png", []byte("truncated screenshot"), true)
+	unknown := filepath.Join(dir, "not-in-index.png")
+	if err := os.WriteFile(unknown, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Verify(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if res.Checked != 2 {
+		t.Errorf("Checked = %d, want 2 (template names are checked against the index)", res.Checked)
+	}
+	if len(res.Corrupt) != 1 || res.Corrupt[0] != bad {
+		t.Fatalf("Corrupt = %v, want [%s]", res.Corrupt, bad)
+	}
+	for _, p := range []string{good, unknown} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should be untouched: %v", p, err)
+		}
+	}
+}
+
+func TestVerify_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeContent(t, dir, []byte("truncated screenshot"), true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Verify(ctx, dir, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Verify() error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(bad); err != nil {
+		t.Errorf("a cancelled sweep still touched %s: %v", bad, err)
+	}
+}